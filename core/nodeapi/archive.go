@@ -0,0 +1,87 @@
+package nodeapi
+
+import (
+	"io"
+)
+
+type (
+	// UploadArchiveRequest is a message of an archive upload stream. The first message carries the
+	// destination, the following ones carry the chunks of a tar archive extracted at that path.
+	UploadArchiveRequest struct {
+		// MachineID is only used by the node service to route the stream to the machine runtime.
+		MachineID string `json:"machine_id,omitempty"`
+		// ContainerID is resolved by the node service, which also accepts a container name and
+		// defaults to the first container of the machine.
+		ContainerID string `json:"container_id,omitempty"`
+		Path        string `json:"path,omitempty"`
+		Data        []byte `json:"data,omitempty"`
+	}
+
+	UploadArchiveResponse struct{}
+
+	// DownloadArchiveRequest streams a tar archive of a file or a directory, the entries of the
+	// archive are rooted at the base name of the path.
+	DownloadArchiveRequest struct {
+		MachineID   string `json:"machine_id,omitempty"`
+		ContainerID string `json:"container_id"`
+		Path        string `json:"path"`
+	}
+
+	DownloadArchiveResponse struct {
+		Data []byte `json:"data"`
+	}
+)
+
+// ArchiveChunkSize is the maximum size of the archive chunks sent in a single message.
+const ArchiveChunkSize = 64 * 1024
+
+type chunkReader struct {
+	receive func() ([]byte, error)
+	buf     []byte
+}
+
+// NewChunkReader returns a reader over the chunks of a stream, receive must return io.EOF once
+// the stream is complete.
+func NewChunkReader(receive func() ([]byte, error)) io.Reader {
+	return &chunkReader{receive: receive}
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		data, err := r.receive()
+		if err != nil {
+			return 0, err
+		}
+
+		r.buf = data
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+type chunkWriter struct {
+	send func(data []byte) error
+}
+
+// NewChunkWriter returns a writer sending its input as chunks of at most ArchiveChunkSize bytes.
+func NewChunkWriter(send func(data []byte) error) io.Writer {
+	return &chunkWriter{send: send}
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		size := min(len(p), ArchiveChunkSize)
+		// messages may be retained after the call, the buffer of the caller can not be shared
+		if err := w.send(append([]byte(nil), p[:size]...)); err != nil {
+			return written, err
+		}
+
+		written += size
+		p = p[size:]
+	}
+
+	return written, nil
+}
@@ -0,0 +1,48 @@
+// Package nodeapi contains the connect services of the node components that are not
+// published in baepo-proto yet. It mirrors the layout of the generated connect code so
+// that moving a service to baepo-proto only requires swapping the imports, messages are
+// plain structs encoded with encoding/json.
+package nodeapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"connectrpc.com/connect"
+)
+
+type codec struct{}
+
+var _ connect.Codec = codec{}
+
+// Name overrides the default protojson codec which only accepts proto messages.
+func (codec) Name() string {
+	return "json"
+}
+
+func (codec) Marshal(message any) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func (codec) Unmarshal(data []byte, message any) error {
+	return json.Unmarshal(data, message)
+}
+
+func newClient[Req, Res any](httpClient connect.HTTPClient, url string, opts []connect.ClientOption) *connect.Client[Req, Res] {
+	return connect.NewClient[Req, Res](
+		httpClient,
+		url,
+		connect.WithCodec(codec{}),
+		connect.WithClientOptions(opts...),
+	)
+}
+
+func newServiceHandler(handlers map[string]http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handler, ok := handlers[r.URL.Path]; ok {
+			handler.ServeHTTP(w, r)
+		} else {
+			http.NotFound(w, r)
+		}
+	})
+}
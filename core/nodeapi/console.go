@@ -0,0 +1,15 @@
+package nodeapi
+
+type (
+	// ConsoleRequest carries the input typed on the serial console of a machine.
+	ConsoleRequest struct {
+		// MachineID is only used by the node service to route the stream to the machine runtime,
+		// it must be set on the first message.
+		MachineID string `json:"machine_id,omitempty"`
+		Input     []byte `json:"input,omitempty"`
+	}
+
+	ConsoleResponse struct {
+		Output []byte `json:"output,omitempty"`
+	}
+)
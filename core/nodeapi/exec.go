@@ -0,0 +1,37 @@
+package nodeapi
+
+type (
+	// ExecRequest is a message of an exec stream. The first message of the stream must carry
+	// Start, the following ones carry the standard input and the terminal resizes.
+	ExecRequest struct {
+		Start      *ExecStart        `json:"start,omitempty"`
+		Stdin      []byte            `json:"stdin,omitempty"`
+		CloseStdin bool              `json:"close_stdin,omitempty"`
+		Resize     *ExecTerminalSize `json:"resize,omitempty"`
+	}
+
+	ExecStart struct {
+		// MachineID is only used by the node service to route the stream to the machine runtime.
+		MachineID string `json:"machine_id,omitempty"`
+		// ContainerID is resolved by the node service, which also accepts a container name and
+		// defaults to the first container of the machine.
+		ContainerID string            `json:"container_id"`
+		Command     []string          `json:"command"`
+		Env         map[string]string `json:"env,omitempty"`
+		WorkingDir  *string           `json:"working_dir,omitempty"`
+		TTY         bool              `json:"tty"`
+		Size        *ExecTerminalSize `json:"size,omitempty"`
+	}
+
+	ExecTerminalSize struct {
+		Rows uint16 `json:"rows"`
+		Cols uint16 `json:"cols"`
+	}
+
+	ExecResponse struct {
+		Stdout []byte `json:"stdout,omitempty"`
+		Stderr []byte `json:"stderr,omitempty"`
+		// ExitCode is only set on the last message of the stream, once the process has exited.
+		ExitCode *int32 `json:"exit_code,omitempty"`
+	}
+)
@@ -0,0 +1,203 @@
+package nodeapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+)
+
+type (
+	// InitConfigureRequest applies a new identity to the vm and starts the containers that are
+	// not running yet, it is used once a vm has been restored from a snapshot.
+	InitConfigureRequest struct {
+		Config coretypes.InitConfig `json:"config"`
+	}
+
+	InitConfigureResponse struct{}
+
+	InitGetStatusRequest struct{}
+
+	InitGetStatusResponse struct {
+		Hostname     string   `json:"hostname"`
+		ContainerIDs []string `json:"container_ids"`
+	}
+)
+
+const (
+	// InitName is the fully-qualified name of the Init service.
+	InitName = "baepo.nodeapi.v1.Init"
+
+	// InitConfigureProcedure is the fully-qualified name of the Init's Configure RPC.
+	InitConfigureProcedure = "/baepo.nodeapi.v1.Init/Configure"
+	// InitGetStatusProcedure is the fully-qualified name of the Init's GetStatus RPC.
+	InitGetStatusProcedure = "/baepo.nodeapi.v1.Init/GetStatus"
+	// InitExecProcedure is the fully-qualified name of the Init's Exec RPC.
+	InitExecProcedure = "/baepo.nodeapi.v1.Init/Exec"
+	// InitUploadArchiveProcedure is the fully-qualified name of the Init's UploadArchive RPC.
+	InitUploadArchiveProcedure = "/baepo.nodeapi.v1.Init/UploadArchive"
+	// InitDownloadArchiveProcedure is the fully-qualified name of the Init's DownloadArchive RPC.
+	InitDownloadArchiveProcedure = "/baepo.nodeapi.v1.Init/DownloadArchive"
+	// InitPortForwardProcedure is the fully-qualified name of the Init's PortForward RPC.
+	InitPortForwardProcedure = "/baepo.nodeapi.v1.Init/PortForward"
+	// InitGetStatsProcedure is the fully-qualified name of the Init's GetStats RPC.
+	InitGetStatsProcedure = "/baepo.nodeapi.v1.Init/GetStats"
+)
+
+// InitClient is a client for the baepo.nodeapi.v1.Init service.
+type InitClient interface {
+	Configure(context.Context, *connect.Request[InitConfigureRequest]) (*connect.Response[InitConfigureResponse], error)
+	GetStatus(context.Context, *connect.Request[InitGetStatusRequest]) (*connect.Response[InitGetStatusResponse], error)
+	Exec(context.Context) *connect.BidiStreamForClient[ExecRequest, ExecResponse]
+	UploadArchive(context.Context) *connect.ClientStreamForClient[UploadArchiveRequest, UploadArchiveResponse]
+	DownloadArchive(context.Context, *connect.Request[DownloadArchiveRequest]) (*connect.ServerStreamForClient[DownloadArchiveResponse], error)
+	PortForward(context.Context) *connect.BidiStreamForClient[PortForwardRequest, PortForwardResponse]
+	GetStats(context.Context, *connect.Request[InitGetStatsRequest]) (*connect.Response[InitGetStatsResponse], error)
+}
+
+type initClient struct {
+	configure       *connect.Client[InitConfigureRequest, InitConfigureResponse]
+	getStatus       *connect.Client[InitGetStatusRequest, InitGetStatusResponse]
+	exec            *connect.Client[ExecRequest, ExecResponse]
+	uploadArchive   *connect.Client[UploadArchiveRequest, UploadArchiveResponse]
+	downloadArchive *connect.Client[DownloadArchiveRequest, DownloadArchiveResponse]
+	portForward     *connect.Client[PortForwardRequest, PortForwardResponse]
+	getStats        *connect.Client[InitGetStatsRequest, InitGetStatsResponse]
+}
+
+// NewInitClient constructs a client for the baepo.nodeapi.v1.Init service.
+func NewInitClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) InitClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &initClient{
+		configure:       newClient[InitConfigureRequest, InitConfigureResponse](httpClient, baseURL+InitConfigureProcedure, opts),
+		getStatus:       newClient[InitGetStatusRequest, InitGetStatusResponse](httpClient, baseURL+InitGetStatusProcedure, opts),
+		exec:            newClient[ExecRequest, ExecResponse](httpClient, baseURL+InitExecProcedure, opts),
+		uploadArchive:   newClient[UploadArchiveRequest, UploadArchiveResponse](httpClient, baseURL+InitUploadArchiveProcedure, opts),
+		downloadArchive: newClient[DownloadArchiveRequest, DownloadArchiveResponse](httpClient, baseURL+InitDownloadArchiveProcedure, opts),
+		portForward:     newClient[PortForwardRequest, PortForwardResponse](httpClient, baseURL+InitPortForwardProcedure, opts),
+		getStats:        newClient[InitGetStatsRequest, InitGetStatsResponse](httpClient, baseURL+InitGetStatsProcedure, opts),
+	}
+}
+
+func (c *initClient) Configure(ctx context.Context, req *connect.Request[InitConfigureRequest]) (*connect.Response[InitConfigureResponse], error) {
+	return c.configure.CallUnary(ctx, req)
+}
+
+func (c *initClient) GetStatus(ctx context.Context, req *connect.Request[InitGetStatusRequest]) (*connect.Response[InitGetStatusResponse], error) {
+	return c.getStatus.CallUnary(ctx, req)
+}
+
+func (c *initClient) Exec(ctx context.Context) *connect.BidiStreamForClient[ExecRequest, ExecResponse] {
+	return c.exec.CallBidiStream(ctx)
+}
+
+func (c *initClient) UploadArchive(ctx context.Context) *connect.ClientStreamForClient[UploadArchiveRequest, UploadArchiveResponse] {
+	return c.uploadArchive.CallClientStream(ctx)
+}
+
+func (c *initClient) DownloadArchive(ctx context.Context, req *connect.Request[DownloadArchiveRequest]) (*connect.ServerStreamForClient[DownloadArchiveResponse], error) {
+	return c.downloadArchive.CallServerStream(ctx, req)
+}
+
+func (c *initClient) PortForward(ctx context.Context) *connect.BidiStreamForClient[PortForwardRequest, PortForwardResponse] {
+	return c.portForward.CallBidiStream(ctx)
+}
+
+func (c *initClient) GetStats(ctx context.Context, req *connect.Request[InitGetStatsRequest]) (*connect.Response[InitGetStatsResponse], error) {
+	return c.getStats.CallUnary(ctx, req)
+}
+
+// InitHandler is an implementation of the baepo.nodeapi.v1.Init service.
+type InitHandler interface {
+	Configure(context.Context, *connect.Request[InitConfigureRequest]) (*connect.Response[InitConfigureResponse], error)
+	GetStatus(context.Context, *connect.Request[InitGetStatusRequest]) (*connect.Response[InitGetStatusResponse], error)
+	Exec(context.Context, *connect.BidiStream[ExecRequest, ExecResponse]) error
+	UploadArchive(context.Context, *connect.ClientStream[UploadArchiveRequest]) (*connect.Response[UploadArchiveResponse], error)
+	DownloadArchive(context.Context, *connect.Request[DownloadArchiveRequest], *connect.ServerStream[DownloadArchiveResponse]) error
+	PortForward(context.Context, *connect.BidiStream[PortForwardRequest, PortForwardResponse]) error
+	GetStats(context.Context, *connect.Request[InitGetStatsRequest]) (*connect.Response[InitGetStatsResponse], error)
+}
+
+// NewInitHandler builds an HTTP handler from the service implementation. It returns the path on
+// which to mount the handler and the handler itself.
+func NewInitHandler(svc InitHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	handlers := map[string]http.Handler{
+		InitConfigureProcedure: connect.NewUnaryHandler(
+			InitConfigureProcedure,
+			svc.Configure,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		InitGetStatusProcedure: connect.NewUnaryHandler(
+			InitGetStatusProcedure,
+			svc.GetStatus,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		InitExecProcedure: connect.NewBidiStreamHandler(
+			InitExecProcedure,
+			svc.Exec,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		InitUploadArchiveProcedure: connect.NewClientStreamHandler(
+			InitUploadArchiveProcedure,
+			svc.UploadArchive,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		InitDownloadArchiveProcedure: connect.NewServerStreamHandler(
+			InitDownloadArchiveProcedure,
+			svc.DownloadArchive,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		InitPortForwardProcedure: connect.NewBidiStreamHandler(
+			InitPortForwardProcedure,
+			svc.PortForward,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		InitGetStatsProcedure: connect.NewUnaryHandler(
+			InitGetStatsProcedure,
+			svc.GetStats,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+	}
+	return "/" + InitName + "/", newServiceHandler(handlers)
+}
+
+// UnimplementedInitHandler returns CodeUnimplemented from all methods.
+type UnimplementedInitHandler struct{}
+
+func (UnimplementedInitHandler) Configure(context.Context, *connect.Request[InitConfigureRequest]) (*connect.Response[InitConfigureResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Init.Configure is not implemented"))
+}
+
+func (UnimplementedInitHandler) GetStatus(context.Context, *connect.Request[InitGetStatusRequest]) (*connect.Response[InitGetStatusResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Init.GetStatus is not implemented"))
+}
+
+func (UnimplementedInitHandler) Exec(context.Context, *connect.BidiStream[ExecRequest, ExecResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Init.Exec is not implemented"))
+}
+
+func (UnimplementedInitHandler) UploadArchive(context.Context, *connect.ClientStream[UploadArchiveRequest]) (*connect.Response[UploadArchiveResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Init.UploadArchive is not implemented"))
+}
+
+func (UnimplementedInitHandler) DownloadArchive(context.Context, *connect.Request[DownloadArchiveRequest], *connect.ServerStream[DownloadArchiveResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Init.DownloadArchive is not implemented"))
+}
+
+func (UnimplementedInitHandler) PortForward(context.Context, *connect.BidiStream[PortForwardRequest, PortForwardResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Init.PortForward is not implemented"))
+}
+
+func (UnimplementedInitHandler) GetStats(context.Context, *connect.Request[InitGetStatsRequest]) (*connect.Response[InitGetStatsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Init.GetStats is not implemented"))
+}
@@ -0,0 +1,532 @@
+package nodeapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+type (
+	Machine struct {
+		MachineID    string `json:"machine_id"`
+		State        string `json:"state"`
+		DesiredState string `json:"desired_state"`
+		Cpus         uint32 `json:"cpus"`
+		MemoryMB     uint64 `json:"memory_mb"`
+		// Ingress is the ingress policy of the machine, all the traffic reaches it when nil.
+		Ingress *IngressPolicy `json:"ingress,omitempty"`
+		// Network is the private network of the machine, empty for the default network.
+		Network string `json:"network,omitempty"`
+		// PublishedPorts are the ports of the node forwarded to the containers of the machine.
+		PublishedPorts []*PublishedPort `json:"published_ports,omitempty"`
+	}
+
+	PublishedPort struct {
+		ContainerID   string `json:"container_id"`
+		Protocol      string `json:"protocol"`
+		HostPort      uint16 `json:"host_port"`
+		ContainerPort uint16 `json:"container_port"`
+	}
+
+	IngressPolicy struct {
+		DefaultDeny bool           `json:"default_deny"`
+		Rules       []*IngressRule `json:"rules"`
+	}
+
+	IngressRule struct {
+		// Protocol is tcp, udp or icmp, the rule applies to every protocol when empty.
+		Protocol    string   `json:"protocol,omitempty"`
+		FromPort    uint16   `json:"from_port,omitempty"`
+		ToPort      uint16   `json:"to_port,omitempty"`
+		SourceCIDRs []string `json:"source_cidrs,omitempty"`
+	}
+
+	IngressCounter struct {
+		Packets uint64 `json:"packets"`
+		Bytes   uint64 `json:"bytes"`
+	}
+
+	GCResource struct {
+		Kind  string  `json:"kind"`
+		Name  string  `json:"name"`
+		Error *string `json:"error,omitempty"`
+	}
+
+	NodeRunGCRequest struct {
+		DryRun bool `json:"dry_run"`
+	}
+
+	NodeRunGCResponse struct {
+		DryRun    bool          `json:"dry_run"`
+		Resources []*GCResource `json:"resources"`
+		Errors    []string      `json:"errors,omitempty"`
+		StartedAt time.Time     `json:"started_at"`
+		Duration  time.Duration `json:"duration"`
+	}
+
+	NodePauseMachineRequest struct {
+		MachineID string `json:"machine_id"`
+	}
+
+	NodePauseMachineResponse struct {
+		Machine *Machine `json:"machine"`
+	}
+
+	NodeResumeMachineRequest struct {
+		MachineID string `json:"machine_id"`
+	}
+
+	NodeResumeMachineResponse struct {
+		Machine *Machine `json:"machine"`
+	}
+
+	Snapshot struct {
+		SnapshotID string    `json:"snapshot_id"`
+		MachineID  string    `json:"machine_id"`
+		Path       string    `json:"path"`
+		VolumeIDs  []string  `json:"volume_ids"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+
+	NodeCreateSnapshotRequest struct {
+		MachineID string `json:"machine_id"`
+	}
+
+	NodeCreateSnapshotResponse struct {
+		Snapshot *Snapshot `json:"snapshot"`
+	}
+
+	NodeListSnapshotsRequest struct {
+		MachineID *string `json:"machine_id,omitempty"`
+	}
+
+	NodeListSnapshotsResponse struct {
+		Snapshots []*Snapshot `json:"snapshots"`
+	}
+
+	NodeRestoreSnapshotRequest struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+
+	NodeRestoreSnapshotResponse struct {
+		Machine *Machine `json:"machine"`
+	}
+
+	NodeDeleteSnapshotRequest struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+
+	NodeDeleteSnapshotResponse struct{}
+
+	WarmPoolStats struct {
+		// Images are the images of the containers of the machines of the pool, in order.
+		Images    []string `json:"images"`
+		Cpus      uint32   `json:"cpus"`
+		MemoryMB  uint64   `json:"memory_mb"`
+		Size      int      `json:"size"`
+		Available int      `json:"available"`
+		Hits      uint64   `json:"hits"`
+		Misses    uint64   `json:"misses"`
+	}
+
+	NodeUpdateMachineSpecRequest struct {
+		MachineID string `json:"machine_id"`
+		// Cpus and MemoryMB keep their current values when both are zero.
+		Cpus     uint32 `json:"cpus"`
+		MemoryMB uint64 `json:"memory_mb"`
+		// Ingress replaces the ingress policy, the current one is kept when nil unless
+		// ClearIngress is set.
+		Ingress      *IngressPolicy `json:"ingress,omitempty"`
+		ClearIngress bool           `json:"clear_ingress,omitempty"`
+	}
+
+	NodeUpdateMachineSpecResponse struct {
+		Machine *Machine `json:"machine"`
+	}
+
+	NodeGetMachineIngressStatsRequest struct {
+		MachineID string `json:"machine_id"`
+	}
+
+	// NodeGetMachineIngressStatsResponse counts the traffic reaching the machine since its
+	// ingress policy was last applied.
+	NodeGetMachineIngressStatsResponse struct {
+		DefaultDeny bool `json:"default_deny"`
+		// Rules are the counters of the rules of the policy, in the same order.
+		Rules     []*IngressCounter `json:"rules"`
+		Unmatched *IngressCounter   `json:"unmatched"`
+	}
+
+	NodeGetWarmPoolStatsRequest struct{}
+
+	NodeGetWarmPoolStatsResponse struct {
+		Pools []*WarmPoolStats `json:"pools"`
+	}
+)
+
+const (
+	// NodeServiceName is the fully-qualified name of the NodeService service.
+	NodeServiceName = "baepo.nodeapi.v1.NodeService"
+
+	// NodeServiceRunGCProcedure is the fully-qualified name of the NodeService's RunGC RPC.
+	NodeServiceRunGCProcedure = "/baepo.nodeapi.v1.NodeService/RunGC"
+	// NodeServicePauseMachineProcedure is the fully-qualified name of the NodeService's PauseMachine RPC.
+	NodeServicePauseMachineProcedure = "/baepo.nodeapi.v1.NodeService/PauseMachine"
+	// NodeServiceResumeMachineProcedure is the fully-qualified name of the NodeService's ResumeMachine RPC.
+	NodeServiceResumeMachineProcedure = "/baepo.nodeapi.v1.NodeService/ResumeMachine"
+	// NodeServiceCreateSnapshotProcedure is the fully-qualified name of the NodeService's CreateSnapshot RPC.
+	NodeServiceCreateSnapshotProcedure = "/baepo.nodeapi.v1.NodeService/CreateSnapshot"
+	// NodeServiceListSnapshotsProcedure is the fully-qualified name of the NodeService's ListSnapshots RPC.
+	NodeServiceListSnapshotsProcedure = "/baepo.nodeapi.v1.NodeService/ListSnapshots"
+	// NodeServiceRestoreSnapshotProcedure is the fully-qualified name of the NodeService's RestoreSnapshot RPC.
+	NodeServiceRestoreSnapshotProcedure = "/baepo.nodeapi.v1.NodeService/RestoreSnapshot"
+	// NodeServiceDeleteSnapshotProcedure is the fully-qualified name of the NodeService's DeleteSnapshot RPC.
+	NodeServiceDeleteSnapshotProcedure = "/baepo.nodeapi.v1.NodeService/DeleteSnapshot"
+	// NodeServiceGetWarmPoolStatsProcedure is the fully-qualified name of the NodeService's GetWarmPoolStats RPC.
+	NodeServiceGetWarmPoolStatsProcedure = "/baepo.nodeapi.v1.NodeService/GetWarmPoolStats"
+	// NodeServiceUpdateMachineSpecProcedure is the fully-qualified name of the NodeService's UpdateMachineSpec RPC.
+	NodeServiceUpdateMachineSpecProcedure = "/baepo.nodeapi.v1.NodeService/UpdateMachineSpec"
+	// NodeServiceExecProcedure is the fully-qualified name of the NodeService's Exec RPC.
+	NodeServiceExecProcedure = "/baepo.nodeapi.v1.NodeService/Exec"
+	// NodeServiceAttachConsoleProcedure is the fully-qualified name of the NodeService's AttachConsole RPC.
+	NodeServiceAttachConsoleProcedure = "/baepo.nodeapi.v1.NodeService/AttachConsole"
+	// NodeServiceUploadArchiveProcedure is the fully-qualified name of the NodeService's UploadArchive RPC.
+	NodeServiceUploadArchiveProcedure = "/baepo.nodeapi.v1.NodeService/UploadArchive"
+	// NodeServiceDownloadArchiveProcedure is the fully-qualified name of the NodeService's DownloadArchive RPC.
+	NodeServiceDownloadArchiveProcedure = "/baepo.nodeapi.v1.NodeService/DownloadArchive"
+	// NodeServicePortForwardProcedure is the fully-qualified name of the NodeService's PortForward RPC.
+	NodeServicePortForwardProcedure = "/baepo.nodeapi.v1.NodeService/PortForward"
+	// NodeServiceGetMachineStatsProcedure is the fully-qualified name of the NodeService's GetMachineStats RPC.
+	NodeServiceGetMachineStatsProcedure = "/baepo.nodeapi.v1.NodeService/GetMachineStats"
+	// NodeServiceGetMachineIngressStatsProcedure is the fully-qualified name of the NodeService's GetMachineIngressStats RPC.
+	NodeServiceGetMachineIngressStatsProcedure = "/baepo.nodeapi.v1.NodeService/GetMachineIngressStats"
+)
+
+// NodeServiceClient is a client for the baepo.nodeapi.v1.NodeService service.
+type NodeServiceClient interface {
+	RunGC(context.Context, *connect.Request[NodeRunGCRequest]) (*connect.Response[NodeRunGCResponse], error)
+	PauseMachine(context.Context, *connect.Request[NodePauseMachineRequest]) (*connect.Response[NodePauseMachineResponse], error)
+	ResumeMachine(context.Context, *connect.Request[NodeResumeMachineRequest]) (*connect.Response[NodeResumeMachineResponse], error)
+	CreateSnapshot(context.Context, *connect.Request[NodeCreateSnapshotRequest]) (*connect.Response[NodeCreateSnapshotResponse], error)
+	ListSnapshots(context.Context, *connect.Request[NodeListSnapshotsRequest]) (*connect.Response[NodeListSnapshotsResponse], error)
+	RestoreSnapshot(context.Context, *connect.Request[NodeRestoreSnapshotRequest]) (*connect.Response[NodeRestoreSnapshotResponse], error)
+	DeleteSnapshot(context.Context, *connect.Request[NodeDeleteSnapshotRequest]) (*connect.Response[NodeDeleteSnapshotResponse], error)
+	GetWarmPoolStats(context.Context, *connect.Request[NodeGetWarmPoolStatsRequest]) (*connect.Response[NodeGetWarmPoolStatsResponse], error)
+	UpdateMachineSpec(context.Context, *connect.Request[NodeUpdateMachineSpecRequest]) (*connect.Response[NodeUpdateMachineSpecResponse], error)
+	Exec(context.Context) *connect.BidiStreamForClient[ExecRequest, ExecResponse]
+	AttachConsole(context.Context) *connect.BidiStreamForClient[ConsoleRequest, ConsoleResponse]
+	UploadArchive(context.Context) *connect.ClientStreamForClient[UploadArchiveRequest, UploadArchiveResponse]
+	DownloadArchive(context.Context, *connect.Request[DownloadArchiveRequest]) (*connect.ServerStreamForClient[DownloadArchiveResponse], error)
+	PortForward(context.Context) *connect.BidiStreamForClient[PortForwardRequest, PortForwardResponse]
+	GetMachineStats(context.Context, *connect.Request[NodeGetMachineStatsRequest]) (*connect.ServerStreamForClient[MachineStats], error)
+	GetMachineIngressStats(context.Context, *connect.Request[NodeGetMachineIngressStatsRequest]) (*connect.Response[NodeGetMachineIngressStatsResponse], error)
+}
+
+type nodeServiceClient struct {
+	runGC                  *connect.Client[NodeRunGCRequest, NodeRunGCResponse]
+	pauseMachine           *connect.Client[NodePauseMachineRequest, NodePauseMachineResponse]
+	resumeMachine          *connect.Client[NodeResumeMachineRequest, NodeResumeMachineResponse]
+	createSnapshot         *connect.Client[NodeCreateSnapshotRequest, NodeCreateSnapshotResponse]
+	listSnapshots          *connect.Client[NodeListSnapshotsRequest, NodeListSnapshotsResponse]
+	restoreSnapshot        *connect.Client[NodeRestoreSnapshotRequest, NodeRestoreSnapshotResponse]
+	deleteSnapshot         *connect.Client[NodeDeleteSnapshotRequest, NodeDeleteSnapshotResponse]
+	getWarmPoolStats       *connect.Client[NodeGetWarmPoolStatsRequest, NodeGetWarmPoolStatsResponse]
+	updateMachineSpec      *connect.Client[NodeUpdateMachineSpecRequest, NodeUpdateMachineSpecResponse]
+	exec                   *connect.Client[ExecRequest, ExecResponse]
+	attachConsole          *connect.Client[ConsoleRequest, ConsoleResponse]
+	uploadArchive          *connect.Client[UploadArchiveRequest, UploadArchiveResponse]
+	downloadArchive        *connect.Client[DownloadArchiveRequest, DownloadArchiveResponse]
+	portForward            *connect.Client[PortForwardRequest, PortForwardResponse]
+	getMachineStats        *connect.Client[NodeGetMachineStatsRequest, MachineStats]
+	getMachineIngressStats *connect.Client[NodeGetMachineIngressStatsRequest, NodeGetMachineIngressStatsResponse]
+}
+
+// NewNodeServiceClient constructs a client for the baepo.nodeapi.v1.NodeService service.
+func NewNodeServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) NodeServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &nodeServiceClient{
+		runGC: newClient[NodeRunGCRequest, NodeRunGCResponse](httpClient, baseURL+NodeServiceRunGCProcedure, opts),
+		pauseMachine: newClient[NodePauseMachineRequest, NodePauseMachineResponse](
+			httpClient, baseURL+NodeServicePauseMachineProcedure, opts),
+		resumeMachine: newClient[NodeResumeMachineRequest, NodeResumeMachineResponse](
+			httpClient, baseURL+NodeServiceResumeMachineProcedure, opts),
+		createSnapshot: newClient[NodeCreateSnapshotRequest, NodeCreateSnapshotResponse](
+			httpClient, baseURL+NodeServiceCreateSnapshotProcedure, opts),
+		listSnapshots: newClient[NodeListSnapshotsRequest, NodeListSnapshotsResponse](
+			httpClient, baseURL+NodeServiceListSnapshotsProcedure, opts),
+		restoreSnapshot: newClient[NodeRestoreSnapshotRequest, NodeRestoreSnapshotResponse](
+			httpClient, baseURL+NodeServiceRestoreSnapshotProcedure, opts),
+		deleteSnapshot: newClient[NodeDeleteSnapshotRequest, NodeDeleteSnapshotResponse](
+			httpClient, baseURL+NodeServiceDeleteSnapshotProcedure, opts),
+		getWarmPoolStats: newClient[NodeGetWarmPoolStatsRequest, NodeGetWarmPoolStatsResponse](
+			httpClient, baseURL+NodeServiceGetWarmPoolStatsProcedure, opts),
+		updateMachineSpec: newClient[NodeUpdateMachineSpecRequest, NodeUpdateMachineSpecResponse](
+			httpClient, baseURL+NodeServiceUpdateMachineSpecProcedure, opts),
+		exec:                   newClient[ExecRequest, ExecResponse](httpClient, baseURL+NodeServiceExecProcedure, opts),
+		attachConsole:          newClient[ConsoleRequest, ConsoleResponse](httpClient, baseURL+NodeServiceAttachConsoleProcedure, opts),
+		uploadArchive:          newClient[UploadArchiveRequest, UploadArchiveResponse](httpClient, baseURL+NodeServiceUploadArchiveProcedure, opts),
+		downloadArchive:        newClient[DownloadArchiveRequest, DownloadArchiveResponse](httpClient, baseURL+NodeServiceDownloadArchiveProcedure, opts),
+		portForward:            newClient[PortForwardRequest, PortForwardResponse](httpClient, baseURL+NodeServicePortForwardProcedure, opts),
+		getMachineStats:        newClient[NodeGetMachineStatsRequest, MachineStats](httpClient, baseURL+NodeServiceGetMachineStatsProcedure, opts),
+		getMachineIngressStats: newClient[NodeGetMachineIngressStatsRequest, NodeGetMachineIngressStatsResponse](httpClient, baseURL+NodeServiceGetMachineIngressStatsProcedure, opts),
+	}
+}
+
+func (c *nodeServiceClient) RunGC(ctx context.Context, req *connect.Request[NodeRunGCRequest]) (*connect.Response[NodeRunGCResponse], error) {
+	return c.runGC.CallUnary(ctx, req)
+}
+
+func (c *nodeServiceClient) PauseMachine(ctx context.Context, req *connect.Request[NodePauseMachineRequest]) (*connect.Response[NodePauseMachineResponse], error) {
+	return c.pauseMachine.CallUnary(ctx, req)
+}
+
+func (c *nodeServiceClient) ResumeMachine(ctx context.Context, req *connect.Request[NodeResumeMachineRequest]) (*connect.Response[NodeResumeMachineResponse], error) {
+	return c.resumeMachine.CallUnary(ctx, req)
+}
+
+func (c *nodeServiceClient) CreateSnapshot(ctx context.Context, req *connect.Request[NodeCreateSnapshotRequest]) (*connect.Response[NodeCreateSnapshotResponse], error) {
+	return c.createSnapshot.CallUnary(ctx, req)
+}
+
+func (c *nodeServiceClient) ListSnapshots(ctx context.Context, req *connect.Request[NodeListSnapshotsRequest]) (*connect.Response[NodeListSnapshotsResponse], error) {
+	return c.listSnapshots.CallUnary(ctx, req)
+}
+
+func (c *nodeServiceClient) RestoreSnapshot(ctx context.Context, req *connect.Request[NodeRestoreSnapshotRequest]) (*connect.Response[NodeRestoreSnapshotResponse], error) {
+	return c.restoreSnapshot.CallUnary(ctx, req)
+}
+
+func (c *nodeServiceClient) DeleteSnapshot(ctx context.Context, req *connect.Request[NodeDeleteSnapshotRequest]) (*connect.Response[NodeDeleteSnapshotResponse], error) {
+	return c.deleteSnapshot.CallUnary(ctx, req)
+}
+
+func (c *nodeServiceClient) GetWarmPoolStats(ctx context.Context, req *connect.Request[NodeGetWarmPoolStatsRequest]) (*connect.Response[NodeGetWarmPoolStatsResponse], error) {
+	return c.getWarmPoolStats.CallUnary(ctx, req)
+}
+
+func (c *nodeServiceClient) UpdateMachineSpec(ctx context.Context, req *connect.Request[NodeUpdateMachineSpecRequest]) (*connect.Response[NodeUpdateMachineSpecResponse], error) {
+	return c.updateMachineSpec.CallUnary(ctx, req)
+}
+
+func (c *nodeServiceClient) Exec(ctx context.Context) *connect.BidiStreamForClient[ExecRequest, ExecResponse] {
+	return c.exec.CallBidiStream(ctx)
+}
+
+func (c *nodeServiceClient) AttachConsole(ctx context.Context) *connect.BidiStreamForClient[ConsoleRequest, ConsoleResponse] {
+	return c.attachConsole.CallBidiStream(ctx)
+}
+
+func (c *nodeServiceClient) UploadArchive(ctx context.Context) *connect.ClientStreamForClient[UploadArchiveRequest, UploadArchiveResponse] {
+	return c.uploadArchive.CallClientStream(ctx)
+}
+
+func (c *nodeServiceClient) DownloadArchive(ctx context.Context, req *connect.Request[DownloadArchiveRequest]) (*connect.ServerStreamForClient[DownloadArchiveResponse], error) {
+	return c.downloadArchive.CallServerStream(ctx, req)
+}
+
+func (c *nodeServiceClient) PortForward(ctx context.Context) *connect.BidiStreamForClient[PortForwardRequest, PortForwardResponse] {
+	return c.portForward.CallBidiStream(ctx)
+}
+
+func (c *nodeServiceClient) GetMachineStats(ctx context.Context, req *connect.Request[NodeGetMachineStatsRequest]) (*connect.ServerStreamForClient[MachineStats], error) {
+	return c.getMachineStats.CallServerStream(ctx, req)
+}
+
+func (c *nodeServiceClient) GetMachineIngressStats(ctx context.Context, req *connect.Request[NodeGetMachineIngressStatsRequest]) (*connect.Response[NodeGetMachineIngressStatsResponse], error) {
+	return c.getMachineIngressStats.CallUnary(ctx, req)
+}
+
+// NodeServiceHandler is an implementation of the baepo.nodeapi.v1.NodeService service.
+type NodeServiceHandler interface {
+	RunGC(context.Context, *connect.Request[NodeRunGCRequest]) (*connect.Response[NodeRunGCResponse], error)
+	PauseMachine(context.Context, *connect.Request[NodePauseMachineRequest]) (*connect.Response[NodePauseMachineResponse], error)
+	ResumeMachine(context.Context, *connect.Request[NodeResumeMachineRequest]) (*connect.Response[NodeResumeMachineResponse], error)
+	CreateSnapshot(context.Context, *connect.Request[NodeCreateSnapshotRequest]) (*connect.Response[NodeCreateSnapshotResponse], error)
+	ListSnapshots(context.Context, *connect.Request[NodeListSnapshotsRequest]) (*connect.Response[NodeListSnapshotsResponse], error)
+	RestoreSnapshot(context.Context, *connect.Request[NodeRestoreSnapshotRequest]) (*connect.Response[NodeRestoreSnapshotResponse], error)
+	DeleteSnapshot(context.Context, *connect.Request[NodeDeleteSnapshotRequest]) (*connect.Response[NodeDeleteSnapshotResponse], error)
+	GetWarmPoolStats(context.Context, *connect.Request[NodeGetWarmPoolStatsRequest]) (*connect.Response[NodeGetWarmPoolStatsResponse], error)
+	UpdateMachineSpec(context.Context, *connect.Request[NodeUpdateMachineSpecRequest]) (*connect.Response[NodeUpdateMachineSpecResponse], error)
+	Exec(context.Context, *connect.BidiStream[ExecRequest, ExecResponse]) error
+	AttachConsole(context.Context, *connect.BidiStream[ConsoleRequest, ConsoleResponse]) error
+	UploadArchive(context.Context, *connect.ClientStream[UploadArchiveRequest]) (*connect.Response[UploadArchiveResponse], error)
+	DownloadArchive(context.Context, *connect.Request[DownloadArchiveRequest], *connect.ServerStream[DownloadArchiveResponse]) error
+	PortForward(context.Context, *connect.BidiStream[PortForwardRequest, PortForwardResponse]) error
+	GetMachineStats(context.Context, *connect.Request[NodeGetMachineStatsRequest], *connect.ServerStream[MachineStats]) error
+	GetMachineIngressStats(context.Context, *connect.Request[NodeGetMachineIngressStatsRequest]) (*connect.Response[NodeGetMachineIngressStatsResponse], error)
+}
+
+// NewNodeServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+func NewNodeServiceHandler(svc NodeServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	handlers := map[string]http.Handler{
+		NodeServiceRunGCProcedure: connect.NewUnaryHandler(
+			NodeServiceRunGCProcedure,
+			svc.RunGC,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServicePauseMachineProcedure: connect.NewUnaryHandler(
+			NodeServicePauseMachineProcedure,
+			svc.PauseMachine,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceResumeMachineProcedure: connect.NewUnaryHandler(
+			NodeServiceResumeMachineProcedure,
+			svc.ResumeMachine,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceCreateSnapshotProcedure: connect.NewUnaryHandler(
+			NodeServiceCreateSnapshotProcedure,
+			svc.CreateSnapshot,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceListSnapshotsProcedure: connect.NewUnaryHandler(
+			NodeServiceListSnapshotsProcedure,
+			svc.ListSnapshots,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceRestoreSnapshotProcedure: connect.NewUnaryHandler(
+			NodeServiceRestoreSnapshotProcedure,
+			svc.RestoreSnapshot,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceDeleteSnapshotProcedure: connect.NewUnaryHandler(
+			NodeServiceDeleteSnapshotProcedure,
+			svc.DeleteSnapshot,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceGetWarmPoolStatsProcedure: connect.NewUnaryHandler(
+			NodeServiceGetWarmPoolStatsProcedure,
+			svc.GetWarmPoolStats,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceUpdateMachineSpecProcedure: connect.NewUnaryHandler(
+			NodeServiceUpdateMachineSpecProcedure,
+			svc.UpdateMachineSpec,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceExecProcedure: connect.NewBidiStreamHandler(
+			NodeServiceExecProcedure,
+			svc.Exec,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceAttachConsoleProcedure: connect.NewBidiStreamHandler(
+			NodeServiceAttachConsoleProcedure,
+			svc.AttachConsole,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceUploadArchiveProcedure: connect.NewClientStreamHandler(
+			NodeServiceUploadArchiveProcedure,
+			svc.UploadArchive,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceDownloadArchiveProcedure: connect.NewServerStreamHandler(
+			NodeServiceDownloadArchiveProcedure,
+			svc.DownloadArchive,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServicePortForwardProcedure: connect.NewBidiStreamHandler(
+			NodeServicePortForwardProcedure,
+			svc.PortForward,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceGetMachineStatsProcedure: connect.NewServerStreamHandler(
+			NodeServiceGetMachineStatsProcedure,
+			svc.GetMachineStats,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		NodeServiceGetMachineIngressStatsProcedure: connect.NewUnaryHandler(
+			NodeServiceGetMachineIngressStatsProcedure,
+			svc.GetMachineIngressStats,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+	}
+	return "/" + NodeServiceName + "/", newServiceHandler(handlers)
+}
+
+// UnimplementedNodeServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedNodeServiceHandler struct{}
+
+func (UnimplementedNodeServiceHandler) RunGC(context.Context, *connect.Request[NodeRunGCRequest]) (*connect.Response[NodeRunGCResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.RunGC is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) PauseMachine(context.Context, *connect.Request[NodePauseMachineRequest]) (*connect.Response[NodePauseMachineResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.PauseMachine is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) ResumeMachine(context.Context, *connect.Request[NodeResumeMachineRequest]) (*connect.Response[NodeResumeMachineResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.ResumeMachine is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) CreateSnapshot(context.Context, *connect.Request[NodeCreateSnapshotRequest]) (*connect.Response[NodeCreateSnapshotResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.CreateSnapshot is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) ListSnapshots(context.Context, *connect.Request[NodeListSnapshotsRequest]) (*connect.Response[NodeListSnapshotsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.ListSnapshots is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) RestoreSnapshot(context.Context, *connect.Request[NodeRestoreSnapshotRequest]) (*connect.Response[NodeRestoreSnapshotResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.RestoreSnapshot is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) DeleteSnapshot(context.Context, *connect.Request[NodeDeleteSnapshotRequest]) (*connect.Response[NodeDeleteSnapshotResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.DeleteSnapshot is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) GetWarmPoolStats(context.Context, *connect.Request[NodeGetWarmPoolStatsRequest]) (*connect.Response[NodeGetWarmPoolStatsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.GetWarmPoolStats is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) UpdateMachineSpec(context.Context, *connect.Request[NodeUpdateMachineSpecRequest]) (*connect.Response[NodeUpdateMachineSpecResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.UpdateMachineSpec is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) Exec(context.Context, *connect.BidiStream[ExecRequest, ExecResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.Exec is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) AttachConsole(context.Context, *connect.BidiStream[ConsoleRequest, ConsoleResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.AttachConsole is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) UploadArchive(context.Context, *connect.ClientStream[UploadArchiveRequest]) (*connect.Response[UploadArchiveResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.UploadArchive is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) DownloadArchive(context.Context, *connect.Request[DownloadArchiveRequest], *connect.ServerStream[DownloadArchiveResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.DownloadArchive is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) PortForward(context.Context, *connect.BidiStream[PortForwardRequest, PortForwardResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.PortForward is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) GetMachineStats(context.Context, *connect.Request[NodeGetMachineStatsRequest], *connect.ServerStream[MachineStats]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.GetMachineStats is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) GetMachineIngressStats(context.Context, *connect.Request[NodeGetMachineIngressStatsRequest]) (*connect.Response[NodeGetMachineIngressStatsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.NodeService.GetMachineIngressStats is not implemented"))
+}
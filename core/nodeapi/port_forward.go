@@ -0,0 +1,20 @@
+package nodeapi
+
+type (
+	// PortForwardRequest is a message of a port forward stream, it tunnels a single tcp connection
+	// to a port of the guest loopback. The first message must set the port, the following ones
+	// carry the data written to the connection.
+	PortForwardRequest struct {
+		// MachineID is only used by the node service to route the stream to the machine runtime.
+		MachineID string `json:"machine_id,omitempty"`
+		// ContainerID is resolved by the node service, the containers of a machine share the
+		// network of the guest so it is only used to check that the container exists.
+		ContainerID string `json:"container_id,omitempty"`
+		Port        uint32 `json:"port,omitempty"`
+		Data        []byte `json:"data,omitempty"`
+	}
+
+	PortForwardResponse struct {
+		Data []byte `json:"data,omitempty"`
+	}
+)
@@ -0,0 +1,326 @@
+package nodeapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+)
+
+type (
+	RuntimePauseRequest struct{}
+
+	RuntimePauseResponse struct{}
+
+	RuntimeResumeRequest struct{}
+
+	RuntimeResumeResponse struct{}
+
+	RuntimeSnapshotRequest struct {
+		// Directory is where the vm memory and device state are written, it must exist.
+		Directory string `json:"directory"`
+	}
+
+	RuntimeSnapshotResponse struct{}
+
+	RuntimeGetInitStatusRequest struct{}
+
+	RuntimeGetInitStatusResponse struct {
+		Status *InitGetStatusResponse `json:"status"`
+	}
+
+	RuntimeResizeRequest struct {
+		Cpus     uint32 `json:"cpus"`
+		MemoryMB uint64 `json:"memory_mb"`
+	}
+
+	RuntimeResizeResponse struct{}
+
+	RuntimeAdoptRequest struct {
+		// Config is the config of the machine taking over the vm, its working directory, disks and
+		// network interface must be the ones the vm was started with.
+		Config coretypes.RuntimeConfig `json:"config"`
+	}
+
+	RuntimeAdoptResponse struct{}
+)
+
+const (
+	// RuntimeName is the fully-qualified name of the Runtime service.
+	RuntimeName = "baepo.nodeapi.v1.Runtime"
+
+	// RuntimePauseProcedure is the fully-qualified name of the Runtime's Pause RPC.
+	RuntimePauseProcedure = "/baepo.nodeapi.v1.Runtime/Pause"
+	// RuntimeResumeProcedure is the fully-qualified name of the Runtime's Resume RPC.
+	RuntimeResumeProcedure = "/baepo.nodeapi.v1.Runtime/Resume"
+	// RuntimeSnapshotProcedure is the fully-qualified name of the Runtime's Snapshot RPC.
+	RuntimeSnapshotProcedure = "/baepo.nodeapi.v1.Runtime/Snapshot"
+	// RuntimeGetInitStatusProcedure is the fully-qualified name of the Runtime's GetInitStatus RPC.
+	RuntimeGetInitStatusProcedure = "/baepo.nodeapi.v1.Runtime/GetInitStatus"
+	// RuntimeResizeProcedure is the fully-qualified name of the Runtime's Resize RPC.
+	RuntimeResizeProcedure = "/baepo.nodeapi.v1.Runtime/Resize"
+	// RuntimeAdoptProcedure is the fully-qualified name of the Runtime's Adopt RPC.
+	RuntimeAdoptProcedure = "/baepo.nodeapi.v1.Runtime/Adopt"
+	// RuntimeExecProcedure is the fully-qualified name of the Runtime's Exec RPC.
+	RuntimeExecProcedure = "/baepo.nodeapi.v1.Runtime/Exec"
+	// RuntimeAttachConsoleProcedure is the fully-qualified name of the Runtime's AttachConsole RPC.
+	RuntimeAttachConsoleProcedure = "/baepo.nodeapi.v1.Runtime/AttachConsole"
+	// RuntimeUploadArchiveProcedure is the fully-qualified name of the Runtime's UploadArchive RPC.
+	RuntimeUploadArchiveProcedure = "/baepo.nodeapi.v1.Runtime/UploadArchive"
+	// RuntimeDownloadArchiveProcedure is the fully-qualified name of the Runtime's DownloadArchive RPC.
+	RuntimeDownloadArchiveProcedure = "/baepo.nodeapi.v1.Runtime/DownloadArchive"
+	// RuntimePortForwardProcedure is the fully-qualified name of the Runtime's PortForward RPC.
+	RuntimePortForwardProcedure = "/baepo.nodeapi.v1.Runtime/PortForward"
+	// RuntimeGetStatsProcedure is the fully-qualified name of the Runtime's GetStats RPC.
+	RuntimeGetStatsProcedure = "/baepo.nodeapi.v1.Runtime/GetStats"
+)
+
+// RuntimeClient is a client for the baepo.nodeapi.v1.Runtime service.
+type RuntimeClient interface {
+	Pause(context.Context, *connect.Request[RuntimePauseRequest]) (*connect.Response[RuntimePauseResponse], error)
+	Resume(context.Context, *connect.Request[RuntimeResumeRequest]) (*connect.Response[RuntimeResumeResponse], error)
+	Snapshot(context.Context, *connect.Request[RuntimeSnapshotRequest]) (*connect.Response[RuntimeSnapshotResponse], error)
+	GetInitStatus(context.Context, *connect.Request[RuntimeGetInitStatusRequest]) (*connect.Response[RuntimeGetInitStatusResponse], error)
+	Resize(context.Context, *connect.Request[RuntimeResizeRequest]) (*connect.Response[RuntimeResizeResponse], error)
+	Adopt(context.Context, *connect.Request[RuntimeAdoptRequest]) (*connect.Response[RuntimeAdoptResponse], error)
+	Exec(context.Context) *connect.BidiStreamForClient[ExecRequest, ExecResponse]
+	AttachConsole(context.Context) *connect.BidiStreamForClient[ConsoleRequest, ConsoleResponse]
+	UploadArchive(context.Context) *connect.ClientStreamForClient[UploadArchiveRequest, UploadArchiveResponse]
+	DownloadArchive(context.Context, *connect.Request[DownloadArchiveRequest]) (*connect.ServerStreamForClient[DownloadArchiveResponse], error)
+	PortForward(context.Context) *connect.BidiStreamForClient[PortForwardRequest, PortForwardResponse]
+	GetStats(context.Context, *connect.Request[RuntimeGetStatsRequest]) (*connect.ServerStreamForClient[MachineStats], error)
+}
+
+type runtimeClient struct {
+	pause           *connect.Client[RuntimePauseRequest, RuntimePauseResponse]
+	resume          *connect.Client[RuntimeResumeRequest, RuntimeResumeResponse]
+	snapshot        *connect.Client[RuntimeSnapshotRequest, RuntimeSnapshotResponse]
+	getInitStatus   *connect.Client[RuntimeGetInitStatusRequest, RuntimeGetInitStatusResponse]
+	resize          *connect.Client[RuntimeResizeRequest, RuntimeResizeResponse]
+	adopt           *connect.Client[RuntimeAdoptRequest, RuntimeAdoptResponse]
+	exec            *connect.Client[ExecRequest, ExecResponse]
+	attachConsole   *connect.Client[ConsoleRequest, ConsoleResponse]
+	uploadArchive   *connect.Client[UploadArchiveRequest, UploadArchiveResponse]
+	downloadArchive *connect.Client[DownloadArchiveRequest, DownloadArchiveResponse]
+	portForward     *connect.Client[PortForwardRequest, PortForwardResponse]
+	getStats        *connect.Client[RuntimeGetStatsRequest, MachineStats]
+}
+
+// NewRuntimeClient constructs a client for the baepo.nodeapi.v1.Runtime service.
+func NewRuntimeClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) RuntimeClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &runtimeClient{
+		pause:  newClient[RuntimePauseRequest, RuntimePauseResponse](httpClient, baseURL+RuntimePauseProcedure, opts),
+		resume: newClient[RuntimeResumeRequest, RuntimeResumeResponse](httpClient, baseURL+RuntimeResumeProcedure, opts),
+		snapshot: newClient[RuntimeSnapshotRequest, RuntimeSnapshotResponse](
+			httpClient, baseURL+RuntimeSnapshotProcedure, opts),
+		getInitStatus: newClient[RuntimeGetInitStatusRequest, RuntimeGetInitStatusResponse](
+			httpClient, baseURL+RuntimeGetInitStatusProcedure, opts),
+		resize:          newClient[RuntimeResizeRequest, RuntimeResizeResponse](httpClient, baseURL+RuntimeResizeProcedure, opts),
+		adopt:           newClient[RuntimeAdoptRequest, RuntimeAdoptResponse](httpClient, baseURL+RuntimeAdoptProcedure, opts),
+		exec:            newClient[ExecRequest, ExecResponse](httpClient, baseURL+RuntimeExecProcedure, opts),
+		attachConsole:   newClient[ConsoleRequest, ConsoleResponse](httpClient, baseURL+RuntimeAttachConsoleProcedure, opts),
+		uploadArchive:   newClient[UploadArchiveRequest, UploadArchiveResponse](httpClient, baseURL+RuntimeUploadArchiveProcedure, opts),
+		downloadArchive: newClient[DownloadArchiveRequest, DownloadArchiveResponse](httpClient, baseURL+RuntimeDownloadArchiveProcedure, opts),
+		portForward:     newClient[PortForwardRequest, PortForwardResponse](httpClient, baseURL+RuntimePortForwardProcedure, opts),
+		getStats:        newClient[RuntimeGetStatsRequest, MachineStats](httpClient, baseURL+RuntimeGetStatsProcedure, opts),
+	}
+}
+
+func (c *runtimeClient) Pause(ctx context.Context, req *connect.Request[RuntimePauseRequest]) (*connect.Response[RuntimePauseResponse], error) {
+	return c.pause.CallUnary(ctx, req)
+}
+
+func (c *runtimeClient) Resume(ctx context.Context, req *connect.Request[RuntimeResumeRequest]) (*connect.Response[RuntimeResumeResponse], error) {
+	return c.resume.CallUnary(ctx, req)
+}
+
+func (c *runtimeClient) Snapshot(ctx context.Context, req *connect.Request[RuntimeSnapshotRequest]) (*connect.Response[RuntimeSnapshotResponse], error) {
+	return c.snapshot.CallUnary(ctx, req)
+}
+
+func (c *runtimeClient) GetInitStatus(ctx context.Context, req *connect.Request[RuntimeGetInitStatusRequest]) (*connect.Response[RuntimeGetInitStatusResponse], error) {
+	return c.getInitStatus.CallUnary(ctx, req)
+}
+
+func (c *runtimeClient) Resize(ctx context.Context, req *connect.Request[RuntimeResizeRequest]) (*connect.Response[RuntimeResizeResponse], error) {
+	return c.resize.CallUnary(ctx, req)
+}
+
+func (c *runtimeClient) Adopt(ctx context.Context, req *connect.Request[RuntimeAdoptRequest]) (*connect.Response[RuntimeAdoptResponse], error) {
+	return c.adopt.CallUnary(ctx, req)
+}
+
+func (c *runtimeClient) Exec(ctx context.Context) *connect.BidiStreamForClient[ExecRequest, ExecResponse] {
+	return c.exec.CallBidiStream(ctx)
+}
+
+func (c *runtimeClient) AttachConsole(ctx context.Context) *connect.BidiStreamForClient[ConsoleRequest, ConsoleResponse] {
+	return c.attachConsole.CallBidiStream(ctx)
+}
+
+func (c *runtimeClient) UploadArchive(ctx context.Context) *connect.ClientStreamForClient[UploadArchiveRequest, UploadArchiveResponse] {
+	return c.uploadArchive.CallClientStream(ctx)
+}
+
+func (c *runtimeClient) DownloadArchive(ctx context.Context, req *connect.Request[DownloadArchiveRequest]) (*connect.ServerStreamForClient[DownloadArchiveResponse], error) {
+	return c.downloadArchive.CallServerStream(ctx, req)
+}
+
+func (c *runtimeClient) PortForward(ctx context.Context) *connect.BidiStreamForClient[PortForwardRequest, PortForwardResponse] {
+	return c.portForward.CallBidiStream(ctx)
+}
+
+func (c *runtimeClient) GetStats(ctx context.Context, req *connect.Request[RuntimeGetStatsRequest]) (*connect.ServerStreamForClient[MachineStats], error) {
+	return c.getStats.CallServerStream(ctx, req)
+}
+
+// RuntimeHandler is an implementation of the baepo.nodeapi.v1.Runtime service.
+type RuntimeHandler interface {
+	Pause(context.Context, *connect.Request[RuntimePauseRequest]) (*connect.Response[RuntimePauseResponse], error)
+	Resume(context.Context, *connect.Request[RuntimeResumeRequest]) (*connect.Response[RuntimeResumeResponse], error)
+	Snapshot(context.Context, *connect.Request[RuntimeSnapshotRequest]) (*connect.Response[RuntimeSnapshotResponse], error)
+	GetInitStatus(context.Context, *connect.Request[RuntimeGetInitStatusRequest]) (*connect.Response[RuntimeGetInitStatusResponse], error)
+	Resize(context.Context, *connect.Request[RuntimeResizeRequest]) (*connect.Response[RuntimeResizeResponse], error)
+	Adopt(context.Context, *connect.Request[RuntimeAdoptRequest]) (*connect.Response[RuntimeAdoptResponse], error)
+	Exec(context.Context, *connect.BidiStream[ExecRequest, ExecResponse]) error
+	AttachConsole(context.Context, *connect.BidiStream[ConsoleRequest, ConsoleResponse]) error
+	UploadArchive(context.Context, *connect.ClientStream[UploadArchiveRequest]) (*connect.Response[UploadArchiveResponse], error)
+	DownloadArchive(context.Context, *connect.Request[DownloadArchiveRequest], *connect.ServerStream[DownloadArchiveResponse]) error
+	PortForward(context.Context, *connect.BidiStream[PortForwardRequest, PortForwardResponse]) error
+	GetStats(context.Context, *connect.Request[RuntimeGetStatsRequest], *connect.ServerStream[MachineStats]) error
+}
+
+// NewRuntimeHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+func NewRuntimeHandler(svc RuntimeHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	handlers := map[string]http.Handler{
+		RuntimePauseProcedure: connect.NewUnaryHandler(
+			RuntimePauseProcedure,
+			svc.Pause,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeResumeProcedure: connect.NewUnaryHandler(
+			RuntimeResumeProcedure,
+			svc.Resume,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeSnapshotProcedure: connect.NewUnaryHandler(
+			RuntimeSnapshotProcedure,
+			svc.Snapshot,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeGetInitStatusProcedure: connect.NewUnaryHandler(
+			RuntimeGetInitStatusProcedure,
+			svc.GetInitStatus,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeResizeProcedure: connect.NewUnaryHandler(
+			RuntimeResizeProcedure,
+			svc.Resize,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeAdoptProcedure: connect.NewUnaryHandler(
+			RuntimeAdoptProcedure,
+			svc.Adopt,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeExecProcedure: connect.NewBidiStreamHandler(
+			RuntimeExecProcedure,
+			svc.Exec,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeAttachConsoleProcedure: connect.NewBidiStreamHandler(
+			RuntimeAttachConsoleProcedure,
+			svc.AttachConsole,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeUploadArchiveProcedure: connect.NewClientStreamHandler(
+			RuntimeUploadArchiveProcedure,
+			svc.UploadArchive,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeDownloadArchiveProcedure: connect.NewServerStreamHandler(
+			RuntimeDownloadArchiveProcedure,
+			svc.DownloadArchive,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimePortForwardProcedure: connect.NewBidiStreamHandler(
+			RuntimePortForwardProcedure,
+			svc.PortForward,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+		RuntimeGetStatsProcedure: connect.NewServerStreamHandler(
+			RuntimeGetStatsProcedure,
+			svc.GetStats,
+			connect.WithCodec(codec{}),
+			connect.WithHandlerOptions(opts...),
+		),
+	}
+	return "/" + RuntimeName + "/", newServiceHandler(handlers)
+}
+
+// UnimplementedRuntimeHandler returns CodeUnimplemented from all methods.
+type UnimplementedRuntimeHandler struct{}
+
+func (UnimplementedRuntimeHandler) Pause(context.Context, *connect.Request[RuntimePauseRequest]) (*connect.Response[RuntimePauseResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.Pause is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) Resume(context.Context, *connect.Request[RuntimeResumeRequest]) (*connect.Response[RuntimeResumeResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.Resume is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) Snapshot(context.Context, *connect.Request[RuntimeSnapshotRequest]) (*connect.Response[RuntimeSnapshotResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.Snapshot is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) GetInitStatus(context.Context, *connect.Request[RuntimeGetInitStatusRequest]) (*connect.Response[RuntimeGetInitStatusResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.GetInitStatus is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) Resize(context.Context, *connect.Request[RuntimeResizeRequest]) (*connect.Response[RuntimeResizeResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.Resize is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) Adopt(context.Context, *connect.Request[RuntimeAdoptRequest]) (*connect.Response[RuntimeAdoptResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.Adopt is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) Exec(context.Context, *connect.BidiStream[ExecRequest, ExecResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.Exec is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) AttachConsole(context.Context, *connect.BidiStream[ConsoleRequest, ConsoleResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.AttachConsole is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) UploadArchive(context.Context, *connect.ClientStream[UploadArchiveRequest]) (*connect.Response[UploadArchiveResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.UploadArchive is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) DownloadArchive(context.Context, *connect.Request[DownloadArchiveRequest], *connect.ServerStream[DownloadArchiveResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.DownloadArchive is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) PortForward(context.Context, *connect.BidiStream[PortForwardRequest, PortForwardResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.PortForward is not implemented"))
+}
+
+func (UnimplementedRuntimeHandler) GetStats(context.Context, *connect.Request[RuntimeGetStatsRequest], *connect.ServerStream[MachineStats]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("baepo.nodeapi.v1.Runtime.GetStats is not implemented"))
+}
@@ -0,0 +1,83 @@
+package nodeapi
+
+import "time"
+
+type (
+	InitGetStatsRequest struct{}
+
+	// InitGetStatsResponse holds the counters read by init from the proc filesystem of the guest,
+	// they are cumulative since the guest booted.
+	InitGetStatsResponse struct {
+		// CPUTimeUs is the time spent by all the cpus of the guest outside of idle and iowait.
+		CPUTimeUs            uint64                `json:"cpu_time_us"`
+		MemoryTotalBytes     uint64                `json:"memory_total_bytes"`
+		MemoryAvailableBytes uint64                `json:"memory_available_bytes"`
+		Containers           []*InitContainerStats `json:"containers"`
+	}
+
+	// InitContainerStats sums the counters of the process tree of a container.
+	InitContainerStats struct {
+		ContainerID    string `json:"container_id"`
+		Processes      uint32 `json:"processes"`
+		CPUTimeUs      uint64 `json:"cpu_time_us"`
+		MemoryRSSBytes uint64 `json:"memory_rss_bytes"`
+	}
+
+	RuntimeGetStatsRequest struct {
+		// IntervalSeconds is the time between two samples, it defaults to DefaultStatsInterval.
+		IntervalSeconds uint32 `json:"interval_seconds,omitempty"`
+	}
+
+	NodeGetMachineStatsRequest struct {
+		MachineID       string `json:"machine_id"`
+		IntervalSeconds uint32 `json:"interval_seconds,omitempty"`
+	}
+
+	// MachineStats is a sample of the resource usage of a machine, the rates and percentages are
+	// computed over the interval since the previous sample. Guest fields are left empty when
+	// init does not answer, as while the machine is paused.
+	MachineStats struct {
+		Timestamp time.Time `json:"timestamp"`
+		Cpus      uint32    `json:"cpus"`
+		// CPUPercent follows top, each cpu of the machine counts for 100%.
+		CPUPercent float64 `json:"cpu_percent"`
+		// MemoryActualBytes is the memory given to the guest by the vmm, after ballooning.
+		MemoryActualBytes    uint64                   `json:"memory_actual_bytes"`
+		MemoryTotalBytes     uint64                   `json:"memory_total_bytes"`
+		MemoryAvailableBytes uint64                   `json:"memory_available_bytes"`
+		Disks                []*MachineDiskStats      `json:"disks"`
+		Networks             []*MachineNetworkStats   `json:"networks"`
+		Containers           []*MachineContainerStats `json:"containers"`
+	}
+
+	MachineDiskStats struct {
+		Name                string  `json:"name"`
+		ReadBytes           uint64  `json:"read_bytes"`
+		WriteBytes          uint64  `json:"write_bytes"`
+		ReadOps             uint64  `json:"read_ops"`
+		WriteOps            uint64  `json:"write_ops"`
+		ReadBytesPerSecond  float64 `json:"read_bytes_per_second"`
+		WriteBytesPerSecond float64 `json:"write_bytes_per_second"`
+	}
+
+	MachineNetworkStats struct {
+		Name             string  `json:"name"`
+		RxBytes          uint64  `json:"rx_bytes"`
+		TxBytes          uint64  `json:"tx_bytes"`
+		RxFrames         uint64  `json:"rx_frames"`
+		TxFrames         uint64  `json:"tx_frames"`
+		RxBytesPerSecond float64 `json:"rx_bytes_per_second"`
+		TxBytesPerSecond float64 `json:"tx_bytes_per_second"`
+	}
+
+	MachineContainerStats struct {
+		ContainerID    string  `json:"container_id"`
+		Processes      uint32  `json:"processes"`
+		CPUPercent     float64 `json:"cpu_percent"`
+		MemoryRSSBytes uint64  `json:"memory_rss_bytes"`
+	}
+)
+
+// DefaultStatsInterval is the time between two samples of a stats stream when the request does
+// not set one.
+const DefaultStatsInterval = 2 * time.Second
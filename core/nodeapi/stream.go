@@ -0,0 +1,67 @@
+package nodeapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"connectrpc.com/connect"
+)
+
+// ProxyStream forwards a bidirectional stream to the next component until it closes the stream.
+// The first message has already been received from the handler stream and is sent first.
+func ProxyStream[Req, Res any](
+	first *Req,
+	handlerStream *connect.BidiStream[Req, Res],
+	clientStream *connect.BidiStreamForClient[Req, Res],
+) error {
+	defer clientStream.CloseResponse()
+
+	if err := clientStream.Send(first); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	go func() {
+		defer clientStream.CloseRequest()
+
+		for {
+			req, err := handlerStream.Receive()
+			if err != nil {
+				return
+			} else if err = clientStream.Send(req); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		res, err := clientStream.Receive()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err = handlerStream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+// ServerProtocols returns the protocols served by the node components. Bidirectional streams
+// require http/2, which is served without tls on the local sockets.
+func ServerProtocols() *http.Protocols {
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
+
+// StreamClientProtocols returns the protocols of the clients opening bidirectional streams on a
+// local socket, they speak http/2 without tls.
+func StreamClientProtocols() *http.Protocols {
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
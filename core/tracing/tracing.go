@@ -0,0 +1,125 @@
+// Package tracing sets up the OpenTelemetry tracing shared by the nodeagent, the runtime and init.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/otelconnect"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/baepo-cloud/baepo-node"
+
+// Setup installs the global tracer provider of a component and returns its shutdown function,
+// which flushes the pending spans. The trace context propagator is installed even when tracing
+// is disabled so that the context of a request still reaches the next component.
+func Setup(ctx context.Context, serviceName string, config coretypes.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if config.Exporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeOutput, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v span exporter: %w", config.Exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeErr := closeOutput(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, config coretypes.TracingConfig) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+	switch config.Exporter {
+	case coretypes.TracingExporterOTLP:
+		var opts []otlptracehttp.Option
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(config.Endpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		return exporter, noClose, err
+	case coretypes.TracingExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, noClose, err
+	case coretypes.TracingExporterFile:
+		if config.File == "" {
+			return nil, nil, fmt.Errorf("the file exporter requires a file")
+		}
+
+		file, err := os.OpenFile(config.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(io.Writer(file)))
+		if err != nil {
+			_ = file.Close()
+			return nil, nil, err
+		}
+		return exporter, file.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown exporter")
+	}
+}
+
+// Start starts a span of the components, it is a child of the span of the context if any.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records the error of the operation on the span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of ctx in a map, to cross a process boundary other than a
+// request, as the config file of the runtime.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract returns ctx with the trace context injected in carrier by Inject.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// Interceptor returns the connect option tracing the calls of a client or a handler, it is
+// accepted by both the client and the handler constructors.
+func Interceptor() connect.Option {
+	interceptor, err := otelconnect.NewInterceptor(otelconnect.WithoutMetrics())
+	if err != nil {
+		panic(fmt.Errorf("failed to create tracing interceptor: %w", err))
+	}
+
+	return connect.WithInterceptors(interceptor)
+}
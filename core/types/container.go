@@ -15,6 +15,17 @@ type (
 		Healthcheck *ContainerHealthcheckSpec
 		WorkingDir  *string
 		Restart     *ContainerRestartSpec
+		// Ports are published on the address of the node.
+		Ports []ContainerPortSpec
+	}
+
+	ContainerPortProtocol string
+
+	ContainerPortSpec struct {
+		Protocol      ContainerPortProtocol
+		ContainerPort uint16
+		// HostPort is the port of the node, one is allocated from the host port range when zero.
+		HostPort uint16
 	}
 
 	ContainerHealthcheckSpec struct {
@@ -38,6 +49,11 @@ type (
 	}
 )
 
+const (
+	ContainerPortProtocolTCP ContainerPortProtocol = "tcp"
+	ContainerPortProtocolUDP ContainerPortProtocol = "udp"
+)
+
 const (
 	ContainerRestartPolicyUnknown   ContainerRestartPolicy = ""
 	ContainerRestartPolicyNo        ContainerRestartPolicy = "no"
@@ -5,14 +5,39 @@ type (
 		IPAddress      string
 		MacAddress     string
 		GatewayAddress string
-		Hostname       string
-		Containers     []InitContainerConfig
+		// IPv6Address is in cidr notation as IPAddress, ipv6 is not configured when it is empty.
+		IPv6Address        string
+		IPv6GatewayAddress string
+		Hostname           string
+		DNS                InitDNSConfig
+		Containers         []InitContainerConfig
+		// DeferContainers keeps the containers stopped until the init server is configured.
+		DeferContainers bool
+		Tracing         TracingConfig
+	}
+
+	// InitDNSConfig is written to the resolv.conf of the containers.
+	InitDNSConfig struct {
+		// Nameservers are the resolvers of the containers, public resolvers are used when empty.
+		Nameservers  []string
+		SearchDomain string
 	}
 
 	InitContainerConfig struct {
 		ContainerSpec
 		ContainerID string
 		Volume      string
+		// DNS is the one of the init config.
+		DNS InitDNSConfig
+	}
+
+	// InitContainerExecConfig describes a process spawned by initcontainer in the root and the
+	// namespaces of a running container.
+	InitContainerExecConfig struct {
+		Command    []string
+		Env        map[string]string
+		WorkingDir *string
+		TTY        bool
 	}
 )
 
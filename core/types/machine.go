@@ -15,7 +15,37 @@ type (
 		Cpus     uint32
 		MemoryMB uint64
 		Timeout  *uint64
+		// Ingress filters the traffic reaching the machine, everything is accepted when nil.
+		Ingress *MachineIngressPolicy
 	}
+
+	// MachineIngressPolicy allows traffic to the machine with its rules, the replies to the
+	// connections opened by the machine are always accepted.
+	MachineIngressPolicy struct {
+		// DefaultDeny drops the traffic no rule allows, the rules only count it otherwise.
+		DefaultDeny bool
+		Rules       []MachineIngressRule
+	}
+
+	MachineIngressRule struct {
+		// Protocol is tcp, udp or icmp, the rule applies to every protocol when empty.
+		Protocol MachineIngressProtocol
+		// FromPort and ToPort are the destination port range of a tcp or udp rule, every port
+		// is allowed when both are zero.
+		FromPort uint16
+		ToPort   uint16
+		// SourceCIDRs restrict the rule to these networks, every source is allowed when empty.
+		SourceCIDRs []string
+	}
+
+	MachineIngressProtocol string
+)
+
+const (
+	MachineIngressProtocolAny  MachineIngressProtocol = ""
+	MachineIngressProtocolTCP  MachineIngressProtocol = "tcp"
+	MachineIngressProtocolUDP  MachineIngressProtocol = "udp"
+	MachineIngressProtocolICMP MachineIngressProtocol = "icmp"
 )
 
 const (
@@ -23,13 +53,18 @@ const (
 	MachineStateStarting    MachineState = "starting"
 	MachineStateRunning     MachineState = "running"
 	MachineStateDegraded    MachineState = "degraded"
+	MachineStatePaused      MachineState = "paused"
 	MachineStateError       MachineState = "error"
+	MachineStateStopping    MachineState = "stopping"
+	MachineStateStopped     MachineState = "stopped"
 	MachineStateTerminating MachineState = "terminating"
 	MachineStateTerminated  MachineState = "terminated"
 	MachineStateUnknown     MachineState = ""
 
 	MachineDesiredStatePending    MachineDesiredState = "pending"
 	MachineDesiredStateRunning    MachineDesiredState = "running"
+	MachineDesiredStatePaused     MachineDesiredState = "paused"
+	MachineDesiredStateStopped    MachineDesiredState = "stopped"
 	MachineDesiredStateTerminated MachineDesiredState = "terminated"
 	MachineDesiredStateUnknown    MachineDesiredState = ""
 )
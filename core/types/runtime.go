@@ -8,8 +8,20 @@ type (
 		MachineID  string
 		Cpus       uint32
 		MemoryMB   uint64
-		Network    RuntimeNetworkConfig
-		Containers []RuntimeContainerConfig
+		// MaxCpus is the number of vcpus the vm can be resized to, it is never lower than Cpus.
+		MaxCpus uint32
+		// HotplugMemoryMB is the memory that can be added to the vm on top of MemoryMB.
+		HotplugMemoryMB uint64
+		Network         RuntimeNetworkConfig
+		Containers      []RuntimeContainerConfig
+		// RestoreFrom is the directory of a vm snapshot, when set the vm is restored from it
+		// instead of being booted.
+		RestoreFrom *string
+		// DeferContainers boots the vm without starting its containers.
+		DeferContainers bool
+		Tracing         TracingConfig
+		// TraceContext carries the span of the node agent the runtime spans are children of.
+		TraceContext map[string]string
 	}
 
 	RuntimeNetworkConfig struct {
@@ -19,6 +31,14 @@ type (
 		GatewayAddress net.IP
 		NetworkCIDR    net.IPNet
 		Hostname       string
+		// IPv6Address, IPv6GatewayAddress and IPv6Prefix are only set when the node has an ipv6 prefix.
+		IPv6Address        net.IP
+		IPv6GatewayAddress net.IP
+		IPv6Prefix         *net.IPNet
+		// Nameservers are the resolvers of the machine, public resolvers are used when empty.
+		Nameservers []net.IP
+		// SearchDomain completes the names without a dot, no search domain is set when empty.
+		SearchDomain string
 	}
 
 	RuntimeContainerConfig struct {
@@ -0,0 +1,19 @@
+package types
+
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+)
+
+// TracingConfig configures the span exporter of a component, tracing is disabled when Exporter
+// is empty. It is passed down to the runtime and init so that a boot is traced end to end.
+type TracingConfig struct {
+	// Exporter is one of TracingExporterOTLP, TracingExporterStdout or TracingExporterFile.
+	Exporter string
+	// Endpoint is the url of the otlp/http collector, the OTEL_EXPORTER_OTLP_* environment
+	// variables apply when it is empty.
+	Endpoint string
+	// File is where the spans are written by the file exporter, one json object per span.
+	File string
+}
@@ -5,6 +5,8 @@ import (
 	corev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/core/v1"
 )
 
+// ToContainerSpec leaves the ports empty, the container spec of the core protocol has no ports
+// yet and the machines of the control plane publish none until it does.
 func ToContainerSpec(spec *corev1pb.ContainerSpec) *types.ContainerSpec {
 	if spec == nil {
 		return nil
@@ -0,0 +1,16 @@
+package v1pbadapter
+
+import (
+	corev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/core/v1"
+)
+
+// The proto definitions do not know about the stopped and paused lifecycles yet. Proto3 enums are open, so
+// these values travel on the wire untouched until the generated code catches up.
+const (
+	MachineStateStopping corev1pb.MachineState = 8
+	MachineStateStopped  corev1pb.MachineState = 9
+	MachineStatePaused   corev1pb.MachineState = 10
+
+	MachineDesiredStateStopped corev1pb.MachineDesiredState = 4
+	MachineDesiredStatePaused  corev1pb.MachineDesiredState = 5
+)
@@ -5,6 +5,15 @@ import (
 	corev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/core/v1"
 )
 
+func FromMachineSpec(spec *types.MachineSpec) *corev1pb.MachineSpec {
+	return &corev1pb.MachineSpec{
+		Name:     spec.Name,
+		Cpus:     spec.Cpus,
+		MemoryMb: spec.MemoryMB,
+		Timeout:  spec.Timeout,
+	}
+}
+
 func FromMachineState(state types.MachineState) corev1pb.MachineState {
 	switch state {
 	case types.MachineStatePending:
@@ -17,6 +26,12 @@ func FromMachineState(state types.MachineState) corev1pb.MachineState {
 		return corev1pb.MachineState_MachineState_Degraded
 	case types.MachineStateError:
 		return corev1pb.MachineState_MachineState_Error
+	case types.MachineStatePaused:
+		return MachineStatePaused
+	case types.MachineStateStopping:
+		return MachineStateStopping
+	case types.MachineStateStopped:
+		return MachineStateStopped
 	case types.MachineStateTerminating:
 		return corev1pb.MachineState_MachineState_Terminating
 	case types.MachineStateTerminated:
@@ -32,6 +47,10 @@ func FromMachineDesiredState(state types.MachineDesiredState) corev1pb.MachineDe
 		return corev1pb.MachineDesiredState_MachineDesiredState_Pending
 	case types.MachineDesiredStateRunning:
 		return corev1pb.MachineDesiredState_MachineDesiredState_Running
+	case types.MachineDesiredStatePaused:
+		return MachineDesiredStatePaused
+	case types.MachineDesiredStateStopped:
+		return MachineDesiredStateStopped
 	case types.MachineDesiredStateTerminated:
 		return corev1pb.MachineDesiredState_MachineDesiredState_Terminated
 	default:
@@ -20,6 +20,10 @@ func ToMachineDesiredState(state corev1pb.MachineDesiredState) types.MachineDesi
 		return types.MachineDesiredStatePending
 	case corev1pb.MachineDesiredState_MachineDesiredState_Running:
 		return types.MachineDesiredStateRunning
+	case MachineDesiredStatePaused:
+		return types.MachineDesiredStatePaused
+	case MachineDesiredStateStopped:
+		return types.MachineDesiredStateStopped
 	case corev1pb.MachineDesiredState_MachineDesiredState_Terminated:
 		return types.MachineDesiredStateTerminated
 	default:
@@ -39,6 +43,12 @@ func ToMachineState(protoState corev1pb.MachineState) types.MachineState {
 		return types.MachineStateDegraded
 	case corev1pb.MachineState_MachineState_Error:
 		return types.MachineStateError
+	case MachineStatePaused:
+		return types.MachineStatePaused
+	case MachineStateStopping:
+		return types.MachineStateStopping
+	case MachineStateStopped:
+		return types.MachineStateStopped
 	case corev1pb.MachineState_MachineState_Terminating:
 		return types.MachineStateTerminating
 	case corev1pb.MachineState_MachineState_Terminated:
@@ -21,8 +21,11 @@ func MountFilesystems() error {
 		data   string
 	}{
 		{"devtmpfs", "/dev", "devtmpfs", unix.MS_NOSUID, "mode=0755"},
+		// pseudo terminals of the exec sessions
+		{"devpts", "/dev/pts", "devpts", unix.MS_NOSUID | unix.MS_NOEXEC, "mode=0620,ptmxmode=0666"},
 	}
 	for _, m := range mounts {
+		_ = os.MkdirAll(m.target, 0755)
 		if err := syscall.Mount(m.source, m.target, m.fstype, m.flags, m.data); err != nil {
 			return fmt.Errorf("failed to mount %s on %s: %v", m.source, m.target, err)
 		}
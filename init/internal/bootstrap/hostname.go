@@ -0,0 +1,18 @@
+package bootstrap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func SetHostname(hostname string) error {
+	if hostname == "" {
+		return nil
+	}
+
+	if err := syscall.Sethostname([]byte(hostname)); err != nil {
+		return fmt.Errorf("failed to set hostname: %w", err)
+	}
+
+	return nil
+}
@@ -1,9 +1,11 @@
 package bootstrap
 
 import (
+	"errors"
 	"fmt"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 	"net"
 )
 
@@ -51,5 +53,98 @@ func SetupNetwork(config coretypes.InitConfig) error {
 		return fmt.Errorf("error adding default route: %v", err)
 	}
 
+	return setupIPv6(eth0, config)
+}
+
+// ReconfigureNetwork replaces the identity of eth0, it is used when a vm restored from a snapshot
+// is handed a new network interface.
+func ReconfigureNetwork(config coretypes.InitConfig) error {
+	eth0, err := netlink.LinkByName("eth0")
+	if err != nil {
+		return fmt.Errorf("error getting eth0 interface: %v", err)
+	}
+
+	macAddr, err := net.ParseMAC(config.MacAddress)
+	if err != nil {
+		return fmt.Errorf("failed to parse mac address: %w", err)
+	}
+
+	ipAddr, err := netlink.ParseAddr(config.IPAddress)
+	if err != nil {
+		return fmt.Errorf("failed to parse ip address: %w", err)
+	}
+
+	addrs, err := netlink.AddrList(eth0, netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to list eth0 addresses: %w", err)
+	}
+
+	for _, addr := range addrs {
+		if addr.Equal(*ipAddr) {
+			continue
+		} else if err = netlink.AddrDel(eth0, &addr); err != nil {
+			return fmt.Errorf("failed to remove ip address %s: %w", addr.IPNet, err)
+		}
+	}
+
+	if err = netlink.LinkSetDown(eth0); err != nil {
+		return fmt.Errorf("error bringing down eth0: %v", err)
+	}
+
+	if err = netlink.LinkSetHardwareAddr(eth0, macAddr); err != nil {
+		return fmt.Errorf("failed to set mac address: %w", err)
+	}
+
+	if err = netlink.AddrReplace(eth0, ipAddr); err != nil {
+		return fmt.Errorf("failed to set ip address: %w", err)
+	}
+
+	if err = netlink.LinkSetUp(eth0); err != nil {
+		return fmt.Errorf("error bringing up eth0: %v", err)
+	}
+
+	route := &netlink.Route{
+		Gw: net.ParseIP(config.GatewayAddress),
+	}
+	if err = netlink.RouteReplace(route); err != nil {
+		return fmt.Errorf("error replacing default route: %v", err)
+	}
+
+	return setupIPv6(eth0, config)
+}
+
+// setupIPv6 configures the ipv6 address and default route of eth0, the default route is removed
+// when the machine has no ipv6 address.
+func setupIPv6(eth0 netlink.Link, config coretypes.InitConfig) error {
+	if config.IPv6Address == "" {
+		defaultRoute := &netlink.Route{
+			LinkIndex: eth0.Attrs().Index,
+			Dst:       &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 8*net.IPv6len)},
+		}
+		if err := netlink.RouteDel(defaultRoute); err != nil && !errors.Is(err, unix.ESRCH) {
+			return fmt.Errorf("error removing ipv6 default route: %v", err)
+		}
+		return nil
+	}
+
+	ipAddr, err := netlink.ParseAddr(config.IPv6Address)
+	if err != nil {
+		return fmt.Errorf("failed to parse ipv6 address: %w", err)
+	}
+
+	// the address is allocated by the node, duplicate address detection would only delay its use
+	ipAddr.Flags = unix.IFA_F_NODAD
+	if err = netlink.AddrReplace(eth0, ipAddr); err != nil {
+		return fmt.Errorf("failed to set ipv6 address: %w", err)
+	}
+
+	route := &netlink.Route{
+		LinkIndex: eth0.Attrs().Index,
+		Gw:        net.ParseIP(config.IPv6GatewayAddress),
+	}
+	if err = netlink.RouteReplace(route); err != nil {
+		return fmt.Errorf("error replacing ipv6 default route: %v", err)
+	}
+
 	return nil
 }
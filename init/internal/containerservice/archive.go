@@ -0,0 +1,224 @@
+package containerservice
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/baepo-cloud/baepo-node/init/internal/types"
+	"golang.org/x/sys/unix"
+)
+
+// UploadArchive extracts a tar archive in the root filesystem of a container. When the destination
+// is an existing directory the archive is extracted in it, otherwise the root entry of the archive
+// is renamed to the destination.
+func (s *Service) UploadArchive(containerID string, destination string, archive io.Reader) error {
+	root, err := s.openContainerRoot(containerID)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	destination = containerRelativePath(destination)
+	info, err := root.Stat(destination)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	extractInDestination := err == nil && info.IsDir()
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if !fs.ValidPath(name) {
+			return fmt.Errorf("invalid archive entry %v", header.Name)
+		}
+
+		target := path.Join(destination, name)
+		if !extractInDestination {
+			_, rest, _ := strings.Cut(name, "/")
+			target = path.Join(destination, rest)
+		}
+
+		if err = extractArchiveEntry(root, target, header, reader); err != nil {
+			return fmt.Errorf("failed to extract %v: %w", header.Name, err)
+		}
+	}
+}
+
+// DownloadArchive writes a tar archive of a file or a directory of the root filesystem of a
+// container, the entries of the archive are rooted at the base name of the source.
+func (s *Service) DownloadArchive(containerID string, source string, archive io.Writer) error {
+	root, err := s.openContainerRoot(containerID)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	source = containerRelativePath(source)
+	base := path.Base(source)
+	writer := tar.NewWriter(archive)
+	err = fs.WalkDir(root.FS(), source, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = readLinkInRoot(root, name); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+
+		// user and group names would be resolved against init, not the container
+		header.Uname, header.Gname = "", ""
+		header.Name = path.Join(base, strings.TrimPrefix(strings.TrimPrefix(name, source), "/"))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err = writer.WriteHeader(header); err != nil {
+			return err
+		} else if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := root.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(writer, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+func (s *Service) openContainerRoot(containerID string) (*os.Root, error) {
+	s.containersMutex.RLock()
+	_, ok := s.containers[containerID]
+	s.containersMutex.RUnlock()
+	if !ok {
+		return nil, types.ErrContainerNotFound
+	}
+
+	// initcontainer mounts the container volume there, operations through the root can not
+	// escape it with symlinks or relative paths
+	root, err := os.OpenRoot("/mnt/" + containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open container root: %w", err)
+	}
+
+	return root, nil
+}
+
+func extractArchiveEntry(root *os.Root, target string, header *tar.Header, content io.Reader) error {
+	mode := header.FileInfo().Mode()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := root.Mkdir(target, mode.Perm()); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+
+		dir, err := root.Open(target)
+		if err != nil {
+			return err
+		}
+		defer dir.Close()
+
+		return applyOwnership(dir, mode, header)
+	case tar.TypeReg:
+		file, err := root.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if _, err = io.Copy(file, content); err != nil {
+			return err
+		}
+
+		return applyOwnership(file, mode, header)
+	case tar.TypeSymlink:
+		// os.Root has no symlink support, the link is created relative to its parent directory
+		parent, err := root.Open(path.Dir(target))
+		if err != nil {
+			return err
+		}
+		defer parent.Close()
+
+		name := path.Base(target)
+		if err = unix.Unlinkat(int(parent.Fd()), name, 0); err != nil && !errors.Is(err, unix.ENOENT) {
+			return err
+		} else if err = unix.Symlinkat(header.Linkname, int(parent.Fd()), name); err != nil {
+			return err
+		}
+
+		return unix.Fchownat(int(parent.Fd()), name, header.Uid, header.Gid, unix.AT_SYMLINK_NOFOLLOW)
+	default:
+		slog.Warn("skipping unsupported archive entry", slog.String("name", header.Name),
+			slog.String("type", string(header.Typeflag)))
+		return nil
+	}
+}
+
+// applyOwnership sets the mode again since it was masked by the umask on creation.
+func applyOwnership(file *os.File, mode fs.FileMode, header *tar.Header) error {
+	if err := file.Chmod(mode); err != nil {
+		return err
+	}
+
+	return file.Chown(header.Uid, header.Gid)
+}
+
+func readLinkInRoot(root *os.Root, name string) (string, error) {
+	parent, err := root.Open(path.Dir(name))
+	if err != nil {
+		return "", err
+	}
+	defer parent.Close()
+
+	buf := make([]byte, unix.PathMax)
+	n, err := unix.Readlinkat(int(parent.Fd()), path.Base(name), buf)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf[:n]), nil
+}
+
+// containerRelativePath turns an absolute path of the container into a path relative to its root.
+func containerRelativePath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
@@ -25,6 +25,7 @@ type Container struct {
 	stdout           io.Writer
 	stderr           io.Writer
 	cmd              *exec.Cmd
+	pid              atomic.Int32
 	startedAt        atomic.Pointer[time.Time]
 	exitError        atomic.Pointer[error]
 	exitedAt         atomic.Pointer[time.Time]
@@ -73,9 +74,11 @@ func (c *Container) run(jsonConfig []byte) {
 			c.log.Warn("failed to start container", slog.Any("error", err))
 			c.exitError.Store(&err)
 		} else {
+			c.pid.Store(int32(c.cmd.Process.Pid))
 			healthcheckCtx, cancel := context.WithCancel(context.Background())
 			go c.healthcheckWorker(healthcheckCtx)
 			err = c.cmd.Wait()
+			c.pid.Store(0)
 			c.log.Warn("container exited",
 				slog.Any("error", err),
 				slog.Int("exit-code", c.cmd.ProcessState.ExitCode()))
@@ -0,0 +1,174 @@
+package containerservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+
+	"github.com/baepo-cloud/baepo-node/init/internal/types"
+	"golang.org/x/sys/unix"
+)
+
+type execProcess struct {
+	cmd      *exec.Cmd
+	terminal *os.File
+	stdin    io.WriteCloser
+	stdout   io.Reader
+	stderr   io.Reader
+}
+
+var _ types.ContainerExecProcess = (*execProcess)(nil)
+
+func (s *Service) Exec(opts types.ContainerExecOptions) (types.ContainerExecProcess, error) {
+	s.containersMutex.RLock()
+	ctr, ok := s.containers[opts.ContainerID]
+	s.containersMutex.RUnlock()
+	if !ok {
+		return nil, types.ErrContainerNotFound
+	}
+
+	jsonConfig, err := json.Marshal(ctr.config)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonExecConfig, err := json.Marshal(opts.InitContainerExecConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr.log.Info("executing command in container", slog.Any("command", opts.Command), slog.Bool("tty", opts.TTY))
+	process := &execProcess{
+		cmd: exec.Command("/initcontainer", "exec", string(jsonConfig), string(jsonExecConfig)),
+	}
+	if opts.TTY {
+		err = process.startWithTerminal(opts.Rows, opts.Cols)
+	} else {
+		err = process.startWithPipes()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start process: %w", err)
+	}
+
+	return process, nil
+}
+
+func (p *execProcess) startWithTerminal(rows, cols uint16) error {
+	terminal, replica, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	// the replica side is owned by the process once started
+	defer replica.Close()
+
+	p.terminal = terminal
+	if rows > 0 && cols > 0 {
+		if err = p.Resize(rows, cols); err != nil {
+			_ = terminal.Close()
+			return err
+		}
+	}
+
+	p.cmd.Stdin = replica
+	p.cmd.Stdout = replica
+	p.cmd.Stderr = replica
+	if err = p.cmd.Start(); err != nil {
+		_ = terminal.Close()
+		return err
+	}
+
+	p.stdin = terminalInput{File: terminal}
+	p.stdout = terminal
+	return nil
+}
+
+func (p *execProcess) startWithPipes() (err error) {
+	if p.stdin, err = p.cmd.StdinPipe(); err != nil {
+		return err
+	} else if p.stdout, err = p.cmd.StdoutPipe(); err != nil {
+		return err
+	} else if p.stderr, err = p.cmd.StderrPipe(); err != nil {
+		return err
+	}
+
+	return p.cmd.Start()
+}
+
+func (p *execProcess) Stdin() io.WriteCloser {
+	return p.stdin
+}
+
+func (p *execProcess) Stdout() io.Reader {
+	return p.stdout
+}
+
+func (p *execProcess) Stderr() io.Reader {
+	return p.stderr
+}
+
+func (p *execProcess) Resize(rows, cols uint16) error {
+	if p.terminal == nil {
+		return nil
+	}
+
+	return controlTerminal(p.terminal, func(fd int) error {
+		return unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, &unix.Winsize{Row: rows, Col: cols})
+	})
+}
+
+// Wait must only be called once the output streams have been read until the end.
+func (p *execProcess) Wait() (int32, error) {
+	err := p.cmd.Wait()
+	if p.terminal != nil {
+		_ = p.terminal.Close()
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return -1, err
+	}
+
+	return int32(p.cmd.ProcessState.ExitCode()), nil
+}
+
+// Close kills the process when it is still running and releases its resources.
+func (p *execProcess) Close() error {
+	if p.cmd.ProcessState != nil {
+		return nil
+	}
+
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+
+	_, err := p.Wait()
+	return err
+}
+
+// terminalInput closes the input of a terminal by sending its end of file character, closing the
+// terminal itself would also close the output of the process. The character only ends the input
+// in canonical mode, a process reading the terminal in raw mode keeps its input open. The input
+// of a process without terminal is a pipe, which is closed.
+type terminalInput struct {
+	*os.File
+}
+
+func (i terminalInput) Close() error {
+	var termios *unix.Termios
+	err := controlTerminal(i.File, func(fd int) (err error) {
+		termios, err = unix.IoctlGetTermios(fd, unix.TCGETS)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get terminal attributes: %w", err)
+	} else if termios.Lflag&unix.ICANON == 0 {
+		return nil
+	}
+
+	_, err = i.Write([]byte{termios.Cc[unix.VEOF]})
+	return err
+}
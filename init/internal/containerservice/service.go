@@ -45,6 +45,17 @@ func (s *Service) Stop() {
 	}
 }
 
+func (s *Service) ListContainerIDs() []string {
+	s.containersMutex.RLock()
+	defer s.containersMutex.RUnlock()
+
+	containerIDs := make([]string, 0, len(s.containers))
+	for containerID := range s.containers {
+		containerIDs = append(containerIDs, containerID)
+	}
+	return containerIDs
+}
+
 func (s *Service) Events(ctx context.Context) <-chan any {
 	events := make(chan any)
 	cancel := s.eventBus.SubscribeToEvents(func(ctx context.Context, event any) {
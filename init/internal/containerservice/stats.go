@@ -0,0 +1,53 @@
+package containerservice
+
+import (
+	"fmt"
+
+	"github.com/baepo-cloud/baepo-node/init/internal/procfs"
+	"github.com/baepo-cloud/baepo-node/init/internal/types"
+)
+
+// GetStats returns the stats of the running containers. Exec processes are spawned by init, they
+// are not part of the process tree of a container and are not accounted.
+func (s *Service) GetStats() ([]types.ContainerStats, error) {
+	processes, err := procfs.ListProcesses()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	processesByPID := make(map[int]procfs.Process, len(processes))
+	children := map[int][]int{}
+	for _, process := range processes {
+		processesByPID[process.PID] = process
+		children[process.ParentID] = append(children[process.ParentID], process.PID)
+	}
+
+	s.containersMutex.RLock()
+	defer s.containersMutex.RUnlock()
+
+	stats := make([]types.ContainerStats, 0, len(s.containers))
+	for containerID, ctr := range s.containers {
+		pid := int(ctr.pid.Load())
+		if pid == 0 {
+			continue
+		}
+
+		containerStats := types.ContainerStats{ContainerID: containerID}
+		pending := []int{pid}
+		for len(pending) > 0 {
+			process, ok := processesByPID[pending[0]]
+			pending = pending[1:]
+			if !ok {
+				continue
+			}
+
+			containerStats.Processes++
+			containerStats.CPUTime += process.CPUTime
+			containerStats.RSSBytes += process.RSSBytes
+			pending = append(pending, children[process.PID]...)
+		}
+		stats = append(stats, containerStats)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,61 @@
+package containerservice
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openTerminal allocates a pseudo terminal from the devpts filesystem mounted by the bootstrap, it
+// returns the multiplexer side kept by init and the replica side given to the process.
+func openTerminal() (*os.File, *os.File, error) {
+	terminal, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open pseudo terminal: %w", err)
+	}
+
+	var replicaNumber int
+	err = controlTerminal(terminal, func(fd int) error {
+		if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+			return fmt.Errorf("failed to unlock pseudo terminal: %w", err)
+		}
+
+		replicaNumber, err = unix.IoctlGetInt(fd, unix.TIOCGPTN)
+		if err != nil {
+			return fmt.Errorf("failed to get pseudo terminal number: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		_ = terminal.Close()
+		return nil, nil, err
+	}
+
+	replica, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", replicaNumber), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		_ = terminal.Close()
+		return nil, nil, fmt.Errorf("failed to open pseudo terminal replica: %w", err)
+	}
+
+	return terminal, replica, nil
+}
+
+// controlTerminal runs an ioctl on the terminal without switching it to blocking mode, as
+// os.File.Fd does, so that closing the terminal still interrupts pending reads.
+func controlTerminal(terminal *os.File, fn func(fd int) error) error {
+	rawConn, err := terminal.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var fnErr error
+	if err = rawConn.Control(func(fd uintptr) {
+		fnErr = fn(int(fd))
+	}); err != nil {
+		return err
+	}
+
+	return fnErr
+}
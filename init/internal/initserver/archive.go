@@ -0,0 +1,70 @@
+package initserver
+
+import (
+	"bufio"
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/init/internal/types"
+	"io"
+	"io/fs"
+	"log/slog"
+)
+
+func (s InitServiceServer) UploadArchive(ctx context.Context, stream *connect.ClientStream[nodeapi.UploadArchiveRequest]) (*connect.Response[nodeapi.UploadArchiveResponse], error) {
+	if !stream.Receive() {
+		if err := stream.Err(); err != nil {
+			return nil, err
+		}
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("missing archive destination"))
+	}
+
+	first := stream.Msg()
+	pending := first.Data
+	archive := nodeapi.NewChunkReader(func() ([]byte, error) {
+		if len(pending) > 0 {
+			data := pending
+			pending = nil
+			return data, nil
+		}
+
+		if !stream.Receive() {
+			if err := stream.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		return stream.Msg().Data, nil
+	})
+
+	s.log.Info("uploading archive", slog.String("container-id", first.ContainerID), slog.String("path", first.Path))
+	if err := s.containerService.UploadArchive(first.ContainerID, first.Path, archive); err != nil {
+		return nil, s.adaptArchiveError(err)
+	}
+
+	return connect.NewResponse(&nodeapi.UploadArchiveResponse{}), nil
+}
+
+func (s InitServiceServer) DownloadArchive(ctx context.Context, req *connect.Request[nodeapi.DownloadArchiveRequest], stream *connect.ServerStream[nodeapi.DownloadArchiveResponse]) error {
+	// tar headers are written in small pieces, they are buffered into full chunks
+	archive := bufio.NewWriterSize(nodeapi.NewChunkWriter(func(data []byte) error {
+		return stream.Send(&nodeapi.DownloadArchiveResponse{Data: data})
+	}), nodeapi.ArchiveChunkSize)
+
+	s.log.Info("downloading archive", slog.String("container-id", req.Msg.ContainerID), slog.String("path", req.Msg.Path))
+	if err := s.containerService.DownloadArchive(req.Msg.ContainerID, req.Msg.Path, archive); err != nil {
+		return s.adaptArchiveError(err)
+	}
+
+	return archive.Flush()
+}
+
+func (s InitServiceServer) adaptArchiveError(err error) error {
+	switch {
+	case errors.Is(err, types.ErrContainerNotFound), errors.Is(err, fs.ErrNotExist):
+		return connect.NewError(connect.CodeNotFound, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,49 @@
+package initserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/init/internal/bootstrap"
+	"log/slog"
+	"os"
+	"slices"
+)
+
+func (s InitServiceServer) Configure(_ context.Context, req *connect.Request[nodeapi.InitConfigureRequest]) (*connect.Response[nodeapi.InitConfigureResponse], error) {
+	config := req.Msg.Config
+	s.log.Info("configuring init", slog.String("hostname", config.Hostname), slog.Int("containers", len(config.Containers)))
+
+	if err := bootstrap.ReconfigureNetwork(config); err != nil {
+		return nil, fmt.Errorf("failed to reconfigure network: %w", err)
+	} else if err = bootstrap.SetHostname(config.Hostname); err != nil {
+		return nil, err
+	}
+
+	runningContainerIDs := s.containerService.ListContainerIDs()
+	for _, containerConfig := range config.Containers {
+		if slices.Contains(runningContainerIDs, containerConfig.ContainerID) {
+			continue
+		}
+
+		containerConfig.DNS = config.DNS
+		if err := s.containerService.StartContainer(containerConfig); err != nil {
+			return nil, fmt.Errorf("failed to start container %s: %w", containerConfig.ContainerID, err)
+		}
+	}
+
+	return connect.NewResponse(&nodeapi.InitConfigureResponse{}), nil
+}
+
+func (s InitServiceServer) GetStatus(_ context.Context, _ *connect.Request[nodeapi.InitGetStatusRequest]) (*connect.Response[nodeapi.InitGetStatusResponse], error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	return connect.NewResponse(&nodeapi.InitGetStatusResponse{
+		Hostname:     hostname,
+		ContainerIDs: s.containerService.ListContainerIDs(),
+	}), nil
+}
@@ -0,0 +1,124 @@
+package initserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/init/internal/types"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+func (s InitServiceServer) Exec(ctx context.Context, stream *connect.BidiStream[nodeapi.ExecRequest, nodeapi.ExecResponse]) error {
+	req, err := stream.Receive()
+	if err != nil {
+		return err
+	} else if req.Start == nil || len(req.Start.Command) == 0 {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("first exec message must start a command"))
+	}
+
+	opts := types.ContainerExecOptions{ContainerID: req.Start.ContainerID}
+	opts.Command = req.Start.Command
+	opts.Env = req.Start.Env
+	opts.WorkingDir = req.Start.WorkingDir
+	opts.TTY = req.Start.TTY
+	if req.Start.Size != nil {
+		opts.Rows, opts.Cols = req.Start.Size.Rows, req.Start.Size.Cols
+	}
+
+	process, err := s.containerService.Exec(opts)
+	if errors.Is(err, types.ErrContainerNotFound) {
+		return connect.NewError(connect.CodeNotFound, err)
+	} else if err != nil {
+		return err
+	}
+	defer process.Close()
+
+	go s.forwardExecInput(stream, process)
+
+	outputs := make(chan *nodeapi.ExecResponse)
+	var wg sync.WaitGroup
+	readOutput := func(reader io.Reader, stderr bool) {
+		defer wg.Done()
+
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := reader.Read(buf)
+			if n > 0 {
+				res := &nodeapi.ExecResponse{}
+				if stderr {
+					res.Stderr = append([]byte(nil), buf[:n]...)
+				} else {
+					res.Stdout = append([]byte(nil), buf[:n]...)
+				}
+
+				select {
+				case outputs <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+			// reading the terminal fails with EIO once the process has exited
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	wg.Add(1)
+	go readOutput(process.Stdout(), false)
+	if stderr := process.Stderr(); stderr != nil {
+		wg.Add(1)
+		go readOutput(stderr, true)
+	}
+	go func() {
+		wg.Wait()
+		close(outputs)
+	}()
+
+	for res := range outputs {
+		if err = stream.Send(res); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	exitCode, err := process.Wait()
+	if err != nil {
+		return fmt.Errorf("failed to wait for process: %w", err)
+	}
+
+	s.log.Info("exec process exited", slog.String("container-id", opts.ContainerID), slog.Int("exit-code", int(exitCode)))
+	return stream.Send(&nodeapi.ExecResponse{ExitCode: &exitCode})
+}
+
+func (s InitServiceServer) forwardExecInput(stream *connect.BidiStream[nodeapi.ExecRequest, nodeapi.ExecResponse], process types.ContainerExecProcess) {
+	stdin := process.Stdin()
+	defer stdin.Close()
+
+	for {
+		req, err := stream.Receive()
+		if err != nil {
+			return
+		}
+
+		if len(req.Stdin) > 0 {
+			if _, err = stdin.Write(req.Stdin); err != nil {
+				return
+			}
+		}
+		if req.Resize != nil {
+			if err = process.Resize(req.Resize.Rows, req.Resize.Cols); err != nil {
+				s.log.Warn("failed to resize terminal", slog.Any("error", err))
+			}
+		}
+		if req.CloseStdin {
+			return
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package initserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/init/internal/procfs"
+)
+
+func (s InitServiceServer) GetStats(ctx context.Context, _ *connect.Request[nodeapi.InitGetStatsRequest]) (*connect.Response[nodeapi.InitGetStatsResponse], error) {
+	cpuTime, err := procfs.ReadCPUTime()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cpu time: %w", err)
+	}
+
+	memoryTotal, memoryAvailable, err := procfs.ReadMemoryInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory info: %w", err)
+	}
+
+	containerStats, err := s.containerService.GetStats()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &nodeapi.InitGetStatsResponse{
+		CPUTimeUs:            uint64(cpuTime.Microseconds()),
+		MemoryTotalBytes:     memoryTotal,
+		MemoryAvailableBytes: memoryAvailable,
+		Containers:           make([]*nodeapi.InitContainerStats, len(containerStats)),
+	}
+	for index, stats := range containerStats {
+		res.Containers[index] = &nodeapi.InitContainerStats{
+			ContainerID:    stats.ContainerID,
+			Processes:      uint32(stats.Processes),
+			CPUTimeUs:      uint64(stats.CPUTime.Microseconds()),
+			MemoryRSSBytes: stats.RSSBytes,
+		}
+	}
+
+	return connect.NewResponse(res), nil
+}
@@ -3,6 +3,8 @@ package initserver
 import (
 	"context"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	"github.com/baepo-cloud/baepo-node/core/vsock"
 	"github.com/baepo-cloud/baepo-node/init/internal/types"
@@ -17,7 +19,10 @@ type InitServiceServer struct {
 	logService       types.LogService
 }
 
-var _ nodev1pbconnect.InitHandler = (*InitServiceServer)(nil)
+var (
+	_ nodev1pbconnect.InitHandler = (*InitServiceServer)(nil)
+	_ nodeapi.InitHandler         = (*InitServiceServer)(nil)
+)
 
 func New(containerService types.ContainerService, logService types.LogService) *InitServiceServer {
 	return &InitServiceServer{
@@ -36,9 +41,11 @@ func (s InitServiceServer) Start() error {
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle(nodev1pbconnect.NewInitHandler(s))
+	mux.Handle(nodev1pbconnect.NewInitHandler(s, tracing.Interceptor()))
+	mux.Handle(nodeapi.NewInitHandler(s, tracing.Interceptor()))
 	server := &http.Server{
-		Handler: mux,
+		Handler:   mux,
+		Protocols: nodeapi.ServerProtocols(),
 	}
 	return server.Serve(ln)
 }
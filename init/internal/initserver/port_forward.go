@@ -0,0 +1,73 @@
+package initserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"log/slog"
+	"net"
+	"strconv"
+)
+
+func (s InitServiceServer) PortForward(ctx context.Context, stream *connect.BidiStream[nodeapi.PortForwardRequest, nodeapi.PortForwardResponse]) error {
+	req, err := stream.Receive()
+	if err != nil {
+		return err
+	} else if req.Port == 0 || req.Port > 65535 {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid port %v", req.Port))
+	}
+
+	// containers share the network namespace of the guest, dialing the loopback from init reaches
+	// them without going through the guest interfaces and their firewall rules
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(req.Port)))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return connect.NewError(connect.CodeUnavailable, fmt.Errorf("failed to dial %v: %w", address, err))
+	}
+	defer conn.Close()
+	// unblocks the read below when the client goes away
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	s.log.Info("forwarding port", slog.Int("port", int(req.Port)))
+	go s.forwardPortInput(stream, conn.(*net.TCPConn), req.Data)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&nodeapi.PortForwardResponse{Data: buf[:n]}); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// the guest closed the connection, ending the stream closes the local connection
+			return nil
+		}
+	}
+}
+
+func (s InitServiceServer) forwardPortInput(stream *connect.BidiStream[nodeapi.PortForwardRequest, nodeapi.PortForwardResponse], conn *net.TCPConn, pending []byte) {
+	for {
+		if len(pending) > 0 {
+			if _, err := conn.Write(pending); err != nil {
+				_ = conn.Close()
+				return
+			}
+		}
+
+		req, err := stream.Receive()
+		if err != nil {
+			// the client closed its side of the stream, the guest still gets to answer
+			_ = conn.CloseWrite()
+			return
+		}
+		pending = req.Data
+	}
+}
@@ -0,0 +1,145 @@
+// Package procfs reads the resource counters of the guest from the proc filesystem.
+package procfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTick is the unit of the cpu times of the proc filesystem, USER_HZ is 100 on every
+// architecture supported by the guest kernel.
+const clockTick = 10 * time.Millisecond
+
+type Process struct {
+	PID      int
+	ParentID int
+	CPUTime  time.Duration
+	RSSBytes uint64
+}
+
+// ReadCPUTime returns the time spent by all the cpus outside of idle and iowait since boot.
+func ReadCPUTime() (time.Duration, error) {
+	content, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, err
+	}
+
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) < 9 || fields[0] != "cpu" {
+		return 0, fmt.Errorf("unexpected /proc/stat format")
+	}
+
+	var ticks uint64
+	// user, nice, system, then irq, softirq and steal, guest times are included in user
+	for _, index := range []int{1, 2, 3, 6, 7, 8} {
+		value, err := strconv.ParseUint(fields[index], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid /proc/stat value %v: %w", fields[index], err)
+		}
+		ticks += value
+	}
+
+	return time.Duration(ticks) * clockTick, nil
+}
+
+// ReadMemoryInfo returns the total and the available memory of the guest in bytes.
+func ReadMemoryInfo() (total uint64, available uint64, err error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		var target *uint64
+		switch fields[0] {
+		case "MemTotal:":
+			target = &total
+		case "MemAvailable:":
+			target = &available
+		default:
+			continue
+		}
+
+		kib, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid /proc/meminfo value %v: %w", fields[1], err)
+		}
+		*target = kib * 1024
+	}
+
+	return total, available, scanner.Err()
+}
+
+// ListProcesses returns the processes of the guest, processes exiting while they are listed are
+// skipped.
+func ListProcesses() ([]Process, error) {
+	paths, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	processes := make([]Process, 0, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		process, err := parseProcessStat(string(content), pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %w", path, err)
+		}
+		processes = append(processes, process)
+	}
+
+	return processes, nil
+}
+
+func parseProcessStat(content string, pageSize uint64) (Process, error) {
+	// the command name is between parentheses and may contain spaces or parentheses itself
+	pid, rest, found := strings.Cut(content, " (")
+	nameEnd := strings.LastIndexByte(rest, ')')
+	if !found || nameEnd < 0 {
+		return Process{}, fmt.Errorf("unexpected format")
+	}
+
+	// fields start with the state, the third field of the stat file
+	fields := strings.Fields(rest[nameEnd+1:])
+	if len(fields) < 22 {
+		return Process{}, fmt.Errorf("unexpected field count %d", len(fields))
+	}
+
+	var process Process
+	var values [4]uint64
+	for index, fieldIndex := range []int{1, 11, 12, 21} {
+		value, err := strconv.ParseUint(fields[fieldIndex], 10, 64)
+		if err != nil {
+			return Process{}, err
+		}
+		values[index] = value
+	}
+
+	var err error
+	if process.PID, err = strconv.Atoi(pid); err != nil {
+		return Process{}, err
+	}
+	process.ParentID = int(values[0])
+	process.CPUTime = time.Duration(values[1]+values[2]) * clockTick
+	process.RSSBytes = values[3] * pageSize
+	return process, nil
+}
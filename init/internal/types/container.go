@@ -2,6 +2,9 @@ package types
 
 import (
 	"context"
+	"errors"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"io"
 	"time"
 )
 
@@ -19,7 +22,53 @@ type (
 		Timestamp        time.Time
 	}
 
+	ContainerExecOptions struct {
+		ContainerID string
+		coretypes.InitContainerExecConfig
+		Rows uint16
+		Cols uint16
+	}
+
+	// ContainerExecProcess is a process spawned in a container, Stderr is nil when the process
+	// has a terminal since both output streams are merged by the terminal.
+	ContainerExecProcess interface {
+		Stdin() io.WriteCloser
+
+		Stdout() io.Reader
+
+		Stderr() io.Reader
+
+		Resize(rows, cols uint16) error
+
+		Wait() (int32, error)
+
+		Close() error
+	}
+
+	// ContainerStats sums the counters of the process tree of a container, from its initcontainer
+	// process.
+	ContainerStats struct {
+		ContainerID string
+		Processes   int
+		CPUTime     time.Duration
+		RSSBytes    uint64
+	}
+
 	ContainerService interface {
 		Events(ctx context.Context) <-chan any
+
+		StartContainer(config coretypes.InitContainerConfig) error
+
+		ListContainerIDs() []string
+
+		Exec(opts ContainerExecOptions) (ContainerExecProcess, error)
+
+		UploadArchive(containerID string, destination string, archive io.Reader) error
+
+		DownloadArchive(containerID string, source string, archive io.Writer) error
+
+		GetStats() ([]ContainerStats, error)
 	}
 )
+
+var ErrContainerNotFound = errors.New("container not found")
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	"github.com/baepo-cloud/baepo-node/core/types"
 	"github.com/baepo-cloud/baepo-node/init/internal/bootstrap"
 	"github.com/baepo-cloud/baepo-node/init/internal/containerservice"
@@ -31,6 +33,13 @@ func main() {
 		panic(fmt.Errorf("failed to mount filesystem: %v", err))
 	} else if err = bootstrap.SetupNetwork(config); err != nil {
 		panic(fmt.Errorf("failed to setup network: %v", err))
+	} else if err = bootstrap.SetHostname(config.Hostname); err != nil {
+		panic(fmt.Errorf("failed to set hostname: %v", err))
+	}
+
+	// init never exits gracefully, spans are exported by the batcher as they end
+	if _, err = tracing.Setup(context.Background(), "baepo-init", config.Tracing); err != nil {
+		slog.Error("failed to setup tracing", slog.Any("error", err))
 	}
 
 	logService, err := logservice.New("/logs")
@@ -42,11 +51,16 @@ func main() {
 	containerService.Start()
 
 	errChan := make(chan error, 1)
-	
-	slog.Info("starting containers", slog.Int("count", len(config.Containers)))
-	for _, containerConfig := range config.Containers {
-		if err = containerService.StartContainer(containerConfig); err != nil {
-			panic(err)
+
+	if config.DeferContainers {
+		slog.Info("deferring containers until init is configured", slog.Int("count", len(config.Containers)))
+	} else {
+		slog.Info("starting containers", slog.Int("count", len(config.Containers)))
+		for _, containerConfig := range config.Containers {
+			containerConfig.DNS = config.DNS
+			if err = containerService.StartContainer(containerConfig); err != nil {
+				panic(err)
+			}
 		}
 	}
 
@@ -1,9 +1,11 @@
 package container
 
 import (
+	"errors"
 	"fmt"
 	"github.com/baepo-cloud/baepo-node/core/types"
 	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"os/user"
@@ -38,13 +40,87 @@ func (c *Container) Start() error {
 		return fmt.Errorf("failed to setup networking: %w", err)
 	}
 
-	workingDir := "/"
-	if c.config.WorkingDir != nil {
-		workingDir = *c.config.WorkingDir
+	cmd, err := c.newCommand(c.config.Command, nil, c.config.WorkingDir)
+	if err != nil {
+		return err
+	}
+
+	cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWNS | // New mount namespace
+		syscall.CLONE_NEWPID | // New PID namespace
+		syscall.CLONE_NEWUTS // New UTS namespace
+	c.cmd = cmd
+	c.cmd.Stdin = os.Stdin
+	c.cmd.Stdout = os.Stdout
+	c.cmd.Stderr = os.Stderr
+	if err = c.cmd.Run(); err != nil {
+		return fmt.Errorf("failed to execute %v: %w", strings.Join(c.config.Command, " "), err)
+	}
+
+	return nil
+}
+
+// Exec runs a process in the namespaces and the root of the already started container and
+// returns its exit code.
+func (c *Container) Exec(config types.InitContainerExecConfig) (int, error) {
+	if _, err := os.Stat(c.rootDir); err != nil {
+		return -1, fmt.Errorf("container root is not mounted: %w", err)
+	}
+
+	pid, err := findContainerProcess(c.rootDir)
+	if err != nil {
+		return -1, err
+	}
+
+	if err = joinContainer(pid, c.rootDir); err != nil {
+		return -1, err
+	}
+
+	workingDir := c.config.WorkingDir
+	if config.WorkingDir != nil {
+		workingDir = config.WorkingDir
+	}
+
+	cmd, err := c.newCommand(config.Command, config.Env, workingDir)
+	if err != nil {
+		return -1, err
+	}
+
+	// the process must not outlive the exec session when initcontainer gets killed
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+	if config.TTY {
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setctty = true
+		cmd.SysProcAttr.Ctty = 0
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+
+		return -1, fmt.Errorf("failed to execute %v: %w", strings.Join(config.Command, " "), err)
+	}
+
+	return 0, nil
+}
+
+// newCommand builds a command running as the container user with the container environment, in
+// the working directory of the current root.
+func (c *Container) newCommand(command []string, extraEnv map[string]string, workingDir *string) (*exec.Cmd, error) {
+	if len(command) == 0 {
+		return nil, errors.New("command is empty")
 	}
 
-	if err := syscall.Chdir(workingDir); err != nil {
-		return fmt.Errorf("failed to change directory to %v: %w", workingDir, err)
+	dir := "/"
+	if workingDir != nil {
+		dir = *workingDir
+	}
+
+	if err := syscall.Chdir(dir); err != nil {
+		return nil, fmt.Errorf("failed to change directory to %v: %w", dir, err)
 	}
 
 	username := "root"
@@ -55,7 +131,7 @@ func (c *Container) Start() error {
 	targetUser, err := user.Lookup(username)
 	if err != nil {
 		if username != "root" {
-			return fmt.Errorf("failed to lookup user %s: %v", username, err)
+			return nil, fmt.Errorf("failed to lookup user %s: %v", username, err)
 		}
 
 		targetUser = &user.User{
@@ -67,27 +143,26 @@ func (c *Container) Start() error {
 		}
 	}
 
-	env := c.config.Env
+	env := map[string]string{}
+	maps.Copy(env, c.config.Env)
+	maps.Copy(env, extraEnv)
 	env["HOME"] = targetUser.HomeDir
 
 	uid, err := strconv.Atoi(targetUser.Uid)
 	if err != nil {
-		return fmt.Errorf("failed to convert user id to int: %v", err)
+		return nil, fmt.Errorf("failed to convert user id to int: %v", err)
 	}
 
 	gid, err := strconv.Atoi(targetUser.Gid)
 	if err != nil {
-		return fmt.Errorf("failed to convert user group id to int: %v", err)
+		return nil, fmt.Errorf("failed to convert user group id to int: %v", err)
 	}
 
-	c.cmd = exec.Command(c.config.Command[0], c.config.Command[1:]...)
+	cmd := exec.Command(command[0], command[1:]...)
 	for key, value := range env {
-		c.cmd.Env = append(c.cmd.Env, fmt.Sprintf("%s=%s", key, value))
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 	}
-	c.cmd.SysProcAttr = &syscall.SysProcAttr{
-		Cloneflags: syscall.CLONE_NEWNS | // New mount namespace
-			syscall.CLONE_NEWPID | // New PID namespace
-			syscall.CLONE_NEWUTS, // New UTS namespace
+	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Credential: &syscall.Credential{
 			Uid:         uint32(uid),
 			Gid:         uint32(gid),
@@ -95,12 +170,5 @@ func (c *Container) Start() error {
 			NoSetGroups: true,
 		},
 	}
-	c.cmd.Stdin = os.Stdin
-	c.cmd.Stdout = os.Stdout
-	c.cmd.Stderr = os.Stderr
-	if err = c.cmd.Run(); err != nil {
-		return fmt.Errorf("failed to execute %v: %w", strings.Join(c.config.Command, " "), err)
-	}
-
-	return nil
+	return cmd, nil
 }
@@ -0,0 +1,103 @@
+package container
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// findContainerProcess returns the pid of the first process of the container, the process with
+// the pid 1 of its own pid namespace whose root directory is rootDir.
+func findContainerProcess(rootDir string) (int, error) {
+	var root unix.Stat_t
+	if err := unix.Stat(rootDir, &root); err != nil {
+		return -1, fmt.Errorf("failed to stat container root: %w", err)
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return -1, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !isNamespaceInit(pid) {
+			continue
+		}
+
+		var processRoot unix.Stat_t
+		if err = unix.Stat(fmt.Sprintf("/proc/%d/root", pid), &processRoot); err == nil &&
+			processRoot.Dev == root.Dev && processRoot.Ino == root.Ino {
+			return pid, nil
+		}
+	}
+	return -1, errors.New("container process is not running")
+}
+
+// isNamespaceInit checks whether the process has the pid 1 in a nested pid namespace, the last
+// pid of the NSpid line is the one of the innermost namespace.
+func isNamespaceInit(pid int) bool {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields, ok := strings.CutPrefix(scanner.Text(), "NSpid:"); ok {
+			pids := strings.Fields(fields)
+			return len(pids) > 1 && pids[len(pids)-1] == "1"
+		}
+	}
+	return false
+}
+
+// joinContainer moves the current thread into the mount, pid and uts namespaces of the container
+// process and into the container root, the processes started by the thread then run inside the
+// container. The thread is locked and never unlocked, so that it is discarded with the
+// goroutine. The filesystem attributes are unshared from the other threads first, the kernel
+// refuses to change the mount namespace of a thread sharing them.
+func joinContainer(pid int, rootDir string) error {
+	runtime.LockOSThread()
+
+	if err := unix.Unshare(unix.CLONE_FS); err != nil {
+		return fmt.Errorf("failed to unshare filesystem attributes: %w", err)
+	}
+
+	// the namespaces are opened first since /proc is out of reach once the mount namespace changes
+	namespaces := []struct {
+		name  string
+		flags int
+	}{{"uts", unix.CLONE_NEWUTS}, {"pid", unix.CLONE_NEWPID}, {"mnt", unix.CLONE_NEWNS}}
+	files := make([]*os.File, len(namespaces))
+	for index, namespace := range namespaces {
+		file, err := os.Open(fmt.Sprintf("/proc/%d/ns/%s", pid, namespace.name))
+		if err != nil {
+			return fmt.Errorf("failed to open %s namespace: %w", namespace.name, err)
+		}
+		defer file.Close()
+		files[index] = file
+	}
+
+	for index, namespace := range namespaces {
+		if err := unix.Setns(int(files[index].Fd()), namespace.flags); err != nil {
+			return fmt.Errorf("failed to join %s namespace: %w", namespace.name, err)
+		}
+	}
+
+	// joining the mount namespace moved the root to the one of the namespace
+	if err := unix.Chroot(rootDir); err != nil {
+		return fmt.Errorf("failed to change the root directory: %w", err)
+	} else if err = syscall.Chdir("/"); err != nil {
+		return fmt.Errorf("failed to change directory: %w", err)
+	}
+	return nil
+}
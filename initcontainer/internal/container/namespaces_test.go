@@ -0,0 +1,115 @@
+package container
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/baepo-cloud/baepo-node/core/types"
+	"golang.org/x/sys/unix"
+)
+
+// newTestRoot mounts a tmpfs with the binaries of the host, its device tells the root apart from
+// the one of the host.
+func newTestRoot(t *testing.T) string {
+	t.Helper()
+
+	rootDir := t.TempDir()
+	if err := unix.Mount("tmpfs", rootDir, "tmpfs", 0, ""); err != nil {
+		t.Skipf("failed to mount tmpfs: %v", err)
+	}
+	t.Cleanup(func() { _ = unix.Unmount(rootDir, unix.MNT_DETACH) })
+
+	for _, dir := range []string{"/bin", "/sbin", "/lib", "/lib64", "/usr", "/dev"} {
+		info, err := os.Lstat(dir)
+		if err != nil {
+			continue
+		}
+
+		target := filepath.Join(rootDir, dir)
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, _ := os.Readlink(dir)
+			if err = os.Symlink(link, target); err != nil {
+				t.Fatalf("failed to link %s: %v", dir, err)
+			}
+			continue
+		}
+
+		if err = os.Mkdir(target, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		} else if err = unix.Mount(dir, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			t.Fatalf("failed to bind %s: %v", dir, err)
+		}
+		t.Cleanup(func() { _ = unix.Unmount(target, unix.MNT_DETACH) })
+	}
+	return rootDir
+}
+
+func TestExecJoinsContainer(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("namespaces can only be created as root")
+	}
+
+	rootDir := newTestRoot(t)
+	init := exec.Command("/bin/sleep", "60")
+	init.SysProcAttr = &syscall.SysProcAttr{
+		Chroot:     rootDir,
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWUTS,
+	}
+	if err := init.Start(); err != nil {
+		t.Skipf("failed to start container process: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = init.Process.Kill()
+		_ = init.Wait()
+	})
+
+	pid, err := findContainerProcess(rootDir)
+	if err != nil {
+		t.Fatalf("findContainerProcess() error = %v", err)
+	} else if pid != init.Process.Pid {
+		t.Fatalf("findContainerProcess() = %d, want %d", pid, init.Process.Pid)
+	}
+
+	// the thread joining the container is discarded along with its goroutine
+	type result struct {
+		output string
+		err    error
+	}
+	done := make(chan result)
+	go func() {
+		if err := joinContainer(pid, rootDir); err != nil {
+			done <- result{err: err}
+			return
+		}
+
+		c := &Container{config: types.InitContainerConfig{}, rootDir: rootDir}
+		cmd, err := c.newCommand([]string{"/bin/sh", "-c", "echo $$; kill -0 1 && echo alive"}, nil, nil)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		output, err := cmd.Output()
+		done <- result{output: string(output), err: err}
+	}()
+
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("exec error = %v", r.err)
+	}
+
+	// a fresh pid namespace would make the shell its pid 1, the container process is pid 1
+	lines := strings.Fields(r.output)
+	if len(lines) != 2 || lines[0] == "1" || lines[1] != "alive" {
+		t.Errorf("exec output = %q, want a pid other than 1 and pid 1 alive", r.output)
+	}
+}
+
+func TestFindContainerProcessNotRunning(t *testing.T) {
+	if _, err := findContainerProcess(t.TempDir()); err == nil {
+		t.Error("findContainerProcess() error = nil without container process")
+	}
+}
@@ -3,11 +3,13 @@ package container
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"syscall"
 )
 
 var (
+	// dnsServers are used when the runtime gives no resolver.
 	dnsServers = []string{
 		"1.1.1.1",
 		"1.0.0.1",
@@ -36,16 +38,26 @@ func (c *Container) setupNetworking() error {
 		return fmt.Errorf("failed to write /etc/hostname: %w", err)
 	}
 
-	resolvEntries := make([]string, len(dnsServers))
-	for index, server := range dnsServers {
-		resolvEntries[index] = fmt.Sprintf("nameserver %v", server)
+	nameservers := c.config.DNS.Nameservers
+	if len(nameservers) == 0 {
+		nameservers = dnsServers
+	}
+
+	var resolvEntries []string
+	if c.config.DNS.SearchDomain != "" {
+		resolvEntries = append(resolvEntries, fmt.Sprintf("search %v", c.config.DNS.SearchDomain))
+	}
+	for _, server := range nameservers {
+		resolvEntries = append(resolvEntries, fmt.Sprintf("nameserver %v", server))
 	}
 
 	if err := os.WriteFile("/etc/resolv.conf", []byte(strings.Join(resolvEntries, "\n")+"\n"), 0x0755); err != nil {
 		return fmt.Errorf("failed to write /etc/resolv.conf: %w", err)
 	}
 
-	if err := os.WriteFile("/etc/hosts", []byte(strings.Join(defaultHosts, "\n")+"\n"), 0x0755); err != nil {
+	// the hostname resolves without asking the resolver
+	hosts := append(slices.Clone(defaultHosts), fmt.Sprintf("127.0.1.1 %v", hostname))
+	if err := os.WriteFile("/etc/hosts", []byte(strings.Join(hosts, "\n")+"\n"), 0x0755); err != nil {
 		return fmt.Errorf("failed to write /etc/hosts: %w", err)
 	}
 
@@ -2,12 +2,18 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/baepo-cloud/baepo-node/core/types"
 	"github.com/baepo-cloud/baepo-node/initcontainer/internal/container"
 	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "exec" {
+		runExec()
+		return
+	}
+
 	var config types.InitContainerConfig
 	if err := json.Unmarshal([]byte(os.Args[1]), &config); err != nil {
 		panic(err)
@@ -17,3 +23,25 @@ func main() {
 		panic(err)
 	}
 }
+
+// runExec spawns a process in a running container: initcontainer exec <container-config> <exec-config>
+func runExec() {
+	var config types.InitContainerConfig
+	if err := json.Unmarshal([]byte(os.Args[2]), &config); err != nil {
+		panic(err)
+	}
+
+	var execConfig types.InitContainerExecConfig
+	if err := json.Unmarshal([]byte(os.Args[3]), &execConfig); err != nil {
+		panic(err)
+	}
+
+	exitCode, err := container.New(config).Exec(execConfig)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		// same exit code as shells when a command can not be executed
+		os.Exit(126)
+	}
+
+	os.Exit(exitCode)
+}
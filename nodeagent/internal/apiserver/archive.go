@@ -0,0 +1,77 @@
+package apiserver
+
+import (
+	"bufio"
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"io"
+)
+
+func (s *Server) UploadArchive(ctx context.Context, stream *connect.ClientStream[nodeapi.UploadArchiveRequest]) (*connect.Response[nodeapi.UploadArchiveResponse], error) {
+	if !stream.Receive() {
+		if err := stream.Err(); err != nil {
+			return nil, err
+		}
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("missing archive destination"))
+	}
+
+	first := stream.Msg()
+	pending := first.Data
+	archive := nodeapi.NewChunkReader(func() ([]byte, error) {
+		if len(pending) > 0 {
+			data := pending
+			pending = nil
+			return data, nil
+		}
+
+		if !stream.Receive() {
+			if err := stream.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		return stream.Msg().Data, nil
+	})
+
+	err := s.machineService.UploadArchive(ctx, types.MachineArchiveOptions{
+		MachineID: first.MachineID,
+		Container: first.ContainerID,
+		Path:      first.Path,
+	}, archive)
+	if err != nil {
+		return nil, s.adaptArchiveError(err)
+	}
+
+	return connect.NewResponse(&nodeapi.UploadArchiveResponse{}), nil
+}
+
+func (s *Server) DownloadArchive(ctx context.Context, req *connect.Request[nodeapi.DownloadArchiveRequest], stream *connect.ServerStream[nodeapi.DownloadArchiveResponse]) error {
+	archive := bufio.NewWriterSize(nodeapi.NewChunkWriter(func(data []byte) error {
+		return stream.Send(&nodeapi.DownloadArchiveResponse{Data: data})
+	}), nodeapi.ArchiveChunkSize)
+
+	err := s.machineService.DownloadArchive(ctx, types.MachineArchiveOptions{
+		MachineID: req.Msg.MachineID,
+		Container: req.Msg.ContainerID,
+		Path:      req.Msg.Path,
+	}, archive)
+	if err != nil {
+		return s.adaptArchiveError(err)
+	}
+
+	return archive.Flush()
+}
+
+func (s *Server) adaptArchiveError(err error) error {
+	switch {
+	case errors.Is(err, types.ErrMachineNotFound), errors.Is(err, types.ErrContainerNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, types.ErrMachineNotRunning):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	default:
+		return err
+	}
+}
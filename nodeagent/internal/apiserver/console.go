@@ -0,0 +1,31 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Server) AttachConsole(ctx context.Context, stream *connect.BidiStream[nodeapi.ConsoleRequest, nodeapi.ConsoleResponse]) error {
+	first, err := stream.Receive()
+	if err != nil {
+		return err
+	} else if first.MachineID == "" {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("first console message must set the machine id"))
+	}
+
+	session, err := s.machineService.AttachConsole(ctx, first.MachineID)
+	switch {
+	case errors.Is(err, types.ErrMachineNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, types.ErrMachineNotRunning):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	case err != nil:
+		return err
+	}
+	defer session.Close()
+
+	return nodeapi.ProxyStream(first, stream, session.Stream)
+}
@@ -0,0 +1,35 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Server) Exec(ctx context.Context, stream *connect.BidiStream[nodeapi.ExecRequest, nodeapi.ExecResponse]) error {
+	start, err := stream.Receive()
+	if err != nil {
+		return err
+	} else if start.Start == nil || len(start.Start.Command) == 0 {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("first exec message must start a command"))
+	}
+
+	session, err := s.machineService.Exec(ctx, types.MachineExecOptions{
+		MachineID: start.Start.MachineID,
+		Container: start.Start.ContainerID,
+	})
+	switch {
+	case errors.Is(err, types.ErrMachineNotFound), errors.Is(err, types.ErrContainerNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, types.ErrMachineNotRunning):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	case err != nil:
+		return err
+	}
+	defer session.Close()
+
+	start.Start.ContainerID = session.ContainerID
+	return nodeapi.ProxyStream(start, stream, session.Stream)
+}
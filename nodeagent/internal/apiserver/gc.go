@@ -0,0 +1,36 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Server) RunGC(ctx context.Context, req *connect.Request[nodeapi.NodeRunGCRequest]) (*connect.Response[nodeapi.NodeRunGCResponse], error) {
+	report, err := s.machineService.PerformGC(ctx, types.GCOptions{DryRun: req.Msg.DryRun})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &nodeapi.NodeRunGCResponse{
+		DryRun:    report.DryRun,
+		Resources: make([]*nodeapi.GCResource, len(report.Resources)),
+		StartedAt: report.StartedAt,
+		Duration:  report.Duration,
+	}
+	for index, resource := range report.Resources {
+		res.Resources[index] = &nodeapi.GCResource{
+			Kind: string(resource.Kind),
+			Name: resource.Name,
+		}
+		if resource.Error != nil {
+			res.Resources[index].Error = typeutil.Ptr(resource.Error.Error())
+		}
+	}
+	for _, err = range report.Errors {
+		res.Errors = append(res.Errors, err.Error())
+	}
+	return connect.NewResponse(res), nil
+}
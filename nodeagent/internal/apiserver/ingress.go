@@ -0,0 +1,72 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Server) GetMachineIngressStats(ctx context.Context, req *connect.Request[nodeapi.NodeGetMachineIngressStatsRequest]) (*connect.Response[nodeapi.NodeGetMachineIngressStatsResponse], error) {
+	stats, err := s.machineService.GetIngressStats(ctx, req.Msg.MachineID)
+	switch {
+	case errors.Is(err, types.ErrMachineNotFound):
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, types.ErrMachineNotRunning):
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	case err != nil:
+		return nil, err
+	}
+
+	res := &nodeapi.NodeGetMachineIngressStatsResponse{
+		DefaultDeny: stats.DefaultDeny,
+		Rules:       make([]*nodeapi.IngressCounter, len(stats.Rules)),
+		Unmatched:   &nodeapi.IngressCounter{Packets: stats.Unmatched.Packets, Bytes: stats.Unmatched.Bytes},
+	}
+	for index, counter := range stats.Rules {
+		res.Rules[index] = &nodeapi.IngressCounter{Packets: counter.Packets, Bytes: counter.Bytes}
+	}
+	return connect.NewResponse(res), nil
+}
+
+func adaptIngressPolicy(policy *nodeapi.IngressPolicy) *coretypes.MachineIngressPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	result := &coretypes.MachineIngressPolicy{
+		DefaultDeny: policy.DefaultDeny,
+		Rules:       make([]coretypes.MachineIngressRule, len(policy.Rules)),
+	}
+	for index, rule := range policy.Rules {
+		result.Rules[index] = coretypes.MachineIngressRule{
+			Protocol:    coretypes.MachineIngressProtocol(rule.Protocol),
+			FromPort:    rule.FromPort,
+			ToPort:      rule.ToPort,
+			SourceCIDRs: rule.SourceCIDRs,
+		}
+	}
+	return result
+}
+
+func adaptAPIIngressPolicy(policy *coretypes.MachineIngressPolicy) *nodeapi.IngressPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	result := &nodeapi.IngressPolicy{
+		DefaultDeny: policy.DefaultDeny,
+		Rules:       make([]*nodeapi.IngressRule, len(policy.Rules)),
+	}
+	for index, rule := range policy.Rules {
+		result.Rules[index] = &nodeapi.IngressRule{
+			Protocol:    string(rule.Protocol),
+			FromPort:    rule.FromPort,
+			ToPort:      rule.ToPort,
+			SourceCIDRs: rule.SourceCIDRs,
+		}
+	}
+	return result
+}
@@ -7,6 +7,13 @@ import (
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	nodev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/node/v1"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	"strconv"
+	"time"
+)
+
+const (
+	MachineExpiresAtHeader        = "Baepo-Machine-Expires-At"
+	MachineRemainingSecondsHeader = "Baepo-Machine-Remaining-Seconds"
 )
 
 func (s *Server) ListMachines(ctx context.Context, _ *connect.Request[nodev1pb.NodeListMachinesRequest]) (*connect.Response[nodev1pb.NodeListMachinesResponse], error) {
@@ -30,9 +37,16 @@ func (s *Server) GetMachine(ctx context.Context, req *connect.Request[nodev1pb.N
 		return nil, err
 	}
 
-	return connect.NewResponse(&nodev1pb.NodeGetMachineResponse{
+	res := connect.NewResponse(&nodev1pb.NodeGetMachineResponse{
 		Machine: s.adaptMachine(machine),
-	}), nil
+	})
+	// nodev1pb.Machine has no timeout fields yet, the expiration is reported through headers
+	if expiresAt := machine.ExpiresAt(); expiresAt != nil {
+		remaining := max(time.Until(*expiresAt), 0)
+		res.Header().Set(MachineExpiresAtHeader, expiresAt.UTC().Format(time.RFC3339))
+		res.Header().Set(MachineRemainingSecondsHeader, strconv.FormatInt(int64(remaining.Seconds()), 10))
+	}
+	return res, nil
 }
 
 func (s *Server) GetMachineLogs(ctx context.Context, req *connect.Request[nodev1pb.NodeGetMachineLogsRequest], stream *connect.ServerStream[nodev1pb.NodeGetMachineLogsResponse]) error {
@@ -0,0 +1,58 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Server) PauseMachine(ctx context.Context, req *connect.Request[nodeapi.NodePauseMachineRequest]) (*connect.Response[nodeapi.NodePauseMachineResponse], error) {
+	machine, err := s.machineService.Pause(ctx, req.Msg.MachineID)
+	if errors.Is(err, types.ErrMachineNotRunning) {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.NodePauseMachineResponse{
+		Machine: s.adaptAPIMachine(machine),
+	}), nil
+}
+
+func (s *Server) ResumeMachine(ctx context.Context, req *connect.Request[nodeapi.NodeResumeMachineRequest]) (*connect.Response[nodeapi.NodeResumeMachineResponse], error) {
+	machine, err := s.machineService.Resume(ctx, req.Msg.MachineID)
+	if errors.Is(err, types.ErrMachineNotPaused) {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.NodeResumeMachineResponse{
+		Machine: s.adaptAPIMachine(machine),
+	}), nil
+}
+
+func (s *Server) adaptAPIMachine(machine *types.Machine) *nodeapi.Machine {
+	apiMachine := &nodeapi.Machine{
+		MachineID:    machine.ID,
+		State:        string(machine.State),
+		DesiredState: string(machine.DesiredState),
+		Cpus:         machine.Spec.Cpus,
+		MemoryMB:     machine.Spec.MemoryMB,
+		Ingress:      adaptAPIIngressPolicy(machine.Spec.Ingress),
+	}
+	if machine.NetworkInterface != nil {
+		apiMachine.Network = machine.NetworkInterface.Network
+	}
+	for _, port := range machine.PublishedPorts {
+		apiMachine.PublishedPorts = append(apiMachine.PublishedPorts, &nodeapi.PublishedPort{
+			ContainerID:   port.ContainerID,
+			Protocol:      string(port.Protocol),
+			HostPort:      port.HostPort,
+			ContainerPort: port.ContainerPort,
+		})
+	}
+	return apiMachine
+}
@@ -0,0 +1,35 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Server) PortForward(ctx context.Context, stream *connect.BidiStream[nodeapi.PortForwardRequest, nodeapi.PortForwardResponse]) error {
+	first, err := stream.Receive()
+	if err != nil {
+		return err
+	} else if first.MachineID == "" || first.Port == 0 {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("first port forward message must set the machine id and the port"))
+	}
+
+	session, err := s.machineService.PortForward(ctx, types.MachinePortForwardOptions{
+		MachineID: first.MachineID,
+		Container: first.ContainerID,
+	})
+	switch {
+	case errors.Is(err, types.ErrMachineNotFound), errors.Is(err, types.ErrContainerNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, types.ErrMachineNotRunning):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	case err != nil:
+		return err
+	}
+	defer session.Close()
+
+	first.ContainerID = session.ContainerID
+	return nodeapi.ProxyStream(first, stream, session.Stream)
+}
@@ -6,6 +6,8 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	"github.com/baepo-cloud/baepo-proto/go/baepo/node/v1/nodev1pbconnect"
 	"github.com/expected-so/canonicallog"
@@ -24,7 +26,10 @@ type Server struct {
 	httpServer          *http.Server
 }
 
-var _ nodev1pbconnect.NodeServiceHandler = (*Server)(nil)
+var (
+	_ nodev1pbconnect.NodeServiceHandler = (*Server)(nil)
+	_ nodeapi.NodeServiceHandler         = (*Server)(nil)
+)
 
 func New(
 	registrationService types.RegistrationService,
@@ -42,11 +47,13 @@ func (s *Server) Start(ctx context.Context) error {
 	slog.Info("starting api server", slog.String("addr", s.config.APIAddr))
 
 	mux := http.NewServeMux()
-	mux.Handle(nodev1pbconnect.NewNodeServiceHandler(s))
+	mux.Handle(nodev1pbconnect.NewNodeServiceHandler(s, tracing.Interceptor()))
+	mux.Handle(nodeapi.NewNodeServiceHandler(s, tracing.Interceptor()))
 
 	s.httpServer = &http.Server{
-		Addr:    s.config.APIAddr,
-		Handler: mux,
+		Addr:      s.config.APIAddr,
+		Handler:   mux,
+		Protocols: nodeapi.ServerProtocols(),
 	}
 
 	unixSocket := filepath.Join(s.config.StorageDirectory, "agent.sock")
@@ -61,6 +68,7 @@ func (s *Server) Start(ctx context.Context) error {
 			config := &tls.Config{
 				ClientAuth: tls.RequireAndVerifyClientCert,
 				MinVersion: tls.VersionTLS12,
+				NextProtos: []string{"h2", "http/1.1"},
 			}
 			if cert := s.registrationService.TLSCertificate(); cert != nil {
 				config.Certificates = []tls.Certificate{*cert}
@@ -0,0 +1,81 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Server) CreateSnapshot(ctx context.Context, req *connect.Request[nodeapi.NodeCreateSnapshotRequest]) (*connect.Response[nodeapi.NodeCreateSnapshotResponse], error) {
+	snapshot, err := s.machineService.CreateSnapshot(ctx, types.MachineCreateSnapshotOptions{
+		MachineID: req.Msg.MachineID,
+	})
+	if errors.Is(err, types.ErrMachineNotRunning) || errors.Is(err, types.ErrMachineBusy) {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.NodeCreateSnapshotResponse{
+		Snapshot: s.adaptSnapshot(snapshot),
+	}), nil
+}
+
+func (s *Server) ListSnapshots(ctx context.Context, req *connect.Request[nodeapi.NodeListSnapshotsRequest]) (*connect.Response[nodeapi.NodeListSnapshotsResponse], error) {
+	snapshots, err := s.machineService.ListSnapshots(ctx, types.MachineListSnapshotsOptions{
+		MachineID: req.Msg.MachineID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &nodeapi.NodeListSnapshotsResponse{
+		Snapshots: make([]*nodeapi.Snapshot, len(snapshots)),
+	}
+	for index, snapshot := range snapshots {
+		res.Snapshots[index] = s.adaptSnapshot(snapshot)
+	}
+	return connect.NewResponse(res), nil
+}
+
+func (s *Server) RestoreSnapshot(ctx context.Context, req *connect.Request[nodeapi.NodeRestoreSnapshotRequest]) (*connect.Response[nodeapi.NodeRestoreSnapshotResponse], error) {
+	machine, err := s.machineService.RestoreSnapshot(ctx, req.Msg.SnapshotID)
+	if errors.Is(err, types.ErrSnapshotNotFound) {
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	} else if errors.Is(err, types.ErrMachineNotStopped) {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.NodeRestoreSnapshotResponse{
+		Machine: s.adaptAPIMachine(machine),
+	}), nil
+}
+
+func (s *Server) DeleteSnapshot(ctx context.Context, req *connect.Request[nodeapi.NodeDeleteSnapshotRequest]) (*connect.Response[nodeapi.NodeDeleteSnapshotResponse], error) {
+	err := s.machineService.DeleteSnapshot(ctx, req.Msg.SnapshotID)
+	if errors.Is(err, types.ErrSnapshotNotFound) {
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.NodeDeleteSnapshotResponse{}), nil
+}
+
+func (s *Server) adaptSnapshot(snapshot *types.Snapshot) *nodeapi.Snapshot {
+	res := &nodeapi.Snapshot{
+		SnapshotID: snapshot.ID,
+		MachineID:  snapshot.MachineID,
+		Path:       snapshot.Path,
+		VolumeIDs:  make([]string, len(snapshot.Volumes)),
+		CreatedAt:  snapshot.CreatedAt,
+	}
+	for index, snapshotVolume := range snapshot.Volumes {
+		res.VolumeIDs[index] = snapshotVolume.VolumeID
+	}
+	return res
+}
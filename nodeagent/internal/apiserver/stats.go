@@ -0,0 +1,23 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"time"
+)
+
+func (s *Server) GetMachineStats(ctx context.Context, req *connect.Request[nodeapi.NodeGetMachineStatsRequest], stream *connect.ServerStream[nodeapi.MachineStats]) error {
+	interval := time.Duration(req.Msg.IntervalSeconds) * time.Second
+	err := s.machineService.GetStats(ctx, req.Msg.MachineID, interval, stream.Send)
+	switch {
+	case errors.Is(err, types.ErrMachineNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, types.ErrMachineNotRunning):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	default:
+		return err
+	}
+}
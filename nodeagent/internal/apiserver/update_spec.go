@@ -0,0 +1,46 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Server) UpdateMachineSpec(ctx context.Context, req *connect.Request[nodeapi.NodeUpdateMachineSpecRequest]) (*connect.Response[nodeapi.NodeUpdateMachineSpecResponse], error) {
+	opts := types.MachineUpdateSpecOptions{
+		MachineID:    req.Msg.MachineID,
+		Cpus:         req.Msg.Cpus,
+		MemoryMB:     req.Msg.MemoryMB,
+		Ingress:      adaptIngressPolicy(req.Msg.Ingress),
+		ClearIngress: req.Msg.ClearIngress,
+	}
+	if opts.Cpus == 0 && opts.MemoryMB == 0 {
+		machine, err := s.machineService.FindByID(ctx, req.Msg.MachineID)
+		if errors.Is(err, types.ErrMachineNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, err)
+		} else if err != nil {
+			return nil, err
+		}
+		opts.Cpus, opts.MemoryMB = machine.Spec.Cpus, machine.Spec.MemoryMB
+	}
+
+	machine, err := s.machineService.UpdateSpec(ctx, opts)
+	switch {
+	case errors.Is(err, types.ErrMachineNotFound):
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, types.ErrInvalidMachineSpec):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, types.ErrInsufficientCapacity):
+		return nil, connect.NewError(connect.CodeResourceExhausted, err)
+	case errors.Is(err, types.ErrMachineBusy):
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	case err != nil:
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.NodeUpdateMachineSpecResponse{
+		Machine: s.adaptAPIMachine(machine),
+	}), nil
+}
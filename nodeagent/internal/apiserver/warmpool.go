@@ -0,0 +1,30 @@
+package apiserver
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+)
+
+func (s *Server) GetWarmPoolStats(ctx context.Context, _ *connect.Request[nodeapi.NodeGetWarmPoolStatsRequest]) (*connect.Response[nodeapi.NodeGetWarmPoolStatsResponse], error) {
+	stats, err := s.machineService.GetWarmPoolStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &nodeapi.NodeGetWarmPoolStatsResponse{
+		Pools: make([]*nodeapi.WarmPoolStats, len(stats)),
+	}
+	for index, pool := range stats {
+		res.Pools[index] = &nodeapi.WarmPoolStats{
+			Images:    pool.Images,
+			Cpus:      pool.Cpus,
+			MemoryMB:  pool.MemoryMB,
+			Size:      pool.Size,
+			Available: pool.Available,
+			Hits:      pool.Hits,
+			Misses:    pool.Misses,
+		}
+	}
+	return connect.NewResponse(res), nil
+}
@@ -0,0 +1,116 @@
+// Package config loads the configuration of the node agent from an optional yaml file and the
+// environment, the environment variables take precedence over the file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the environment variable of the config file path, when the path is not given as a flag.
+const FileEnv = "NODE_CONFIG_FILE"
+
+// Load returns the validated configuration, the file is skipped when path is empty.
+func Load(path string) (*types.Config, error) {
+	config := Default()
+	if path != "" {
+		if err := decodeFile(path, config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(config); err != nil {
+		return nil, err
+	}
+
+	if config.StorageDirectory != "" && !filepath.IsAbs(config.StorageDirectory) {
+		absPath, err := filepath.Abs(config.StorageDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path of the storage directory: %w", err)
+		}
+		config.StorageDirectory = absPath
+	}
+
+	if err := Validate(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Default returns the configuration used for the settings set by neither the file nor the environment.
+func Default() *types.Config {
+	return &types.Config{
+		APIAddr:          ":3443",
+		GatewayAddr:      ":8443",
+		StorageDirectory: "/var/lib/baepo",
+		VolumeGroup:      "vg_baepo",
+		ControlPlaneURL:  "https://api.baepo.cloud",
+		MachineMaxCpus:   uint32(runtime.NumCPU()),
+		Events: types.EventsConfig{
+			Retention:           7 * 24 * time.Hour,
+			TerminatedRetention: 24 * time.Hour,
+			MaxPerMachine:       1000,
+			ReplayLimit:         100,
+		},
+		Admission: types.AdmissionConfig{
+			SystemReservedMemoryMB: 512,
+			CpuOvercommitRatio:     1,
+			MemoryOvercommitRatio:  1,
+		},
+		Network: types.NetworkConfig{
+			CIDR:              "192.168.100.0/24",
+			BridgeInterface:   "br0",
+			BlockedNetworks:   []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"},
+			FirewallBackend:   types.FirewallBackendIptables,
+			HostPortRange:     "30000-32767",
+			ReconcileInterval: 5 * time.Minute,
+			DNS: types.DNSConfig{
+				Domain:    "baepo.internal",
+				Upstreams: []string{"1.1.1.1", "1.0.0.1", "2606:4700:4700::1111", "2606:4700:4700::1001"},
+				CacheSize: 4096,
+			},
+		},
+	}
+}
+
+// Print writes the configuration as yaml, secrets are redacted.
+func Print(w io.Writer, config *types.Config) error {
+	redacted := *config
+	if redacted.BootstrapToken != "" {
+		redacted.BootstrapToken = "<redacted>"
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&redacted); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func decodeFile(path string, config *types.Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// unknown keys are rejected, a typo would otherwise silently fall back to the default
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to decode config file %v: %w", path, err)
+	}
+
+	return nil
+}
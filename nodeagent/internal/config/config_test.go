@@ -0,0 +1,206 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// setupRequired sets the settings without defaults through the environment, a runtime binary is
+// created as it must exist.
+func setupRequired(t *testing.T) {
+	t.Helper()
+
+	runtimeBinary := filepath.Join(t.TempDir(), "baepo-vmruntime")
+	if err := os.WriteFile(runtimeBinary, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("NODE_CLUSTER_ID", "cluster")
+	t.Setenv("NODE_BOOTSTRAP_TOKEN", "token")
+	t.Setenv("NODE_RUNTIME_BINARY", runtimeBinary)
+	t.Setenv("NODE_STORAGE_DIRECTORY", t.TempDir())
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		env    map[string]string
+		assert func(t *testing.T, config *types.Config)
+	}{
+		{
+			name: "defaults",
+			assert: func(t *testing.T, config *types.Config) {
+				if config.APIAddr != ":3443" {
+					t.Errorf("api_addr = %q, want the default", config.APIAddr)
+				}
+				if config.Events.Retention != 7*24*time.Hour {
+					t.Errorf("events.retention = %v, want the default", config.Events.Retention)
+				}
+			},
+		},
+		{
+			name: "file over defaults",
+			file: "api_addr: \":4443\"\nevents:\n  retention: 1h\n",
+			assert: func(t *testing.T, config *types.Config) {
+				if config.APIAddr != ":4443" {
+					t.Errorf("api_addr = %q, want the file value", config.APIAddr)
+				}
+				if config.Events.Retention != time.Hour {
+					t.Errorf("events.retention = %v, want the file value", config.Events.Retention)
+				}
+				if config.Events.MaxPerMachine != 1000 {
+					t.Errorf("events.max_per_machine = %d, want the default", config.Events.MaxPerMachine)
+				}
+			},
+		},
+		{
+			name: "environment over file",
+			file: "api_addr: \":4443\"\nevents:\n  retention: 1h\n",
+			env: map[string]string{
+				"NODE_API_ADDR":        ":5443",
+				"NODE_EVENT_RETENTION": "2h",
+			},
+			assert: func(t *testing.T, config *types.Config) {
+				if config.APIAddr != ":5443" {
+					t.Errorf("api_addr = %q, want the environment value", config.APIAddr)
+				}
+				if config.Events.Retention != 2*time.Hour {
+					t.Errorf("events.retention = %v, want the environment value", config.Events.Retention)
+				}
+			},
+		},
+		{
+			name: "empty environment values are ignored",
+			file: "api_addr: \":4443\"\n",
+			env:  map[string]string{"NODE_API_ADDR": ""},
+			assert: func(t *testing.T, config *types.Config) {
+				if config.APIAddr != ":4443" {
+					t.Errorf("api_addr = %q, want the file value", config.APIAddr)
+				}
+			},
+		},
+		{
+			name: "environment lists replace file lists",
+			file: "network:\n  dns:\n    upstreams: [\"9.9.9.9\"]\n",
+			env:  map[string]string{"NODE_NETWORK_DNS_UPSTREAMS": "8.8.8.8, 8.8.4.4"},
+			assert: func(t *testing.T, config *types.Config) {
+				want := []string{"8.8.8.8", "8.8.4.4"}
+				if !reflect.DeepEqual(config.Network.DNS.Upstreams, want) {
+					t.Errorf("network.dns.upstreams = %v, want %v", config.Network.DNS.Upstreams, want)
+				}
+			},
+		},
+		{
+			name: "empty blocked networks unblock every network",
+			env:  map[string]string{"NODE_NETWORK_BLOCKED_NETWORKS": ""},
+			assert: func(t *testing.T, config *types.Config) {
+				if len(config.Network.BlockedNetworks) != 0 {
+					t.Errorf("network.blocked_networks = %v, want none", config.Network.BlockedNetworks)
+				}
+			},
+		},
+		{
+			name: "warm pools from the environment",
+			file: "warm_pools:\n  - image: nginx\n    cpus: 1\n    memory_mb: 256\n",
+			env:  map[string]string{"NODE_WARM_POOLS": `[{"images":["nginx","redis"],"cpus":2,"memory_mb":512,"size":1}]`},
+			assert: func(t *testing.T, config *types.Config) {
+				want := []types.WarmPoolConfig{{Images: []string{"nginx", "redis"}, Cpus: 2, MemoryMB: 512, Size: 1}}
+				if !reflect.DeepEqual(config.WarmPools, want) {
+					t.Errorf("warm_pools = %+v, want %+v", config.WarmPools, want)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setupRequired(t)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			var path string
+			if test.file != "" {
+				path = writeConfigFile(t, test.file)
+			}
+
+			config, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			test.assert(t, config)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "unknown file key",
+			file:    "api_adr: \":4443\"\n",
+			wantErr: "field api_adr not found",
+		},
+		{
+			name:    "invalid environment duration",
+			env:     map[string]string{"NODE_EVENT_RETENTION": "soon"},
+			wantErr: "invalid NODE_EVENT_RETENTION env variable",
+		},
+		{
+			name:    "environment number out of range",
+			env:     map[string]string{"NODE_MACHINE_MAX_CPUS": "4294967296"},
+			wantErr: "invalid NODE_MACHINE_MAX_CPUS env variable",
+		},
+		{
+			name:    "invalid environment warm pools",
+			env:     map[string]string{"NODE_WARM_POOLS": "nginx"},
+			wantErr: "invalid NODE_WARM_POOLS env variable",
+		},
+		{
+			name:    "invalid value from the environment",
+			file:    "api_addr: \":4443\"\n",
+			env:     map[string]string{"NODE_API_ADDR": "4443"},
+			wantErr: "api_addr:",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setupRequired(t)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			var path string
+			if test.file != "" {
+				path = writeConfigFile(t, test.file)
+			}
+
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Load() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func applyEnv(config *types.Config) error {
+	envBool("DEBUG", &config.Debug)
+	envString("NODE_CLUSTER_ID", &config.ClusterID)
+	envString("NODE_BOOTSTRAP_TOKEN", &config.BootstrapToken)
+	envString("NODE_IP_ADDR", &config.IPAddr)
+	envString("NODE_API_ADDR", &config.APIAddr)
+	envString("NODE_GATEWAY_ADDR", &config.GatewayAddr)
+	envString("NODE_METRICS_ADDR", &config.MetricsAddr)
+	envString("NODE_STORAGE_DIRECTORY", &config.StorageDirectory)
+	envString("NODE_RUNTIME_BINARY", &config.RuntimeBinary)
+	envString("NODE_VOLUME_GROUP", &config.VolumeGroup)
+	envString("NODE_CONTROL_PLANE_URL", &config.ControlPlaneURL)
+	envBool("NODE_GC_DRY_RUN", &config.GCDryRun)
+	envString("NODE_SNAPSHOT_DIRECTORY", &config.SnapshotDirectory)
+	envString("NODE_TRACING_EXPORTER", &config.Tracing.Exporter)
+	envString("NODE_TRACING_ENDPOINT", &config.Tracing.Endpoint)
+	envString("NODE_TRACING_FILE", &config.Tracing.File)
+	envString("NODE_NETWORK_CIDR", &config.Network.CIDR)
+	envString("NODE_NETWORK_IPV6_PREFIX", &config.Network.IPv6Prefix)
+	envString("NODE_NETWORK_BRIDGE_INTERFACE", &config.Network.BridgeInterface)
+	envString("NODE_NETWORK_UPLINK_INTERFACE", &config.Network.UplinkInterface)
+	envString("NODE_NETWORK_HOST_PORT_RANGE", &config.Network.HostPortRange)
+	envBool("NODE_NETWORK_DNS_DISABLED", &config.Network.DNS.Disabled)
+	envString("NODE_NETWORK_DNS_DOMAIN", &config.Network.DNS.Domain)
+	if backend, ok := os.LookupEnv("NODE_NETWORK_FIREWALL_BACKEND"); ok {
+		config.Network.FirewallBackend = types.FirewallBackend(backend)
+	}
+	if blockedNetworks, ok := os.LookupEnv("NODE_NETWORK_BLOCKED_NETWORKS"); ok {
+		// an empty value unblocks every network
+		config.Network.BlockedNetworks = nil
+		for _, network := range strings.Split(blockedNetworks, ",") {
+			if network = strings.TrimSpace(network); network != "" {
+				config.Network.BlockedNetworks = append(config.Network.BlockedNetworks, network)
+			}
+		}
+	}
+
+	if upstreams := os.Getenv("NODE_NETWORK_DNS_UPSTREAMS"); upstreams != "" {
+		config.Network.DNS.Upstreams = nil
+		for _, upstream := range strings.Split(upstreams, ",") {
+			if upstream = strings.TrimSpace(upstream); upstream != "" {
+				config.Network.DNS.Upstreams = append(config.Network.DNS.Upstreams, upstream)
+			}
+		}
+	}
+
+	if err := envDuration("NODE_EVENT_RETENTION", &config.Events.Retention); err != nil {
+		return err
+	} else if err = envDuration("NODE_EVENT_TERMINATED_RETENTION", &config.Events.TerminatedRetention); err != nil {
+		return err
+	} else if err = envInt("NODE_EVENT_MAX_PER_MACHINE", &config.Events.MaxPerMachine); err != nil {
+		return err
+	} else if err = envInt("NODE_EVENT_REPLAY_LIMIT", &config.Events.ReplayLimit); err != nil {
+		return err
+	} else if err = envUint("NODE_SYSTEM_RESERVED_CPUS", &config.Admission.SystemReservedCpus); err != nil {
+		return err
+	} else if err = envUint("NODE_SYSTEM_RESERVED_MEMORY_MB", &config.Admission.SystemReservedMemoryMB); err != nil {
+		return err
+	} else if err = envUint("NODE_SYSTEM_RESERVED_DISK_MB", &config.Admission.SystemReservedDiskMB); err != nil {
+		return err
+	} else if err = envFloat("NODE_CPU_OVERCOMMIT_RATIO", &config.Admission.CpuOvercommitRatio); err != nil {
+		return err
+	} else if err = envFloat("NODE_MEMORY_OVERCOMMIT_RATIO", &config.Admission.MemoryOvercommitRatio); err != nil {
+		return err
+	} else if err = envUint("NODE_MACHINE_MAX_CPUS", &config.MachineMaxCpus); err != nil {
+		return err
+	} else if err = envUint("NODE_MACHINE_HOTPLUG_MEMORY_MB", &config.MachineHotplugMemoryMB); err != nil {
+		return err
+	} else if err = envInt("NODE_NETWORK_DNS_CACHE_SIZE", &config.Network.DNS.CacheSize); err != nil {
+		return err
+	} else if err = envDuration("NODE_NETWORK_RECONCILE_INTERVAL", &config.Network.ReconcileInterval); err != nil {
+		return err
+	}
+
+	if warmPools := os.Getenv("NODE_WARM_POOLS"); warmPools != "" {
+		config.WarmPools = nil
+		if err := json.Unmarshal([]byte(warmPools), &config.WarmPools); err != nil {
+			return fmt.Errorf("invalid NODE_WARM_POOLS env variable: %w", err)
+		}
+	}
+
+	if privateNetworks := os.Getenv("NODE_NETWORK_PRIVATE_NETWORKS"); privateNetworks != "" {
+		config.Network.PrivateNetworks = nil
+		if err := json.Unmarshal([]byte(privateNetworks), &config.Network.PrivateNetworks); err != nil {
+			return fmt.Errorf("invalid NODE_NETWORK_PRIVATE_NETWORKS env variable: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func envString(name string, target *string) {
+	if value := os.Getenv(name); value != "" {
+		*target = value
+	}
+}
+
+func envBool(name string, target *bool) {
+	if value := os.Getenv(name); value != "" {
+		*target = value == "true"
+	}
+}
+
+func envDuration(name string, target *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s env variable: %w", name, err)
+	}
+	*target = duration
+	return nil
+}
+
+func envInt(name string, target *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s env variable: %w", name, err)
+	}
+	*target = number
+	return nil
+}
+
+func envUint[T uint32 | uint64](name string, target *T) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err == nil && uint64(T(number)) != number {
+		err = strconv.ErrRange
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s env variable: %w", name, err)
+	}
+	*target = T(number)
+	return nil
+}
+
+func envFloat(name string, target *float64) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s env variable: %w", name, err)
+	}
+	*target = number
+	return nil
+}
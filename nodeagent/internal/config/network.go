@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/vishvananda/netlink"
+)
+
+// maxInterfaceNameLength is IFNAMSIZ without the terminating null byte.
+const maxInterfaceNameLength = 15
+
+func validateNetwork(config types.NetworkConfig, check func(field string, err error)) {
+	check("network.bridge_interface", validateInterfaceName(config.BridgeInterface))
+	if config.UplinkInterface != "" {
+		check("network.uplink_interface", validateInterfaceName(config.UplinkInterface))
+	}
+	switch config.FirewallBackend {
+	case "", types.FirewallBackendIptables, types.FirewallBackendNftables:
+	default:
+		check("network.firewall_backend", fmt.Errorf("unknown backend %v", config.FirewallBackend))
+	}
+	if _, _, err := config.ParseHostPortRange(); err != nil {
+		check("network.host_port_range", err)
+	}
+	for index, network := range config.BlockedNetworks {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			check(fmt.Sprintf("network.blocked_networks[%d]", index), err)
+		}
+	}
+
+	if config.ReconcileInterval <= 0 {
+		check("network.reconcile_interval", errors.New("must be positive"))
+	}
+	validateDNS(config.DNS, check)
+
+	cidr := validateNetworkCIDR("network.cidr", config.CIDR, config.BridgeInterface, check)
+	validatePrivateNetworks(config, cidr, check)
+	if cidr == nil || config.IPv6Prefix == "" {
+		return
+	}
+
+	_, prefix, err := net.ParseCIDR(config.IPv6Prefix)
+	if err != nil {
+		check("network.ipv6_prefix", err)
+		return
+	}
+
+	// every offset of the ipv4 network must have an address in the prefix
+	ones, bits := cidr.Mask.Size()
+	prefixOnes, prefixBits := prefix.Mask.Size()
+	if prefix.IP.To4() != nil {
+		check("network.ipv6_prefix", errors.New("must be an ipv6 network"))
+	} else if prefixBits-prefixOnes < bits-ones {
+		check("network.ipv6_prefix", fmt.Errorf("must have at least as many host bits as network.cidr, /%d at most", prefixBits-(bits-ones)))
+	}
+	check("network.ipv6_prefix", validateNoRouteOverlap(prefix, config.BridgeInterface, netlink.FAMILY_V6))
+}
+
+// validateNetworkCIDR returns the network of the machines of a bridge, nil when it is invalid.
+func validateNetworkCIDR(field, value, bridgeInterface string, check func(field string, err error)) *net.IPNet {
+	_, cidr, err := net.ParseCIDR(value)
+	if err != nil {
+		check(field, err)
+		return nil
+	}
+
+	// the tap interfaces and the mac addresses are numbered after the address offset, which
+	// leaves 24 bits for the host part
+	ones, bits := cidr.Mask.Size()
+	if cidr.IP.To4() == nil {
+		check(field, errors.New("must be an ipv4 network"))
+	} else if ones < 8 || ones > 30 {
+		check(field, fmt.Errorf("prefix length must be between 8 and 30, got %d", ones))
+	} else if bits-ones > 24 {
+		check(field, errors.New("host part must not exceed 24 bits"))
+	}
+	check(field, validateNoRouteOverlap(cidr, bridgeInterface, netlink.FAMILY_V4))
+	return cidr
+}
+
+// validatePrivateNetworks rejects the networks sharing a name, an id, a bridge or addresses
+// with another network, the default one included.
+func validatePrivateNetworks(config types.NetworkConfig, defaultCIDR *net.IPNet, check func(field string, err error)) {
+	var (
+		names   = map[string]bool{}
+		ids     = map[uint8]bool{}
+		bridges = map[string]bool{config.BridgeInterface: true}
+		cidrs   []*net.IPNet
+	)
+	if defaultCIDR != nil {
+		cidrs = append(cidrs, defaultCIDR)
+	}
+	for index, network := range config.PrivateNetworks {
+		field := fmt.Sprintf("network.private_networks[%d]", index)
+		if network.Name == "" {
+			check(field+".name", errors.New("required"))
+		} else if names[network.Name] {
+			check(field+".name", fmt.Errorf("network %v is already defined", network.Name))
+		}
+		if network.ID == 0 {
+			check(field+".id", errors.New("must be between 1 and 255"))
+		} else if ids[network.ID] {
+			check(field+".id", fmt.Errorf("id %d is already used", network.ID))
+		}
+		if err := validateInterfaceName(network.BridgeInterface); err != nil {
+			check(field+".bridge_interface", err)
+		} else if bridges[network.BridgeInterface] {
+			check(field+".bridge_interface", fmt.Errorf("bridge %v is already used", network.BridgeInterface))
+		}
+		names[network.Name], ids[network.ID], bridges[network.BridgeInterface] = true, true, true
+
+		cidr := validateNetworkCIDR(field+".cidr", network.CIDR, network.BridgeInterface, check)
+		if cidr == nil {
+			continue
+		}
+		for _, other := range cidrs {
+			if other.Contains(cidr.IP) || cidr.Contains(other.IP) {
+				check(field+".cidr", fmt.Errorf("overlaps the network %v", other))
+			}
+		}
+		cidrs = append(cidrs, cidr)
+	}
+}
+
+func validateDNS(config types.DNSConfig, check func(field string, err error)) {
+	if len(config.Upstreams) == 0 {
+		check("network.dns.upstreams", errors.New("required"))
+	}
+	for index, upstream := range config.Upstreams {
+		if net.ParseIP(upstream) == nil {
+			check(fmt.Sprintf("network.dns.upstreams[%d]", index), fmt.Errorf("invalid ip address %v", upstream))
+		}
+	}
+	if config.CacheSize < 0 {
+		check("network.dns.cache_size", errors.New("must not be negative"))
+	}
+
+	// the domain is written to the resolv.conf of the containers
+	if config.Domain != "" && !isDomainName(config.Domain) {
+		check("network.dns.domain", fmt.Errorf("invalid domain %v", config.Domain))
+	}
+}
+
+// isDomainName accepts the lowercase names made of letters, digits and hyphens.
+func isDomainName(name string) bool {
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") ||
+			strings.Trim(label, "abcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func validateInterfaceName(name string) error {
+	if name == "" {
+		return errors.New("required")
+	} else if len(name) > maxInterfaceNameLength {
+		return fmt.Errorf("%v is longer than %d characters", name, maxInterfaceNameLength)
+	}
+	return nil
+}
+
+// validateNoRouteOverlap rejects a network overlapping a route of the host, the routes of the
+// bridge are skipped since they are the network itself once the node agent has started.
+func validateNoRouteOverlap(cidr *net.IPNet, bridgeInterface string, family int) error {
+	routes, err := netlink.RouteList(nil, family)
+	if err != nil {
+		return fmt.Errorf("failed to list host routes: %w", err)
+	}
+
+	bridgeIndex := -1
+	if bridge, err := netlink.LinkByName(bridgeInterface); err == nil {
+		bridgeIndex = bridge.Attrs().Index
+	}
+
+	for _, route := range routes {
+		if route.Dst == nil || route.LinkIndex == bridgeIndex || route.Dst.IP.IsLinkLocalUnicast() || route.Dst.IP.IsMulticast() {
+			continue
+		} else if ones, _ := route.Dst.Mask.Size(); ones == 0 {
+			continue
+		}
+
+		if route.Dst.Contains(cidr.IP) || cidr.Contains(route.Dst.IP) {
+			return fmt.Errorf("overlaps the host route %v", route.Dst)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// Validate checks the whole configuration and returns all the problems found at once.
+func Validate(config *types.Config) error {
+	var errs []error
+	check := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", field, err))
+		}
+	}
+
+	check("cluster_id", required(config.ClusterID))
+	check("bootstrap_token", required(config.BootstrapToken))
+	check("volume_group", required(config.VolumeGroup))
+	if config.IPAddr != "" && net.ParseIP(config.IPAddr) == nil {
+		check("ip_addr", fmt.Errorf("invalid ip address %v", config.IPAddr))
+	}
+	check("api_addr", validateListenAddr(config.APIAddr))
+	check("gateway_addr", validateListenAddr(config.GatewayAddr))
+	if config.MetricsAddr != "" {
+		check("metrics_addr", validateListenAddr(config.MetricsAddr))
+	}
+	check("control_plane_url", validateURL(config.ControlPlaneURL))
+	check("storage_directory", validateDirectory(config.StorageDirectory))
+	if config.SnapshotDirectory != "" {
+		check("snapshot_directory", validateDirectory(config.SnapshotDirectory))
+	}
+	check("runtime_binary", validateBinary(config.RuntimeBinary))
+
+	if config.MachineMaxCpus == 0 {
+		check("machine_max_cpus", errors.New("must be positive"))
+	}
+	if config.Events.Retention <= 0 {
+		check("events.retention", errors.New("must be positive"))
+	}
+	if config.Events.TerminatedRetention <= 0 {
+		check("events.terminated_retention", errors.New("must be positive"))
+	}
+	if config.Events.MaxPerMachine <= 0 {
+		check("events.max_per_machine", errors.New("must be positive"))
+	}
+	if config.Events.ReplayLimit < 0 {
+		check("events.replay_limit", errors.New("must not be negative"))
+	}
+	if config.Admission.CpuOvercommitRatio <= 0 {
+		check("admission.cpu_overcommit_ratio", errors.New("must be positive"))
+	}
+	if config.Admission.MemoryOvercommitRatio <= 0 {
+		check("admission.memory_overcommit_ratio", errors.New("must be positive"))
+	}
+
+	for index, pool := range config.WarmPools {
+		if pool.Image != "" && len(pool.Images) > 0 {
+			check(fmt.Sprintf("warm_pools[%d]", index), errors.New("image and images are mutually exclusive"))
+		} else if slices.Contains(pool.GetImages(), "") || pool.Cpus == 0 || pool.MemoryMB == 0 {
+			check(fmt.Sprintf("warm_pools[%d]", index), errors.New("an image, cpus and memory_mb are required"))
+		}
+	}
+
+	validateNetwork(config.Network, check)
+
+	switch config.Tracing.Exporter {
+	case "", coretypes.TracingExporterStdout:
+	case coretypes.TracingExporterOTLP:
+		if config.Tracing.Endpoint != "" {
+			check("tracing.endpoint", validateURL(config.Tracing.Endpoint))
+		}
+	case coretypes.TracingExporterFile:
+		if config.Tracing.File == "" {
+			check("tracing.file", errors.New("required by the file exporter"))
+		} else {
+			check("tracing.file", validateDirectory(filepath.Dir(config.Tracing.File)))
+		}
+	default:
+		check("tracing.exporter", fmt.Errorf("unknown exporter %v", config.Tracing.Exporter))
+	}
+
+	return errors.Join(errs...)
+}
+
+func required(value string) error {
+	if value == "" {
+		return errors.New("required")
+	}
+	return nil
+}
+
+func validateListenAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if number, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port %v", port)
+	} else if number == 0 {
+		return errors.New("port must not be 0")
+	}
+	return nil
+}
+
+func validateURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return err
+	} else if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", parsed.Scheme)
+	} else if parsed.Host == "" {
+		return errors.New("missing host")
+	}
+	return nil
+}
+
+// validateDirectory accepts a missing directory, it only rejects a path which can not be one.
+func validateDirectory(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("%v is not an absolute path", path)
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%v is not a directory", path)
+	}
+	return nil
+}
+
+func validateBinary(path string) error {
+	if path == "" {
+		return errors.New("required")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	} else if !info.Mode().IsRegular() {
+		return fmt.Errorf("%v is not a regular file", path)
+	} else if info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("%v is not executable", path)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// validConfig returns the default configuration completed with the settings without defaults.
+func validConfig(t *testing.T) *types.Config {
+	t.Helper()
+
+	runtimeBinary := filepath.Join(t.TempDir(), "baepo-vmruntime")
+	if err := os.WriteFile(runtimeBinary, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Default()
+	config.ClusterID = "cluster"
+	config.BootstrapToken = "token"
+	config.RuntimeBinary = runtimeBinary
+	config.StorageDirectory = t.TempDir()
+	return config
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(t *testing.T, config *types.Config)
+		wantErr []string
+	}{
+		{
+			name:   "valid",
+			mutate: func(t *testing.T, config *types.Config) {},
+		},
+		{
+			name: "missing required settings",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.ClusterID = ""
+				config.BootstrapToken = ""
+				config.VolumeGroup = ""
+			},
+			wantErr: []string{"cluster_id: required", "bootstrap_token: required", "volume_group: required"},
+		},
+		{
+			name: "invalid addresses",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.IPAddr = "node"
+				config.APIAddr = ":0"
+				config.GatewayAddr = ":http"
+				config.ControlPlaneURL = "ftp://api.baepo.cloud"
+			},
+			wantErr: []string{"ip_addr:", "api_addr: port must not be 0", "gateway_addr: invalid port", "control_plane_url: unsupported scheme"},
+		},
+		{
+			name: "relative storage directory",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.StorageDirectory = "baepo"
+			},
+			wantErr: []string{"storage_directory: baepo is not an absolute path"},
+		},
+		{
+			name: "storage directory is a file",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.StorageDirectory = config.RuntimeBinary
+			},
+			wantErr: []string{"storage_directory:", "is not a directory"},
+		},
+		{
+			name: "runtime binary is not executable",
+			mutate: func(t *testing.T, config *types.Config) {
+				if err := os.Chmod(config.RuntimeBinary, 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: []string{"runtime_binary:", "is not executable"},
+		},
+		{
+			name: "non positive limits",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.MachineMaxCpus = 0
+				config.Events.Retention = 0
+				config.Events.ReplayLimit = -1
+				config.Admission.MemoryOvercommitRatio = 0
+			},
+			wantErr: []string{
+				"machine_max_cpus: must be positive",
+				"events.retention: must be positive",
+				"events.replay_limit: must not be negative",
+				"admission.memory_overcommit_ratio: must be positive",
+			},
+		},
+		{
+			name: "incomplete warm pool",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.WarmPools = []types.WarmPoolConfig{
+					{Image: "nginx", Cpus: 1, MemoryMB: 256},
+					{Image: "nginx", Cpus: 1},
+					{Images: []string{"nginx", ""}, Cpus: 1, MemoryMB: 256},
+				}
+			},
+			wantErr: []string{"warm_pools[1]: an image, cpus and memory_mb are required", "warm_pools[2]: an image"},
+		},
+		{
+			name: "warm pool with image and images",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.WarmPools = []types.WarmPoolConfig{{Image: "nginx", Images: []string{"redis"}, Cpus: 1, MemoryMB: 256}}
+			},
+			wantErr: []string{"warm_pools[0]: image and images are mutually exclusive"},
+		},
+		{
+			name: "unknown backends",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.Network.FirewallBackend = "pf"
+				config.Tracing.Exporter = "jaeger"
+			},
+			wantErr: []string{"network.firewall_backend: unknown backend pf", "tracing.exporter: unknown exporter jaeger"},
+		},
+		{
+			name: "file exporter without file",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.Tracing.Exporter = "file"
+			},
+			wantErr: []string{"tracing.file: required by the file exporter"},
+		},
+		{
+			name: "invalid network",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.Network.CIDR = "192.168.100.0/31"
+				config.Network.BlockedNetworks = []string{"10.0.0.0"}
+				config.Network.HostPortRange = "32767-30000"
+			},
+			wantErr: []string{"network.cidr: prefix length must be between", "network.blocked_networks[0]:", "network.host_port_range:"},
+		},
+		{
+			name: "ipv4 prefix as ipv6 prefix",
+			mutate: func(t *testing.T, config *types.Config) {
+				config.Network.IPv6Prefix = "10.1.0.0/16"
+			},
+			wantErr: []string{"network.ipv6_prefix: must be an ipv6 network"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := validConfig(t)
+			test.mutate(t, config)
+
+			err := Validate(config)
+			if len(test.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			} else if err == nil {
+				t.Fatalf("Validate() error = nil, want %q", test.wantErr)
+			}
+
+			// every problem is reported at once
+			for _, wantErr := range test.wantErr {
+				if !strings.Contains(err.Error(), wantErr) {
+					t.Errorf("Validate() error = %v, want %q", err, wantErr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package dnsserver
+
+import (
+	"golang.org/x/net/dns/dnsmessage"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCacheTTL bounds how long an answer is kept, whatever its ttl.
+const maxCacheTTL = time.Hour
+
+type (
+	// cache keeps the forwarded answers until their ttl expires. When it is full the expired
+	// answers are evicted first, then arbitrary ones.
+	cache struct {
+		size    int
+		lock    sync.Mutex
+		entries map[cacheKey]cacheEntry
+	}
+
+	cacheKey struct {
+		name  string
+		qtype dnsmessage.Type
+		class dnsmessage.Class
+	}
+
+	cacheEntry struct {
+		answer    *answer
+		storedAt  time.Time
+		expiresAt time.Time
+	}
+)
+
+func newCache(size int) *cache {
+	return &cache{size: size, entries: map[cacheKey]cacheEntry{}}
+}
+
+// get returns a copy of the cached answer, with the ttl of its records lowered by the time it
+// spent in the cache.
+func (c *cache) get(question dnsmessage.Question) (*answer, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := newCacheKey(question)
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	} else if now := time.Now(); !now.Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	elapsed := uint32(time.Since(entry.storedAt) / time.Second)
+	return &answer{
+		rcode:       entry.answer.rcode,
+		answers:     ageResources(entry.answer.answers, elapsed),
+		authorities: ageResources(entry.answer.authorities, elapsed),
+	}, true
+}
+
+// put caches the answer for its ttl, the answers without a ttl are not cached.
+func (c *cache) put(question dnsmessage.Question, answer *answer) {
+	ttl := min(answerTTL(answer), maxCacheTTL)
+	if c.size == 0 || ttl <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.entries) >= c.size {
+		now := time.Now()
+		for key, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.size {
+			break
+		}
+		delete(c.entries, key)
+	}
+
+	now := time.Now()
+	c.entries[newCacheKey(question)] = cacheEntry{answer: answer, storedAt: now, expiresAt: now.Add(ttl)}
+}
+
+func newCacheKey(question dnsmessage.Question) cacheKey {
+	return cacheKey{name: strings.ToLower(question.Name.String()), qtype: question.Type, class: question.Class}
+}
+
+// answerTTL is the lowest ttl of the records. A negative answer is kept for the ttl of the soa
+// record of the zone, bounded by its minimum, it is not cached without one.
+func answerTTL(answer *answer) time.Duration {
+	var ttl uint32
+	found := false
+	for _, resource := range answer.answers {
+		if !found || resource.Header.TTL < ttl {
+			ttl, found = resource.Header.TTL, true
+		}
+	}
+	if len(answer.answers) == 0 {
+		for _, resource := range answer.authorities {
+			if soa, ok := resource.Body.(*dnsmessage.SOAResource); ok {
+				ttl, found = min(resource.Header.TTL, soa.MinTTL), true
+			}
+		}
+	}
+	if !found {
+		return 0
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+func ageResources(resources []dnsmessage.Resource, elapsed uint32) []dnsmessage.Resource {
+	aged := make([]dnsmessage.Resource, len(resources))
+	for index, resource := range resources {
+		aged[index] = resource
+		aged[index].Header.TTL -= min(elapsed, resource.Header.TTL)
+	}
+	return aged
+}
@@ -0,0 +1,179 @@
+package dnsserver
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func newQuestion(t *testing.T, name string, qtype dnsmessage.Type) dnsmessage.Question {
+	t.Helper()
+	return dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}
+}
+
+func newAResource(t *testing.T, name string, ttl uint32) dnsmessage.Resource {
+	t.Helper()
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+	}
+}
+
+func newSOAResource(t *testing.T, name string, ttl, minTTL uint32) dnsmessage.Resource {
+	t.Helper()
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body: &dnsmessage.SOAResource{
+			NS:     dnsmessage.MustNewName("ns." + name),
+			MBox:   dnsmessage.MustNewName("hostmaster." + name),
+			MinTTL: minTTL,
+		},
+	}
+}
+
+func TestCache(t *testing.T) {
+	question := newQuestion(t, "example.com.", dnsmessage.TypeA)
+
+	t.Run("get stored answer", func(t *testing.T) {
+		c := newCache(10)
+		c.put(question, &answer{answers: []dnsmessage.Resource{newAResource(t, "example.com.", 60)}})
+
+		// the name is matched whatever its case
+		result, ok := c.get(newQuestion(t, "EXAMPLE.com.", dnsmessage.TypeA))
+		if !ok {
+			t.Fatal("get() ok = false, want true")
+		} else if len(result.answers) != 1 || result.answers[0].Header.TTL != 60 {
+			t.Errorf("get() answers = %+v, want one record with a ttl of 60", result.answers)
+		}
+
+		if _, ok = c.get(newQuestion(t, "example.com.", dnsmessage.TypeAAAA)); ok {
+			t.Error("get() ok = true for another type")
+		}
+	})
+
+	t.Run("ttl lowered by the time spent in the cache", func(t *testing.T) {
+		c := newCache(10)
+		c.put(question, &answer{answers: []dnsmessage.Resource{newAResource(t, "example.com.", 60)}})
+		entry := c.entries[newCacheKey(question)]
+		entry.storedAt = entry.storedAt.Add(-20 * time.Second)
+		c.entries[newCacheKey(question)] = entry
+
+		result, ok := c.get(question)
+		if !ok {
+			t.Fatal("get() ok = false, want true")
+		} else if ttl := result.answers[0].Header.TTL; ttl != 40 {
+			t.Errorf("get() ttl = %d, want 40", ttl)
+		} else if ttl = entry.answer.answers[0].Header.TTL; ttl != 60 {
+			t.Errorf("cached ttl = %d, want 60 to be left as is", ttl)
+		}
+	})
+
+	t.Run("expired answer", func(t *testing.T) {
+		c := newCache(10)
+		c.put(question, &answer{answers: []dnsmessage.Resource{newAResource(t, "example.com.", 60)}})
+		entry := c.entries[newCacheKey(question)]
+		entry.expiresAt = time.Now().Add(-time.Second)
+		c.entries[newCacheKey(question)] = entry
+
+		if _, ok := c.get(question); ok {
+			t.Error("get() ok = true for an expired answer")
+		} else if len(c.entries) != 0 {
+			t.Errorf("cache has %d entries, want the expired one evicted", len(c.entries))
+		}
+	})
+
+	t.Run("answers not cached", func(t *testing.T) {
+		c := newCache(10)
+		c.put(question, &answer{answers: []dnsmessage.Resource{newAResource(t, "example.com.", 0)}})
+		c.put(newQuestion(t, "missing.example.com.", dnsmessage.TypeA), &answer{rcode: dnsmessage.RCodeNameError})
+		if len(c.entries) != 0 {
+			t.Errorf("cache has %d entries, want none", len(c.entries))
+		}
+
+		disabled := newCache(0)
+		disabled.put(question, &answer{answers: []dnsmessage.Resource{newAResource(t, "example.com.", 60)}})
+		if len(disabled.entries) != 0 {
+			t.Errorf("disabled cache has %d entries, want none", len(disabled.entries))
+		}
+	})
+
+	t.Run("eviction when full", func(t *testing.T) {
+		c := newCache(2)
+		expired := newQuestion(t, "expired.example.com.", dnsmessage.TypeA)
+		c.put(expired, &answer{answers: []dnsmessage.Resource{newAResource(t, "expired.example.com.", 60)}})
+		entry := c.entries[newCacheKey(expired)]
+		entry.expiresAt = time.Now().Add(-time.Second)
+		c.entries[newCacheKey(expired)] = entry
+		c.put(question, &answer{answers: []dnsmessage.Resource{newAResource(t, "example.com.", 60)}})
+
+		c.put(newQuestion(t, "other.example.com.", dnsmessage.TypeA), &answer{answers: []dnsmessage.Resource{newAResource(t, "other.example.com.", 60)}})
+		if len(c.entries) != 2 {
+			t.Errorf("cache has %d entries, want 2", len(c.entries))
+		} else if _, ok := c.entries[newCacheKey(expired)]; ok {
+			t.Error("expired answer kept over a live one")
+		}
+
+		c.put(newQuestion(t, "last.example.com.", dnsmessage.TypeA), &answer{answers: []dnsmessage.Resource{newAResource(t, "last.example.com.", 60)}})
+		if len(c.entries) != 2 {
+			t.Errorf("cache has %d entries, want 2", len(c.entries))
+		}
+	})
+}
+
+func TestAnswerTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer *answer
+		want   time.Duration
+	}{
+		{
+			name: "lowest ttl of the records",
+			answer: &answer{answers: []dnsmessage.Resource{
+				newAResource(t, "example.com.", 300),
+				newAResource(t, "example.com.", 30),
+			}},
+			want: 30 * time.Second,
+		},
+		{
+			name: "negative answer bounded by the soa minimum",
+			answer: &answer{
+				rcode:       dnsmessage.RCodeNameError,
+				authorities: []dnsmessage.Resource{newSOAResource(t, "example.com.", 3600, 120)},
+			},
+			want: 120 * time.Second,
+		},
+		{
+			name: "negative answer bounded by the soa ttl",
+			answer: &answer{
+				rcode:       dnsmessage.RCodeNameError,
+				authorities: []dnsmessage.Resource{newSOAResource(t, "example.com.", 60, 120)},
+			},
+			want: 60 * time.Second,
+		},
+		{
+			name:   "negative answer without soa",
+			answer: &answer{rcode: dnsmessage.RCodeNameError},
+			want:   0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := answerTTL(test.answer); got != test.want {
+				t.Errorf("answerTTL() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCacheMaxTTL(t *testing.T) {
+	c := newCache(10)
+	question := newQuestion(t, "example.com.", dnsmessage.TypeA)
+	c.put(question, &answer{answers: []dnsmessage.Resource{newAResource(t, "example.com.", 86400)}})
+
+	entry := c.entries[newCacheKey(question)]
+	if ttl := entry.expiresAt.Sub(entry.storedAt); ttl != maxCacheTTL {
+		t.Errorf("cached for %v, want %v", ttl, maxCacheTTL)
+	}
+}
@@ -0,0 +1,91 @@
+package dnsserver
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/dns/dnsmessage"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+)
+
+const upstreamTimeout = 2 * time.Second
+
+// forward asks the upstream resolvers in order until one answers, the answer is cached.
+func (s *Server) forward(question dnsmessage.Question) (*answer, error) {
+	var errs []error
+	for _, upstream := range s.config.Network.DNS.Upstreams {
+		result, err := exchange(net.JoinHostPort(upstream, fmt.Sprint(port)), question)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to query %v: %w", upstream, err))
+			continue
+		}
+
+		s.cache.put(question, result)
+		return result, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// exchange sends the question to an upstream resolver over udp, and again over tcp when the
+// reply is truncated. A failure of the resolver is an error, so that the next one is asked.
+func exchange(addr string, question dnsmessage.Question) (*answer, error) {
+	var opt dnsmessage.ResourceHeader
+	_ = opt.SetEDNS0(maxUDPSize, dnsmessage.RCodeSuccess, false)
+	query := dnsmessage.Message{
+		Header:      dnsmessage.Header{ID: uint16(rand.Uint32()), RecursionDesired: true},
+		Questions:   []dnsmessage.Question{question},
+		Additionals: []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}},
+	}
+	packedQuery, err := query.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack query: %w", err)
+	}
+
+	reply, err := exchangeMessage("udp", addr, packedQuery, query.Header.ID, question)
+	if err == nil && reply.Truncated {
+		reply, err = exchangeMessage("tcp", addr, packedQuery, query.Header.ID, question)
+	}
+	if err != nil {
+		return nil, err
+	} else if reply.RCode != dnsmessage.RCodeSuccess && reply.RCode != dnsmessage.RCodeNameError {
+		return nil, fmt.Errorf("upstream replied %v", reply.RCode)
+	}
+
+	return &answer{rcode: reply.RCode, answers: reply.Answers, authorities: reply.Authorities}, nil
+}
+
+func exchangeMessage(network, addr string, query []byte, id uint16, question dnsmessage.Question) (*dnsmessage.Message, error) {
+	conn, err := net.DialTimeout(network, addr, upstreamTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(upstreamTimeout))
+
+	var message []byte
+	if network == "tcp" {
+		if err = writeTCPMessage(conn, query); err == nil {
+			message, err = readTCPMessage(conn)
+		}
+	} else if _, err = conn.Write(query); err == nil {
+		buffer := make([]byte, maxUDPSize)
+		var n int
+		n, err = conn.Read(buffer)
+		message = buffer[:n]
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reply dnsmessage.Message
+	if err = reply.Unpack(message); err != nil {
+		return nil, fmt.Errorf("failed to unpack reply: %w", err)
+	} else if !reply.Response || reply.ID != id || len(reply.Questions) != 1 ||
+		!strings.EqualFold(reply.Questions[0].Name.String(), question.Name.String()) ||
+		reply.Questions[0].Type != question.Type || reply.Questions[0].Class != question.Class {
+		return nil, errors.New("reply does not match the query")
+	}
+	return &reply, nil
+}
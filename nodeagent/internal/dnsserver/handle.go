@@ -0,0 +1,112 @@
+package dnsserver
+
+import (
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
+	"golang.org/x/net/dns/dnsmessage"
+	"log/slog"
+	"net"
+)
+
+const (
+	// minUDPSize is the size of the udp replies to the clients without edns.
+	minUDPSize = 512
+	maxUDPSize = 4096
+)
+
+// answer is the part of a reply which does not depend on the query, as kept in the cache.
+type answer struct {
+	rcode         dnsmessage.RCode
+	authoritative bool
+	answers       []dnsmessage.Resource
+	authorities   []dnsmessage.Resource
+}
+
+// handle returns the reply to a query, nil when the query can not be parsed. A reply over udp
+// larger than the client accepts is truncated, the client then retries over tcp.
+func (s *Server) handle(query []byte, source net.IP, tcp bool) []byte {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil || header.Response {
+		return nil
+	}
+
+	reply := &dnsmessage.Message{Header: dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		OpCode:             header.OpCode,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: true,
+	}}
+	question, err := parser.Question()
+	if err != nil {
+		reply.RCode = dnsmessage.RCodeFormatError
+		return s.pack(reply, minUDPSize, tcp)
+	}
+	reply.Questions = []dnsmessage.Question{question}
+
+	udpSize, edns := minUDPSize, false
+	if err = parser.SkipAllQuestions(); err == nil {
+		err = parser.SkipAllAnswers()
+	}
+	if err == nil {
+		err = parser.SkipAllAuthorities()
+	}
+	for err == nil {
+		var resourceHeader dnsmessage.ResourceHeader
+		if resourceHeader, err = parser.AdditionalHeader(); err == nil && resourceHeader.Type == dnsmessage.TypeOPT {
+			// the class of the opt record is the udp size of the client
+			udpSize, edns = min(max(int(resourceHeader.Class), minUDPSize), maxUDPSize), true
+		}
+		if err == nil {
+			err = parser.SkipAdditional()
+		}
+	}
+	if edns {
+		var opt dnsmessage.ResourceHeader
+		_ = opt.SetEDNS0(maxUDPSize, dnsmessage.RCodeSuccess, false)
+		reply.Additionals = []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}}
+	}
+
+	result, answeredBy := s.resolve(source, header, question)
+	metrics.DNSQueries.WithLabelValues(answeredBy).Inc()
+	reply.Header.Authoritative, reply.RCode = result.authoritative, result.rcode
+	reply.Answers, reply.Authorities = result.answers, result.authorities
+	return s.pack(reply, udpSize, tcp)
+}
+
+// resolve returns the answer to the question along with where it comes from: local, cache,
+// upstream or error.
+func (s *Server) resolve(source net.IP, header dnsmessage.Header, question dnsmessage.Question) (*answer, string) {
+	if header.OpCode != 0 {
+		return &answer{rcode: dnsmessage.RCodeNotImplemented}, "error"
+	} else if result, ok := s.resolveLocal(source, question); ok {
+		return result, "local"
+	} else if result, ok = s.cache.get(question); ok {
+		return result, "cache"
+	}
+
+	result, err := s.forward(question)
+	if err != nil {
+		slog.Debug("failed to forward dns query",
+			slog.String("name", question.Name.String()),
+			slog.String("type", question.Type.String()),
+			slog.Any("error", err))
+		return &answer{rcode: dnsmessage.RCodeServerFailure}, "error"
+	}
+	return result, "upstream"
+}
+
+// pack encodes the reply, it is truncated to the questions when it does not fit in udpSize.
+func (s *Server) pack(reply *dnsmessage.Message, udpSize int, tcp bool) []byte {
+	message, err := reply.Pack()
+	if err == nil && !tcp && len(message) > udpSize {
+		reply.Header.Truncated = true
+		reply.Answers, reply.Authorities = nil, nil
+		message, err = reply.Pack()
+	}
+	if err != nil {
+		slog.Warn("failed to pack dns reply", slog.Any("error", err))
+		return nil
+	}
+	return message
+}
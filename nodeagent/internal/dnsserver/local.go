@@ -0,0 +1,109 @@
+package dnsserver
+
+import (
+	"context"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"golang.org/x/net/dns/dnsmessage"
+	"net"
+	"strings"
+)
+
+// localTTL is short since the addresses change with the machines.
+const localTTL = 5
+
+// resolveLocal answers the names of the node for the machine sending the query, ok is false for
+// the names to forward. A machine resolves the names of its containers to its own address, and
+// the name or the id of the machines of its network to their address. A container of another
+// machine is resolved as <container>.<machine>. The names under the search domain are never
+// forwarded.
+func (s *Server) resolveLocal(source net.IP, question dnsmessage.Question) (*answer, bool) {
+	name := strings.ToLower(strings.TrimSuffix(question.Name.String(), "."))
+	inDomain := false
+	if domain := s.config.Network.DNS.Domain; domain != "" && name == domain {
+		return &answer{authoritative: true}, true
+	} else if domain != "" {
+		if trimmed, ok := strings.CutSuffix(name, "."+domain); ok {
+			name, inDomain = trimmed, true
+		}
+	}
+
+	var target *types.Machine
+	if machines, err := s.machineService.List(context.Background()); err == nil {
+		if querier := findMachineByAddress(machines, source); querier != nil {
+			target = lookupMachine(machines, querier, name)
+		}
+	}
+	if target == nil {
+		if inDomain {
+			return &answer{rcode: dnsmessage.RCodeNameError, authoritative: true}, true
+		}
+		return nil, false
+	}
+
+	result := &answer{authoritative: true}
+	header := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: localTTL}
+	networkInterface := target.NetworkInterface
+	if ip := networkInterface.IPAddress.To4(); question.Type == dnsmessage.TypeA && ip != nil {
+		header.Type = dnsmessage.TypeA
+		result.answers = append(result.answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: [4]byte(ip)}})
+	}
+	if ip := networkInterface.IPv6Address.To16(); question.Type == dnsmessage.TypeAAAA && ip != nil {
+		header.Type = dnsmessage.TypeAAAA
+		result.answers = append(result.answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AAAAResource{AAAA: [16]byte(ip)}})
+	}
+	return result, true
+}
+
+// lookupMachine returns the machine a name resolves to for the querier, nil when there is none.
+func lookupMachine(machines []*types.Machine, querier *types.Machine, name string) *types.Machine {
+	labels := strings.Split(name, ".")
+	switch len(labels) {
+	case 1:
+		if hasContainer(querier, labels[0]) {
+			return querier
+		}
+		return findMachineByName(machines, querier.NetworkInterface.Network, labels[0])
+	case 2:
+		if machine := findMachineByName(machines, querier.NetworkInterface.Network, labels[1]); machine != nil && hasContainer(machine, labels[0]) {
+			return machine
+		}
+	}
+	return nil
+}
+
+// findMachineByAddress returns the machine with the address, nil when the query does not come
+// from a machine.
+func findMachineByAddress(machines []*types.Machine, ip net.IP) *types.Machine {
+	for _, machine := range machines {
+		if isReachable(machine) && (machine.NetworkInterface.IPAddress.Equal(ip) || machine.NetworkInterface.IPv6Address.Equal(ip)) {
+			return machine
+		}
+	}
+	return nil
+}
+
+// findMachineByName matches the id or the name of the machines of the network, the machines of
+// the other networks can not be reached anyway.
+func findMachineByName(machines []*types.Machine, network, name string) *types.Machine {
+	for _, machine := range machines {
+		if !isReachable(machine) || machine.NetworkInterface.Network != network {
+			continue
+		} else if strings.EqualFold(machine.ID, name) || (machine.Spec != nil && machine.Spec.Name != nil && strings.EqualFold(*machine.Spec.Name, name)) {
+			return machine
+		}
+	}
+	return nil
+}
+
+func hasContainer(machine *types.Machine, name string) bool {
+	for _, container := range machine.Containers {
+		if strings.EqualFold(container.ID, name) || (container.Spec != nil && container.Spec.Name != nil && strings.EqualFold(*container.Spec.Name, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isReachable(machine *types.Machine) bool {
+	return machine.TerminatedAt == nil && machine.NetworkInterface != nil
+}
@@ -0,0 +1,185 @@
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type fakeMachineService struct {
+	types.MachineService
+	machines []*types.Machine
+}
+
+func (s *fakeMachineService) List(ctx context.Context) ([]*types.Machine, error) {
+	return s.machines, nil
+}
+
+func newMachine(id, name, network, ip string, containers ...string) *types.Machine {
+	machine := &types.Machine{
+		ID:   id,
+		Spec: &types.MachineSpec{Name: &name},
+		NetworkInterface: &types.NetworkInterface{
+			Network:     network,
+			IPAddress:   net.ParseIP(ip),
+			IPv6Address: net.ParseIP("fd00::" + ip[len(ip)-1:]),
+		},
+	}
+	for _, container := range containers {
+		machine.Containers = append(machine.Containers, &types.Container{
+			ID:   container + "-id",
+			Spec: &types.ContainerSpec{Name: &container},
+		})
+	}
+	return machine
+}
+
+func newTestServer(machines ...*types.Machine) *Server {
+	config := &types.Config{}
+	config.Network.DNS.Domain = "baepo.internal"
+	return &Server{
+		config:         config,
+		machineService: &fakeMachineService{machines: machines},
+		cache:          newCache(10),
+	}
+}
+
+func TestResolveLocal(t *testing.T) {
+	web := newMachine("m-web", "web", "default", "10.0.0.2", "nginx")
+	db := newMachine("m-db", "db", "default", "10.0.0.3", "postgres")
+	other := newMachine("m-other", "other", "isolated", "10.1.0.2", "app")
+	terminatedAt := time.Now()
+	terminated := newMachine("m-old", "old", "default", "10.0.0.4")
+	terminated.TerminatedAt = &terminatedAt
+	s := newTestServer(web, db, other, terminated)
+
+	tests := []struct {
+		name   string
+		source string
+		qname  string
+		qtype  dnsmessage.Type
+		ok     bool
+		rcode  dnsmessage.RCode
+		want   string
+	}{
+		{name: "own container", source: "10.0.0.2", qname: "nginx.", qtype: dnsmessage.TypeA, ok: true, want: "10.0.0.2"},
+		{name: "machine by name", source: "10.0.0.2", qname: "db.", qtype: dnsmessage.TypeA, ok: true, want: "10.0.0.3"},
+		{name: "machine by id", source: "10.0.0.2", qname: "M-DB.", qtype: dnsmessage.TypeA, ok: true, want: "10.0.0.3"},
+		{name: "container of another machine", source: "10.0.0.2", qname: "postgres.db.", qtype: dnsmessage.TypeA, ok: true, want: "10.0.0.3"},
+		{name: "search domain", source: "10.0.0.2", qname: "db.baepo.internal.", qtype: dnsmessage.TypeA, ok: true, want: "10.0.0.3"},
+		{name: "ipv6 address", source: "10.0.0.2", qname: "db.", qtype: dnsmessage.TypeAAAA, ok: true, want: "fd00::3"},
+		{name: "no record of the type", source: "10.0.0.2", qname: "db.", qtype: dnsmessage.TypeMX, ok: true},
+		{name: "domain itself", source: "10.0.0.2", qname: "baepo.internal.", qtype: dnsmessage.TypeA, ok: true},
+		{name: "machine of another network", source: "10.0.0.2", qname: "other.", qtype: dnsmessage.TypeA},
+		{name: "terminated machine", source: "10.0.0.2", qname: "old.", qtype: dnsmessage.TypeA},
+		{name: "unknown name in the domain", source: "10.0.0.2", qname: "other.baepo.internal.", qtype: dnsmessage.TypeA, ok: true, rcode: dnsmessage.RCodeNameError},
+		{name: "query from outside the machines", source: "192.0.2.1", qname: "db.", qtype: dnsmessage.TypeA},
+		{name: "name to forward", source: "10.0.0.2", qname: "example.com.", qtype: dnsmessage.TypeA},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, ok := s.resolveLocal(net.ParseIP(test.source), newQuestion(t, test.qname, test.qtype))
+			if ok != test.ok {
+				t.Fatalf("resolveLocal() ok = %v, want %v", ok, test.ok)
+			} else if !ok {
+				return
+			}
+
+			if !result.authoritative {
+				t.Error("resolveLocal() answer is not authoritative")
+			} else if result.rcode != test.rcode {
+				t.Errorf("resolveLocal() rcode = %v, want %v", result.rcode, test.rcode)
+			}
+
+			var got string
+			for _, resource := range result.answers {
+				if resource.Header.TTL != localTTL {
+					t.Errorf("resolveLocal() ttl = %d, want %d", resource.Header.TTL, localTTL)
+				}
+				switch body := resource.Body.(type) {
+				case *dnsmessage.AResource:
+					got = net.IP(body.A[:]).String()
+				case *dnsmessage.AAAAResource:
+					got = net.IP(body.AAAA[:]).String()
+				}
+			}
+			if got != test.want {
+				t.Errorf("resolveLocal() address = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	s := newTestServer(newMachine("m-web", "web", "default", "10.0.0.2", "nginx"))
+	source := net.ParseIP("10.0.0.2")
+
+	query := func(t *testing.T, name string) []byte {
+		t.Helper()
+		message := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+			Questions: []dnsmessage.Question{newQuestion(t, name, dnsmessage.TypeA)},
+		}
+		packed, err := message.Pack()
+		if err != nil {
+			t.Fatalf("failed to pack query: %v", err)
+		}
+		return packed
+	}
+	reply := func(t *testing.T, packed []byte) dnsmessage.Message {
+		t.Helper()
+		var message dnsmessage.Message
+		if err := message.Unpack(packed); err != nil {
+			t.Fatalf("failed to unpack reply: %v", err)
+		}
+		return message
+	}
+
+	t.Run("local name", func(t *testing.T) {
+		message := reply(t, s.handle(query(t, "nginx."), source, false))
+		if message.ID != 42 || !message.Response || !message.Authoritative || message.RCode != dnsmessage.RCodeSuccess {
+			t.Errorf("handle() header = %+v", message.Header)
+		} else if len(message.Answers) != 1 {
+			t.Errorf("handle() has %d answers, want 1", len(message.Answers))
+		}
+	})
+
+	t.Run("cached name", func(t *testing.T) {
+		question := newQuestion(t, "example.com.", dnsmessage.TypeA)
+		s.cache.put(question, &answer{answers: []dnsmessage.Resource{newAResource(t, "example.com.", 60)}})
+
+		message := reply(t, s.handle(query(t, "example.com."), source, false))
+		if message.Authoritative || message.RCode != dnsmessage.RCodeSuccess || len(message.Answers) != 1 {
+			t.Errorf("handle() = %+v, want the cached answer", message)
+		}
+	})
+
+	t.Run("truncated over udp", func(t *testing.T) {
+		question := newQuestion(t, "large.example.com.", dnsmessage.TypeA)
+		large := &answer{}
+		for range 64 {
+			large.answers = append(large.answers, newAResource(t, "large.example.com.", 60))
+		}
+		s.cache.put(question, large)
+
+		if message := reply(t, s.handle(query(t, "large.example.com."), source, false)); !message.Truncated || len(message.Answers) != 0 {
+			t.Errorf("handle() over udp truncated = %v with %d answers", message.Truncated, len(message.Answers))
+		}
+		if message := reply(t, s.handle(query(t, "large.example.com."), source, true)); message.Truncated || len(message.Answers) != 64 {
+			t.Errorf("handle() over tcp truncated = %v with %d answers", message.Truncated, len(message.Answers))
+		}
+	})
+
+	t.Run("response ignored", func(t *testing.T) {
+		message := dnsmessage.Message{Header: dnsmessage.Header{ID: 1, Response: true}}
+		packed, _ := message.Pack()
+		if got := s.handle(packed, source, false); got != nil {
+			t.Errorf("handle() = % x, want nil", got)
+		}
+	})
+}
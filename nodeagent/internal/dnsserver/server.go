@@ -0,0 +1,184 @@
+// Package dnsserver is the resolver of the machines. It listens on the gateway address of every
+// network, answers the names of the machines and of their containers and forwards the other
+// names to the upstream resolvers.
+package dnsserver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	port = 53
+	// tcpIdleTimeout closes the tcp connections without a query for this long.
+	tcpIdleTimeout = 10 * time.Second
+)
+
+type Server struct {
+	config          *types.Config
+	machineService  types.MachineService
+	networkProvider types.NetworkProvider
+	cache           *cache
+	udpConns        []net.PacketConn
+	tcpListeners    []net.Listener
+	wg              sync.WaitGroup
+}
+
+func New(config *types.Config, machineService types.MachineService, networkProvider types.NetworkProvider) *Server {
+	return &Server{
+		config:          config,
+		machineService:  machineService,
+		networkProvider: networkProvider,
+		cache:           newCache(config.Network.DNS.CacheSize),
+	}
+}
+
+// Start listens on udp and tcp on the gateway address of every network, nothing is served when
+// the resolver is disabled.
+func (s *Server) Start(ctx context.Context) error {
+	if s.config.Network.DNS.Disabled {
+		return nil
+	}
+
+	for _, gateway := range s.networkProvider.GetGatewayAddresses() {
+		addr := net.JoinHostPort(gateway.String(), fmt.Sprint(port))
+		slog.Info("starting dns server", slog.String("addr", addr))
+
+		udpConn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			s.close()
+			return fmt.Errorf("failed to setup udp listener for dns server: %w", err)
+		}
+		s.udpConns = append(s.udpConns, udpConn)
+
+		tcpListener, err := net.Listen("tcp", addr)
+		if err != nil {
+			s.close()
+			return fmt.Errorf("failed to setup tcp listener for dns server: %w", err)
+		}
+		s.tcpListeners = append(s.tcpListeners, tcpListener)
+
+		s.wg.Add(2)
+		go s.serveUDP(udpConn)
+		go s.serveTCP(tcpListener)
+	}
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	if len(s.udpConns) == 0 {
+		return nil
+	}
+
+	slog.Info("shutting down dns server")
+	s.close()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) close() {
+	for _, udpConn := range s.udpConns {
+		_ = udpConn.Close()
+	}
+	for _, tcpListener := range s.tcpListeners {
+		_ = tcpListener.Close()
+	}
+}
+
+func (s *Server) serveUDP(conn net.PacketConn) {
+	defer s.wg.Done()
+
+	buffer := make([]byte, maxUDPSize)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			slog.Warn("failed to read dns query", slog.Any("error", err))
+			continue
+		}
+
+		query := append([]byte(nil), buffer[:n]...)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			if response := s.handle(query, addr.(*net.UDPAddr).IP, false); response != nil {
+				_, _ = conn.WriteTo(response, addr)
+			}
+		}()
+	}
+}
+
+func (s *Server) serveTCP(listener net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			slog.Warn("failed to accept dns connection", slog.Any("error", err))
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+
+			source := conn.RemoteAddr().(*net.TCPAddr).IP
+			for {
+				_ = conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+				query, err := readTCPMessage(conn)
+				if err != nil {
+					return
+				}
+
+				response := s.handle(query, source, true)
+				if response == nil {
+					return
+				} else if err = writeTCPMessage(conn, response); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// readTCPMessage reads a message prefixed with its length, as sent over tcp.
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	message := make([]byte, length)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func writeTCPMessage(w io.Writer, message []byte) error {
+	_, err := w.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(message))), message...))
+	return err
+}
@@ -3,10 +3,13 @@ package gatewayserver
 import (
 	"errors"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"strconv"
+	"time"
 )
 
 func (s *Server) Handler() http.Handler {
@@ -17,6 +20,7 @@ func (s *Server) Handler() http.Handler {
 			return
 		}
 
+		startedAt := time.Now()
 		machine, err := s.machineService.FindByID(r.Context(), machineID)
 		if errors.Is(err, types.ErrMachineNotFound) {
 			w.WriteHeader(http.StatusNotFound)
@@ -24,6 +28,19 @@ func (s *Server) Handler() http.Handler {
 		} else if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
+		}
+
+		// the host is sent by the client, only the machines of the node are used as labels
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		w = recorder
+		defer func() {
+			metrics.GatewayRequests.WithLabelValues(machine.ID, strconv.Itoa(recorder.status)).Inc()
+			metrics.GatewayRequestDuration.WithLabelValues(machine.ID).Observe(time.Since(startedAt).Seconds())
+		}()
+
+		if machine.State == coretypes.MachineStatePaused {
+			http.Error(w, "machine is paused", http.StatusServiceUnavailable)
+			return
 		} else if machine.NetworkInterface == nil || machine.State != coretypes.MachineStateRunning {
 			w.WriteHeader(http.StatusBadGateway)
 			return
@@ -36,3 +53,20 @@ func (s *Server) Handler() http.Handler {
 		proxy.ServeHTTP(w, r)
 	})
 }
+
+// statusRecorder keeps the status code written by the proxy for the request metrics.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the flusher and hijacker of the connection, which
+// the reverse proxy uses for streamed responses and websocket upgrades.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
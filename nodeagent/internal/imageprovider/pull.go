@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	"github.com/baepo-cloud/baepo-node/core/typeutil"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/sourcegraph/conc/pool"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sys/unix"
 	"io"
 	"log/slog"
@@ -24,6 +27,15 @@ func (p *Provider) Pull(ctx context.Context, image *types.Image) error {
 		return nil
 	}
 
+	ctx, span := tracing.Start(ctx, "imageprovider.Pull", attribute.String("image.name", image.Name))
+	startedAt := time.Now()
+	err := p.pull(ctx, image)
+	tracing.End(span, err)
+	metrics.ImagePullDuration.WithLabelValues(metrics.Result(err)).Observe(time.Since(startedAt).Seconds())
+	return err
+}
+
+func (p *Provider) pull(ctx context.Context, image *types.Image) error {
 	log := p.logger.With(slog.String("image-id", image.ID), slog.String("image-name", image.Name))
 	log.Info("pulling image")
 
@@ -103,7 +115,9 @@ func (p *Provider) extractImage(image v1.Image, tmpDir, outputDir string) error
 				return fmt.Errorf("failed to create layer file %d: %v", i, err)
 			}
 
-			if _, err = io.Copy(layerFile, rc); err != nil {
+			written, err := io.Copy(layerFile, rc)
+			metrics.ImagePullBytes.Add(float64(written))
+			if err != nil {
 				layerFile.Close()
 				return fmt.Errorf("failed to write layer %d: %v", i, err)
 			}
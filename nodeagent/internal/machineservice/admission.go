@@ -0,0 +1,114 @@
+package machineservice
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/machineservice/machinecontroller"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+type admissionRequest struct {
+	// ExcludedMachineID is a machine whose resources are not counted as reserved, it is set
+	// when an existing machine is resized.
+	ExcludedMachineID string
+	Cpus              uint32
+	MemoryMB          uint64
+	DiskMB            uint64
+}
+
+func (s *Service) GetCapacity(ctx context.Context) (*types.NodeCapacity, error) {
+	return s.getCapacity(ctx, "")
+}
+
+func (s *Service) getCapacity(ctx context.Context, excludedMachineID string) (*types.NodeCapacity, error) {
+	memInfo, err := mem.VirtualMemory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory info: %w", err)
+	}
+
+	totalDiskMB, err := s.volumeProvider.GetCapacity(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config := s.config.Admission
+	capacity := &types.NodeCapacity{
+		TotalCpus:     uint32(runtime.NumCPU()),
+		TotalMemoryMB: memInfo.Total / 1024 / 1024,
+		TotalDiskMB:   totalDiskMB,
+	}
+	capacity.AllocatableCpus = uint32(float64(capacity.TotalCpus-min(config.SystemReservedCpus, capacity.TotalCpus)) *
+		config.CpuOvercommitRatio)
+	capacity.AllocatableMemoryMB = uint64(float64(capacity.TotalMemoryMB-min(config.SystemReservedMemoryMB, capacity.TotalMemoryMB)) *
+		config.MemoryOvercommitRatio)
+	capacity.AllocatableDiskMB = capacity.TotalDiskMB - min(config.SystemReservedDiskMB, capacity.TotalDiskMB)
+
+	// stopped and errored machines keep their reservation, they are started again without going
+	// through admission
+	s.machineControllers.ForEach(func(machineID string, ctrl *machinecontroller.Controller) bool {
+		machine := ctrl.GetState().Machine
+		if machineID == excludedMachineID || machine.State == coretypes.MachineStateTerminated {
+			return true
+		}
+
+		capacity.ReservedCpus += machine.Spec.Cpus
+		capacity.ReservedMemoryMB += machine.Spec.MemoryMB
+		for _, machineVolume := range machine.Volumes {
+			if machineVolume.Volume != nil {
+				capacity.ReservedDiskMB += machineVolume.Volume.Size
+			}
+		}
+		return true
+	})
+
+	// the idle machines of the warm pools are booted, they are charged until a machine adopts them
+	var warmPoolEntries []*types.WarmPoolEntry
+	if err = s.db.WithContext(ctx).Preload("WarmPool").Preload("Volumes.Volume").Find(&warmPoolEntries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list warm pool entries: %w", err)
+	}
+
+	for _, entry := range warmPoolEntries {
+		capacity.ReservedCpus += entry.WarmPool.Cpus
+		capacity.ReservedMemoryMB += entry.WarmPool.MemoryMB
+		for _, entryVolume := range entry.Volumes {
+			capacity.ReservedDiskMB += entryVolume.Volume.Size
+		}
+	}
+
+	return capacity, nil
+}
+
+// admit verifies that the node can fit the requested resources next to the non terminated
+// machines, it returns a types.InsufficientCapacityError otherwise.
+func (s *Service) admit(ctx context.Context, req admissionRequest) error {
+	capacity, err := s.getCapacity(ctx, req.ExcludedMachineID)
+	if err != nil {
+		return fmt.Errorf("failed to compute node capacity: %w", err)
+	}
+
+	checks := []struct {
+		resource    types.CapacityResource
+		requested   uint64
+		reserved    uint64
+		allocatable uint64
+	}{
+		{types.CapacityResourceCpu, uint64(req.Cpus), uint64(capacity.ReservedCpus), uint64(capacity.AllocatableCpus)},
+		{types.CapacityResourceMemory, req.MemoryMB, capacity.ReservedMemoryMB, capacity.AllocatableMemoryMB},
+		{types.CapacityResourceDisk, req.DiskMB, capacity.ReservedDiskMB, capacity.AllocatableDiskMB},
+	}
+	for _, check := range checks {
+		if check.reserved+check.requested > check.allocatable {
+			return &types.InsufficientCapacityError{
+				Resource:  check.resource,
+				Requested: check.requested,
+				Available: check.allocatable - min(check.reserved, check.allocatable),
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package machineservice
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+
+	"connectrpc.com/connect"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Service) UploadArchive(ctx context.Context, opts types.MachineArchiveOptions, archive io.Reader) error {
+	machine, container, err := s.findRunningContainer(opts.MachineID, opts.Container)
+	if err != nil {
+		return err
+	}
+
+	runtimeClient, closeRuntimeClient := s.runtimeService.GetStreamAPIClient(machine.ID)
+	defer closeRuntimeClient()
+
+	stream := runtimeClient.UploadArchive(ctx)
+	err = stream.Send(&nodeapi.UploadArchiveRequest{ContainerID: container.ID, Path: opts.Path})
+	if err == nil {
+		_, err = io.Copy(nodeapi.NewChunkWriter(func(data []byte) error {
+			return stream.Send(&nodeapi.UploadArchiveRequest{Data: data})
+		}), archive)
+	}
+	// a send error means the runtime closed the stream, its error is returned on close
+	_, closeErr := stream.CloseAndReceive()
+	if closeErr != nil {
+		return closeErr
+	} else if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) DownloadArchive(ctx context.Context, opts types.MachineArchiveOptions, archive io.Writer) error {
+	machine, container, err := s.findRunningContainer(opts.MachineID, opts.Container)
+	if err != nil {
+		return err
+	}
+
+	runtimeClient, closeRuntimeClient := s.runtimeService.GetStreamAPIClient(machine.ID)
+	defer closeRuntimeClient()
+
+	stream, err := runtimeClient.DownloadArchive(ctx, connect.NewRequest(&nodeapi.DownloadArchiveRequest{
+		ContainerID: container.ID,
+		Path:        opts.Path,
+	}))
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	writer := bufio.NewWriter(archive)
+	for stream.Receive() {
+		if _, err = writer.Write(stream.Msg().Data); err != nil {
+			return err
+		}
+	}
+	if err = stream.Err(); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,32 @@
+package machineservice
+
+import (
+	"context"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// AttachConsole opens the serial console of a machine. Unlike exec it does not require the
+// machine to be running, the console is mostly useful when init fails to start.
+func (s *Service) AttachConsole(ctx context.Context, machineID string) (*types.MachineConsoleSession, error) {
+	ctrl, ok := s.machineControllers.Get(machineID)
+	if !ok {
+		return nil, types.ErrMachineNotFound
+	}
+
+	switch ctrl.GetState().Machine.State {
+	case coretypes.MachineStatePending, coretypes.MachineStateStopped, coretypes.MachineStateTerminated:
+		return nil, types.ErrMachineNotRunning
+	}
+
+	runtimeClient, closeRuntimeClient := s.runtimeService.GetStreamAPIClient(machineID)
+	stream := runtimeClient.AttachConsole(ctx)
+	return &types.MachineConsoleSession{
+		Stream: stream,
+		Close: func() {
+			_ = stream.CloseResponse()
+			closeRuntimeClient()
+		},
+	}, nil
+}
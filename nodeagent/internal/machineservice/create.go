@@ -3,15 +3,80 @@ package machineservice
 import (
 	"context"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	"github.com/nrednav/cuid2"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 	"log/slog"
 )
 
+// machineVolumeSizeMB is the size of the volume of each container.
+const machineVolumeSizeMB = 1024
+
 func (s *Service) Create(ctx context.Context, opts types.MachineCreateOptions) (machine *types.Machine, err error) {
+	ctx, span := tracing.Start(ctx, "machineservice.Create", attribute.String("machine.id", opts.MachineID))
+	defer func() { tracing.End(span, err) }()
+
 	s.log.Info("requesting machine creation", slog.String("machine-id", opts.MachineID))
+	if err = validateContainerPorts(opts.Containers); err != nil {
+		return nil, err
+	}
+
+	// the images are resolved before the admission, fetching them may reach the registry
+	images := make([]*types.Image, len(opts.Containers))
+	for index, containerOpt := range opts.Containers {
+		images[index], err = s.imageProvider.FetchDetails(ctx, types.ImageFetchOptions{
+			Image: containerOpt.Spec.Image,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch container image details (%v): %v", containerOpt.Spec.Image, err)
+		}
+	}
+
+	// the machine must be registered before the lock is released so that concurrent creations
+	// see its resources as reserved
+	s.admissionLock.Lock()
+	defer s.admissionLock.Unlock()
+
+	// the vm of a warm pool entry is adopted along with its volume and network interface, they
+	// must not leak if the creation fails
+	var warmPoolEntry *types.WarmPoolEntry
+	defer func() {
+		if err != nil && warmPoolEntry != nil {
+			_ = s.releaseWarmPoolEntry(context.Background(), warmPoolEntry)
+		}
+	}()
+
+	// the vms of the warm pools are on the default network, they are only handed out to machines
+	// which start right away. The entry is taken before the admission as it is not charged anymore.
+	if opts.Network == "" && opts.DesiredState == coretypes.MachineDesiredStateRunning {
+		imageNames := make([]string, len(opts.Containers))
+		for index, containerOpt := range opts.Containers {
+			imageNames[index] = containerOpt.Spec.Image
+		}
+
+		entry, takeErr := s.takeWarmPoolEntry(ctx, imageNames, images, opts.Spec)
+		if takeErr != nil {
+			s.log.Error("failed to take warm pool entry", slog.String("machine-id", opts.MachineID), slog.Any("error", takeErr))
+		} else if entry != nil {
+			s.log.Info("adopting warm pool vm", slog.String("machine-id", opts.MachineID),
+				slog.String("warm-pool-id", entry.WarmPoolID))
+			warmPoolEntry = entry
+		}
+	}
+
+	err = s.admit(ctx, admissionRequest{
+		Cpus:     opts.Spec.Cpus,
+		MemoryMB: opts.Spec.MemoryMB,
+		DiskMB:   uint64(len(opts.Containers)) * machineVolumeSizeMB,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	machine = &types.Machine{
 		ID:           opts.MachineID,
 		State:        coretypes.MachineStatePending,
@@ -21,13 +86,7 @@ func (s *Service) Create(ctx context.Context, opts types.MachineCreateOptions) (
 	}
 
 	for index, containerOpt := range opts.Containers {
-		image, err := s.imageProvider.FetchDetails(ctx, types.ImageFetchOptions{
-			Image: containerOpt.Spec.Image,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed ot fetch container image details (%v): %v", containerOpt.Spec.Image, err)
-		}
-
+		image := images[index]
 		container := &types.Container{
 			ID:        containerOpt.ContainerID,
 			MachineID: machine.ID,
@@ -35,10 +94,13 @@ func (s *Service) Create(ctx context.Context, opts types.MachineCreateOptions) (
 		}
 		volume := &types.Volume{
 			ID:       cuid2.Generate(),
-			Size:     1024, // 1 gib
+			Size:     machineVolumeSizeMB,
 			SourceID: &image.Volume.ID,
 			Source:   image.Volume,
 		}
+		if warmPoolEntry != nil {
+			volume = warmPoolEntry.Volumes[index].Volume
+		}
 		machine.Containers[index] = container
 		machine.Volumes = append(machine.Volumes, &types.MachineVolume{
 			ID:          cuid2.Generate(),
@@ -52,24 +114,31 @@ func (s *Service) Create(ctx context.Context, opts types.MachineCreateOptions) (
 		})
 	}
 
-	networkInterface, err := s.networkProvider.AllocateInterface(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to allocate network interface: %w", err)
-	}
-
-	machine.NetworkInterfaceID = &networkInterface.ID
-	machine.NetworkInterface = networkInterface
-	defer func() {
+	if warmPoolEntry != nil {
+		machine.NetworkInterfaceID = &warmPoolEntry.NetworkInterfaceID
+		machine.NetworkInterface = warmPoolEntry.NetworkInterface
+		machine.AdoptFrom = typeutil.Ptr(warmPoolEntry.RuntimeID())
+	} else {
+		var networkInterface *types.NetworkInterface
+		networkInterface, err = s.networkProvider.AllocateInterface(ctx, opts.Network)
 		if err != nil {
-			_ = s.networkProvider.ReleaseInterface(context.Background(), networkInterface)
+			return nil, fmt.Errorf("failed to allocate network interface: %w", err)
 		}
-	}()
+
+		machine.NetworkInterfaceID = &networkInterface.ID
+		machine.NetworkInterface = networkInterface
+		defer func() {
+			if err != nil {
+				_ = s.networkProvider.ReleaseInterface(context.Background(), networkInterface)
+			}
+		}()
+	}
 
 	err = s.db.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Create(&machine).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to create machine: %w", err)
 	}
 
-	s.machineControllers.Set(machine.ID, s.newMachineController(machine))
+	s.machineControllers.Set(machine.ID, s.newMachineController(ctx, machine))
 	return machine, nil
 }
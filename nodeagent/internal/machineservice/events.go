@@ -4,14 +4,18 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
 	"github.com/baepo-cloud/baepo-node/core/v1pbadapter"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/machineservice/machinecontroller"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	corev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/core/v1"
 	"github.com/nrednav/cuid2"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm/clause"
 )
@@ -57,6 +61,83 @@ func (s *Service) handleMachineEventsStorage(machine *types.Machine) func(contex
 					},
 				},
 			}
+		case *machinecontroller.MachineStartedMessage:
+			machineEvent = &types.MachineEvent{
+				ID:        cuid2.Generate(),
+				Type:      types.MachineEventTypeStarted,
+				MachineID: machine.ID,
+				Timestamp: event.Timestamp,
+			}
+			startedEvent := &corev1pb.MachineEvent_StartedEvent{}
+			if event.ExpiresAt != nil {
+				startedEvent.ExpiresAt = timestamppb.New(*event.ExpiresAt)
+			}
+			protoMessage = &corev1pb.MachineEvent{
+				EventId:   machineEvent.ID,
+				MachineId: machine.ID,
+				Timestamp: timestamppb.New(event.Timestamp),
+				Event: &corev1pb.MachineEvent_Started{
+					Started: startedEvent,
+				},
+			}
+		case *machinecontroller.SpecChangedMessage:
+			machineEvent = &types.MachineEvent{
+				ID:        cuid2.Generate(),
+				Type:      types.MachineEventTypeSpecChanged,
+				MachineID: machine.ID,
+				Timestamp: event.Timestamp,
+			}
+			// the control plane protocol has no spec changed machine event, the spec is stored
+			// for the node api and is not forwarded
+			protoMessage = v1pbadapter.FromMachineSpec(event.Spec.ToCore())
+		case *machinecontroller.PortsPublishedMessage:
+			machineEvent = &types.MachineEvent{
+				ID:        cuid2.Generate(),
+				Type:      types.MachineEventTypePortsPublished,
+				MachineID: machine.ID,
+				Timestamp: event.Timestamp,
+			}
+			// the control plane protocol has no published ports machine event either, the ports
+			// are forwarded in a field it does not declare yet, see the registration service
+			payload, err := publishedPortsPayload(event.Ports)
+			if err != nil {
+				s.log.Error("failed to encode published ports", slog.String("machine-id", machine.ID), slog.Any("error", err))
+				return
+			}
+			protoMessage = payload
+		case *machinecontroller.MachineExpiredMessage:
+			machineEvent = &types.MachineEvent{
+				ID:        cuid2.Generate(),
+				Type:      types.MachineEventTypeExpired,
+				MachineID: machine.ID,
+				Timestamp: event.Timestamp,
+			}
+			// the control plane learns of the expiration from the desired state change that
+			// follows, and of the termination once the machine is actually terminated
+			payload, err := structpb.NewStruct(map[string]any{"timeout": event.Timeout.String()})
+			if err != nil {
+				s.log.Error("failed to encode machine expiration", slog.String("machine-id", machine.ID), slog.Any("error", err))
+				return
+			}
+			protoMessage = payload
+		case *machinecontroller.MachineTerminatedMessage:
+			machineEvent = &types.MachineEvent{
+				ID:        cuid2.Generate(),
+				Type:      types.MachineEventTypeTerminated,
+				MachineID: machine.ID,
+				Timestamp: event.Timestamp,
+			}
+			protoMessage = &corev1pb.MachineEvent{
+				EventId:   machineEvent.ID,
+				MachineId: machine.ID,
+				Timestamp: timestamppb.New(event.Timestamp),
+				Event: &corev1pb.MachineEvent_Terminated{
+					Terminated: &corev1pb.MachineEvent_TerminatedEvent{
+						Cause:              corev1pb.MachineTerminationCause_MachineTerminationCause_Expired,
+						TerminationDetails: typeutil.Ptr(fmt.Sprintf("timeout of %v exceeded", event.Timeout)),
+					},
+				},
+			}
 		case *machinecontroller.ContainerStateChangedMessage:
 			for _, current := range machine.Containers {
 				if current.ID == event.Event.ContainerId {
@@ -104,10 +185,16 @@ func (s *Service) handleMachineEventsStorage(machine *types.Machine) func(contex
 		}
 
 		machineEvent.Payload = payloadBytes
-		err = s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&machineEvent).Error
+		collapsed, err := s.collapseContainerHealthFlap(ctx, machineEvent)
 		if err != nil {
-			s.log.Error("failed to insert machine event", slog.Any("error", err))
+			s.log.Error("failed to collapse container health flap", slog.Any("error", err))
 			return
+		} else if !collapsed {
+			err = s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&machineEvent).Error
+			if err != nil {
+				s.log.Error("failed to insert machine event", slog.Any("error", err))
+				return
+			}
 		}
 
 		machineEvent.Machine = machine
@@ -136,16 +223,37 @@ func (s *Service) handleMachineTerminated(machine *types.Machine) func(context.C
 		}
 
 		s.machineControllers.Del(machine.ID)
+		metrics.DeleteMachine(machine.ID)
+
+		// the garbage collector deletes the snapshots left behind on failure
+		if err := s.deleteMachineSnapshots(ctx, machine.ID); err != nil {
+			s.log.Error("failed to delete machine snapshots",
+				slog.String("machine-id", machine.ID),
+				slog.Any("error", err))
+		}
 	}
 }
 
-func (s *Service) ListEvents(ctx context.Context, machineID string) ([]*types.MachineEvent, error) {
+func (s *Service) ListEvents(ctx context.Context, opts types.MachineListEventsOptions) ([]*types.MachineEvent, error) {
+	db := s.db.WithContext(ctx)
+	query := db.Where("machine_id = ?", opts.MachineID).Order("timestamp DESC")
+	if opts.Limit > 0 {
+		// the latest event of each type is listed beyond the limit, a replay must carry the
+		// current state of the machine even when other events flooded it
+		recentEventIDs := db.Model(&types.MachineEvent{}).
+			Select("id").
+			Where("machine_id = ?", opts.MachineID).
+			Order("timestamp DESC").
+			Limit(opts.Limit)
+		query = query.Where("(id IN (?) OR id IN (?))", recentEventIDs, latestEventIDs(db))
+	}
+
 	var events []*types.MachineEvent
-	err := s.db.WithContext(ctx).Find(&events, "machine_id = ?", machineID).Order("timestamp").Error
-	if err != nil {
+	if err := query.Find(&events).Error; err != nil {
 		return nil, fmt.Errorf("could not list machine events: %w", err)
 	}
 
+	slices.Reverse(events)
 	return events, nil
 }
 
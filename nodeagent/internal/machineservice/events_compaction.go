@@ -0,0 +1,167 @@
+package machineservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	corev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/core/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+func (s *Service) startEventsCompactionWorker(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CompactEvents(ctx); err != nil {
+				s.log.Error("failed to compact machine events", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+func (s *Service) CompactEvents(ctx context.Context) error {
+	config := s.config.Events
+	db := s.db.WithContext(ctx)
+	var deleted int64
+
+	if config.TerminatedRetention > 0 {
+		result := db.
+			Where("machine_id IN (?)", db.Model(&types.Machine{}).
+				Select("id").
+				Where("terminated_at < ?", time.Now().Add(-config.TerminatedRetention))).
+			Delete(&types.MachineEvent{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete events of terminated machines: %w", result.Error)
+		}
+		deleted += result.RowsAffected
+	}
+
+	if config.Retention > 0 {
+		result := db.
+			Where("timestamp < ? AND id NOT IN (?)", time.Now().Add(-config.Retention), latestEventIDs(db)).
+			Delete(&types.MachineEvent{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete expired events: %w", result.Error)
+		}
+		deleted += result.RowsAffected
+	}
+
+	if config.MaxPerMachine > 0 {
+		overflowingEvents := db.Table("(?)", db.Model(&types.MachineEvent{}).
+			Select("id, ROW_NUMBER() OVER (PARTITION BY machine_id ORDER BY timestamp DESC) AS position")).
+			Select("id").
+			Where("position > ?", config.MaxPerMachine)
+		result := db.Where("id IN (?) AND id NOT IN (?)", overflowingEvents, latestEventIDs(db)).Delete(&types.MachineEvent{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete overflowing events: %w", result.Error)
+		}
+		deleted += result.RowsAffected
+	}
+
+	if deleted > 0 {
+		s.log.Info("compacted machine events", slog.Int64("deleted", deleted))
+	}
+	return nil
+}
+
+// latestEventIDs selects the ids of the latest event of each type of each machine. They are kept
+// regardless of their age and of the number of events, so that a reconnect replay always carries
+// the current state of the machine.
+func latestEventIDs(db *gorm.DB) *gorm.DB {
+	return db.Table("(?)", db.Model(&types.MachineEvent{}).
+		Select("id, ROW_NUMBER() OVER (PARTITION BY machine_id, type ORDER BY timestamp DESC) AS position")).
+		Select("id").
+		Where("position = 1")
+}
+
+// collapseContainerHealthFlap merges a container event into the latest event of the machine
+// when both only differ by the container health. It returns false when the event must be
+// inserted as is. A merged event takes the id of the stored one, so that the control plane gets
+// the same id live and on replay. The number of merged flaps is only kept in CollapsedCount, the
+// protocol has no field for it.
+func (s *Service) collapseContainerHealthFlap(ctx context.Context, machineEvent *types.MachineEvent) (bool, error) {
+	if machineEvent.Type != types.MachineEventTypeContainerStateChanged {
+		return false, nil
+	}
+
+	collapsed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var previousEvent types.MachineEvent
+		err := tx.Where("machine_id = ?", machineEvent.MachineID).Order("timestamp DESC").Take(&previousEvent).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to find previous event: %w", err)
+		}
+
+		if previousEvent.ID == machineEvent.ID ||
+			previousEvent.Type != types.MachineEventTypeContainerStateChanged ||
+			previousEvent.ContainerID == nil || *previousEvent.ContainerID != *machineEvent.ContainerID ||
+			!machineEvent.Timestamp.After(previousEvent.Timestamp) {
+			return nil
+		}
+
+		previousState, err := containerStateChangedPayload(&previousEvent)
+		if err != nil {
+			return err
+		}
+		currentState, err := containerStateChangedPayload(machineEvent)
+		if err != nil {
+			return err
+		}
+		if previousState.State != currentState.State || previousState.RestartCount != currentState.RestartCount {
+			return nil
+		}
+
+		collapsedCount := previousEvent.CollapsedCount + 1
+		payload, err := proto.Marshal(&corev1pb.ContainerEvent{
+			EventId:     previousEvent.ID,
+			ContainerId: *machineEvent.ContainerID,
+			Timestamp:   timestamppb.New(machineEvent.Timestamp),
+			Event:       &corev1pb.ContainerEvent_StateChanged{StateChanged: currentState},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal collapsed event payload: %w", err)
+		}
+
+		err = tx.Model(&previousEvent).Updates(map[string]any{
+			"payload":         payload,
+			"timestamp":       machineEvent.Timestamp,
+			"collapsed_count": collapsedCount,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to update previous event: %w", err)
+		}
+
+		machineEvent.ID = previousEvent.ID
+		machineEvent.Payload = payload
+		machineEvent.CollapsedCount = collapsedCount
+		collapsed = true
+		return nil
+	})
+	return collapsed, err
+}
+
+func containerStateChangedPayload(event *types.MachineEvent) (*corev1pb.ContainerEvent_StateChangedEvent, error) {
+	payload, err := event.ProtoPayload()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode event payload: %w", err)
+	}
+
+	containerEvent, ok := payload.(*corev1pb.ContainerEvent)
+	if !ok || containerEvent.GetStateChanged() == nil {
+		return nil, fmt.Errorf("unexpected payload for event %s", event.ID)
+	}
+	return containerEvent.GetStateChanged(), nil
+}
@@ -0,0 +1,72 @@
+package machineservice
+
+import (
+	"context"
+	"fmt"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Service) Exec(ctx context.Context, opts types.MachineExecOptions) (*types.MachineExecSession, error) {
+	machine, container, err := s.findRunningContainer(opts.MachineID, opts.Container)
+	if err != nil {
+		return nil, err
+	}
+
+	runtimeClient, closeRuntimeClient := s.runtimeService.GetStreamAPIClient(machine.ID)
+	stream := runtimeClient.Exec(ctx)
+	return &types.MachineExecSession{
+		ContainerID: container.ID,
+		Stream:      stream,
+		Close: func() {
+			_ = stream.CloseResponse()
+			closeRuntimeClient()
+		},
+	}, nil
+}
+
+// findRunningContainer returns a container of a machine whose runtime accepts requests for its
+// containers, as exec or archive transfers.
+func (s *Service) findRunningContainer(machineID string, reference string) (*types.Machine, *types.Container, error) {
+	ctrl, ok := s.machineControllers.Get(machineID)
+	if !ok {
+		return nil, nil, types.ErrMachineNotFound
+	}
+
+	machine := ctrl.GetState().Machine
+	if !typeutil.Includes([]coretypes.MachineState{
+		coretypes.MachineStateRunning,
+		coretypes.MachineStateDegraded,
+	}, machine.State) {
+		return nil, nil, types.ErrMachineNotRunning
+	}
+
+	container, err := s.findMachineContainer(machine, reference)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return machine, container, nil
+}
+
+// findMachineContainer resolves a container by id or by name, an empty reference designates the
+// first container of the machine.
+func (s *Service) findMachineContainer(machine *types.Machine, reference string) (*types.Container, error) {
+	if reference == "" {
+		if len(machine.Containers) == 0 {
+			return nil, fmt.Errorf("%w: machine has no container", types.ErrContainerNotFound)
+		}
+
+		return machine.Containers[0], nil
+	}
+
+	for _, container := range machine.Containers {
+		if container.ID == reference || (container.Spec != nil && container.Spec.Name != nil && *container.Spec.Name == reference) {
+			return container, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %v", types.ErrContainerNotFound, reference)
+}
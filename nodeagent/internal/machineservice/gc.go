@@ -2,37 +2,167 @@ package machineservice
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"time"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/machineservice/machinecontroller"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 )
 
+// runtimeDirectoryRetention is how long the runtime directory of a terminated machine is
+// kept, it holds the logs served for machines that no longer have a controller. The directory
+// of a machine is kept as long as the machine has snapshots, which are stored in it unless a
+// snapshot directory is configured.
+const runtimeDirectoryRetention = 24 * time.Hour
+
 func (s *Service) startGCWorker(ctx context.Context) {
 	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			s.performGC()
+			_, _ = s.PerformGC(ctx, types.GCOptions{DryRun: s.config.GCDryRun})
 		}
 	}
 }
 
-func (s *Service) performGC() {
-	startedAt := time.Now()
-	s.log.Info("performing garbage collection")
-
-	//err := s.runtimeProvider.GC(context.Background(), func() []string {
-	//	var machineIDs []string
-	//	s.machineControllers.ForEach(func(machineID string, _ *machinecontroller.Controller) bool {
-	//		machineIDs = append(machineIDs, machineID)
-	//		return true
-	//	})
-	//	return machineIDs
-	//})
-	//if err != nil {
-	//	s.log.Error("failed to perform runtime garbage collection", slog.Any("error", err))
-	//}
-
-	s.log.Info("garbage collection completed", slog.Duration("duration", time.Now().Sub(startedAt)))
+func (s *Service) PerformGC(ctx context.Context, opts types.GCOptions) (*types.GCReport, error) {
+	s.gcLock.Lock()
+	defer s.gcLock.Unlock()
+
+	report := &types.GCReport{
+		DryRun:    opts.DryRun,
+		StartedAt: time.Now(),
+	}
+	log := s.log.With(slog.Bool("dry-run", opts.DryRun))
+	log.Info("performing garbage collection")
+
+	runtimeOpts, err := s.newRuntimeGCOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	collectors := []struct {
+		kind    types.GCResourceKind
+		collect func(ctx context.Context) ([]types.GCResource, error)
+	}{
+		// snapshots go first so that their volumes are released rather than collected
+		{kind: types.GCResourceKindSnapshot, collect: func(ctx context.Context) ([]types.GCResource, error) {
+			return s.collectSnapshots(ctx, opts)
+		}},
+		{kind: types.GCResourceKindRuntime, collect: func(ctx context.Context) ([]types.GCResource, error) {
+			return s.runtimeService.GC(ctx, runtimeOpts)
+		}},
+		{kind: types.GCResourceKindNetworkInterface, collect: func(ctx context.Context) ([]types.GCResource, error) {
+			return s.networkProvider.GC(ctx, opts)
+		}},
+		{kind: types.GCResourceKindVolume, collect: func(ctx context.Context) ([]types.GCResource, error) {
+			return s.volumeProvider.GC(ctx, opts)
+		}},
+	}
+	for _, collector := range collectors {
+		resources, err := collector.collect(ctx)
+		if err != nil {
+			log.Error("garbage collector failed", slog.String("kind", string(collector.kind)), slog.Any("error", err))
+			report.Errors = append(report.Errors, fmt.Errorf("%v collector: %w", collector.kind, err))
+			continue
+		}
+
+		for _, resource := range resources {
+			resourceLog := log.With(slog.String("kind", string(resource.Kind)), slog.String("name", resource.Name))
+			if resource.Error != nil {
+				resourceLog.Error("failed to collect resource", slog.Any("error", resource.Error))
+			} else if opts.DryRun {
+				resourceLog.Info("resource would be collected")
+			} else {
+				resourceLog.Info("resource collected")
+			}
+		}
+		report.Resources = append(report.Resources, resources...)
+	}
+
+	report.Duration = time.Now().Sub(report.StartedAt)
+	summary := []any{slog.Duration("duration", report.Duration), slog.Int("errors", len(report.Errors))}
+	for _, kind := range []types.GCResourceKind{
+		types.GCResourceKindRuntime,
+		types.GCResourceKindSocket,
+		types.GCResourceKindNetworkInterface,
+		types.GCResourceKindVolume,
+		types.GCResourceKindSnapshot,
+	} {
+		collected, failed := report.Count(kind)
+		summary = append(summary, slog.Group(string(kind), slog.Int("collected", collected), slog.Int("failed", failed)))
+	}
+	log.Info("garbage collection completed", summary...)
+	return report, nil
+}
+
+func (s *Service) newRuntimeGCOptions(ctx context.Context, opts types.GCOptions) (types.RuntimeGCOptions, error) {
+	runtimeOpts := types.RuntimeGCOptions{GCOptions: opts}
+	s.machineControllers.ForEach(func(machineID string, ctrl *machinecontroller.Controller) bool {
+		state := ctrl.GetState()
+		runtimeOpts.KeepMachineIDs = append(runtimeOpts.KeepMachineIDs, machineID)
+		if state.Machine.AdoptFrom != nil {
+			runtimeOpts.KeepMachineIDs = append(runtimeOpts.KeepMachineIDs, *state.Machine.AdoptFrom)
+		}
+		if state.Reconciliation == nil {
+			runtimeOpts.IdleMachineIDs = append(runtimeOpts.IdleMachineIDs, machineID)
+		}
+		return true
+	})
+
+	var retainedMachineIDs []string
+	err := s.db.WithContext(ctx).
+		Model(&types.Machine{}).
+		Where("terminated_at IS NULL OR terminated_at > ?", time.Now().Add(-runtimeDirectoryRetention)).
+		Pluck("id", &retainedMachineIDs).
+		Error
+	if err != nil {
+		return runtimeOpts, fmt.Errorf("failed to list retained machines: %w", err)
+	}
+
+	var snapshotMachineIDs []string
+	err = s.db.WithContext(ctx).Model(&types.Snapshot{}).Distinct("machine_id").Pluck("machine_id", &snapshotMachineIDs).Error
+	if err != nil {
+		return runtimeOpts, fmt.Errorf("failed to list machines with snapshots: %w", err)
+	}
+
+	var warmPoolEntries []*types.WarmPoolEntry
+	if err = s.db.WithContext(ctx).Select("id").Find(&warmPoolEntries).Error; err != nil {
+		return runtimeOpts, fmt.Errorf("failed to list warm pool entries: %w", err)
+	}
+	for _, entry := range warmPoolEntries {
+		runtimeOpts.KeepMachineIDs = append(runtimeOpts.KeepMachineIDs, entry.RuntimeID())
+	}
+
+	runtimeOpts.KeepMachineIDs = append(runtimeOpts.KeepMachineIDs, retainedMachineIDs...)
+	runtimeOpts.KeepMachineIDs = append(runtimeOpts.KeepMachineIDs, snapshotMachineIDs...)
+	return runtimeOpts, nil
+}
+
+// collectSnapshots deletes the snapshots of terminated machines which were not deleted on
+// termination, a snapshot can only be restored on the machine it was taken from.
+func (s *Service) collectSnapshots(ctx context.Context, opts types.GCOptions) ([]types.GCResource, error) {
+	var snapshotIDs []string
+	err := s.db.WithContext(ctx).
+		Model(&types.Snapshot{}).
+		Where("machine_id IN (?)", s.db.Model(&types.Machine{}).Select("id").Where("terminated_at IS NOT NULL")).
+		Pluck("id", &snapshotIDs).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots of terminated machines: %w", err)
+	}
+
+	resources := make([]types.GCResource, len(snapshotIDs))
+	for index, snapshotID := range snapshotIDs {
+		resources[index] = types.GCResource{Kind: types.GCResourceKindSnapshot, Name: snapshotID}
+		if !opts.DryRun {
+			resources[index].Error = s.DeleteSnapshot(ctx, snapshotID)
+		}
+	}
+	return resources, nil
 }
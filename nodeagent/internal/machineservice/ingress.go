@@ -0,0 +1,54 @@
+package machineservice
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// GetIngressStats returns the counters of the ingress policy of a machine, they are reset
+// whenever the policy is applied.
+func (s *Service) GetIngressStats(ctx context.Context, machineID string) (*types.NetworkIngressStats, error) {
+	ctrl, ok := s.machineControllers.Get(machineID)
+	if !ok {
+		return nil, types.ErrMachineNotFound
+	}
+
+	machine := ctrl.GetState().Machine
+	if machine.NetworkInterface == nil || machine.NetworkInterface.ReleasedAt != nil {
+		return nil, types.ErrMachineNotRunning
+	}
+
+	return s.networkProvider.GetIngressStats(ctx, machine.NetworkInterface)
+}
+
+func validateIngressPolicy(policy *coretypes.MachineIngressPolicy) error {
+	if policy == nil {
+		return nil
+	}
+
+	for index, rule := range policy.Rules {
+		switch rule.Protocol {
+		case coretypes.MachineIngressProtocolTCP, coretypes.MachineIngressProtocolUDP:
+			if rule.FromPort > rule.ToPort {
+				return fmt.Errorf("%w: ingress rule %d has a port range ending before its start", types.ErrInvalidMachineSpec, index)
+			}
+		case coretypes.MachineIngressProtocolAny, coretypes.MachineIngressProtocolICMP:
+			if rule.FromPort != 0 || rule.ToPort != 0 {
+				return fmt.Errorf("%w: ingress rule %d can only have ports with tcp or udp", types.ErrInvalidMachineSpec, index)
+			}
+		default:
+			return fmt.Errorf("%w: ingress rule %d has an unknown protocol %q", types.ErrInvalidMachineSpec, index, rule.Protocol)
+		}
+
+		for _, cidr := range rule.SourceCIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("%w: ingress rule %d has an invalid source cidr %q", types.ErrInvalidMachineSpec, index, cidr)
+			}
+		}
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	"github.com/baepo-cloud/baepo-node/core/typeutil"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
@@ -20,6 +21,9 @@ type (
 		Machine         *types.Machine
 		Reconciliation  *Reconciliation
 		RuntimeListener *RuntimeListener
+		Expiration      *Expiration
+		// Expired is set once the expiration timer requested the termination of the machine.
+		Expired bool
 	}
 
 	Controller struct {
@@ -30,6 +34,8 @@ type (
 		cancel    context.CancelFunc
 		wg        sync.WaitGroup
 		stopping  atomic.Bool
+		// origin is the span which last requested a new state, the next reconciliation is its child.
+		origin atomic.Pointer[trace.SpanContext]
 
 		db              *gorm.DB
 		volumeProvider  types.VolumeProvider
@@ -40,6 +46,7 @@ type (
 )
 
 func New(
+	ctx context.Context,
 	machine *types.Machine,
 	db *gorm.DB,
 	volumeProvider types.VolumeProvider,
@@ -47,7 +54,7 @@ func New(
 	runtimeService types.RuntimeService,
 	imageProvider types.ImageProvider,
 ) *Controller {
-	ctx, cancel := context.WithCancel(context.Background())
+	dispatcherCtx, cancel := context.WithCancel(context.Background())
 	ctrl := &Controller{
 		log: slog.With(
 			slog.String("component", "machinecontroller"),
@@ -68,9 +75,11 @@ func New(
 	go func() {
 		defer ctrl.wg.Done()
 
-		ctrl.eventBus.StartDispatcher(ctx)
+		ctrl.eventBus.StartDispatcher(dispatcherCtx)
 	}()
 
+	ctrl.setOrigin(ctx)
+
 	ctrl.eventBus.SubscribeToEvents(ctrl.eventHandler)
 	ctrl.eventBus.PublishEvent(&AssessStateMessage{})
 	return ctrl
@@ -83,6 +92,9 @@ func (c *Controller) Stop() error {
 	if state.RuntimeListener != nil {
 		state.RuntimeListener.Cancel()
 	}
+	if state.Expiration != nil {
+		state.Expiration.Cancel()
+	}
 
 	waitChan := make(chan struct{}, 1)
 	go func() {
@@ -117,6 +129,9 @@ func (c *Controller) GetState() *State {
 	if c.state.RuntimeListener != nil {
 		stateCopy.RuntimeListener = typeutil.Ptr(*c.state.RuntimeListener)
 	}
+	if c.state.Expiration != nil {
+		stateCopy.Expiration = typeutil.Ptr(*c.state.Expiration)
+	}
 	return &stateCopy
 }
 
@@ -133,8 +148,71 @@ func (c *Controller) SetState(updater func(state *State) error) error {
 	return nil
 }
 
-func (c *Controller) SetDesiredState(desiredState coretypes.MachineDesiredState) {
+// RestoreSnapshot starts the machine from the snapshot, its volumes must already be restored.
+func (c *Controller) RestoreSnapshot(ctx context.Context, snapshot *types.Snapshot) {
+	c.setOrigin(ctx)
+	c.eventBus.PublishEvent(&RestoreSnapshotMessage{Snapshot: snapshot})
+}
+
+// UpdateSpec persists the new spec of the machine, the runtime must already be resized.
+func (c *Controller) UpdateSpec(ctx context.Context, spec *types.MachineSpec) error {
+	err := c.SetState(func(s *State) error {
+		s.Machine.Spec = spec
+		return c.db.WithContext(ctx).Select("Spec").Save(&s.Machine).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	c.eventBus.PublishEvent(&SpecChangedMessage{
+		Spec:      spec,
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// RunExclusive runs the operation while the machine is marked as being reconciled, the desired
+// states requested meanwhile are only reconciled once it returns. It fails with
+// types.ErrMachineBusy when the machine is already being reconciled.
+func (c *Controller) RunExclusive(ctx context.Context, operation func(ctx context.Context, machine *types.Machine) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	err := c.SetState(func(s *State) error {
+		if s.Reconciliation != nil {
+			return types.ErrMachineBusy
+		}
+
+		s.Reconciliation = &Reconciliation{
+			DesiredState: s.Machine.DesiredState,
+			StartedAt:    time.Now(),
+			Cancel:       cancel,
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = c.SetState(func(s *State) error {
+			s.Reconciliation = nil
+			return nil
+		})
+		c.eventBus.PublishEvent(&AssessStateMessage{})
+	}()
+	return operation(ctx, c.GetState().Machine)
+}
+
+func (c *Controller) SetDesiredState(ctx context.Context, desiredState coretypes.MachineDesiredState) {
 	c.log.Debug("setting new desired state",
 		slog.String("caller", strings.Join(typeutil.StackTrace(), ", ")))
+	c.setOrigin(ctx)
 	c.eventBus.PublishEvent(NewDesiredStateChangedMessage(desiredState))
 }
+
+func (c *Controller) setOrigin(ctx context.Context) {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		c.origin.Store(&spanContext)
+	}
+}
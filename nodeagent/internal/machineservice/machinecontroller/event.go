@@ -3,6 +3,7 @@ package machinecontroller
 import (
 	"context"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	nodev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/node/v1"
 	"time"
 )
@@ -31,6 +32,38 @@ type (
 		Timestamp time.Time
 	}
 
+	MachineStartedMessage struct {
+		ExpiresAt *time.Time
+		Timestamp time.Time
+	}
+
+	MachineExpiredMessage struct {
+		Timeout   time.Duration
+		Timestamp time.Time
+	}
+
+	// MachineTerminatedMessage is published once a machine which exceeded its timeout is
+	// terminated, the other terminations are only reported by the state change.
+	MachineTerminatedMessage struct {
+		Timeout   time.Duration
+		Timestamp time.Time
+	}
+
+	SpecChangedMessage struct {
+		Spec      *types.MachineSpec
+		Timestamp time.Time
+	}
+
+	// PortsPublishedMessage carries every port published by the machine, none once terminated.
+	PortsPublishedMessage struct {
+		Ports     []*types.PublishedPort
+		Timestamp time.Time
+	}
+
+	RestoreSnapshotMessage struct {
+		Snapshot *types.Snapshot
+	}
+
 	RuntimeListenerConnectedMessage struct{}
 
 	RuntimeListenerDisconnectedMessage struct {
@@ -25,6 +25,12 @@ func (c *Controller) eventHandler(ctx context.Context, anyEvent any) {
 		} else if !shouldStartRuntimeListener && state.RuntimeListener != nil {
 			state.RuntimeListener.Cancel()
 		}
+		shouldStartExpirationTimer := c.shouldStartExpirationTimer(state.Machine)
+		if shouldStartExpirationTimer && state.Expiration == nil {
+			c.startExpirationTimer(state.Machine)
+		} else if !shouldStartExpirationTimer && state.Expiration != nil {
+			c.stopExpirationTimer()
+		}
 	case *DesiredStateChangedMessage:
 		c.log.Debug("desired state changed", slog.String("new-state", string(event.DesiredState)))
 		_ = c.SetState(func(s *State) error {
@@ -34,15 +40,48 @@ func (c *Controller) eventHandler(ctx context.Context, anyEvent any) {
 		c.eventBus.PublishEvent(&AssessStateMessage{})
 	case *StateChangedMessage:
 		c.log.Debug("machine state changed", slog.String("new-state", string(event.State)))
+		started := false
 		_ = c.SetState(func(s *State) error {
 			s.Machine.State = event.State
 			s.Machine.TerminatedAt = nil
-			if event.State == coretypes.MachineStateTerminated {
+			switch event.State {
+			case coretypes.MachineStatePending, coretypes.MachineStateStopped:
+				s.Machine.StartedAt = nil
+			case coretypes.MachineStateRunning:
+				if s.Machine.StartedAt == nil {
+					s.Machine.StartedAt = typeutil.Ptr(event.Timestamp)
+					started = true
+				}
+			case coretypes.MachineStateTerminated:
 				s.Machine.TerminatedAt = typeutil.Ptr(time.Now())
 			}
-			return c.db.WithContext(ctx).Select("State", "TerminatedAt").Save(&s.Machine).Error
+			return c.db.WithContext(ctx).Select("State", "StartedAt", "TerminatedAt").Save(&s.Machine).Error
 		})
+		if started {
+			c.eventBus.PublishEvent(&MachineStartedMessage{
+				ExpiresAt: c.GetState().Machine.ExpiresAt(),
+				Timestamp: event.Timestamp,
+			})
+		}
 		c.eventBus.PublishEvent(&AssessStateMessage{})
+	case *RestoreSnapshotMessage:
+		c.log.Info("restoring snapshot", slog.String("snapshot-id", event.Snapshot.ID))
+		_ = c.SetState(func(s *State) error {
+			s.Machine.RestoreFrom = &event.Snapshot.Path
+			return c.db.WithContext(ctx).Select("RestoreFrom").Save(&s.Machine).Error
+		})
+		c.eventBus.PublishEvent(NewDesiredStateChangedMessage(coretypes.MachineDesiredStateRunning))
+	case *MachineExpiredMessage:
+		if state := c.GetState(); state.Machine.DesiredState != coretypes.MachineDesiredStateRunning {
+			return
+		}
+
+		c.log.Info("machine timeout exceeded, terminating", slog.Duration("timeout", event.Timeout))
+		_ = c.SetState(func(s *State) error {
+			s.Expired = true
+			return nil
+		})
+		c.eventBus.PublishEvent(NewDesiredStateChangedMessage(coretypes.MachineDesiredStateTerminated))
 	case *RuntimeListenerConnectedMessage:
 		if state := c.GetState(); state.Machine.State != coretypes.MachineStateRunning {
 			c.eventBus.PublishEvent(NewStateChangedMessage(coretypes.MachineStateRunning))
@@ -52,6 +91,16 @@ func (c *Controller) eventHandler(ctx context.Context, anyEvent any) {
 			return nil
 		})
 	case *RuntimeListenerDisconnectedMessage:
+		// the listener is cancelled on purpose when the machine is paused or stopped, and the vm
+		// is paused while an exclusive operation snapshots it, only the failures of a machine
+		// meant to run degrade it
+		if event.Error == nil {
+			return
+		} else if state := c.GetState(); state.Machine.DesiredState != coretypes.MachineDesiredStateRunning ||
+			state.Reconciliation != nil {
+			return
+		}
+
 		_ = c.SetState(func(s *State) error {
 			if s.RuntimeListener != nil {
 				s.RuntimeListener.ConsecutiveErrorCount++
@@ -0,0 +1,68 @@
+package machinecontroller
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+type Expiration struct {
+	ExpiresAt time.Time
+	Cancel    context.CancelFunc
+}
+
+func (c *Controller) shouldStartExpirationTimer(machine *types.Machine) bool {
+	return machine.DesiredState == coretypes.MachineDesiredStateRunning &&
+		machine.ExpiresAt() != nil &&
+		typeutil.Includes([]coretypes.MachineState{
+			coretypes.MachineStateRunning,
+			coretypes.MachineStateDegraded,
+		}, machine.State)
+}
+
+func (c *Controller) startExpirationTimer(machine *types.Machine) {
+	expiresAt := *machine.ExpiresAt()
+	c.log.Debug("starting expiration timer", slog.Time("expires-at", expiresAt))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = c.SetState(func(state *State) error {
+		state.Expiration = &Expiration{
+			ExpiresAt: expiresAt,
+			Cancel:    cancel,
+		}
+		return nil
+	})
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		// time.NewTimer fires immediately for a negative duration, which covers machines
+		// that expired while the agent was down.
+		timer := time.NewTimer(time.Until(expiresAt))
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+			c.eventBus.PublishEvent(&MachineExpiredMessage{
+				Timeout:   time.Duration(*machine.Spec.Timeout) * time.Second,
+				Timestamp: time.Now(),
+			})
+		}
+	}()
+}
+
+func (c *Controller) stopExpirationTimer() {
+	_ = c.SetState(func(state *State) error {
+		if state.Expiration != nil {
+			state.Expiration.Cancel()
+			state.Expiration = nil
+		}
+		return nil
+	})
+}
@@ -7,10 +7,16 @@ import (
 	"fmt"
 	"github.com/sourcegraph/conc/pool"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"os"
+	"slices"
 	"time"
 
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"log/slog"
 )
 
@@ -26,6 +32,10 @@ func (c *Controller) shouldReconcile(machine *types.Machine) bool {
 		return machine.State != coretypes.MachineStatePending
 	case coretypes.MachineDesiredStateRunning:
 		return machine.State != coretypes.MachineStateRunning && machine.State != coretypes.MachineStateDegraded
+	case coretypes.MachineDesiredStatePaused:
+		return machine.State != coretypes.MachineStatePaused
+	case coretypes.MachineDesiredStateStopped:
+		return machine.State != coretypes.MachineStateStopped
 	case coretypes.MachineDesiredStateTerminated:
 		return machine.State != coretypes.MachineStateTerminated
 	default:
@@ -41,7 +51,14 @@ func (c *Controller) reconcile() {
 	}
 
 	desired := state.Machine.DesiredState
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx := context.Background()
+	if origin := c.origin.Swap(nil); origin != nil {
+		ctx = trace.ContextWithSpanContext(ctx, *origin)
+	}
+	ctx, span := tracing.Start(ctx, "machinecontroller.reconcile",
+		attribute.String("machine.id", state.Machine.ID),
+		attribute.String("machine.desired_state", string(desired)))
+	ctx, cancel := context.WithCancel(ctx)
 	startTime := time.Now()
 
 	_ = c.SetState(func(s *State) error {
@@ -72,12 +89,19 @@ func (c *Controller) reconcile() {
 			newState, err = c.reconcileToPending(ctx, state.Machine)
 		case coretypes.MachineDesiredStateRunning:
 			newState, err = c.reconcileToRunning(ctx, state.Machine)
+		case coretypes.MachineDesiredStatePaused:
+			newState, err = c.reconcileToPaused(ctx, state.Machine)
+		case coretypes.MachineDesiredStateStopped:
+			newState, err = c.reconcileToStopped(ctx, state.Machine)
 		case coretypes.MachineDesiredStateTerminated:
 			newState, err = c.reconcileToTerminated(ctx, state.Machine)
 		default:
 			err = fmt.Errorf("unknown desired state: %v", desired)
 		}
 
+		tracing.End(span, err)
+		metrics.ReconciliationDuration.WithLabelValues(string(desired), metrics.Result(err)).
+			Observe(time.Since(startTime).Seconds())
 		c.eventBus.PublishEvent(NewStateChangedMessage(newState))
 		c.eventBus.PublishEvent(&ReconciliationCompleteMessage{
 			Success: err == nil,
@@ -94,6 +118,7 @@ func (c *Controller) reconcile() {
 
 func (c *Controller) reconcileToPending(ctx context.Context, machine *types.Machine) (coretypes.MachineState, error) {
 	c.log.Debug("reconciling to pending state")
+	c.discardAdoptableRuntime(ctx, machine)
 
 	if c.isMachineRuntimeStarted(ctx, machine) {
 		if machine.State != coretypes.MachineStateTerminating {
@@ -106,7 +131,7 @@ func (c *Controller) reconcileToPending(ctx context.Context, machine *types.Mach
 		}
 	}
 
-	if err := c.prepareMachine(ctx, machine); err != nil {
+	if err := c.prepareMachine(ctx, machine, false); err != nil {
 		return coretypes.MachineStateError, fmt.Errorf("failed to prepare resources: %w", err)
 	}
 
@@ -114,6 +139,15 @@ func (c *Controller) reconcileToPending(ctx context.Context, machine *types.Mach
 }
 
 func (c *Controller) reconcileToRunning(ctx context.Context, machine *types.Machine) (coretypes.MachineState, error) {
+	if machine.State == coretypes.MachineStatePaused {
+		c.log.Debug("resuming runtime")
+		if err := c.runtimeService.Resume(ctx, machine.ID); err != nil {
+			return coretypes.MachineStateError, fmt.Errorf("failed to resume runtime: %w", err)
+		}
+
+		return coretypes.MachineStateRunning, nil
+	}
+
 	if machine.State != coretypes.MachineStateStarting {
 		c.eventBus.PublishEvent(NewStateChangedMessage(coretypes.MachineStateStarting))
 	}
@@ -126,7 +160,17 @@ func (c *Controller) reconcileToRunning(ctx context.Context, machine *types.Mach
 		}
 	}
 
-	if err := c.prepareMachine(ctx, machine); err != nil {
+	adopted := false
+	if machine.AdoptFrom != nil {
+		c.log.Debug("adopting warm pool runtime", slog.String("runtime-id", *machine.AdoptFrom))
+		if err := c.adoptRuntime(ctx, machine); err != nil {
+			c.log.Warn("failed to adopt warm pool runtime, booting instead", slog.Any("error", err))
+		} else {
+			adopted = true
+		}
+	}
+
+	if err := c.prepareMachine(ctx, machine, adopted); err != nil {
 		return coretypes.MachineStateError, fmt.Errorf("failed to prepare resources: %w", err)
 	}
 
@@ -136,19 +180,65 @@ func (c *Controller) reconcileToRunning(ctx context.Context, machine *types.Mach
 			return coretypes.MachineStateError, ctx.Err()
 		}
 
-		err := c.runtimeService.Start(ctx, types.RuntimeStartOptions{Machine: machine})
+		opts := types.RuntimeStartOptions{Machine: machine}
+		if machine.RestoreFrom != nil {
+			c.log.Debug("restoring runtime from snapshot", slog.String("path", *machine.RestoreFrom))
+			opts.RestoreFrom = machine.RestoreFrom
+		}
+
+		err := c.runtimeService.Start(ctx, opts)
 		if err != nil {
 			return coretypes.MachineStateError, fmt.Errorf("failed to start runtime: %w", err)
 		}
 
+		if machine.RestoreFrom != nil {
+			_ = c.SetState(func(s *State) error {
+				s.Machine.RestoreFrom = nil
+				return c.db.WithContext(ctx).Select("RestoreFrom").Save(&s.Machine).Error
+			})
+		}
+
 		c.log.Debug("runtime started successfully")
 	}
 
 	return coretypes.MachineStateRunning, nil
 }
 
+// reconcileToPaused freezes the vm in place, the guest memory stays in the runtime so that
+// resuming is instant.
+func (c *Controller) reconcileToPaused(ctx context.Context, machine *types.Machine) (coretypes.MachineState, error) {
+	c.log.Debug("reconciling to paused state")
+	if !c.isMachineRuntimeStarted(ctx, machine) {
+		return coretypes.MachineStateError, errors.New("cannot pause a machine without runtime")
+	}
+
+	if err := c.runtimeService.Pause(ctx, machine.ID); err != nil {
+		return coretypes.MachineStateError, fmt.Errorf("failed to pause runtime: %w", err)
+	}
+
+	return coretypes.MachineStatePaused, nil
+}
+
+// reconcileToStopped shuts the runtime down but keeps the volumes and the network interface
+// allocated, so that the machine comes back with the same disks and ip address.
+func (c *Controller) reconcileToStopped(ctx context.Context, machine *types.Machine) (coretypes.MachineState, error) {
+	c.log.Debug("reconciling to stopped state")
+	c.discardAdoptableRuntime(ctx, machine)
+	if c.isMachineRuntimeStarted(ctx, machine) {
+		if machine.State != coretypes.MachineStateStopping {
+			c.eventBus.PublishEvent(NewStateChangedMessage(coretypes.MachineStateStopping))
+		}
+		if err := c.runtimeService.Terminate(ctx, machine.ID); err != nil {
+			return coretypes.MachineStateError, fmt.Errorf("failed to terminate runtime: %w", err)
+		}
+	}
+
+	return coretypes.MachineStateStopped, nil
+}
+
 func (c *Controller) reconcileToTerminated(ctx context.Context, machine *types.Machine) (coretypes.MachineState, error) {
 	c.log.Debug("reconciling to terminated state")
+	c.discardAdoptableRuntime(ctx, machine)
 	if c.isMachineRuntimeStarted(ctx, machine) {
 		if machine.State != coretypes.MachineStateTerminating {
 			c.eventBus.PublishEvent(NewStateChangedMessage(coretypes.MachineStateTerminating))
@@ -158,6 +248,11 @@ func (c *Controller) reconcileToTerminated(ctx context.Context, machine *types.M
 		}
 	}
 
+	if err := c.networkProvider.UnpublishPorts(ctx, machine.ID); err != nil {
+		return coretypes.MachineStateError, fmt.Errorf("failed to unpublish ports: %w", err)
+	}
+	c.setPublishedPorts(nil)
+
 	if err := c.networkProvider.ReleaseInterface(ctx, machine.NetworkInterface); err != nil {
 		return coretypes.MachineStateError, fmt.Errorf("failed to release network interface (%v): %w", machine.NetworkInterface.ID, err)
 	}
@@ -168,10 +263,22 @@ func (c *Controller) reconcileToTerminated(ctx context.Context, machine *types.M
 		}
 	}
 
+	// a machine terminated past its expiration by the control plane was not terminated for
+	// exceeding its timeout, only the termination requested by the timer is
+	if c.GetState().Expired {
+		c.eventBus.PublishEvent(&MachineTerminatedMessage{
+			Timeout:   time.Duration(*machine.Spec.Timeout) * time.Second,
+			Timestamp: time.Now(),
+		})
+	}
+
 	return coretypes.MachineStateTerminated, nil
 }
 
-func (c *Controller) prepareMachine(ctx context.Context, machine *types.Machine) error {
+// prepareMachine sets up the network interface, publishes the ports and allocates the volumes of
+// the machine. The tap interface of an adopted runtime is in use by its vm, only its ingress policy
+// is applied.
+func (c *Controller) prepareMachine(ctx context.Context, machine *types.Machine, adopted bool) error {
 	c.log.Debug("preparing machine", slog.Int("containers", len(machine.Containers)))
 	containersByID := map[string]*types.Container{}
 	for _, container := range machine.Containers {
@@ -180,11 +287,35 @@ func (c *Controller) prepareMachine(ctx context.Context, machine *types.Machine)
 
 	p := pool.New().WithErrors().WithContext(ctx)
 	p.Go(func(ctx context.Context) error {
-		c.log.Debug("setting up network interface")
-		err := c.networkProvider.SetupInterface(ctx, machine.NetworkInterface)
+		if adopted {
+			c.log.Debug("applying ingress policy")
+			err := c.networkProvider.UpdateIngressPolicy(ctx, machine.NetworkInterface, machine.Spec.Ingress)
+			if err != nil {
+				return fmt.Errorf("failed to apply ingress policy: %w", err)
+			}
+		} else {
+			c.log.Debug("setting up network interface")
+			err := c.networkProvider.SetupInterface(ctx, machine.NetworkInterface, machine.Spec.Ingress)
+			if err != nil {
+				return fmt.Errorf("failed to set up network interface: %w", err)
+			}
+		}
+
+		var ports []types.NetworkPublishPortOptions
+		for _, container := range machine.Containers {
+			for _, port := range container.Spec.Ports {
+				ports = append(ports, types.NetworkPublishPortOptions{ContainerID: container.ID, Spec: port})
+			}
+		}
+		publishedPorts, err := c.networkProvider.PublishPorts(ctx, types.NetworkPublishPortsOptions{
+			MachineID:        machine.ID,
+			NetworkInterface: machine.NetworkInterface,
+			Ports:            ports,
+		})
 		if err != nil {
-			return fmt.Errorf("failed to set up network interface: %w", err)
+			return fmt.Errorf("failed to publish ports: %w", err)
 		}
+		c.setPublishedPorts(publishedPorts)
 
 		return nil
 	})
@@ -215,6 +346,59 @@ func (c *Controller) prepareMachine(ctx context.Context, machine *types.Machine)
 	return p.Wait()
 }
 
+// setPublishedPorts reports the published ports of the machine when they changed.
+func (c *Controller) setPublishedPorts(ports []*types.PublishedPort) {
+	changed := false
+	_ = c.SetState(func(s *State) error {
+		changed = !slices.EqualFunc(s.Machine.PublishedPorts, ports, func(a, b *types.PublishedPort) bool {
+			return a.ID == b.ID
+		})
+		s.Machine.PublishedPorts = ports
+		return nil
+	})
+	if changed {
+		c.eventBus.PublishEvent(&PortsPublishedMessage{
+			Ports:     ports,
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// adoptRuntime takes over the warm pool vm the machine was created from. The vm is only adopted
+// once, it is terminated when it can not be adopted and the machine is booted instead.
+func (c *Controller) adoptRuntime(ctx context.Context, machine *types.Machine) error {
+	runtimeID := *machine.AdoptFrom
+	err := c.SetState(func(s *State) error {
+		s.Machine.AdoptFrom = nil
+		return c.db.WithContext(ctx).Select("AdoptFrom").Save(&s.Machine).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear adopted runtime: %w", err)
+	}
+
+	return c.runtimeService.Adopt(ctx, types.RuntimeAdoptOptions{
+		RuntimeID: runtimeID,
+		Machine:   machine,
+	})
+}
+
+// discardAdoptableRuntime terminates the warm pool vm of a machine which is not started, its
+// volumes and network interface stay with the machine.
+func (c *Controller) discardAdoptableRuntime(ctx context.Context, machine *types.Machine) {
+	if machine.AdoptFrom == nil {
+		return
+	}
+
+	runtimeID := *machine.AdoptFrom
+	c.log.Debug("discarding warm pool runtime", slog.String("runtime-id", runtimeID))
+	_ = c.runtimeService.Terminate(ctx, runtimeID)
+	_ = os.RemoveAll(c.runtimeService.GetMachineDirectory(runtimeID))
+	_ = c.SetState(func(s *State) error {
+		s.Machine.AdoptFrom = nil
+		return c.db.WithContext(ctx).Select("AdoptFrom").Save(&s.Machine).Error
+	})
+}
+
 func (c *Controller) isMachineRuntimeStarted(ctx context.Context, machine *types.Machine) bool {
 	client, closeClient := c.runtimeService.GetClient(machine.ID)
 	defer closeClient()
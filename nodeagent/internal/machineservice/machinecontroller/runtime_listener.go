@@ -52,7 +52,7 @@ func (c *Controller) startRuntimeListener(machine *types.Machine) {
 				return
 			case <-ticker.C:
 				err := c.connectToRuntimeListener(ctx, machine.ID)
-				if errors.Is(err, context.Canceled) {
+				if ctx.Err() != nil || errors.Is(err, context.Canceled) || connect.CodeOf(err) == connect.CodeCanceled {
 					err = nil
 				}
 				c.eventBus.PublishEvent(&RuntimeListenerDisconnectedMessage{Error: err})
@@ -0,0 +1,41 @@
+package machineservice
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
+)
+
+func (s *Service) startNetworkReconciliationWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Network.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reconcileNetwork(ctx); err != nil {
+				s.log.Error("failed to reconcile network", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// reconcileNetwork repairs the tap interfaces and the firewall rules which drifted from the
+// allocated interfaces, every drift is logged and counted.
+func (s *Service) reconcileNetwork(ctx context.Context) error {
+	drifts, err := s.networkProvider.Reconcile(ctx)
+	for _, drift := range drifts {
+		metrics.NetworkDrifts.WithLabelValues(string(drift.Kind), metrics.Result(drift.Error)).Inc()
+		driftLog := s.log.With(slog.String("kind", string(drift.Kind)), slog.String("interface", drift.Interface))
+		if drift.Error != nil {
+			driftLog.Error("failed to repair network drift", slog.Any("error", drift.Error))
+		} else {
+			driftLog.Warn("network drift repaired")
+		}
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package machineservice
+
+import (
+	"context"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Service) Pause(ctx context.Context, machineID string) (*types.Machine, error) {
+	ctrl, ok := s.machineControllers.Get(machineID)
+	if !ok {
+		return nil, types.ErrMachineNotFound
+	}
+
+	machine := ctrl.GetState().Machine
+	if machine.DesiredState != coretypes.MachineDesiredStateRunning || !typeutil.Includes([]coretypes.MachineState{
+		coretypes.MachineStateRunning,
+		coretypes.MachineStateDegraded,
+	}, machine.State) {
+		return nil, types.ErrMachineNotRunning
+	}
+
+	ctrl.SetDesiredState(ctx, coretypes.MachineDesiredStatePaused)
+	return ctrl.GetState().Machine, nil
+}
+
+func (s *Service) Resume(ctx context.Context, machineID string) (*types.Machine, error) {
+	ctrl, ok := s.machineControllers.Get(machineID)
+	if !ok {
+		return nil, types.ErrMachineNotFound
+	}
+
+	if machine := ctrl.GetState().Machine; machine.DesiredState != coretypes.MachineDesiredStatePaused {
+		return nil, types.ErrMachineNotPaused
+	}
+
+	ctrl.SetDesiredState(ctx, coretypes.MachineDesiredStateRunning)
+	return ctrl.GetState().Machine, nil
+}
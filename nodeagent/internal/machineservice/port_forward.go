@@ -0,0 +1,27 @@
+package machineservice
+
+import (
+	"context"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// PortForward opens a tunnel to the loopback of a machine through its runtime. The containers of
+// a machine share its network, the container is only resolved to reject unknown references.
+func (s *Service) PortForward(ctx context.Context, opts types.MachinePortForwardOptions) (*types.MachinePortForwardSession, error) {
+	machine, container, err := s.findRunningContainer(opts.MachineID, opts.Container)
+	if err != nil {
+		return nil, err
+	}
+
+	runtimeClient, closeRuntimeClient := s.runtimeService.GetStreamAPIClient(machine.ID)
+	stream := runtimeClient.PortForward(ctx)
+	return &types.MachinePortForwardSession{
+		ContainerID: container.ID,
+		Stream:      stream,
+		Close: func() {
+			_ = stream.CloseResponse()
+			closeRuntimeClient()
+		},
+	}, nil
+}
@@ -0,0 +1,63 @@
+package machineservice
+
+import (
+	"fmt"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// publishedPortsPayload encodes the published ports as a struct, the core protocol has no
+// message for them.
+func publishedPortsPayload(ports []*types.PublishedPort) (*structpb.Struct, error) {
+	values := make([]any, len(ports))
+	for index, port := range ports {
+		values[index] = map[string]any{
+			"container_id":   port.ContainerID,
+			"protocol":       string(port.Protocol),
+			"host_port":      port.HostPort,
+			"container_port": port.ContainerPort,
+		}
+	}
+	return structpb.NewStruct(map[string]any{"ports": values})
+}
+
+// validateContainerPorts rejects the ports of a machine which could never be published, whether
+// a host port is free is only known when the machine starts.
+func validateContainerPorts(containers []types.MachineCreateContainerOptions) error {
+	type publishedPort struct {
+		protocol coretypes.ContainerPortProtocol
+		port     uint16
+	}
+
+	// the containers share the address of the machine
+	containerPorts, hostPorts := map[publishedPort]bool{}, map[publishedPort]bool{}
+	for _, container := range containers {
+		for _, port := range container.Spec.Ports {
+			switch port.Protocol {
+			case coretypes.ContainerPortProtocolTCP, coretypes.ContainerPortProtocolUDP:
+			default:
+				return fmt.Errorf("%w: container %s has a port with an unknown protocol %q", types.ErrInvalidMachineSpec,
+					container.ContainerID, port.Protocol)
+			}
+
+			containerPort := publishedPort{port.Protocol, port.ContainerPort}
+			if port.ContainerPort == 0 {
+				return fmt.Errorf("%w: container %s has a port without container port", types.ErrInvalidMachineSpec, container.ContainerID)
+			} else if containerPorts[containerPort] {
+				return fmt.Errorf("%w: %s container port %d is published twice", types.ErrInvalidMachineSpec,
+					port.Protocol, port.ContainerPort)
+			}
+			containerPorts[containerPort] = true
+
+			hostPort := publishedPort{port.Protocol, port.HostPort}
+			if port.HostPort != 0 && hostPorts[hostPort] {
+				return fmt.Errorf("%w: %s host port %d is published twice", types.ErrInvalidMachineSpec, port.Protocol, port.HostPort)
+			} else if port.HostPort != 0 {
+				hostPorts[hostPort] = true
+			}
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/alphadose/haxmap"
 	"github.com/baepo-cloud/baepo-node/core/eventbus"
@@ -22,6 +23,14 @@ type Service struct {
 	imageProvider         types.ImageProvider
 	config                *types.Config
 	cancelGCWorker        context.CancelFunc
+	cancelEventsCompactor context.CancelFunc
+	cancelWarmPoolWorker  context.CancelFunc
+	cancelNetworkWorker   context.CancelFunc
+	warmPoolRefill        chan struct{}
+	warmPoolLock          sync.Mutex
+	warmPoolCounters      map[string]*warmPoolCounters
+	gcLock                sync.Mutex
+	admissionLock         sync.Mutex
 	machineControllers    *haxmap.Map[string, *machinecontroller.Controller]
 	cancelEventDispatcher context.CancelFunc
 	machineEvents         *eventbus.Bus[*types.MachineEvent]
@@ -47,6 +56,8 @@ func New(
 		config:             config,
 		machineControllers: haxmap.New[string, *machinecontroller.Controller](),
 		machineEvents:      eventbus.NewBus[*types.MachineEvent](),
+		warmPoolRefill:     make(chan struct{}, 1),
+		warmPoolCounters:   map[string]*warmPoolCounters{},
 	}
 }
 
@@ -55,6 +66,11 @@ func (s *Service) Start(ctx context.Context) error {
 	go s.machineEvents.StartDispatcher(eventDispatcherCtx)
 	s.cancelEventDispatcher = cancelEventDispatcher
 
+	// the tap interfaces of the machines are repaired before their controllers reconcile them
+	if err := s.reconcileNetwork(ctx); err != nil {
+		s.log.Error("failed to reconcile network", slog.Any("error", err))
+	}
+
 	if err := s.loadMachines(ctx); err != nil {
 		return fmt.Errorf("failed to load machines: %w", err)
 	}
@@ -63,6 +79,18 @@ func (s *Service) Start(ctx context.Context) error {
 	go s.startGCWorker(gcWorkerCtx)
 	s.cancelGCWorker = cancelGCWorker
 
+	eventsCompactorCtx, cancelEventsCompactor := context.WithCancel(context.Background())
+	go s.startEventsCompactionWorker(eventsCompactorCtx)
+	s.cancelEventsCompactor = cancelEventsCompactor
+
+	warmPoolWorkerCtx, cancelWarmPoolWorker := context.WithCancel(context.Background())
+	go s.startWarmPoolWorker(warmPoolWorkerCtx)
+	s.cancelWarmPoolWorker = cancelWarmPoolWorker
+
+	networkWorkerCtx, cancelNetworkWorker := context.WithCancel(context.Background())
+	go s.startNetworkReconciliationWorker(networkWorkerCtx)
+	s.cancelNetworkWorker = cancelNetworkWorker
+
 	return nil
 }
 
@@ -70,6 +98,15 @@ func (s *Service) Stop(ctx context.Context) error {
 	if s.cancelGCWorker != nil {
 		s.cancelGCWorker()
 	}
+	if s.cancelEventsCompactor != nil {
+		s.cancelEventsCompactor()
+	}
+	if s.cancelWarmPoolWorker != nil {
+		s.cancelWarmPoolWorker()
+	}
+	if s.cancelNetworkWorker != nil {
+		s.cancelNetworkWorker()
+	}
 	if s.cancelEventDispatcher != nil {
 		s.cancelEventDispatcher()
 	}
@@ -85,6 +122,7 @@ func (s *Service) loadMachines(ctx context.Context) error {
 		Preload("Volumes.Image.Volume").
 		Preload("Containers").
 		Preload("NetworkInterface").
+		Preload("PublishedPorts").
 		Where("machines.state NOT IN ?", []coretypes.MachineState{coretypes.MachineStateTerminated}).
 		Find(&machines).
 		Error
@@ -93,13 +131,13 @@ func (s *Service) loadMachines(ctx context.Context) error {
 	}
 
 	for _, machine := range machines {
-		s.machineControllers.Set(machine.ID, s.newMachineController(machine))
+		s.machineControllers.Set(machine.ID, s.newMachineController(ctx, machine))
 	}
 	return nil
 }
 
-func (s *Service) newMachineController(machine *types.Machine) *machinecontroller.Controller {
-	ctrl := machinecontroller.New(machine, s.db, s.volumeProvider, s.networkProvider, s.runtimeService,
+func (s *Service) newMachineController(ctx context.Context, machine *types.Machine) *machinecontroller.Controller {
+	ctrl := machinecontroller.New(ctx, machine, s.db, s.volumeProvider, s.networkProvider, s.runtimeService,
 		s.imageProvider)
 	ctrl.SubscribeToEvents(s.handleMachineEventsStorage(machine))
 	ctrl.SubscribeToEvents(s.handleMachineTerminated(machine))
@@ -0,0 +1,226 @@
+package machineservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/nrednav/cuid2"
+	"gorm.io/gorm"
+)
+
+func (s *Service) CreateSnapshot(ctx context.Context, opts types.MachineCreateSnapshotOptions) (*types.Snapshot, error) {
+	ctrl, ok := s.machineControllers.Get(opts.MachineID)
+	if !ok {
+		return nil, types.ErrMachineNotFound
+	}
+
+	// the controller holds the machine for the whole operation, a concurrent pause, stop or
+	// resize would otherwise change the vm under the snapshot
+	var snapshot *types.Snapshot
+	err := ctrl.RunExclusive(ctx, func(ctx context.Context, machine *types.Machine) (err error) {
+		snapshot, err = s.snapshotMachine(ctx, machine)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+func (s *Service) snapshotMachine(ctx context.Context, machine *types.Machine) (_ *types.Snapshot, err error) {
+	if !typeutil.Includes([]coretypes.MachineState{
+		coretypes.MachineStateRunning,
+		coretypes.MachineStateDegraded,
+		coretypes.MachineStatePaused,
+	}, machine.State) {
+		return nil, types.ErrMachineNotRunning
+	}
+
+	snapshot := &types.Snapshot{
+		ID:        cuid2.Generate(),
+		MachineID: machine.ID,
+	}
+	snapshot.Path = s.getSnapshotDirectory(machine.ID, snapshot.ID)
+	log := s.log.With(slog.String("machine-id", machine.ID), slog.String("snapshot-id", snapshot.ID))
+	log.Info("creating snapshot")
+
+	if err = os.MkdirAll(snapshot.Path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			s.releaseSnapshotResources(snapshot)
+		}
+	}()
+
+	// the vm is paused for the whole operation so that the memory and the volumes are consistent
+	if machine.State != coretypes.MachineStatePaused {
+		if err = s.runtimeService.Pause(ctx, machine.ID); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if resumeErr := s.runtimeService.Resume(context.Background(), machine.ID); resumeErr != nil {
+				log.Error("failed to resume machine after snapshot", slog.Any("error", resumeErr))
+			}
+		}()
+	}
+
+	if err = s.runtimeService.Snapshot(ctx, machine.ID, snapshot.Path); err != nil {
+		return nil, err
+	}
+
+	for _, machineVolume := range machine.Volumes {
+		volume, err := s.volumeProvider.Snapshot(ctx, machineVolume.Volume)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot volume (%v): %w", machineVolume.VolumeID, err)
+		}
+
+		snapshot.Volumes = append(snapshot.Volumes, &types.SnapshotVolume{
+			ID:          cuid2.Generate(),
+			SnapshotID:  snapshot.ID,
+			ContainerID: machineVolume.ContainerID,
+			VolumeID:    volume.ID,
+			Volume:      volume,
+		})
+	}
+
+	if err = s.db.WithContext(ctx).Create(&snapshot).Error; err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	log.Info("snapshot created")
+	return snapshot, nil
+}
+
+func (s *Service) ListSnapshots(ctx context.Context, opts types.MachineListSnapshotsOptions) ([]*types.Snapshot, error) {
+	query := s.db.WithContext(ctx).Preload("Volumes.Volume").Order("created_at")
+	if opts.MachineID != nil {
+		query = query.Where("machine_id = ?", *opts.MachineID)
+	}
+
+	var snapshots []*types.Snapshot
+	if err := query.Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// RestoreSnapshot rolls the volumes of a stopped machine back to the snapshot and starts the
+// machine from the snapshot memory. Snapshots can only be restored on the machine they were
+// taken from as the hypervisor state references its volumes and network interface.
+func (s *Service) RestoreSnapshot(ctx context.Context, snapshotID string) (*types.Machine, error) {
+	snapshot, err := s.findSnapshot(ctx, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl, ok := s.machineControllers.Get(snapshot.MachineID)
+	if !ok {
+		return nil, types.ErrMachineNotFound
+	}
+
+	machine := ctrl.GetState().Machine
+	if machine.State != coretypes.MachineStateStopped || machine.DesiredState != coretypes.MachineDesiredStateStopped {
+		return nil, types.ErrMachineNotStopped
+	}
+
+	snapshotVolumes := map[string]*types.SnapshotVolume{}
+	for _, snapshotVolume := range snapshot.Volumes {
+		snapshotVolumes[snapshotVolume.ContainerID] = snapshotVolume
+	}
+
+	s.log.Info("restoring snapshot", slog.String("machine-id", machine.ID), slog.String("snapshot-id", snapshot.ID))
+	for _, machineVolume := range machine.Volumes {
+		snapshotVolume, ok := snapshotVolumes[machineVolume.ContainerID]
+		if !ok {
+			return nil, fmt.Errorf("snapshot has no volume for container %v", machineVolume.ContainerID)
+		}
+
+		if err = s.volumeProvider.Restore(ctx, machineVolume.Volume, snapshotVolume.Volume); err != nil {
+			return nil, fmt.Errorf("failed to restore volume (%v): %w", machineVolume.VolumeID, err)
+		}
+	}
+
+	ctrl.RestoreSnapshot(ctx, snapshot)
+	return ctrl.GetState().Machine, nil
+}
+
+func (s *Service) DeleteSnapshot(ctx context.Context, snapshotID string) error {
+	snapshot, err := s.findSnapshot(ctx, snapshotID)
+	if err != nil {
+		return err
+	}
+
+	for _, snapshotVolume := range snapshot.Volumes {
+		if err = s.volumeProvider.Release(ctx, snapshotVolume.Volume); err != nil {
+			return fmt.Errorf("failed to release volume (%v): %w", snapshotVolume.VolumeID, err)
+		}
+	}
+
+	if err = os.RemoveAll(snapshot.Path); err != nil {
+		return fmt.Errorf("failed to remove snapshot directory: %w", err)
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("snapshot_id = ?", snapshot.ID).Delete(&types.SnapshotVolume{}).Error; err != nil {
+			return fmt.Errorf("failed to delete snapshot volumes: %w", err)
+		}
+		if err := tx.Delete(&snapshot).Error; err != nil {
+			return fmt.Errorf("failed to delete snapshot: %w", err)
+		}
+		return nil
+	})
+}
+
+// deleteMachineSnapshots deletes the snapshots of a terminated machine, they can only be restored
+// on the machine they were taken from.
+func (s *Service) deleteMachineSnapshots(ctx context.Context, machineID string) error {
+	var snapshotIDs []string
+	err := s.db.WithContext(ctx).Model(&types.Snapshot{}).Where("machine_id = ?", machineID).Pluck("id", &snapshotIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to list machine snapshots: %w", err)
+	}
+
+	for _, snapshotID := range snapshotIDs {
+		if err = s.DeleteSnapshot(ctx, snapshotID); err != nil {
+			return fmt.Errorf("failed to delete snapshot (%v): %w", snapshotID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) findSnapshot(ctx context.Context, snapshotID string) (*types.Snapshot, error) {
+	var snapshot *types.Snapshot
+	err := s.db.WithContext(ctx).Preload("Volumes.Volume").First(&snapshot, "id = ?", snapshotID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, types.ErrSnapshotNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+func (s *Service) releaseSnapshotResources(snapshot *types.Snapshot) {
+	for _, snapshotVolume := range snapshot.Volumes {
+		_ = s.volumeProvider.Release(context.Background(), snapshotVolume.Volume)
+	}
+	_ = os.RemoveAll(snapshot.Path)
+}
+
+func (s *Service) getSnapshotDirectory(machineID string, snapshotID string) string {
+	if s.config.SnapshotDirectory != "" {
+		return path.Join(s.config.SnapshotDirectory, snapshotID)
+	}
+
+	return path.Join(s.runtimeService.GetMachineDirectory(machineID), "snapshots", snapshotID)
+}
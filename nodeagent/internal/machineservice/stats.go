@@ -0,0 +1,44 @@
+package machineservice
+
+import (
+	"context"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// GetStats streams the stats sampled by the runtime of a machine to fn until the context is done.
+func (s *Service) GetStats(ctx context.Context, machineID string, interval time.Duration, fn func(*nodeapi.MachineStats) error) error {
+	ctrl, ok := s.machineControllers.Get(machineID)
+	if !ok {
+		return types.ErrMachineNotFound
+	}
+
+	switch ctrl.GetState().Machine.State {
+	case coretypes.MachineStatePending, coretypes.MachineStateStopped, coretypes.MachineStateTerminated:
+		return types.ErrMachineNotRunning
+	}
+
+	// samples are sent once per interval, the regular client would time out waiting for them
+	runtimeClient, closeRuntimeClient := s.runtimeService.GetStreamAPIClient(machineID)
+	defer closeRuntimeClient()
+
+	stream, err := runtimeClient.GetStats(ctx, connect.NewRequest(&nodeapi.RuntimeGetStatsRequest{
+		IntervalSeconds: uint32(interval / time.Second),
+	}))
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	for stream.Receive() {
+		if err = fn(stream.Msg()); err != nil {
+			return err
+		}
+	}
+
+	return stream.Err()
+}
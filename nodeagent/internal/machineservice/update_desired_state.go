@@ -12,6 +12,6 @@ func (s *Service) UpdateDesiredState(ctx context.Context, opts types.MachineUpda
 		return nil, types.ErrMachineNotFound
 	}
 
-	ctrl.SetDesiredState(opts.DesiredState)
+	ctrl.SetDesiredState(ctx, opts.DesiredState)
 	return ctrl.GetState().Machine, nil
 }
@@ -0,0 +1,95 @@
+package machineservice
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// UpdateSpec changes the cpus, memory and ingress policy of a machine. A running machine is
+// resized in place while the others pick the new spec up on their next start, the ingress policy
+// is applied right away whenever the machine has a network interface.
+func (s *Service) UpdateSpec(ctx context.Context, opts types.MachineUpdateSpecOptions) (*types.Machine, error) {
+	ctrl, ok := s.machineControllers.Get(opts.MachineID)
+	if !ok {
+		return nil, types.ErrMachineNotFound
+	}
+
+	state := ctrl.GetState()
+	machine := state.Machine
+	ingress := machine.Spec.Ingress
+	if opts.ClearIngress {
+		ingress = nil
+	} else if opts.Ingress != nil {
+		ingress = opts.Ingress
+	}
+
+	if opts.Cpus == 0 || opts.MemoryMB == 0 {
+		return nil, fmt.Errorf("%w: cpus and memory must be positive", types.ErrInvalidMachineSpec)
+	} else if err := validateIngressPolicy(ingress); err != nil {
+		return nil, err
+	} else if state.Reconciliation != nil {
+		return nil, types.ErrMachineBusy
+	}
+
+	resize := opts.Cpus != machine.Spec.Cpus || opts.MemoryMB != machine.Spec.MemoryMB
+	ingressChanged := !reflect.DeepEqual(ingress, machine.Spec.Ingress)
+	if !resize && !ingressChanged {
+		return machine, nil
+	}
+
+	// the vm of a running machine can only be given memory within its hotplug size
+	running := typeutil.Includes([]coretypes.MachineState{
+		coretypes.MachineStateRunning,
+		coretypes.MachineStateDegraded,
+		coretypes.MachineStatePaused,
+	}, machine.State)
+	if running && opts.MemoryMB != machine.Spec.MemoryMB && s.config.MachineHotplugMemoryMB == 0 {
+		return nil, fmt.Errorf("%w: hotplug memory not configured, the memory of a running machine cannot change",
+			types.ErrInvalidMachineSpec)
+	}
+
+	if resize {
+		s.admissionLock.Lock()
+		defer s.admissionLock.Unlock()
+
+		err := s.admit(ctx, admissionRequest{
+			ExcludedMachineID: machine.ID,
+			Cpus:              opts.Cpus,
+			MemoryMB:          opts.MemoryMB,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.log.Info("updating machine spec", slog.String("machine-id", machine.ID),
+		slog.Uint64("cpus", uint64(opts.Cpus)), slog.Uint64("memory-mb", opts.MemoryMB),
+		slog.Bool("ingress-changed", ingressChanged))
+	if resize && running {
+		if err := s.runtimeService.Resize(ctx, machine.ID, opts.Cpus, opts.MemoryMB); err != nil {
+			return nil, err
+		}
+	}
+
+	if ingressChanged && machine.NetworkInterface != nil && machine.NetworkInterface.ReleasedAt == nil {
+		if err := s.networkProvider.UpdateIngressPolicy(ctx, machine.NetworkInterface, ingress); err != nil {
+			return nil, fmt.Errorf("failed to update ingress policy: %w", err)
+		}
+	}
+
+	spec := *machine.Spec
+	spec.Cpus = opts.Cpus
+	spec.MemoryMB = opts.MemoryMB
+	spec.Ingress = ingress
+	if err := ctrl.UpdateSpec(ctx, &spec); err != nil {
+		return nil, fmt.Errorf("failed to update machine spec: %w", err)
+	}
+
+	return ctrl.GetState().Machine, nil
+}
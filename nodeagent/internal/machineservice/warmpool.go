@@ -0,0 +1,668 @@
+package machineservice
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/nrednav/cuid2"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+)
+
+// warmPoolContainerID is the id of the container of golden vms, it is never started.
+const warmPoolContainerID = "warmpool"
+
+type warmPoolCounters struct {
+	hits   uint64
+	misses uint64
+}
+
+func (s *Service) startWarmPoolWorker(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if err := s.refillWarmPools(ctx); err != nil {
+			s.log.Error("failed to refill warm pools", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.warmPoolRefill:
+		}
+	}
+}
+
+func (s *Service) requestWarmPoolRefill() {
+	select {
+	case s.warmPoolRefill <- struct{}{}:
+	default:
+	}
+}
+
+// refillWarmPools builds the golden snapshot of every configured pool and tops up their idle
+// machines. Pools which are no longer configured, or built from outdated images, are drained.
+func (s *Service) refillWarmPools(ctx context.Context) error {
+	var pools []*types.WarmPool
+	err := s.db.WithContext(ctx).
+		Preload("Volumes", orderByPosition).
+		Preload("Volumes.Volume").
+		Preload("Entries.Volumes", orderByPosition).
+		Preload("Entries.Volumes.Volume").
+		Preload("Entries.NetworkInterface").
+		Find(&pools).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to list warm pools: %w", err)
+	}
+
+	// the images of a pool are nil when their details could not be fetched, its pools are kept
+	configImages := make([][]*types.Image, len(s.config.WarmPools))
+	for index, config := range s.config.WarmPools {
+		images, err := s.fetchWarmPoolImages(ctx, config)
+		if err != nil {
+			s.log.Error("failed to fetch warm pool images details", slog.Any("images", config.GetImages()), slog.Any("error", err))
+			continue
+		}
+		configImages[index] = images
+	}
+
+	for _, pool := range pools {
+		configured := false
+		for index, config := range s.config.WarmPools {
+			images := configImages[index]
+			if config.Matches(pool) && (images == nil || slices.Equal(getImageIDs(images), pool.GetImageIDs())) {
+				configured = true
+				break
+			}
+		}
+
+		if !configured {
+			if err := s.drainWarmPool(ctx, pool); err != nil {
+				s.log.Error("failed to drain warm pool", slog.String("warm-pool-id", pool.ID), slog.Any("error", err))
+			}
+		} else {
+			s.pruneWarmPoolEntries(ctx, pool)
+		}
+	}
+
+	for index, config := range s.config.WarmPools {
+		images := configImages[index]
+		if images == nil {
+			continue
+		}
+
+		var pool *types.WarmPool
+		for _, candidate := range pools {
+			if config.Matches(candidate) && slices.Equal(getImageIDs(images), candidate.GetImageIDs()) {
+				pool = candidate
+				break
+			}
+		}
+
+		log := s.log.With(slog.Any("images", config.GetImages()), slog.Uint64("cpus", uint64(config.Cpus)),
+			slog.Uint64("memory-mb", config.MemoryMB))
+		if pool == nil {
+			var err error
+			if pool, err = s.buildWarmPool(ctx, config, images); err != nil {
+				log.Error("failed to build warm pool", slog.Any("error", err))
+				continue
+			}
+		}
+
+		for count := s.countWarmPoolEntries(ctx, pool); count < config.Size; count++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			err := s.bootWarmPoolEntry(ctx, pool, images)
+			if errors.Is(err, types.ErrInsufficientCapacity) {
+				log.Debug("no capacity left for warm pool entries", slog.Any("error", err))
+				break
+			} else if err != nil {
+				log.Error("failed to boot warm pool entry", slog.Any("error", err))
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) fetchWarmPoolImages(ctx context.Context, config types.WarmPoolConfig) ([]*types.Image, error) {
+	images := make([]*types.Image, len(config.GetImages()))
+	for index, imageName := range config.GetImages() {
+		image, err := s.imageProvider.FetchDetails(ctx, types.ImageFetchOptions{Image: imageName})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch image details (%v): %w", imageName, err)
+		}
+		images[index] = image
+	}
+	return images, nil
+}
+
+// buildWarmPool boots a vm for the images and size without starting its containers, then
+// snapshots its memory and volumes. The idle machines of the pool are restored from it.
+func (s *Service) buildWarmPool(ctx context.Context, config types.WarmPoolConfig, images []*types.Image) (pool *types.WarmPool, err error) {
+	// the golden vm is not a machine, the garbage collector must not run while it exists
+	s.gcLock.Lock()
+	defer s.gcLock.Unlock()
+
+	pool = &types.WarmPool{
+		ID:       cuid2.Generate(),
+		Cpus:     config.Cpus,
+		MemoryMB: config.MemoryMB,
+		Volumes:  make([]*types.WarmPoolVolume, len(images)),
+	}
+	pool.SnapshotPath = path.Join(s.config.StorageDirectory, "warmpools", pool.ID)
+	log := s.log.With(slog.String("warm-pool-id", pool.ID), slog.Any("images", config.GetImages()))
+	log.Info("building warm pool")
+
+	templateVolumes := make([]*types.Volume, len(images))
+	defer func() {
+		for _, templateVolume := range templateVolumes {
+			if templateVolume != nil {
+				_ = s.volumeProvider.Release(context.Background(), templateVolume)
+			}
+		}
+	}()
+
+	for index, image := range images {
+		if err = s.imageProvider.Pull(ctx, image); err != nil {
+			return nil, fmt.Errorf("failed to pull image (%v): %w", config.GetImages()[index], err)
+		}
+
+		templateVolume := &types.Volume{
+			ID:       cuid2.Generate(),
+			Size:     machineVolumeSizeMB,
+			SourceID: &image.Volume.ID,
+			Source:   image.Volume,
+		}
+		if err = s.db.WithContext(ctx).Omit("Source").Create(&templateVolume).Error; err != nil {
+			return nil, fmt.Errorf("failed to create template volume: %w", err)
+		} else if err = s.volumeProvider.Allocate(ctx, templateVolume); err != nil {
+			return nil, fmt.Errorf("failed to allocate template volume: %w", err)
+		}
+
+		templateVolumes[index] = templateVolume
+		pool.Volumes[index] = &types.WarmPoolVolume{
+			ID:         cuid2.Generate(),
+			WarmPoolID: pool.ID,
+			Position:   index,
+			Image:      config.GetImages()[index],
+			ImageID:    image.ID,
+		}
+	}
+
+	// the vms restored from the pool are on the default network, a machine adopting one of them
+	// keeps its network interface
+	networkInterface, err := s.networkProvider.AllocateInterface(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate network interface: %w", err)
+	}
+	defer func() {
+		_ = s.networkProvider.ReleaseInterface(context.Background(), networkInterface)
+	}()
+
+	if err = s.networkProvider.SetupInterface(ctx, networkInterface, nil); err != nil {
+		return nil, fmt.Errorf("failed to set up network interface: %w", err)
+	}
+
+	goldenMachine := newWarmPoolMachine("warmpool-"+pool.ID, pool, images, templateVolumes, networkInterface)
+	err = s.runtimeService.Start(ctx, types.RuntimeStartOptions{
+		Machine:         goldenMachine,
+		DeferContainers: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start golden vm: %w", err)
+	}
+	defer func() {
+		_ = s.runtimeService.Terminate(context.Background(), goldenMachine.ID)
+		_ = os.RemoveAll(s.runtimeService.GetMachineDirectory(goldenMachine.ID))
+	}()
+
+	if err = s.waitForInit(ctx, goldenMachine.ID); err != nil {
+		return nil, err
+	} else if err = s.runtimeService.Pause(ctx, goldenMachine.ID); err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(pool.SnapshotPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(pool.SnapshotPath)
+			for _, poolVolume := range pool.Volumes {
+				if poolVolume.Volume != nil {
+					_ = s.volumeProvider.Release(context.Background(), poolVolume.Volume)
+				}
+			}
+		}
+	}()
+
+	if err = s.runtimeService.Snapshot(ctx, goldenMachine.ID, pool.SnapshotPath); err != nil {
+		return nil, err
+	}
+
+	for index, templateVolume := range templateVolumes {
+		goldenVolume, err := s.volumeProvider.Snapshot(ctx, templateVolume)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot template volume: %w", err)
+		}
+
+		pool.Volumes[index].VolumeID = goldenVolume.ID
+		pool.Volumes[index].Volume = goldenVolume
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Volumes", "Entries").Create(&pool).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Volume").Create(&pool.Volumes).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create warm pool: %w", err)
+	}
+
+	log.Info("warm pool built")
+	return pool, nil
+}
+
+// bootWarmPoolEntry restores a vm from the golden snapshot of the pool and pauses it once its
+// init is up. The entry is charged against the node capacity from its creation, it is only handed
+// out once ready.
+func (s *Service) bootWarmPoolEntry(ctx context.Context, pool *types.WarmPool, images []*types.Image) (err error) {
+	entry := &types.WarmPoolEntry{
+		ID:         cuid2.Generate(),
+		WarmPoolID: pool.ID,
+		WarmPool:   pool,
+	}
+	if err = s.createWarmPoolEntry(ctx, entry); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = s.destroyWarmPoolEntry(context.Background(), entry)
+		}
+	}()
+
+	if err = s.networkProvider.SetupInterface(ctx, entry.NetworkInterface, nil); err != nil {
+		return fmt.Errorf("failed to set up network interface: %w", err)
+	}
+
+	err = s.runtimeService.Start(ctx, types.RuntimeStartOptions{
+		Machine:         newWarmPoolMachine(entry.RuntimeID(), pool, images, entry.GetVolumes(), entry.NetworkInterface),
+		RestoreFrom:     &pool.SnapshotPath,
+		DeferContainers: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start vm: %w", err)
+	}
+
+	if err = s.waitForInit(ctx, entry.RuntimeID()); err != nil {
+		return err
+	} else if err = s.runtimeService.Pause(ctx, entry.RuntimeID()); err != nil {
+		return err
+	}
+
+	if err = s.db.WithContext(ctx).Model(&entry).Update("ready", true).Error; err != nil {
+		return fmt.Errorf("failed to mark warm pool entry as ready: %w", err)
+	}
+	return nil
+}
+
+// createWarmPoolEntry allocates the volumes and the network interface of an entry when the node
+// has the capacity for its vm.
+func (s *Service) createWarmPoolEntry(ctx context.Context, entry *types.WarmPoolEntry) (err error) {
+	s.admissionLock.Lock()
+	defer s.admissionLock.Unlock()
+
+	err = s.admit(ctx, admissionRequest{
+		Cpus:     entry.WarmPool.Cpus,
+		MemoryMB: entry.WarmPool.MemoryMB,
+		DiskMB:   uint64(len(entry.WarmPool.Volumes)) * machineVolumeSizeMB,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			for _, entryVolume := range entry.Volumes {
+				_ = s.volumeProvider.Release(context.Background(), entryVolume.Volume)
+			}
+		}
+	}()
+
+	for index, poolVolume := range entry.WarmPool.Volumes {
+		volume, err := s.volumeProvider.Snapshot(ctx, poolVolume.Volume)
+		if err != nil {
+			return fmt.Errorf("failed to create volume: %w", err)
+		}
+
+		entry.Volumes = append(entry.Volumes, &types.WarmPoolEntryVolume{
+			ID:              cuid2.Generate(),
+			WarmPoolEntryID: entry.ID,
+			Position:        index,
+			VolumeID:        volume.ID,
+			Volume:          volume,
+		})
+	}
+
+	networkInterface, err := s.networkProvider.AllocateInterface(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to allocate network interface: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = s.networkProvider.ReleaseInterface(context.Background(), networkInterface)
+		}
+	}()
+
+	entry.NetworkInterfaceID = networkInterface.ID
+	entry.NetworkInterface = networkInterface
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("WarmPool", "Volumes", "NetworkInterface").Create(&entry).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Volume").Create(&entry.Volumes).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create warm pool entry: %w", err)
+	}
+	return nil
+}
+
+// pruneWarmPoolEntries destroys the entries whose vm is gone, and the ones left booting by a
+// previous run since the entries are only booted by the warm pool worker.
+func (s *Service) pruneWarmPoolEntries(ctx context.Context, pool *types.WarmPool) {
+	for _, entry := range pool.Entries {
+		if entry.Ready && s.isWarmPoolEntryRunning(ctx, entry) {
+			continue
+		}
+
+		entry.WarmPool = pool
+		s.log.Warn("destroying stale warm pool entry", slog.String("warm-pool-id", pool.ID),
+			slog.String("warm-pool-entry-id", entry.ID), slog.Bool("ready", entry.Ready))
+		if err := s.destroyWarmPoolEntry(ctx, entry); err != nil {
+			s.log.Error("failed to destroy warm pool entry", slog.String("warm-pool-entry-id", entry.ID),
+				slog.Any("error", err))
+		}
+	}
+}
+
+func (s *Service) isWarmPoolEntryRunning(ctx context.Context, entry *types.WarmPoolEntry) bool {
+	client, closeClient := s.runtimeService.GetClient(entry.RuntimeID())
+	defer closeClient()
+
+	_, err := client.GetState(ctx, connect.NewRequest(&emptypb.Empty{}))
+	return err == nil
+}
+
+// destroyWarmPoolEntry deletes an entry and releases its vm, unless a machine took it first.
+func (s *Service) destroyWarmPoolEntry(ctx context.Context, entry *types.WarmPoolEntry) error {
+	claimed, err := s.claimWarmPoolEntry(ctx, entry)
+	if err != nil || !claimed {
+		return err
+	}
+
+	return s.releaseWarmPoolEntry(ctx, entry)
+}
+
+// claimWarmPoolEntry deletes an entry along with its volume rows, it returns false when the entry
+// was already claimed. Whoever claims the entry owns its vm, volumes and network interface.
+func (s *Service) claimWarmPoolEntry(ctx context.Context, entry *types.WarmPoolEntry) (claimed bool, err error) {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&types.WarmPoolEntry{}, "id = ?", entry.ID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		claimed = true
+		return tx.Delete(&types.WarmPoolEntryVolume{}, "warm_pool_entry_id = ?", entry.ID).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete warm pool entry: %w", err)
+	}
+	return claimed, nil
+}
+
+// releaseWarmPoolEntry terminates the vm of an entry and releases its volumes and network
+// interface.
+func (s *Service) releaseWarmPoolEntry(ctx context.Context, entry *types.WarmPoolEntry) error {
+	_ = s.runtimeService.Terminate(ctx, entry.RuntimeID())
+	if err := os.RemoveAll(s.runtimeService.GetMachineDirectory(entry.RuntimeID())); err != nil {
+		return fmt.Errorf("failed to remove runtime directory: %w", err)
+	} else if err = s.networkProvider.ReleaseInterface(ctx, entry.NetworkInterface); err != nil {
+		return fmt.Errorf("failed to release network interface (%v): %w", entry.NetworkInterfaceID, err)
+	}
+
+	for _, entryVolume := range entry.Volumes {
+		if err := s.volumeProvider.Release(ctx, entryVolume.Volume); err != nil {
+			return fmt.Errorf("failed to release volume (%v): %w", entryVolume.VolumeID, err)
+		}
+	}
+
+	return nil
+}
+
+// newWarmPoolMachine describes a vm of the pool to the runtime, it runs the images of the pool
+// without starting their containers.
+func newWarmPoolMachine(runtimeID string, pool *types.WarmPool, images []*types.Image, volumes []*types.Volume, networkInterface *types.NetworkInterface) *types.Machine {
+	machine := &types.Machine{
+		ID:               runtimeID,
+		Spec:             &types.MachineSpec{Cpus: pool.Cpus, MemoryMB: pool.MemoryMB},
+		NetworkInterface: networkInterface,
+	}
+	for index, poolVolume := range pool.Volumes {
+		containerID := fmt.Sprintf("%s-%d", warmPoolContainerID, index)
+		machine.Containers = append(machine.Containers, &types.Container{
+			ID:   containerID,
+			Spec: &types.ContainerSpec{Image: poolVolume.Image},
+		})
+		machine.Volumes = append(machine.Volumes, &types.MachineVolume{
+			Position:    index,
+			ContainerID: containerID,
+			Image:       images[index],
+			VolumeID:    volumes[index].ID,
+			Volume:      volumes[index],
+		})
+	}
+	return machine
+}
+
+func (s *Service) waitForInit(ctx context.Context, machineID string) error {
+	client, closeClient := s.runtimeService.GetAPIClient(machineID)
+	defer closeClient()
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	for {
+		_, err := client.GetInitStatus(ctx, connect.NewRequest(&nodeapi.RuntimeGetInitStatusRequest{}))
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for init: %w", errors.Join(ctx.Err(), err))
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+func (s *Service) drainWarmPool(ctx context.Context, pool *types.WarmPool) error {
+	s.warmPoolLock.Lock()
+	defer s.warmPoolLock.Unlock()
+
+	s.log.Info("draining warm pool", slog.String("warm-pool-id", pool.ID), slog.Any("images", pool.GetImages()))
+	for _, entry := range pool.Entries {
+		if err := s.destroyWarmPoolEntry(ctx, entry); err != nil {
+			return fmt.Errorf("failed to destroy warm pool entry (%v): %w", entry.ID, err)
+		}
+	}
+
+	for _, poolVolume := range pool.Volumes {
+		if err := s.volumeProvider.Release(ctx, poolVolume.Volume); err != nil {
+			return fmt.Errorf("failed to release volume (%v): %w", poolVolume.VolumeID, err)
+		}
+	}
+	if err := os.RemoveAll(pool.SnapshotPath); err != nil {
+		return fmt.Errorf("failed to remove snapshot directory: %w", err)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&types.WarmPoolVolume{}, "warm_pool_id = ?", pool.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&pool).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete warm pool: %w", err)
+	}
+	return nil
+}
+
+// takeWarmPoolEntry hands out a ready idle machine of the pool matching the images and the size,
+// it returns a nil entry on a miss. The entry is not charged anymore once taken, its vm is adopted
+// by the machine.
+func (s *Service) takeWarmPoolEntry(ctx context.Context, imageNames []string, images []*types.Image, spec *types.MachineSpec) (*types.WarmPoolEntry, error) {
+	var config *types.WarmPoolConfig
+	for _, candidate := range s.config.WarmPools {
+		if slices.Equal(candidate.GetImages(), imageNames) && candidate.Cpus == spec.Cpus && candidate.MemoryMB == spec.MemoryMB {
+			config = &candidate
+			break
+		}
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	s.warmPoolLock.Lock()
+	defer s.warmPoolLock.Unlock()
+	defer s.requestWarmPoolRefill()
+
+	var pools []*types.WarmPool
+	err := s.db.WithContext(ctx).
+		Preload("Volumes", orderByPosition).
+		Where("cpus = ? AND memory_mb = ?", spec.Cpus, spec.MemoryMB).
+		Find(&pools).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list warm pools: %w", err)
+	}
+
+	var poolIDs []string
+	for _, pool := range pools {
+		if slices.Equal(pool.GetImageIDs(), getImageIDs(images)) {
+			poolIDs = append(poolIDs, pool.ID)
+		}
+	}
+
+	counters := s.getWarmPoolCounters(*config)
+	var entry *types.WarmPoolEntry
+	err = s.db.WithContext(ctx).
+		Preload("Volumes", orderByPosition).
+		Preload("Volumes.Volume").
+		Preload("NetworkInterface").
+		Where("ready = ? AND warm_pool_id IN ?", true, poolIDs).
+		Order("created_at").
+		First(&entry).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		counters.misses++
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find warm pool entry: %w", err)
+	}
+
+	// the entry is claimed by deleting it, the warm pool worker may be destroying it
+	claimed, err := s.claimWarmPoolEntry(ctx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take warm pool entry: %w", err)
+	} else if !claimed {
+		counters.misses++
+		return nil, nil
+	}
+
+	counters.hits++
+	return entry, nil
+}
+
+func (s *Service) countWarmPoolEntries(ctx context.Context, pool *types.WarmPool) int {
+	var count int64
+	_ = s.db.WithContext(ctx).Model(&types.WarmPoolEntry{}).Where("warm_pool_id = ?", pool.ID).Count(&count).Error
+	return int(count)
+}
+
+func (s *Service) getWarmPoolCounters(config types.WarmPoolConfig) *warmPoolCounters {
+	key := fmt.Sprintf("%s/%d/%d", strings.Join(config.GetImages(), ","), config.Cpus, config.MemoryMB)
+	counters, ok := s.warmPoolCounters[key]
+	if !ok {
+		counters = &warmPoolCounters{}
+		s.warmPoolCounters[key] = counters
+	}
+	return counters
+}
+
+func (s *Service) GetWarmPoolStats(ctx context.Context) ([]types.WarmPoolStats, error) {
+	var pools []*types.WarmPool
+	err := s.db.WithContext(ctx).
+		Preload("Volumes", orderByPosition).
+		Preload("Entries", "ready = ?", true).
+		Find(&pools).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list warm pools: %w", err)
+	}
+
+	s.warmPoolLock.Lock()
+	defer s.warmPoolLock.Unlock()
+
+	stats := make([]types.WarmPoolStats, len(s.config.WarmPools))
+	for index, config := range s.config.WarmPools {
+		counters := s.getWarmPoolCounters(config)
+		stats[index] = types.WarmPoolStats{
+			Images:   config.GetImages(),
+			Cpus:     config.Cpus,
+			MemoryMB: config.MemoryMB,
+			Size:     config.Size,
+			Hits:     counters.hits,
+			Misses:   counters.misses,
+		}
+		for _, pool := range pools {
+			if config.Matches(pool) {
+				stats[index].Available += len(pool.Entries)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+func getImageIDs(images []*types.Image) []string {
+	imageIDs := make([]string, len(images))
+	for index, image := range images {
+		imageIDs[index] = image.ID
+	}
+	return imageIDs
+}
+
+// orderByPosition preloads the volumes of warm pools and entries in the order of the containers.
+func orderByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
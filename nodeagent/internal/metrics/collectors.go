@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var machineStates = []coretypes.MachineState{
+	coretypes.MachineStatePending,
+	coretypes.MachineStateStarting,
+	coretypes.MachineStateRunning,
+	coretypes.MachineStateDegraded,
+	coretypes.MachineStatePaused,
+	coretypes.MachineStateError,
+	coretypes.MachineStateStopping,
+	coretypes.MachineStateStopped,
+	coretypes.MachineStateTerminating,
+	coretypes.MachineStateTerminated,
+}
+
+// machineCollector counts the machines by state when the metrics are scraped, every state is
+// reported so that a state without machines reads 0 instead of disappearing.
+type machineCollector struct {
+	machineService types.MachineService
+	machines       *prometheus.Desc
+}
+
+func newMachineCollector(machineService types.MachineService) *machineCollector {
+	return &machineCollector{
+		machineService: machineService,
+		machines: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "machines"),
+			"Machines by state.", []string{"state"}, nil),
+	}
+}
+
+func (c *machineCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.machines
+}
+
+func (c *machineCollector) Collect(metrics chan<- prometheus.Metric) {
+	machines, err := c.machineService.List(context.Background())
+	if err != nil {
+		metrics <- prometheus.NewInvalidMetric(c.machines, err)
+		return
+	}
+
+	counts := make(map[coretypes.MachineState]int, len(machineStates))
+	for _, machine := range machines {
+		counts[machine.State]++
+	}
+	for _, state := range machineStates {
+		metrics <- prometheus.MustNewConstMetric(c.machines, prometheus.GaugeValue, float64(counts[state]), string(state))
+	}
+}
+
+type networkCollector struct {
+	networkProvider types.NetworkProvider
+	addresses       *prometheus.Desc
+}
+
+func newNetworkCollector(networkProvider types.NetworkProvider) *networkCollector {
+	return &networkCollector{
+		networkProvider: networkProvider,
+		addresses: prometheus.NewDesc(prometheus.BuildFQName(namespace, "network", "addresses"),
+			"Addresses of the machine network by status.", []string{"status"}, nil),
+	}
+}
+
+func (c *networkCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.addresses
+}
+
+func (c *networkCollector) Collect(metrics chan<- prometheus.Metric) {
+	stats := c.networkProvider.GetPoolStats()
+	metrics <- prometheus.MustNewConstMetric(c.addresses, prometheus.GaugeValue, float64(stats.Allocated), "allocated")
+	metrics <- prometheus.MustNewConstMetric(c.addresses, prometheus.GaugeValue, float64(stats.Free), "free")
+}
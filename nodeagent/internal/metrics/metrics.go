@@ -0,0 +1,117 @@
+// Package metrics holds the prometheus collectors of the node agent. They are registered on a
+// dedicated registry, exposed by the metrics server when a metrics address is configured.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "baepo_node"
+
+var (
+	Registry = prometheus.NewRegistry()
+
+	ReconciliationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconciliation_duration_seconds",
+		Help:      "Duration of the machine reconciliations by desired state and result.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"desired_state", "result"})
+
+	ImagePullDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "image_pull_duration_seconds",
+		Help:      "Duration of the image pulls by result.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"result"})
+
+	ImagePullBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "image_pull_bytes_total",
+		Help:      "Compressed bytes of the image layers downloaded.",
+	})
+
+	VolumeCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "volume_command_duration_seconds",
+		Help:      "Duration of the commands run by the volume provider, as lvcreate and lvremove.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"command"})
+
+	VolumeCommandFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "volume_command_failures_total",
+		Help:      "Failed commands run by the volume provider, as lvcreate and lvremove.",
+	}, []string{"command"})
+
+	RegistrationConnected = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "registration_connected",
+		Help:      "Whether the node is registered and connected to the control plane.",
+	})
+
+	RegistrationReconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registration_reconnects_total",
+		Help:      "Connections opened to the control plane after the first one.",
+	})
+
+	GatewayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gateway_requests_total",
+		Help:      "Requests proxied by the gateway by machine and status code.",
+	}, []string{"machine_id", "code"})
+
+	GatewayRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "gateway_request_duration_seconds",
+		Help:      "Duration of the requests proxied by the gateway by machine.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"machine_id"})
+
+	DNSQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dns_queries_total",
+		Help:      "Queries of the machines answered by the resolver of the node by answer source, local, cache, upstream or error.",
+	}, []string{"source"})
+
+	NetworkDrifts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "network_drifts_total",
+		Help:      "Differences between the tap interfaces or the firewall rules and the allocated interfaces by kind and repair result.",
+	}, []string{"kind", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ReconciliationDuration,
+		ImagePullDuration,
+		ImagePullBytes,
+		VolumeCommandDuration,
+		VolumeCommandFailures,
+		RegistrationConnected,
+		RegistrationReconnects,
+		GatewayRequests,
+		GatewayRequestDuration,
+		DNSQueries,
+		NetworkDrifts,
+	)
+}
+
+// DeleteMachine drops the series of a terminated machine, the machine ids would otherwise pile up
+// in the collectors for the lifetime of the agent.
+func DeleteMachine(machineID string) {
+	GatewayRequests.DeletePartialMatch(prometheus.Labels{"machine_id": machineID})
+	GatewayRequestDuration.DeleteLabelValues(machineID)
+}
+
+// Result returns the result label of an operation.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
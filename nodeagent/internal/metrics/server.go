@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type Server struct {
+	config     *types.Config
+	httpServer *http.Server
+}
+
+func New(config *types.Config, machineService types.MachineService, networkProvider types.NetworkProvider) *Server {
+	Registry.MustRegister(newMachineCollector(machineService), newNetworkCollector(networkProvider))
+	return &Server{config: config}
+}
+
+// Start serves the metrics when a metrics address is configured, the metrics are still
+// collected otherwise.
+func (s *Server) Start(ctx context.Context) error {
+	if s.config.MetricsAddr == "" {
+		return nil
+	}
+
+	slog.Info("starting metrics server", slog.String("addr", s.config.MetricsAddr))
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry}))
+	s.httpServer = &http.Server{Addr: s.config.MetricsAddr, Handler: mux}
+
+	lis, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to setup listener for metrics server: %w", err)
+	}
+
+	go s.httpServer.Serve(lis)
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+
+	slog.Info("shutting down metrics server")
+	return s.httpServer.Shutdown(ctx)
+}
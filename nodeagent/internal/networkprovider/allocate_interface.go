@@ -10,25 +10,37 @@ import (
 	"time"
 )
 
-func (p *Provider) AllocateInterface(ctx context.Context) (*types.NetworkInterface, error) {
+func (p *Provider) AllocateInterface(ctx context.Context, network string) (*types.NetworkInterface, error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	index := p.findAvailableOffset()
+	n, err := p.findNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+
+	index := n.findAvailableOffset()
 	if index == -1 {
-		return nil, fmt.Errorf("no available ip addresses in network %s", p.networkCIDR)
+		return nil, fmt.Errorf("no available ip addresses in network %s", n.cidr)
 	}
 
 	networkInterface := &types.NetworkInterface{
 		ID:             cuid2.Generate(),
-		Name:           fmt.Sprintf("tap%d", index),
-		GatewayAddress: p.gatewayAddr,
-		NetworkCIDR:    typeutil.Ptr(types.GormNetIPNet(*p.networkCIDR)),
-		IPAddress:      p.calculateIPFromOffset(index),
+		Name:           n.interfaceName(index),
+		GatewayAddress: n.gatewayAddr,
+		NetworkCIDR:    typeutil.Ptr(types.GormNetIPNet(*n.cidr)),
+		Network:        n.name,
+		IPAddress:      n.calculateIPFromOffset(index),
 		AllocatedAt:    typeutil.Ptr(time.Now()),
 	}
+	if n == p.defaultNetwork && p.ipv6Prefix != nil {
+		networkInterface.IPv6Address = p.calculateIPv6FromOffset(index)
+		networkInterface.IPv6GatewayAddress = p.ipv6GatewayAddr
+		networkInterface.IPv6Prefix = typeutil.Ptr(types.GormNetIPNet(*p.ipv6Prefix))
+	}
 
-	macAddress := fmt.Sprintf("52:54:00:%02x:%02x:%02x", (index>>16)&0xFF, (index>>8)&0xFF, index&0xFF)
+	// the third byte is the id of the network, which keeps the addresses of the networks apart
+	macAddress := fmt.Sprintf("52:54:%02x:%02x:%02x:%02x", n.id, (index>>16)&0xFF, (index>>8)&0xFF, index&0xFF)
 	hwAddress, err := net.ParseMAC(macAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse mac address: %w", err)
@@ -39,13 +51,13 @@ func (p *Provider) AllocateInterface(ctx context.Context) (*types.NetworkInterfa
 		return nil, fmt.Errorf("failed to create network interface in database: %w", err)
 	}
 
-	p.allocatedIPs[index] = networkInterface.Name
+	n.allocatedIPs[index] = networkInterface.Name
 	return networkInterface, nil
 }
 
-func (p *Provider) findAvailableOffset() int {
-	for index := 0; index < len(p.allocatedIPs); index++ {
-		if p.allocatedIPs[index] == "" {
+func (n *network) findAvailableOffset() int {
+	for index := 0; index < n.size; index++ {
+		if _, ok := n.allocatedIPs[index]; !ok {
 			return index
 		}
 	}
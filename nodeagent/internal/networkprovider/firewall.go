@@ -0,0 +1,44 @@
+package networkprovider
+
+import (
+	"context"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// firewall keeps the machines to their own mac and ip addresses, and away from the blocked
+// networks and the machines of the other networks of the node. It also translates the traffic of
+// the machines to the uplink address and enforces the ingress policy of each interface, and
+// forwards the published ports of the node address to the machines.
+type firewall interface {
+	// Setup applies the rules of the bridges, interfaces and ports are the allocated interfaces
+	// and the published ports loaded from the database.
+	Setup(ctx context.Context, interfaces []*types.NetworkInterface, ports []*types.PublishedPort) error
+
+	AddInterface(ctx context.Context, networkInterface *types.NetworkInterface) error
+
+	RemoveInterface(ctx context.Context, networkInterface *types.NetworkInterface) error
+
+	// UpdateIngressPolicy applies the ingress policy of an interface already added.
+	UpdateIngressPolicy(ctx context.Context, networkInterface *types.NetworkInterface) error
+
+	IngressStats(ctx context.Context, networkInterface *types.NetworkInterface) (*types.NetworkIngressStats, error)
+
+	// PublishPorts forwards the ports to their machine, the ports already forwarded are left as
+	// is.
+	PublishPorts(ctx context.Context, ports []*types.PublishedPort) error
+
+	UnpublishPorts(ctx context.Context, ports []*types.PublishedPort) error
+
+	// Reconcile compares the rules of the bridges, of the interfaces and of the ports with the
+	// allocated interfaces and the published ports, it applies the missing rules and removes
+	// the rules of the interfaces and the ports which are not allocated. An error is returned
+	// when the rules can not be listed.
+	Reconcile(ctx context.Context, interfaces []*types.NetworkInterface, ports []*types.PublishedPort) ([]types.NetworkDrift, error)
+}
+
+func newFirewall(p *Provider, backend types.FirewallBackend) firewall {
+	if backend == types.FirewallBackendNftables {
+		return newNftablesFirewall(p)
+	}
+	return &iptablesFirewall{p: p}
+}
@@ -0,0 +1,12 @@
+package networkprovider
+
+import "net"
+
+// GetGatewayAddresses returns the gateway address of every network, the default network first.
+func (p *Provider) GetGatewayAddresses() []net.IP {
+	var addresses []net.IP
+	for _, n := range p.networks() {
+		addresses = append(addresses, n.gatewayAddr)
+	}
+	return addresses
+}
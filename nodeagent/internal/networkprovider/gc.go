@@ -0,0 +1,74 @@
+package networkprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/vishvananda/netlink"
+)
+
+func (p *Provider) GC(ctx context.Context, opts types.GCOptions) ([]types.GCResource, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// the networks by index of their bridge
+	networks := map[int]*network{}
+	for _, n := range p.networks() {
+		bridge, err := netlink.LinkByName(n.bridgeInterface)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find bridge %s: %w", n.bridgeInterface, err)
+		}
+		networks[bridge.Attrs().Index] = n
+	}
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	var interfaceNames []string
+	err = p.db.WithContext(ctx).Model(&types.NetworkInterface{}).Where("released_at IS NULL").Pluck("name", &interfaceNames).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	knownInterfaces := map[string]bool{}
+	for _, name := range interfaceNames {
+		knownInterfaces[name] = true
+	}
+
+	var resources []types.GCResource
+	for _, link := range links {
+		attrs := link.Attrs()
+		n, onBridge := networks[attrs.MasterIndex]
+		if link.Type() != "tuntap" || !strings.HasPrefix(attrs.Name, "tap") || !onBridge || knownInterfaces[attrs.Name] {
+			continue
+		}
+
+		resource := types.GCResource{Kind: types.GCResourceKindNetworkInterface, Name: attrs.Name}
+		if !opts.DryRun {
+			resource.Error = p.deleteOrphanLink(ctx, n, link)
+		}
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+func (p *Provider) deleteOrphanLink(ctx context.Context, n *network, link netlink.Link) error {
+	// the firewall rules are keyed on the mac and ip addresses, both can be derived
+	// from the link hardware address since allocation is deterministic
+	if index := n.calculateIndexFromHwAddr(link.Attrs().HardwareAddr); index != -1 {
+		networkInterface := p.derivedInterface(n, index)
+		networkInterface.Name = link.Attrs().Name
+		_ = p.firewall.RemoveInterface(ctx, networkInterface)
+	}
+
+	if err := netlink.LinkDel(link); err != nil {
+		return fmt.Errorf("failed to delete interface %s: %w", link.Attrs().Name, err)
+	}
+
+	return nil
+}
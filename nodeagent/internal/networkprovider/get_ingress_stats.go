@@ -0,0 +1,12 @@
+package networkprovider
+
+import (
+	"context"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+// GetIngressStats returns empty stats when the interface has no ingress policy, the counters are
+// reset whenever the policy is applied.
+func (p *Provider) GetIngressStats(ctx context.Context, networkInterface *types.NetworkInterface) (*types.NetworkIngressStats, error) {
+	return p.firewall.IngressStats(ctx, networkInterface)
+}
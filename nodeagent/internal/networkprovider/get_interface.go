@@ -1,6 +1,7 @@
 package networkprovider
 
 import (
+	"fmt"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	"github.com/vishvananda/netlink"
 )
@@ -11,9 +12,23 @@ func (p *Provider) GetInterface(name string) (*types.NetworkInterface, error) {
 		return nil, err
 	}
 
-	return &types.NetworkInterface{
-		Name:       name,
-		IPAddress:  p.calculateIPFromOffset(p.calculateIndexFromHwAddr(link.Attrs().HardwareAddr)),
-		MacAddress: link.Attrs().HardwareAddr,
-	}, nil
+	// the id of the network is part of the mac address
+	for _, n := range p.networks() {
+		index := n.calculateIndexFromHwAddr(link.Attrs().HardwareAddr)
+		if index == -1 {
+			continue
+		}
+
+		networkInterface := &types.NetworkInterface{
+			Name:       name,
+			IPAddress:  n.calculateIPFromOffset(index),
+			MacAddress: link.Attrs().HardwareAddr,
+			Network:    n.name,
+		}
+		if n == p.defaultNetwork && p.ipv6Prefix != nil {
+			networkInterface.IPv6Address = p.calculateIPv6FromOffset(index)
+		}
+		return networkInterface, nil
+	}
+	return nil, fmt.Errorf("%w: %s is in no network", types.ErrNetworkInterfaceNotFound, name)
 }
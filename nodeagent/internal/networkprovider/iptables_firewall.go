@@ -0,0 +1,321 @@
+package networkprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// iptablesFirewall is the legacy firewall, every rule is checked and applied by running
+// iptables, ip6tables, ebtables or arptables.
+type iptablesFirewall struct {
+	p *Provider
+}
+
+var _ firewall = (*iptablesFirewall)(nil)
+
+// Setup only applies the rules of the bridges, the rules of an interface and of the ports of its
+// machine are applied when the machine starts. The tables of the nftables backend are removed as
+// they are left over when the node switched backends.
+func (f *iptablesFirewall) Setup(ctx context.Context, _ []*types.NetworkInterface, _ []*types.PublishedPort) error {
+	for _, rule := range f.bridgeRules() {
+		if err := f.applyRule(ctx, rule); err != nil {
+			return fmt.Errorf("failed to apply %s rule: %w", rule.description, err)
+		}
+	}
+
+	if err := newNftablesFirewall(f.p).removeTables(); err != nil {
+		f.p.log.Warn("failed to remove nftables tables", slog.Any("error", err))
+	}
+	return nil
+}
+
+// AddInterface removes the rules left over by a previous setup of the interface first, so that
+// setting up an interface again does not duplicate its rules.
+func (f *iptablesFirewall) AddInterface(ctx context.Context, networkInterface *types.NetworkInterface) error {
+	_ = f.applyTapFirewallRules(ctx, networkInterface, true)
+	if err := f.applyTapFirewallRules(ctx, networkInterface, false); err != nil {
+		return err
+	}
+	return f.UpdateIngressPolicy(ctx, networkInterface)
+}
+
+func (f *iptablesFirewall) RemoveInterface(ctx context.Context, networkInterface *types.NetworkInterface) error {
+	f.removeIngressRules(ctx, networkInterface)
+	return f.applyTapFirewallRules(ctx, networkInterface, true)
+}
+
+// removeRules deletes the rules of the firewall, they are left over when the node switched to the
+// nftables backend. The rules of the interfaces and the ports are only looked for when rules of
+// the bridges are found.
+func (f *iptablesFirewall) removeRules(ctx context.Context, interfaces []*types.NetworkInterface, ports []*types.PublishedPort) {
+	found := false
+	for _, rule := range f.bridgeRules() {
+		// the duplicates of a rule are deleted as well
+		for f.p.runCmd(ctx, rule.command(), append([]string{"-t", rule.table, "-D", rule.chain}, rule.spec...)...) == nil {
+			found = true
+		}
+	}
+	if !found {
+		return
+	}
+
+	f.p.log.Info("removing iptables rules left over by the previous firewall backend")
+	for _, networkInterface := range interfaces {
+		_ = f.RemoveInterface(ctx, networkInterface)
+	}
+	_ = f.UnpublishPorts(ctx, ports)
+}
+
+// bridgeRules are the rules of the bridges and of the isolation of the networks, applied in
+// order.
+func (f *iptablesFirewall) bridgeRules() []iptablesRule {
+	var rules []iptablesRule
+	for _, n := range f.p.networks() {
+		rules = append(rules, f.bridgeFirewallRules(n)...)
+	}
+	rules = append(rules, f.networkIsolationRules()...)
+	if f.p.ipv6Prefix != nil {
+		rules = append(rules, f.bridgeIPv6FirewallRules()...)
+	}
+	return rules
+}
+
+func (f *iptablesFirewall) bridgeFirewallRules(n *network) []iptablesRule {
+	var rules []iptablesRule
+	// NAT for external access
+	for _, gateway := range n.gateways() {
+		network := &net.IPNet{IP: gateway.IP.Mask(gateway.Mask), Mask: gateway.Mask}
+		rules = append(rules,
+			iptablesRule{description: "NAT", table: "nat", chain: "POSTROUTING", spec: []string{
+				"-s", network.String(),
+				"-o", f.p.uplinkInterface,
+				"-j", "MASQUERADE"}},
+			// a machine reaching a published port of another one gets the replies through the node
+			iptablesRule{description: "published ports NAT", table: "nat", chain: "POSTROUTING", spec: []string{
+				"-s", network.String(),
+				"-o", n.bridgeInterface,
+				"-m", "conntrack",
+				"--ctstate", "DNAT",
+				"-j", "MASQUERADE"}},
+		)
+	}
+
+	rules = append(rules,
+		// Allow forwarding for VM traffic going to external networks
+		iptablesRule{description: "VM outbound traffic", table: "filter", chain: "FORWARD", spec: []string{
+			"-i", n.bridgeInterface,
+			"-o", f.p.uplinkInterface,
+			"-j", "ACCEPT"}},
+		// Allow established connections
+		iptablesRule{description: "established connections", table: "filter", chain: "FORWARD", spec: []string{
+			"-i", f.p.uplinkInterface,
+			"-o", n.bridgeInterface,
+			"-m", "state",
+			"--state", "RELATED,ESTABLISHED",
+			"-j", "ACCEPT"}},
+	)
+
+	// Block VM access to local networks, the rules must come before the accept rule above
+	for _, network := range f.p.blockedNetworks {
+		if network.IP.To4() == nil {
+			continue
+		}
+
+		rules = append(rules, iptablesRule{description: "local network " + network.String() + " blocking",
+			table: "filter", chain: "FORWARD", insert: true, spec: []string{
+				"-i", n.bridgeInterface,
+				"-o", f.p.uplinkInterface,
+				"-d", network.String(),
+				"-j", "DROP"}})
+	}
+
+	return rules
+}
+
+// networkIsolationRules keep the machines of a network from reaching the ones of another network
+// through the node, the published ports stay reachable from every network. The rules are in the
+// mangle table, which comes before the filter table, so that no accept of the filter table can
+// bypass them.
+func (f *iptablesFirewall) networkIsolationRules() []iptablesRule {
+	var rules []iptablesRule
+	for _, from := range f.p.networks() {
+		for _, to := range f.p.networks() {
+			if from == to {
+				continue
+			}
+
+			rules = append(rules, iptablesRule{
+				description: fmt.Sprintf("isolation of network %s from %s", from.bridgeInterface, to.bridgeInterface),
+				table:       "mangle",
+				chain:       "FORWARD",
+				spec: []string{
+					"-i", from.bridgeInterface,
+					"-o", to.bridgeInterface,
+					"-m", "conntrack",
+					"!", "--ctstate", "DNAT",
+					"-j", "DROP"},
+			})
+		}
+	}
+	return rules
+}
+
+func (f *iptablesFirewall) bridgeIPv6FirewallRules() []iptablesRule {
+	var rules []iptablesRule
+	// a unique local prefix is not routed to the node, it is translated to the uplink address
+	if f.p.ipv6Prefix.IP.IsPrivate() {
+		rules = append(rules, iptablesRule{description: "ipv6 NAT", ipv6: true, table: "nat", chain: "POSTROUTING", spec: []string{
+			"-s", f.p.ipv6Prefix.String(),
+			"-o", f.p.uplinkInterface,
+			"-j", "MASQUERADE"}})
+	}
+
+	rules = append(rules,
+		iptablesRule{description: "VM ipv6 outbound traffic", ipv6: true, table: "filter", chain: "FORWARD", spec: []string{
+			"-i", f.p.defaultNetwork.bridgeInterface,
+			"-o", f.p.uplinkInterface,
+			"-j", "ACCEPT"}},
+		iptablesRule{description: "ipv6 established connections", ipv6: true, table: "filter", chain: "FORWARD", spec: []string{
+			"-i", f.p.uplinkInterface,
+			"-o", f.p.defaultNetwork.bridgeInterface,
+			"-m", "state",
+			"--state", "RELATED,ESTABLISHED",
+			"-j", "ACCEPT"}},
+	)
+
+	for _, network := range f.p.blockedNetworks {
+		if network.IP.To4() != nil {
+			continue
+		}
+
+		rules = append(rules, iptablesRule{description: "local network " + network.String() + " blocking",
+			ipv6: true, table: "filter", chain: "FORWARD", insert: true, spec: []string{
+				"-i", f.p.defaultNetwork.bridgeInterface,
+				"-o", f.p.uplinkInterface,
+				"-d", network.String(),
+				"-j", "DROP"}})
+	}
+
+	return rules
+}
+
+// applyRule appends the rule to its chain, or inserts it at the top, unless it is already there.
+func (f *iptablesFirewall) applyRule(ctx context.Context, rule iptablesRule) error {
+	if f.hasRule(ctx, rule) {
+		return nil
+	}
+
+	addArgs := append([]string{"-t", rule.table, "-A", rule.chain}, rule.spec...)
+	if rule.insert {
+		addArgs = append([]string{"-t", rule.table, "-I", rule.chain, "1"}, rule.spec...)
+	}
+	return f.p.runCmd(ctx, rule.command(), addArgs...)
+}
+
+func (f *iptablesFirewall) hasRule(ctx context.Context, rule iptablesRule) bool {
+	return f.p.runCmd(ctx, rule.command(), append([]string{"-t", rule.table, "-C", rule.chain}, rule.spec...)...) == nil
+}
+
+// applyTapFirewallRules appends or deletes the rules of the interface. A deletion goes through
+// every rule even when some fail, so that an interface set up halfway is removed entirely.
+func (f *iptablesFirewall) applyTapFirewallRules(ctx context.Context, networkInterface *types.NetworkInterface, shouldRemove bool) error {
+	operation := "-A" // append
+	if shouldRemove {
+		operation = "-D" // delete
+	}
+
+	_ = f.p.runCmd(ctx, "arptables", "-N", "FORWARD")
+	var errs []error
+	for _, rule := range iptablesTapRules(networkInterface) {
+		if err := f.p.runCmd(ctx, rule.command, append([]string{operation, "FORWARD"}, rule.spec...)...); err != nil {
+			errs = append(errs, fmt.Errorf("failed to apply %s rule: %w", rule.description, err))
+			if !shouldRemove {
+				return errs[0]
+			}
+		}
+	}
+
+	if networkInterface.IPv6Address != nil {
+		if err := f.applyTapIPv6FirewallRules(ctx, networkInterface, shouldRemove); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// iptablesTapRule is a rule of the FORWARD chain of the filter table of a command.
+type iptablesTapRule struct {
+	command     string
+	description string
+	spec        []string
+}
+
+// iptablesTapRules are the rules keeping the interface to the mac and ip addresses of its machine.
+func iptablesTapRules(networkInterface *types.NetworkInterface) []iptablesTapRule {
+	macAddress := strings.ToLower(networkInterface.MacAddress.String())
+	rules := []iptablesTapRule{
+		{command: "ebtables", description: "mac filtering", spec: []string{
+			"-i", networkInterface.Name,
+			"-s", "!", macAddress,
+			"-j", "DROP"}},
+		{command: "iptables", description: "ip filtering", spec: []string{
+			"-i", networkInterface.Name,
+			"!", "-s", networkInterface.IPAddress.String(),
+			"-j", "DROP"}},
+		{command: "arptables", description: "arp filtering", spec: []string{
+			"-i", networkInterface.Name,
+			"!", "--source-mac", macAddress,
+			"-j", "DROP"}},
+	}
+	if networkInterface.IPv6Address != nil {
+		rules = append(rules, iptablesTapRule{command: "ip6tables", description: "ipv6 filtering", spec: []string{
+			"-i", networkInterface.Name,
+			"!", "-s", networkInterface.IPv6Address.String(),
+			"-j", "DROP"}})
+	}
+	return rules
+}
+
+// applyTapIPv6FirewallRules filters the neighbor advertisements, the ipv6 counterpart of arp
+// replies. They must come from the address of the machine or a link-local one, which takes a
+// chain of its own since ebtables can not negate two addresses in a single rule.
+func (f *iptablesFirewall) applyTapIPv6FirewallRules(ctx context.Context, networkInterface *types.NetworkInterface, shouldRemove bool) error {
+	chain := "ND-" + networkInterface.Name
+	jumpRule := []string{"FORWARD",
+		"-i", networkInterface.Name,
+		"-p", "IPv6",
+		"--ip6-proto", "ipv6-icmp",
+		"--ip6-icmp-type", "neighbour-advertisement",
+		"-j", chain}
+	if shouldRemove {
+		err := f.p.runCmd(ctx, "ebtables", append([]string{"-D"}, jumpRule...)...)
+		_ = f.p.runCmd(ctx, "ebtables", "-F", chain)
+		_ = f.p.runCmd(ctx, "ebtables", "-X", chain)
+		if err != nil {
+			return fmt.Errorf("failed to apply neighbor advertisement filtering rule: %w", err)
+		}
+		return nil
+	}
+
+	// the chain is left over when the interface was not released cleanly
+	_ = f.p.runCmd(ctx, "ebtables", "-N", chain, "-P", "DROP")
+	_ = f.p.runCmd(ctx, "ebtables", "-F", chain)
+	var err error
+	for _, source := range []string{networkInterface.IPv6Address.String(), "fe80::/10"} {
+		err = f.p.runCmd(ctx, "ebtables", "-A", chain, "-p", "IPv6", "--ip6-src", source, "-j", "RETURN")
+		if err != nil {
+			return fmt.Errorf("failed to apply neighbor advertisement filtering rule: %w", err)
+		}
+	}
+	if err = f.p.runCmd(ctx, "ebtables", append([]string{"-A"}, jumpRule...)...); err != nil {
+		return fmt.Errorf("failed to apply neighbor advertisement filtering rule: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package networkprovider
+
+import (
+	"context"
+	"fmt"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const iptablesIngressUnmatchedComment = "ingress-unmatched"
+
+// UpdateIngressPolicy rewrites the ingress chain of the interface, which resets its counters.
+//
+// The ingress chain is jumped to from the FORWARD and OUTPUT chains for the traffic sent to the
+// address of the machine through the bridge. The traffic a machine sends to another one of the
+// bridge is only filtered when br_netfilter passes the bridged traffic to iptables.
+func (f *iptablesFirewall) UpdateIngressPolicy(ctx context.Context, networkInterface *types.NetworkInterface) error {
+	if networkInterface.IngressPolicy == nil {
+		f.removeIngressRules(ctx, networkInterface)
+		return nil
+	}
+
+	chain := iptablesIngressChain(networkInterface)
+	bridge := f.p.interfaceNetwork(networkInterface).bridgeInterface
+	for command, address := range iptablesIngressAddresses(networkInterface) {
+		// the chain is left over when the interface was not released cleanly
+		_ = f.p.runCmd(ctx, command, "-N", chain)
+		if err := f.p.runCmd(ctx, command, "-F", chain); err != nil {
+			return fmt.Errorf("failed to flush ingress chain: %w", err)
+		}
+
+		for _, rule := range iptablesIngressRules(networkInterface.IngressPolicy, command == "ip6tables") {
+			if err := f.p.runCmd(ctx, command, append([]string{"-A", chain}, rule...)...); err != nil {
+				return fmt.Errorf("failed to apply ingress rule: %w", err)
+			}
+		}
+
+		for _, hook := range []string{"FORWARD", "OUTPUT"} {
+			err := f.applyRule(ctx, iptablesRule{ipv6: command == "ip6tables", insert: true, table: "filter", chain: hook,
+				spec: []string{"-o", bridge, "-d", address, "-j", chain}})
+			if err != nil {
+				return fmt.Errorf("failed to jump to ingress chain: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *iptablesFirewall) IngressStats(ctx context.Context, networkInterface *types.NetworkInterface) (*types.NetworkIngressStats, error) {
+	stats := &types.NetworkIngressStats{}
+	if networkInterface.IngressPolicy == nil {
+		return stats, nil
+	}
+
+	stats.DefaultDeny = networkInterface.IngressPolicy.DefaultDeny
+	stats.Rules = make([]types.NetworkIngressCounter, len(networkInterface.IngressPolicy.Rules))
+	for command := range iptablesIngressAddresses(networkInterface) {
+		output, err := f.p.runCmdOutput(ctx, command, "-t", "filter", "-L", iptablesIngressChain(networkInterface), "-v", "-x", "-n")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ingress rules: %w", err)
+		}
+
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.Fields(line)
+			start, end := strings.Index(line, "/* "), strings.Index(line, " */")
+			if len(fields) < 2 || start == -1 || end < start {
+				continue
+			}
+			packets, err := strconv.ParseUint(fields[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			bytes, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+
+			var counter *types.NetworkIngressCounter
+			comment := line[start+3 : end]
+			if comment == iptablesIngressUnmatchedComment {
+				counter = &stats.Unmatched
+			} else if index, err := strconv.Atoi(strings.TrimPrefix(comment, "ingress-rule-")); err == nil && index >= 0 && index < len(stats.Rules) {
+				counter = &stats.Rules[index]
+			} else {
+				continue
+			}
+			counter.Packets += packets
+			counter.Bytes += bytes
+		}
+	}
+	return stats, nil
+}
+
+func (f *iptablesFirewall) removeIngressRules(ctx context.Context, networkInterface *types.NetworkInterface) {
+	chain := iptablesIngressChain(networkInterface)
+	bridge := f.p.interfaceNetwork(networkInterface).bridgeInterface
+	for command, address := range iptablesIngressAddresses(networkInterface) {
+		for _, hook := range []string{"FORWARD", "OUTPUT"} {
+			_ = f.p.runCmd(ctx, command, "-D", hook, "-o", bridge, "-d", address, "-j", chain)
+		}
+		_ = f.p.runCmd(ctx, command, "-F", chain)
+		_ = f.p.runCmd(ctx, command, "-X", chain)
+	}
+}
+
+func iptablesIngressChain(networkInterface *types.NetworkInterface) string {
+	return "ING-" + networkInterface.Name
+}
+
+// iptablesIngressAddresses returns the address of the machine by command filtering its family.
+func iptablesIngressAddresses(networkInterface *types.NetworkInterface) map[string]string {
+	addresses := map[string]string{"iptables": networkInterface.IPAddress.String()}
+	if networkInterface.IPv6Address != nil {
+		addresses["ip6tables"] = networkInterface.IPv6Address.String()
+	}
+	return addresses
+}
+
+// iptablesIngressRules builds the rules of an ingress chain for one family, each rule of the
+// policy is commented with its index to find its counters. Source networks of the other family
+// are left out, along with the rules which only have such networks.
+func iptablesIngressRules(policy *types.NetworkIngressPolicy, ipv6 bool) [][]string {
+	rules := [][]string{{"-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"}}
+	if ipv6 {
+		for _, icmpType := range []string{"router-advertisement", "neighbour-solicitation", "neighbour-advertisement"} {
+			rules = append(rules, []string{"-p", "ipv6-icmp", "--icmpv6-type", icmpType, "-j", "ACCEPT"})
+		}
+	}
+
+	for index, policyRule := range policy.Rules {
+		var protocol []string
+		switch policyRule.Protocol {
+		case coretypes.MachineIngressProtocolICMP:
+			if ipv6 {
+				protocol = []string{"-p", "ipv6-icmp"}
+			} else {
+				protocol = []string{"-p", "icmp"}
+			}
+		case coretypes.MachineIngressProtocolTCP, coretypes.MachineIngressProtocolUDP:
+			protocol = []string{"-p", string(policyRule.Protocol)}
+			if policyRule.FromPort != 0 || policyRule.ToPort != 0 {
+				protocol = append(protocol, "--dport", fmt.Sprintf("%d:%d", policyRule.FromPort, policyRule.ToPort))
+			}
+		}
+		protocol = append(protocol, "-m", "comment", "--comment", fmt.Sprintf("ingress-rule-%d", index), "-j", "ACCEPT")
+
+		if len(policyRule.SourceCIDRs) == 0 {
+			rules = append(rules, protocol)
+			continue
+		}
+		for _, cidr := range policyRule.SourceCIDRs {
+			_, source, err := net.ParseCIDR(cidr)
+			if err != nil || (source.IP.To4() == nil) != ipv6 {
+				continue
+			}
+			rules = append(rules, append([]string{"-s", source.String()}, protocol...))
+		}
+	}
+
+	target := "RETURN"
+	if policy.DefaultDeny {
+		target = "DROP"
+	}
+	return append(rules, []string{"-m", "comment", "--comment", iptablesIngressUnmatchedComment, "-j", target})
+}
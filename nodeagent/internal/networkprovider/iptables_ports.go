@@ -0,0 +1,75 @@
+package networkprovider
+
+import (
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"net"
+	"strconv"
+)
+
+// iptablesRule is a rule of a chain of iptables, or of ip6tables for an ipv6 rule. An inserted
+// rule goes at the top of its chain.
+type iptablesRule struct {
+	description string
+	ipv6        bool
+	insert      bool
+	table       string
+	chain       string
+	spec        []string
+}
+
+func (r iptablesRule) command() string {
+	if r.ipv6 {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+// PublishPorts translates the destination in PREROUTING for the traffic coming from the network
+// and in OUTPUT for the traffic of the node itself. The translated connections are accepted at
+// the end of FORWARD, after the jumps to the ingress chains which still filter them.
+func (f *iptablesFirewall) PublishPorts(ctx context.Context, ports []*types.PublishedPort) error {
+	for _, port := range ports {
+		for _, rule := range f.publishedPortRules(port) {
+			if err := f.applyRule(ctx, rule); err != nil {
+				return fmt.Errorf("failed to publish port %s/%d: %w", port.Protocol, port.HostPort, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *iptablesFirewall) UnpublishPorts(ctx context.Context, ports []*types.PublishedPort) error {
+	for _, port := range ports {
+		for _, rule := range f.publishedPortRules(port) {
+			// the rule is missing when the port was never forwarded
+			_ = f.p.runCmd(ctx, "iptables", append([]string{"-t", rule.table, "-D", rule.chain}, rule.spec...)...)
+		}
+	}
+	return nil
+}
+
+func (f *iptablesFirewall) publishedPortRules(port *types.PublishedPort) []iptablesRule {
+	destination := net.JoinHostPort(port.GuestAddress.String(), strconv.Itoa(int(port.ContainerPort)))
+	dnat := []string{
+		"-d", f.p.hostAddr.String(),
+		"-p", string(port.Protocol),
+		"--dport", strconv.Itoa(int(port.HostPort)),
+		"-j", "DNAT",
+		"--to-destination", destination,
+	}
+	return []iptablesRule{
+		{table: "nat", chain: "PREROUTING", spec: dnat},
+		{table: "nat", chain: "OUTPUT", spec: dnat},
+		{table: "filter", chain: "FORWARD", spec: []string{
+			"-o", f.p.addressNetwork(port.GuestAddress).bridgeInterface,
+			"-d", port.GuestAddress.String(),
+			"-p", string(port.Protocol),
+			"--dport", strconv.Itoa(int(port.ContainerPort)),
+			"-m", "conntrack",
+			"--ctstate", "DNAT",
+			"-j", "ACCEPT",
+		}},
+	}
+}
@@ -0,0 +1,198 @@
+package networkprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"slices"
+	"strings"
+)
+
+// Reconcile counts the rules of the FORWARD chains matching each tap interface, an allocated
+// interface with fewer rules than expected gets all its rules applied again. The listings are
+// only searched for the interface names, their format differs between the commands and their
+// legacy and nft variants. The rules of the bridges and of the published ports are then checked
+// one by one.
+func (f *iptablesFirewall) Reconcile(ctx context.Context, interfaces []*types.NetworkInterface, ports []*types.PublishedPort) ([]types.NetworkDrift, error) {
+	commands := []string{"ebtables", "iptables", "arptables"}
+	if f.p.ipv6Prefix != nil {
+		commands = append(commands, "ip6tables")
+	}
+
+	// the number of rules by command and interface name, and the ingress chains by command
+	rules := map[string]map[string]int{}
+	ingressChains := map[string]map[string]bool{}
+	for _, command := range commands {
+		listing, err := f.listForwardRules(ctx, command)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s rules: %w", command, err)
+		}
+
+		rules[command], ingressChains[command] = map[string]int{}, map[string]bool{}
+		for _, line := range strings.Split(listing, "\n") {
+			fields := strings.Fields(line)
+			for index := 0; index+1 < len(fields); index++ {
+				if fields[index] == "-i" && strings.HasPrefix(fields[index+1], "tap") {
+					rules[command][fields[index+1]]++
+				} else if fields[index] == "-j" && strings.HasPrefix(fields[index+1], "ING-") {
+					ingressChains[command][strings.TrimPrefix(fields[index+1], "ING-")] = true
+				}
+			}
+		}
+	}
+
+	var drifts []types.NetworkDrift
+	allocated := map[string]bool{}
+	for _, networkInterface := range interfaces {
+		allocated[networkInterface.Name] = true
+		if !f.hasInterfaceRules(networkInterface, rules, ingressChains) {
+			drift := types.NetworkDrift{Kind: types.NetworkDriftKindMissingRules, Interface: networkInterface.Name}
+			drift.Error = f.AddInterface(ctx, networkInterface)
+			drifts = append(drifts, drift)
+		}
+	}
+
+	orphans := map[string]bool{}
+	for _, command := range commands {
+		for name := range rules[command] {
+			orphans[name] = !allocated[name]
+		}
+		for name := range ingressChains[command] {
+			orphans[name] = !allocated[name]
+		}
+	}
+	for name, orphan := range orphans {
+		if !orphan {
+			continue
+		}
+
+		drift := types.NetworkDrift{Kind: types.NetworkDriftKindOrphanRules, Interface: name}
+		if n, index := f.p.findInterfaceIndex(name); n == nil {
+			drift.Error = errors.New("interface belongs to no configured network, its rules can not be derived")
+		} else {
+			drift.Error = f.RemoveInterface(ctx, f.p.derivedInterface(n, index))
+		}
+		drifts = append(drifts, drift)
+	}
+
+	drifts = append(drifts, f.reconcileBridgeRules(ctx)...)
+	portDrifts, err := f.reconcilePorts(ctx, interfaces, ports)
+	return append(drifts, portDrifts...), err
+}
+
+// reconcileBridgeRules applies the missing rules of the bridges, of the address translation and of
+// the isolation of the networks.
+func (f *iptablesFirewall) reconcileBridgeRules(ctx context.Context) []types.NetworkDrift {
+	var drifts []types.NetworkDrift
+	for _, rule := range f.bridgeRules() {
+		if !f.hasRule(ctx, rule) {
+			drifts = append(drifts, types.NetworkDrift{Kind: types.NetworkDriftKindBridgeRules, Error: f.applyRule(ctx, rule)})
+		}
+	}
+	return drifts
+}
+
+// reconcilePorts applies the missing rules of the published ports, then removes the translations
+// and the accept rules of the ports which are not published. The rules are found in the listings
+// of iptables -S by their options.
+func (f *iptablesFirewall) reconcilePorts(ctx context.Context, interfaces []*types.NetworkInterface, ports []*types.PublishedPort) ([]types.NetworkDrift, error) {
+	if f.p.hostAddr == nil {
+		return nil, nil
+	}
+
+	interfaceNames := map[string]string{}
+	for _, networkInterface := range interfaces {
+		interfaceNames[networkInterface.ID] = networkInterface.Name
+	}
+
+	var drifts []types.NetworkDrift
+	hostPorts, guestPorts := map[string]bool{}, map[string]bool{}
+	for _, port := range ports {
+		hostPorts[fmt.Sprintf("%s/%d", port.Protocol, port.HostPort)] = true
+		guestPorts[fmt.Sprintf("%s/%s/%d", port.GuestAddress, port.Protocol, port.ContainerPort)] = true
+		if slices.ContainsFunc(f.publishedPortRules(port), func(rule iptablesRule) bool { return !f.hasRule(ctx, rule) }) {
+			drifts = append(drifts, types.NetworkDrift{
+				Kind:      types.NetworkDriftKindMissingPortRules,
+				Interface: interfaceNames[port.NetworkInterfaceID],
+				Error:     f.PublishPorts(ctx, []*types.PublishedPort{port}),
+			})
+		}
+	}
+
+	hostAddr := f.p.hostAddr.String() + "/32"
+	for _, chain := range []struct{ table, name string }{{"nat", "PREROUTING"}, {"nat", "OUTPUT"}, {"filter", "FORWARD"}} {
+		listing, err := f.p.runCmdOutput(ctx, "iptables", "-t", chain.table, "-S", chain.name)
+		if err != nil {
+			return drifts, fmt.Errorf("failed to list %s %s rules: %w", chain.table, chain.name, err)
+		}
+
+		for _, line := range strings.Split(listing, "\n") {
+			fields := strings.Fields(line)
+			options := ruleOptions(fields)
+			var orphan bool
+			if options["-j"] == "DNAT" && options["-d"] == hostAddr {
+				orphan = !hostPorts[options["-p"]+"/"+options["--dport"]]
+			} else if options["-j"] == "ACCEPT" && options["--ctstate"] == "DNAT" {
+				orphan = !guestPorts[strings.TrimSuffix(options["-d"], "/32")+"/"+options["-p"]+"/"+options["--dport"]]
+			}
+			if !orphan {
+				continue
+			}
+
+			drifts = append(drifts, types.NetworkDrift{
+				Kind:  types.NetworkDriftKindOrphanPortRules,
+				Error: f.p.runCmd(ctx, "iptables", append([]string{"-t", chain.table, "-D"}, fields[1:]...)...),
+			})
+		}
+	}
+	return drifts, nil
+}
+
+// ruleOptions maps each option of a rule listed by iptables -S to its value, the last value of
+// an option given twice is kept.
+func ruleOptions(fields []string) map[string]string {
+	options := map[string]string{}
+	for index := 0; index+1 < len(fields); index++ {
+		if strings.HasPrefix(fields[index], "-") {
+			options[fields[index]] = fields[index+1]
+		}
+	}
+	return options
+}
+
+// hasInterfaceRules checks that every rule of the interface is listed, the neighbor advertisement
+// jump of ebtables included.
+func (f *iptablesFirewall) hasInterfaceRules(networkInterface *types.NetworkInterface, rules map[string]map[string]int, ingressChains map[string]map[string]bool) bool {
+	expected := map[string]int{}
+	for _, rule := range iptablesTapRules(networkInterface) {
+		expected[rule.command]++
+	}
+	if networkInterface.IPv6Address != nil {
+		expected["ebtables"]++
+	}
+	for command, count := range expected {
+		if commandRules, listed := rules[command]; listed && commandRules[networkInterface.Name] < count {
+			return false
+		}
+	}
+
+	if networkInterface.IngressPolicy != nil {
+		for command := range iptablesIngressAddresses(networkInterface) {
+			if commandChains, listed := ingressChains[command]; listed && !commandChains[networkInterface.Name] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// listForwardRules lists the rules of the FORWARD chain of the filter table.
+func (f *iptablesFirewall) listForwardRules(ctx context.Context, command string) (string, error) {
+	if command == "iptables" || command == "ip6tables" {
+		return f.p.runCmdOutput(ctx, command, "-t", "filter", "-S", "FORWARD")
+	} else if command == "arptables" {
+		_ = f.p.runCmd(ctx, command, "-N", "FORWARD")
+	}
+	return f.p.runCmdOutput(ctx, command, "-L", "FORWARD")
+}
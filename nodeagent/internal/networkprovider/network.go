@@ -0,0 +1,198 @@
+package networkprovider
+
+import (
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"net"
+	"slices"
+)
+
+// network is a bridge with the addresses of its machines. The default network has no name and
+// the id 0, it is the only one with an ipv6 prefix. The id of a network is the third byte of the
+// mac addresses of its machines.
+type network struct {
+	name            string
+	id              uint8
+	bridgeInterface string
+	cidr            *net.IPNet
+	gatewayAddr     net.IP
+	// previousNetworks are the networks of the interfaces allocated before a change of the
+	// network cidr, their gateways stay on the bridge until the interfaces are released.
+	previousNetworks []*net.IPNet
+	// allocatedIPs are the claimed offsets, ip address = offset + base address, value = tap
+	// interface name. A map keeps large networks from costing memory for their free addresses.
+	allocatedIPs map[int]string
+	// size is the number of addresses of the network.
+	size int
+}
+
+func newNetwork(name string, id uint8, cidr, bridgeInterface string) (*network, error) {
+	_, networkCIDR, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network CIDR: %w", err)
+	}
+
+	n := &network{
+		name:            name,
+		id:              id,
+		bridgeInterface: bridgeInterface,
+		cidr:            networkCIDR,
+	}
+	n.gatewayAddr = n.calculateIPFromOffset(1)
+
+	ones, bits := n.cidr.Mask.Size()
+	n.size = 1 << (bits - ones)
+	n.allocatedIPs = map[int]string{
+		n.calculateOffsetFromIP(n.cidr.IP):     "network",       // claim network address
+		n.calculateOffsetFromIP(n.gatewayAddr): bridgeInterface, // claim gateway address
+		n.size - 1:                             "broadcast",     // claim broadcast address
+	}
+	return n, nil
+}
+
+// networks are the default network followed by the private ones.
+func (p *Provider) networks() []*network {
+	return append([]*network{p.defaultNetwork}, p.privateNetworks...)
+}
+
+func (p *Provider) findNetwork(name string) (*network, error) {
+	for _, n := range p.networks() {
+		if n.name == name {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", types.ErrNetworkNotFound, name)
+}
+
+// interfaceNetwork returns the network of the interface, the default network stands in for a
+// network which is no longer configured so that the rules of the interface can still be removed.
+func (p *Provider) interfaceNetwork(networkInterface *types.NetworkInterface) *network {
+	if n, err := p.findNetwork(networkInterface.Network); err == nil {
+		return n
+	}
+	return p.defaultNetwork
+}
+
+// addressNetwork returns the network the address belongs to, a previous network of its bridge
+// included.
+func (p *Provider) addressNetwork(ip net.IP) *network {
+	for _, n := range p.networks() {
+		if slices.ContainsFunc(n.gateways(), func(gateway *net.IPNet) bool { return gateway.Contains(ip) }) {
+			return n
+		}
+	}
+	return p.defaultNetwork
+}
+
+// gateways are the addresses of the bridge, the gateway of the current network comes first.
+func (n *network) gateways() []*net.IPNet {
+	return append([]*net.IPNet{{IP: n.gatewayAddr, Mask: n.cidr.Mask}}, n.previousNetworks...)
+}
+
+// interfaceName keeps the names of the tap interfaces of the private networks apart from the
+// ones of the default network.
+func (n *network) interfaceName(index int) string {
+	if n.id == 0 {
+		return fmt.Sprintf("tap%d", index)
+	}
+	return fmt.Sprintf("tap%d-%d", n.id, index)
+}
+
+// findInterfaceIndex returns the network and the index of a tap interface name, nil when the
+// name is not one of a configured network.
+func (p *Provider) findInterfaceIndex(name string) (*network, int) {
+	for _, n := range p.networks() {
+		var index int
+		if n.id == 0 {
+			if _, err := fmt.Sscanf(name, "tap%d", &index); err != nil {
+				continue
+			}
+		} else {
+			var id uint8
+			if _, err := fmt.Sscanf(name, "tap%d-%d", &id, &index); err != nil || id != n.id {
+				continue
+			}
+		}
+		if index >= 0 && index < n.size && n.interfaceName(index) == name {
+			return n, index
+		}
+	}
+	return nil, -1
+}
+
+// derivedInterface returns the interface allocated at the index, its addresses are derived from
+// the index since the allocation is deterministic. It is used to remove the firewall rules of an
+// interface which is no longer in the database.
+func (p *Provider) derivedInterface(n *network, index int) *types.NetworkInterface {
+	networkInterface := &types.NetworkInterface{
+		Name:       n.interfaceName(index),
+		IPAddress:  n.calculateIPFromOffset(index),
+		MacAddress: net.HardwareAddr{0x52, 0x54, n.id, byte(index >> 16), byte(index >> 8), byte(index)},
+		Network:    n.name,
+	}
+	if n == p.defaultNetwork && p.ipv6Prefix != nil {
+		networkInterface.IPv6Address = p.calculateIPv6FromOffset(index)
+	}
+	return networkInterface
+}
+
+// releaseAllocatedInterface frees every offset claimed by the interface.
+func (n *network) releaseAllocatedInterface(name string) {
+	for index, allocatedName := range n.allocatedIPs {
+		if allocatedName == name {
+			delete(n.allocatedIPs, index)
+		}
+	}
+}
+
+func (n *network) calculateOffsetFromIP(ip net.IP) int {
+	ipCopy := make(net.IP, len(ip))
+	copy(ipCopy, ip)
+
+	baseIPCopy := make(net.IP, len(n.cidr.IP))
+	copy(baseIPCopy, n.cidr.IP)
+
+	if ipCopy.To4() != nil {
+		ipCopy = ipCopy.To4()
+	}
+
+	if baseIPCopy.To4() != nil {
+		baseIPCopy = baseIPCopy.To4()
+	}
+
+	offset := 0
+	for i := 0; i < len(ipCopy); i++ {
+		offset = (offset << 8) | int(ipCopy[i]-baseIPCopy[i])
+	}
+	return offset
+}
+
+func (n *network) calculateIPFromOffset(offset int) net.IP {
+	ip := make(net.IP, len(n.cidr.IP))
+	copy(ip, n.cidr.IP)
+
+	if ip.To4() != nil {
+		ip = ip.To4()
+	}
+
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i] += byte(offset & 0xFF)
+		offset >>= 8
+
+		if offset == 0 {
+			break
+		}
+	}
+	return ip
+}
+
+func (n *network) calculateIndexFromHwAddr(hwAddr net.HardwareAddr) int {
+	if len(hwAddr) == 6 && hwAddr[0] == 0x52 && hwAddr[1] == 0x54 && hwAddr[2] == n.id {
+		index := (int(hwAddr[3]) << 16) | (int(hwAddr[4]) << 8) | int(hwAddr[5])
+		if index >= 0 && index < n.size {
+			return index
+		}
+	}
+
+	return -1
+}
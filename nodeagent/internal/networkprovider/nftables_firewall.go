@@ -0,0 +1,486 @@
+package networkprovider
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/nftables"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"golang.org/x/sys/unix"
+	"net"
+	"sync"
+)
+
+const nftablesTableName = "baepo"
+
+// Priority of the filter chains of the bridge family, the one of ebtables.
+const nftablesBridgeFilterPriority = -200
+
+// nftablesFirewall keeps its rules in two tables named baepo. The bridge table drops what a tap
+// interface sends with a mac or ip address which is not the one of its machine, the inet table
+// blocks the local networks and the traffic between the networks of the node, and translates the
+// addresses of the machines.
+//
+// The rules are the same for every interface, they lookup the interface name and the source
+// address in sets, so adding or removing an interface only rewrites the elements of the sets.
+// The ingress policy of an interface is the exception, it gets a chain of its own found through
+// the ingress verdict map.
+// The inet table accepts the traffic of the machines, which can still be dropped by the rules of
+// another table since each table is evaluated on its own. It also translates the published ports
+// of the node address with the ports map.
+type nftablesFirewall struct {
+	p          *Provider
+	lock       sync.Mutex
+	interfaces map[string]*types.NetworkInterface
+
+	bridgeTable   *nftables.Table
+	inetTable     *nftables.Table
+	interfacesSet *nftables.Set
+	macSet        *nftables.Set
+	ipv4Set       *nftables.Set
+	ipv6Set       *nftables.Set
+	ingressMap    *nftables.Set
+	// ingressChains are the names of the interfaces with an ingress chain in the bridge table.
+	ingressChains map[string]bool
+	// publishedPorts are the elements of the ports map by id.
+	publishedPorts map[string]*types.PublishedPort
+	portsMap       *nftables.Set
+}
+
+var _ firewall = (*nftablesFirewall)(nil)
+
+func newNftablesFirewall(p *Provider) *nftablesFirewall {
+	bridgeTable := &nftables.Table{Family: nftables.FamilyBridge, Name: nftablesTableName}
+	inetTable := &nftables.Table{Family: nftables.FamilyINet, Name: nftablesTableName}
+	return &nftablesFirewall{
+		p:           p,
+		interfaces:  map[string]*types.NetworkInterface{},
+		bridgeTable: bridgeTable,
+		inetTable:   inetTable,
+		interfacesSet: &nftables.Set{
+			Table:   bridgeTable,
+			Name:    "interfaces",
+			KeyType: nftables.TypeIfName,
+			KeyLen:  unix.IFNAMSIZ,
+		},
+		macSet: &nftables.Set{
+			Table:   bridgeTable,
+			Name:    "interface_mac",
+			KeyType: nftables.ConcatType(nftables.TypeIfName, nftables.TypeEtherAddr),
+			KeyLen:  uint32(len(nftables.Concat(nftables.IfName(""), make([]byte, 6)))),
+		},
+		ipv4Set: &nftables.Set{
+			Table:   bridgeTable,
+			Name:    "interface_ipv4",
+			KeyType: nftables.ConcatType(nftables.TypeIfName, nftables.TypeIPAddr),
+			KeyLen:  unix.IFNAMSIZ + net.IPv4len,
+		},
+		ipv6Set: &nftables.Set{
+			Table:   bridgeTable,
+			Name:    "interface_ipv6",
+			KeyType: nftables.ConcatType(nftables.TypeIfName, nftables.TypeIP6Addr),
+			KeyLen:  unix.IFNAMSIZ + net.IPv6len,
+		},
+		ingressMap: &nftables.Set{
+			Table:      bridgeTable,
+			Name:       "ingress",
+			KeyType:    nftables.TypeIfName,
+			KeyLen:     unix.IFNAMSIZ,
+			VerdictMap: true,
+		},
+		ingressChains:  map[string]bool{},
+		publishedPorts: map[string]*types.PublishedPort{},
+		portsMap: &nftables.Set{
+			Table:    inetTable,
+			Name:     "published_ports",
+			KeyType:  nftables.ConcatType(nftables.TypeInetProto, nftables.TypeInetServ),
+			KeyLen:   uint32(len(publishedPortKey(0, 0))),
+			DataType: nftables.ConcatType(nftables.TypeIPAddr, nftables.TypeInetServ),
+			DataLen:  uint32(len(nftables.Concat(make([]byte, net.IPv4len), make([]byte, 2)))),
+		},
+	}
+}
+
+// Setup replaces both tables in a single transaction, whatever drifted from the database or was
+// left over by a previous configuration is dropped. The rules of the iptables backend are removed
+// as they are left over when the node switched backends.
+func (f *nftablesFirewall) Setup(ctx context.Context, interfaces []*types.NetworkInterface, ports []*types.PublishedPort) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	(&iptablesFirewall{p: f.p}).removeRules(ctx, interfaces, ports)
+
+	f.interfaces = map[string]*types.NetworkInterface{}
+	for _, networkInterface := range interfaces {
+		f.interfaces[networkInterface.Name] = networkInterface
+	}
+	f.publishedPorts = map[string]*types.PublishedPort{}
+	for _, port := range ports {
+		f.publishedPorts[port.ID] = port
+	}
+
+	batch := &nftables.Batch{}
+	for _, table := range []*nftables.Table{f.bridgeTable, f.inetTable} {
+		// the table is created first so that the deletion can not fail
+		batch.AddTable(table)
+		batch.DelTable(table)
+		batch.AddTable(table)
+	}
+	f.addBridgeTable(batch)
+	f.addInetTable(batch)
+	ingressChains := map[string]bool{}
+	for name, networkInterface := range f.interfaces {
+		if networkInterface.IngressPolicy == nil {
+			continue
+		}
+		if err := f.addIngressChain(batch, networkInterface); err != nil {
+			return err
+		}
+		ingressChains[name] = true
+	}
+	f.addInterfaceElements(batch)
+	f.addPortElements(batch)
+
+	if err := nftables.Apply(batch); err != nil {
+		return fmt.Errorf("failed to apply nftables ruleset: %w", err)
+	}
+	f.ingressChains = ingressChains
+	return nil
+}
+
+// removeTables deletes both tables, the ones which do not exist included.
+func (f *nftablesFirewall) removeTables() error {
+	batch := &nftables.Batch{}
+	for _, table := range []*nftables.Table{f.bridgeTable, f.inetTable} {
+		// the table is created first so that the deletion can not fail
+		batch.AddTable(table)
+		batch.DelTable(table)
+	}
+	return nftables.Apply(batch)
+}
+
+func (f *nftablesFirewall) AddInterface(_ context.Context, networkInterface *types.NetworkInterface) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	previous, exists := f.interfaces[networkInterface.Name]
+	f.interfaces[networkInterface.Name] = networkInterface
+	if err := f.syncInterface(networkInterface.Name); err != nil {
+		if exists {
+			f.interfaces[networkInterface.Name] = previous
+		} else {
+			delete(f.interfaces, networkInterface.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+func (f *nftablesFirewall) RemoveInterface(_ context.Context, networkInterface *types.NetworkInterface) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	previous, exists := f.interfaces[networkInterface.Name]
+	if !exists {
+		return nil
+	}
+
+	delete(f.interfaces, networkInterface.Name)
+	if err := f.syncInterface(networkInterface.Name); err != nil {
+		f.interfaces[networkInterface.Name] = previous
+		return err
+	}
+	return nil
+}
+
+// syncInterface rewrites the elements of the sets and the ingress chain of the named interface,
+// a failed update leaves the previous elements and rules in place. The ingress chains of the
+// other interfaces are kept, along with their counters.
+func (f *nftablesFirewall) syncInterface(name string) error {
+	batch := &nftables.Batch{}
+	for _, set := range f.interfaceSets() {
+		batch.FlushSet(set)
+	}
+
+	networkInterface, exists := f.interfaces[name]
+	hasIngressChain := exists && networkInterface.IngressPolicy != nil
+	if hasIngressChain {
+		if err := f.addIngressChain(batch, networkInterface); err != nil {
+			return err
+		}
+	}
+	f.addInterfaceElements(batch)
+	if !hasIngressChain && f.ingressChains[name] {
+		// the chain is no longer referenced once the ingress map is flushed
+		chain := f.ingressChain(name)
+		batch.FlushChain(chain)
+		batch.DelChain(chain)
+	}
+
+	if err := nftables.Apply(batch); err != nil {
+		return fmt.Errorf("failed to update nftables interface sets: %w", err)
+	}
+	if hasIngressChain {
+		f.ingressChains[name] = true
+	} else {
+		delete(f.ingressChains, name)
+	}
+	return nil
+}
+
+func (f *nftablesFirewall) addInterfaceElements(batch *nftables.Batch) {
+	elements := f.interfaceElements(f.interfaces)
+	for _, set := range f.interfaceSets() {
+		batch.AddElements(set, elements[set])
+	}
+}
+
+// interfaceSets are the sets and the map holding the elements of the interfaces.
+func (f *nftablesFirewall) interfaceSets() []*nftables.Set {
+	return []*nftables.Set{f.interfacesSet, f.macSet, f.ipv4Set, f.ipv6Set, f.ingressMap}
+}
+
+// interfaceElements returns the elements of the interfaces by set.
+func (f *nftablesFirewall) interfaceElements(interfaces map[string]*types.NetworkInterface) map[*nftables.Set][]nftables.Element {
+	elements := map[*nftables.Set][]nftables.Element{}
+	for name, networkInterface := range interfaces {
+		elements[f.interfacesSet] = append(elements[f.interfacesSet], nftables.Element{Key: nftables.IfName(name)})
+		elements[f.macSet] = append(elements[f.macSet], nftables.Element{
+			Key: nftables.Concat(nftables.IfName(name), networkInterface.MacAddress),
+		})
+		if ip := networkInterface.IPAddress.To4(); ip != nil {
+			elements[f.ipv4Set] = append(elements[f.ipv4Set], nftables.Element{Key: nftables.Concat(nftables.IfName(name), ip)})
+		}
+		if ip := networkInterface.IPv6Address.To16(); ip != nil {
+			elements[f.ipv6Set] = append(elements[f.ipv6Set], nftables.Element{Key: nftables.Concat(nftables.IfName(name), ip)})
+		}
+		if networkInterface.IngressPolicy != nil {
+			elements[f.ingressMap] = append(elements[f.ingressMap], nftables.Element{
+				Key:   nftables.IfName(name),
+				Chain: f.ingressChain(name).Name,
+			})
+		}
+	}
+	return elements
+}
+
+// addBridgeTable filters the frames a tap interface sends to another port of the bridge or to
+// the bridge itself, which is where the routed traffic goes. It also filters the frames sent to a
+// tap interface with its ingress chain, the frames of the bridge itself go through the output
+// hook.
+func (f *nftablesFirewall) addBridgeTable(batch *nftables.Batch) {
+	for _, set := range f.interfaceSets() {
+		batch.AddSet(set)
+	}
+
+	antispoof := &nftables.Chain{Table: f.bridgeTable, Name: "antispoof"}
+	batch.AddChain(antispoof)
+	for _, hook := range []struct {
+		name string
+		num  uint32
+	}{{"forward", nftables.HookForward}, {"input", nftables.HookInput}} {
+		chain := &nftables.Chain{
+			Table: f.bridgeTable,
+			Name:  hook.name,
+			Hook:  &nftables.Hook{Type: nftables.ChainTypeFilter, Num: hook.num, Priority: nftablesBridgeFilterPriority},
+		}
+		batch.AddChain(chain)
+		batch.AddRule(&nftables.Rule{Chain: chain, Exprs: []nftables.Expr{
+			nftables.Verdict(nftables.VerdictJump, antispoof.Name),
+		}})
+		if hook.num == nftables.HookForward {
+			f.addIngressRule(batch, chain)
+		}
+	}
+
+	output := &nftables.Chain{
+		Table: f.bridgeTable,
+		Name:  "output",
+		Hook:  &nftables.Hook{Type: nftables.ChainTypeFilter, Num: nftables.HookOutput, Priority: nftablesBridgeFilterPriority},
+	}
+	batch.AddChain(output)
+	f.addIngressRule(batch, output)
+
+	linkLocalPrefix := &net.IPNet{IP: net.ParseIP("fe80::"), Mask: net.CIDRMask(10, 128)}
+	rules := [][]nftables.Expr{
+		// the bridge has other ports than the tap interfaces of the machines
+		{
+			nftables.Meta(unix.NFT_META_IIFNAME, nftables.Reg1),
+			nftables.Lookup(f.interfacesSet, nftables.Reg1, true),
+			nftables.Verdict(nftables.VerdictReturn, ""),
+		},
+		append(loadInterfaceAnd(nftables.Payload(unix.NFT_PAYLOAD_LL_HEADER, 6, 6, nftables.Reg32_0+4)),
+			nftables.Lookup(f.macSet, nftables.Reg32_0, true),
+			nftables.Verdict(nftables.VerdictDrop, "")),
+		// the sender hardware address of arp packets
+		append(append(matchEtherType(unix.ETH_P_ARP),
+			loadInterfaceAnd(nftables.Payload(unix.NFT_PAYLOAD_NETWORK_HEADER, 8, 6, nftables.Reg32_0+4))...),
+			nftables.Lookup(f.macSet, nftables.Reg32_0, true),
+			nftables.Verdict(nftables.VerdictDrop, "")),
+		append(append(matchEtherType(unix.ETH_P_IP),
+			loadInterfaceAnd(nftables.Payload(unix.NFT_PAYLOAD_NETWORK_HEADER, 12, net.IPv4len, nftables.Reg32_0+4))...),
+			nftables.Lookup(f.ipv4Set, nftables.Reg32_0, true),
+			nftables.Verdict(nftables.VerdictDrop, "")),
+		// neighbor discovery uses link-local addresses, and the unspecified one during duplicate
+		// address detection
+		append(append(matchEtherType(unix.ETH_P_IPV6), matchNetwork(8, linkLocalPrefix)...),
+			nftables.Verdict(nftables.VerdictReturn, "")),
+		append(append(matchEtherType(unix.ETH_P_IPV6), matchNetwork(8, &net.IPNet{IP: net.IPv6unspecified, Mask: net.CIDRMask(128, 128)})...),
+			nftables.Verdict(nftables.VerdictReturn, "")),
+		append(append(matchEtherType(unix.ETH_P_IPV6),
+			loadInterfaceAnd(nftables.Payload(unix.NFT_PAYLOAD_NETWORK_HEADER, 8, net.IPv6len, nftables.Reg32_0+4))...),
+			nftables.Lookup(f.ipv6Set, nftables.Reg32_0, true),
+			nftables.Verdict(nftables.VerdictDrop, "")),
+	}
+	for _, exprs := range rules {
+		batch.AddRule(&nftables.Rule{Chain: antispoof, Exprs: exprs})
+	}
+}
+
+// addIngressRule jumps to the ingress chain of the output interface, when it has one.
+func (f *nftablesFirewall) addIngressRule(batch *nftables.Batch, chain *nftables.Chain) {
+	batch.AddRule(&nftables.Rule{Chain: chain, Exprs: []nftables.Expr{
+		nftables.Meta(unix.NFT_META_OIFNAME, nftables.Reg1),
+		nftables.VerdictMap(f.ingressMap, nftables.Reg1),
+	}})
+}
+
+// addInetTable mirrors the bridge rules of the iptables firewall.
+func (f *nftablesFirewall) addInetTable(batch *nftables.Batch) {
+	forward := &nftables.Chain{
+		Table: f.inetTable,
+		Name:  "forward",
+		Hook:  &nftables.Hook{Type: nftables.ChainTypeFilter, Num: nftables.HookForward, Priority: 0},
+	}
+	postrouting := &nftables.Chain{
+		Table: f.inetTable,
+		Name:  "postrouting",
+		Hook:  &nftables.Hook{Type: nftables.ChainTypeNAT, Num: nftables.HookPostrouting, Priority: 100},
+	}
+	batch.AddChain(forward)
+	batch.AddChain(postrouting)
+	f.addNetworkIsolationRules(batch, forward)
+	f.addPortsRules(batch, forward, postrouting)
+
+	established := make([]byte, 4)
+	binary.NativeEndian.PutUint32(established, ctStateEstablished|ctStateRelated)
+	var networks []*net.IPNet
+	for _, n := range f.p.networks() {
+		outbound := append(matchInterface(unix.NFT_META_IIFNAME, n.bridgeInterface),
+			matchInterface(unix.NFT_META_OIFNAME, f.p.uplinkInterface)...)
+		for _, network := range f.p.blockedNetworks {
+			exprs := append(append(append([]nftables.Expr{}, outbound...), matchFamily(network.IP)...),
+				matchNetwork(destinationOffset(network.IP), network)...)
+			batch.AddRule(&nftables.Rule{Chain: forward, Exprs: append(exprs, nftables.Verdict(nftables.VerdictDrop, ""))})
+		}
+		batch.AddRule(&nftables.Rule{Chain: forward, Exprs: append(append([]nftables.Expr{}, outbound...),
+			nftables.Verdict(nftables.VerdictAccept, ""))})
+
+		batch.AddRule(&nftables.Rule{Chain: forward, Exprs: append(append(
+			matchInterface(unix.NFT_META_IIFNAME, f.p.uplinkInterface),
+			matchInterface(unix.NFT_META_OIFNAME, n.bridgeInterface)...),
+			nftables.Ct(unix.NFT_CT_STATE, nftables.Reg1),
+			nftables.Bitwise(nftables.Reg1, established, make([]byte, 4)),
+			nftables.Cmp(unix.NFT_CMP_NEQ, nftables.Reg1, make([]byte, 4)),
+			nftables.Verdict(nftables.VerdictAccept, ""),
+		)})
+
+		for _, gateway := range n.gateways() {
+			networks = append(networks, &net.IPNet{IP: gateway.IP.Mask(gateway.Mask), Mask: gateway.Mask})
+		}
+	}
+
+	// a unique local prefix is not routed to the node, it is translated to the uplink address
+	if f.p.ipv6Prefix != nil && f.p.ipv6Prefix.IP.IsPrivate() {
+		networks = append(networks, f.p.ipv6Prefix)
+	}
+	for _, network := range networks {
+		exprs := append(append(matchFamily(network.IP), matchNetwork(sourceOffset(network.IP), network)...),
+			matchInterface(unix.NFT_META_OIFNAME, f.p.uplinkInterface)...)
+		batch.AddRule(&nftables.Rule{Chain: postrouting, Exprs: append(exprs, nftables.Masquerade())})
+	}
+}
+
+// addNetworkIsolationRules keeps the machines of a network from reaching the ones of another
+// network through the node, the published ports stay reachable from every network.
+func (f *nftablesFirewall) addNetworkIsolationRules(batch *nftables.Batch, forward *nftables.Chain) {
+	for _, from := range f.p.networks() {
+		for _, to := range f.p.networks() {
+			if from == to {
+				continue
+			}
+
+			exprs := append(append(append(matchInterface(unix.NFT_META_IIFNAME, from.bridgeInterface),
+				matchInterface(unix.NFT_META_OIFNAME, to.bridgeInterface)...), matchCtStatus(ctStatusDNAT, false)...),
+				nftables.Verdict(nftables.VerdictDrop, ""))
+			batch.AddRule(&nftables.Rule{Chain: forward, Exprs: exprs})
+		}
+	}
+}
+
+// Bits of the conntrack state, NF_CT_STATE_BIT of the established and related states.
+const (
+	ctStateEstablished = 1 << 1
+	ctStateRelated     = 1 << 2
+)
+
+// loadInterfaceAnd loads the input interface name followed by the value loaded by expr, the
+// concatenated key of the interface sets.
+func loadInterfaceAnd(expr nftables.Expr) []nftables.Expr {
+	return []nftables.Expr{nftables.Meta(unix.NFT_META_IIFNAME, nftables.Reg32_0), expr}
+}
+
+func matchInterface(key uint32, name string) []nftables.Expr {
+	return []nftables.Expr{
+		nftables.Meta(key, nftables.Reg1),
+		nftables.Cmp(unix.NFT_CMP_EQ, nftables.Reg1, nftables.IfName(name)),
+	}
+}
+
+func matchEtherType(etherType uint16) []nftables.Expr {
+	return []nftables.Expr{
+		nftables.Meta(unix.NFT_META_PROTOCOL, nftables.Reg1),
+		nftables.Cmp(unix.NFT_CMP_EQ, nftables.Reg1, binary.BigEndian.AppendUint16(nil, etherType)),
+	}
+}
+
+// matchFamily must come before the address of a packet is loaded in the inet family.
+func matchFamily(ip net.IP) []nftables.Expr {
+	family := byte(unix.NFPROTO_IPV6)
+	if ip.To4() != nil {
+		family = unix.NFPROTO_IPV4
+	}
+	return []nftables.Expr{
+		nftables.Meta(unix.NFT_META_NFPROTO, nftables.Reg1),
+		nftables.Cmp(unix.NFT_CMP_EQ, nftables.Reg1, []byte{family}),
+	}
+}
+
+// matchNetwork matches the address at offset of the network header against the network.
+func matchNetwork(offset uint32, network *net.IPNet) []nftables.Expr {
+	ip, mask := network.IP.To4(), network.Mask
+	if ip == nil {
+		ip = network.IP.To16()
+	} else if len(mask) == net.IPv6len {
+		mask = mask[net.IPv6len-net.IPv4len:]
+	}
+	return []nftables.Expr{
+		nftables.Payload(unix.NFT_PAYLOAD_NETWORK_HEADER, offset, uint32(len(ip)), nftables.Reg1),
+		nftables.Bitwise(nftables.Reg1, mask, make([]byte, len(ip))),
+		nftables.Cmp(unix.NFT_CMP_EQ, nftables.Reg1, ip.Mask(mask)),
+	}
+}
+
+func sourceOffset(ip net.IP) uint32 {
+	if ip.To4() != nil {
+		return 12
+	}
+	return 8
+}
+
+func destinationOffset(ip net.IP) uint32 {
+	if ip.To4() != nil {
+		return 16
+	}
+	return 24
+}
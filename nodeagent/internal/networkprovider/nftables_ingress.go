@@ -0,0 +1,205 @@
+package networkprovider
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/nftables"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"golang.org/x/sys/unix"
+	"net"
+)
+
+// Types of the icmpv6 messages of the neighbor discovery, from the router solicitation to the
+// redirect.
+const (
+	icmpv6NeighborDiscoveryFirst = 133
+	icmpv6NeighborDiscoveryLast  = 137
+)
+
+// nftablesIngressRule is a rule of the ingress chain of an interface with a counter.
+type nftablesIngressRule struct {
+	exprs []nftables.Expr
+	// policyRule is the index of the rule of the policy it was built from, -1 for the last rule
+	// which counts the unmatched traffic.
+	policyRule int
+}
+
+func (f *nftablesFirewall) UpdateIngressPolicy(_ context.Context, networkInterface *types.NetworkInterface) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	previous, exists := f.interfaces[networkInterface.Name]
+	if !exists {
+		// the policy is applied when the interface is added
+		return nil
+	}
+
+	f.interfaces[networkInterface.Name] = networkInterface
+	if err := f.syncInterface(networkInterface.Name); err != nil {
+		f.interfaces[networkInterface.Name] = previous
+		return err
+	}
+	return nil
+}
+
+func (f *nftablesFirewall) IngressStats(_ context.Context, networkInterface *types.NetworkInterface) (*types.NetworkIngressStats, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	stats := &types.NetworkIngressStats{}
+	current, exists := f.interfaces[networkInterface.Name]
+	if !exists || current.IngressPolicy == nil {
+		return stats, nil
+	}
+
+	stats.DefaultDeny = current.IngressPolicy.DefaultDeny
+	stats.Rules = make([]types.NetworkIngressCounter, len(current.IngressPolicy.Rules))
+	rules, err := nftablesIngressRules(current.IngressPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	counters, err := nftables.ListCounters(f.ingressChain(current.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingress counters: %w", err)
+	} else if len(counters) != len(rules) {
+		return nil, fmt.Errorf("ingress chain of %s has %d counters, expected %d", current.Name, len(counters), len(rules))
+	}
+
+	for index, rule := range rules {
+		counter := &stats.Unmatched
+		if rule.policyRule >= 0 {
+			counter = &stats.Rules[rule.policyRule]
+		}
+		counter.Packets += counters[index].Packets
+		counter.Bytes += counters[index].Bytes
+	}
+	return stats, nil
+}
+
+func (f *nftablesFirewall) ingressChain(name string) *nftables.Chain {
+	return &nftables.Chain{Table: f.bridgeTable, Name: "ingress-" + name}
+}
+
+// addIngressChain replaces the rules of the ingress chain of the interface, which resets its
+// counters. The conntrack state of bridged frames takes the nf_conntrack_bridge module, the
+// kernel loads it when the chain is added and rejects the rules without it.
+func (f *nftablesFirewall) addIngressChain(batch *nftables.Batch, networkInterface *types.NetworkInterface) error {
+	rules, err := nftablesIngressRules(networkInterface.IngressPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to build ingress rules of %s: %w", networkInterface.Name, err)
+	}
+
+	chain := f.ingressChain(networkInterface.Name)
+	batch.AddChain(chain)
+	batch.FlushChain(chain)
+
+	established := make([]byte, 4)
+	binary.NativeEndian.PutUint32(established, ctStateEstablished|ctStateRelated)
+	accepted := [][]nftables.Expr{
+		matchEtherType(unix.ETH_P_ARP),
+		append(matchL4Proto(unix.IPPROTO_ICMPV6),
+			nftables.Payload(unix.NFT_PAYLOAD_TRANSPORT_HEADER, 0, 1, nftables.Reg1),
+			nftables.Cmp(unix.NFT_CMP_GTE, nftables.Reg1, []byte{icmpv6NeighborDiscoveryFirst}),
+			nftables.Cmp(unix.NFT_CMP_LTE, nftables.Reg1, []byte{icmpv6NeighborDiscoveryLast})),
+		{
+			nftables.Ct(unix.NFT_CT_STATE, nftables.Reg1),
+			nftables.Bitwise(nftables.Reg1, established, make([]byte, 4)),
+			nftables.Cmp(unix.NFT_CMP_NEQ, nftables.Reg1, make([]byte, 4)),
+		},
+	}
+	for _, exprs := range accepted {
+		batch.AddRule(&nftables.Rule{Chain: chain, Exprs: append(exprs, nftables.Verdict(nftables.VerdictAccept, ""))})
+	}
+	for _, rule := range rules {
+		batch.AddRule(&nftables.Rule{Chain: chain, Exprs: rule.exprs})
+	}
+	return nil
+}
+
+// nftablesIngressRules builds the counted rules of an ingress chain, a rule of the policy with
+// several source networks or matching icmp of both families takes several rules.
+func nftablesIngressRules(policy *types.NetworkIngressPolicy) ([]nftablesIngressRule, error) {
+	var rules []nftablesIngressRule
+	for index, policyRule := range policy.Rules {
+		var sources []*net.IPNet
+		for _, cidr := range policyRule.SourceCIDRs {
+			_, source, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source cidr %s: %w", cidr, err)
+			}
+			sources = append(sources, source)
+		}
+
+		var matches [][]nftables.Expr
+		if len(sources) == 0 {
+			switch policyRule.Protocol {
+			case coretypes.MachineIngressProtocolAny:
+				matches = append(matches, nil)
+			case coretypes.MachineIngressProtocolICMP:
+				matches = append(matches, matchL4Proto(unix.IPPROTO_ICMP), matchL4Proto(unix.IPPROTO_ICMPV6))
+			default:
+				matches = append(matches, matchIngressProtocol(policyRule, false))
+			}
+		}
+		for _, source := range sources {
+			etherType := uint16(unix.ETH_P_IPV6)
+			if source.IP.To4() != nil {
+				etherType = unix.ETH_P_IP
+			}
+			exprs := append(matchEtherType(etherType), matchNetwork(sourceOffset(source.IP), source)...)
+			matches = append(matches, append(exprs, matchIngressProtocol(policyRule, etherType == unix.ETH_P_IPV6)...))
+		}
+
+		for _, exprs := range matches {
+			exprs = append(append([]nftables.Expr{}, exprs...),
+				nftables.Counter(), nftables.Verdict(nftables.VerdictAccept, ""))
+			rules = append(rules, nftablesIngressRule{exprs: exprs, policyRule: index})
+		}
+	}
+
+	unmatched := []nftables.Expr{nftables.Counter()}
+	if policy.DefaultDeny {
+		unmatched = append(unmatched, nftables.Verdict(nftables.VerdictDrop, ""))
+	}
+	return append(rules, nftablesIngressRule{exprs: unmatched, policyRule: -1}), nil
+}
+
+// matchIngressProtocol matches the protocol and the destination ports of the rule.
+func matchIngressProtocol(rule coretypes.MachineIngressRule, ipv6 bool) []nftables.Expr {
+	switch rule.Protocol {
+	case coretypes.MachineIngressProtocolICMP:
+		if ipv6 {
+			return matchL4Proto(unix.IPPROTO_ICMPV6)
+		}
+		return matchL4Proto(unix.IPPROTO_ICMP)
+	case coretypes.MachineIngressProtocolTCP, coretypes.MachineIngressProtocolUDP:
+		proto := byte(unix.IPPROTO_TCP)
+		if rule.Protocol == coretypes.MachineIngressProtocolUDP {
+			proto = unix.IPPROTO_UDP
+		}
+		exprs := matchL4Proto(proto)
+		if rule.FromPort == 0 && rule.ToPort == 0 {
+			return exprs
+		}
+
+		exprs = append(exprs, nftables.Payload(unix.NFT_PAYLOAD_TRANSPORT_HEADER, 2, 2, nftables.Reg1))
+		if rule.FromPort == rule.ToPort {
+			return append(exprs, nftables.Cmp(unix.NFT_CMP_EQ, nftables.Reg1, binary.BigEndian.AppendUint16(nil, rule.FromPort)))
+		}
+		return append(exprs,
+			nftables.Cmp(unix.NFT_CMP_GTE, nftables.Reg1, binary.BigEndian.AppendUint16(nil, rule.FromPort)),
+			nftables.Cmp(unix.NFT_CMP_LTE, nftables.Reg1, binary.BigEndian.AppendUint16(nil, rule.ToPort)))
+	default:
+		return nil
+	}
+}
+
+func matchL4Proto(proto byte) []nftables.Expr {
+	return []nftables.Expr{
+		nftables.Meta(unix.NFT_META_L4PROTO, nftables.Reg1),
+		nftables.Cmp(unix.NFT_CMP_EQ, nftables.Reg1, []byte{proto}),
+	}
+}
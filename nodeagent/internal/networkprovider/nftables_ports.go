@@ -0,0 +1,169 @@
+package networkprovider
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/nftables"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"golang.org/x/sys/unix"
+	"net"
+)
+
+// Priority of the nat chains translating the destination, the one of iptables.
+const nftablesDNATPriority = -100
+
+// ctStatusDNAT is IPS_DST_NAT, the conntrack status bit of the connections with a translated
+// destination.
+const ctStatusDNAT = 1 << 5
+
+func (f *nftablesFirewall) PublishPorts(_ context.Context, ports []*types.PublishedPort) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	previous := f.publishedPorts
+	f.publishedPorts = make(map[string]*types.PublishedPort, len(previous)+len(ports))
+	for id, port := range previous {
+		f.publishedPorts[id] = port
+	}
+	for _, port := range ports {
+		f.publishedPorts[port.ID] = port
+	}
+
+	if err := f.syncPublishedPorts(); err != nil {
+		f.publishedPorts = previous
+		return err
+	}
+	return nil
+}
+
+func (f *nftablesFirewall) UnpublishPorts(_ context.Context, ports []*types.PublishedPort) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	previous := f.publishedPorts
+	f.publishedPorts = make(map[string]*types.PublishedPort, len(previous))
+	for id, port := range previous {
+		f.publishedPorts[id] = port
+	}
+	for _, port := range ports {
+		delete(f.publishedPorts, port.ID)
+	}
+
+	if err := f.syncPublishedPorts(); err != nil {
+		f.publishedPorts = previous
+		return err
+	}
+	return nil
+}
+
+// syncPublishedPorts rewrites the elements of the ports map, a failed update leaves the previous
+// elements in place.
+func (f *nftablesFirewall) syncPublishedPorts() error {
+	batch := &nftables.Batch{}
+	batch.FlushSet(f.portsMap)
+	f.addPortElements(batch)
+	if err := nftables.Apply(batch); err != nil {
+		return fmt.Errorf("failed to update nftables published ports: %w", err)
+	}
+	return nil
+}
+
+func (f *nftablesFirewall) addPortElements(batch *nftables.Batch) {
+	var elements []nftables.Element
+	for _, port := range f.publishedPorts {
+		if element, ok := publishedPortElement(port); ok {
+			elements = append(elements, element)
+		}
+	}
+	batch.AddElements(f.portsMap, elements)
+}
+
+// publishedPortElement is the element of the ports map of the port, ok is false when the port
+// has no ipv4 guest address.
+func publishedPortElement(port *types.PublishedPort) (nftables.Element, bool) {
+	guestAddress := port.GuestAddress.To4()
+	if guestAddress == nil {
+		return nftables.Element{}, false
+	}
+	return nftables.Element{
+		Key:  publishedPortKey(publishedPortProto(port), port.HostPort),
+		Data: nftables.Concat(guestAddress, binary.BigEndian.AppendUint16(nil, port.ContainerPort)),
+	}, true
+}
+
+// addPortsRules translates the destination in prerouting for the traffic coming from the network
+// and in output for the traffic of the node itself, the ports map gives the address and the port
+// of the machine. A machine reaching a published port of another one is translated to the
+// address of the bridge so that the replies go through the node.
+func (f *nftablesFirewall) addPortsRules(batch *nftables.Batch, forward, postrouting *nftables.Chain) {
+	batch.AddSet(f.portsMap)
+
+	matchDNAT := matchCtStatus(ctStatusDNAT, true)
+	for _, n := range f.p.networks() {
+		batch.AddRule(&nftables.Rule{Chain: forward, Exprs: append(append(
+			matchInterface(unix.NFT_META_OIFNAME, n.bridgeInterface), matchDNAT...),
+			nftables.Verdict(nftables.VerdictAccept, ""),
+		)})
+		for _, gateway := range n.gateways() {
+			network := &net.IPNet{IP: gateway.IP.Mask(gateway.Mask), Mask: gateway.Mask}
+			exprs := append(append(append(matchFamily(network.IP), matchNetwork(sourceOffset(network.IP), network)...),
+				matchInterface(unix.NFT_META_OIFNAME, n.bridgeInterface)...), matchDNAT...)
+			batch.AddRule(&nftables.Rule{Chain: postrouting, Exprs: append(exprs, nftables.Masquerade())})
+		}
+	}
+
+	if f.p.hostAddr == nil {
+		return
+	}
+
+	hostNetwork := &net.IPNet{IP: f.p.hostAddr, Mask: net.CIDRMask(32, 32)}
+	for _, hook := range []struct {
+		name string
+		num  uint32
+	}{{"prerouting", nftables.HookPrerouting}, {"output", nftables.HookOutput}} {
+		chain := &nftables.Chain{
+			Table: f.inetTable,
+			Name:  hook.name,
+			Hook:  &nftables.Hook{Type: nftables.ChainTypeNAT, Num: hook.num, Priority: nftablesDNATPriority},
+		}
+		batch.AddChain(chain)
+		batch.AddRule(&nftables.Rule{Chain: chain, Exprs: append(
+			append(matchFamily(f.p.hostAddr), matchNetwork(destinationOffset(f.p.hostAddr), hostNetwork)...),
+			nftables.Meta(unix.NFT_META_L4PROTO, nftables.Reg32_0),
+			nftables.Payload(unix.NFT_PAYLOAD_TRANSPORT_HEADER, 2, 2, nftables.Reg32_0+1),
+			nftables.MapLookup(f.portsMap, nftables.Reg32_0, nftables.Reg32_0),
+			nftables.DNAT(nftables.Reg32_0, nftables.Reg32_0+1),
+		)})
+	}
+}
+
+// matchCtStatus matches the connections with the status bit set, or without it when set is
+// false.
+func matchCtStatus(bit uint32, set bool) []nftables.Expr {
+	mask := make([]byte, 4)
+	binary.NativeEndian.PutUint32(mask, bit)
+	op := uint32(unix.NFT_CMP_EQ)
+	if set {
+		op = unix.NFT_CMP_NEQ
+	}
+	return []nftables.Expr{
+		nftables.Ct(unix.NFT_CT_STATUS, nftables.Reg1),
+		nftables.Bitwise(nftables.Reg1, mask, make([]byte, 4)),
+		nftables.Cmp(op, nftables.Reg1, make([]byte, 4)),
+	}
+}
+
+func publishedPortProto(port *types.PublishedPort) byte {
+	if port.Protocol == coretypes.ContainerPortProtocolUDP {
+		return unix.IPPROTO_UDP
+	}
+	return unix.IPPROTO_TCP
+}
+
+// publishedPortKey is the key of the ports map, the transport protocol followed by the
+// destination port.
+func publishedPortKey(proto byte, port uint16) []byte {
+	return nftables.Concat([]byte{proto}, binary.BigEndian.AppendUint16(nil, port))
+}
@@ -0,0 +1,201 @@
+package networkprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/nftables"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"golang.org/x/sys/unix"
+	"slices"
+)
+
+// Reconcile compares the elements of the interface sets and of the ports map with the ones of
+// the allocated interfaces and the published ports, then the chains of both tables and their
+// rules with the ones added by Setup. The tables are replaced by Setup when anything differs or
+// is missing.
+func (f *nftablesFirewall) Reconcile(ctx context.Context, interfaces []*types.NetworkInterface, ports []*types.PublishedPort) ([]types.NetworkDrift, error) {
+	allocated := map[string]*types.NetworkInterface{}
+	for _, networkInterface := range interfaces {
+		allocated[networkInterface.Name] = networkInterface
+	}
+
+	drifts, err := f.interfaceDrifts(allocated)
+	if err != nil {
+		return nil, err
+	}
+
+	portDrifts, err := f.portDrifts(interfaces, ports)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, portDrifts...)
+
+	rulesDrifted, err := f.rulesDrifted(allocated)
+	if err != nil {
+		return nil, err
+	} else if rulesDrifted {
+		drifts = append(drifts, types.NetworkDrift{Kind: types.NetworkDriftKindBridgeRules})
+	}
+
+	if len(drifts) == 0 {
+		return nil, nil
+	}
+
+	err = f.Setup(ctx, interfaces, ports)
+	for index := range drifts {
+		drifts[index].Error = err
+	}
+	return drifts, nil
+}
+
+// interfaceDrifts compares the elements of the interface sets with the ones of the allocated
+// interfaces.
+func (f *nftablesFirewall) interfaceDrifts(allocated map[string]*types.NetworkInterface) ([]types.NetworkDrift, error) {
+	missing, orphans := map[string]bool{}, map[string]bool{}
+	expected := f.interfaceElements(allocated)
+	for _, set := range f.interfaceSets() {
+		elements, err := nftables.ListElements(set)
+		if errors.Is(err, unix.ENOENT) {
+			// the tables were deleted, every rule is missing
+			for name := range allocated {
+				missing[name] = true
+			}
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		expectedKeys := map[string]bool{}
+		for _, element := range expected[set] {
+			expectedKeys[elementKey(element)] = true
+		}
+		keys := map[string]bool{}
+		for _, element := range elements {
+			keys[elementKey(element)] = true
+			name := elementInterfaceName(element.Key)
+			if _, ok := allocated[name]; !ok {
+				orphans[name] = true
+			} else if !expectedKeys[elementKey(element)] {
+				missing[name] = true
+			}
+		}
+		for _, element := range expected[set] {
+			if !keys[elementKey(element)] {
+				missing[elementInterfaceName(element.Key)] = true
+			}
+		}
+	}
+
+	var drifts []types.NetworkDrift
+	for name := range missing {
+		drifts = append(drifts, types.NetworkDrift{Kind: types.NetworkDriftKindMissingRules, Interface: name})
+	}
+	for name := range orphans {
+		drifts = append(drifts, types.NetworkDrift{Kind: types.NetworkDriftKindOrphanRules, Interface: name})
+	}
+	return drifts, nil
+}
+
+// portDrifts compares the elements of the ports map with the ones of the published ports.
+func (f *nftablesFirewall) portDrifts(interfaces []*types.NetworkInterface, ports []*types.PublishedPort) ([]types.NetworkDrift, error) {
+	interfaceNames := map[string]string{}
+	for _, networkInterface := range interfaces {
+		interfaceNames[networkInterface.ID] = networkInterface.Name
+	}
+
+	expected := map[string]*types.PublishedPort{}
+	for _, port := range ports {
+		if element, ok := publishedPortElement(port); ok {
+			expected[elementKey(element)] = port
+		}
+	}
+
+	elements, err := nftables.ListElements(f.portsMap)
+	if err != nil && !errors.Is(err, unix.ENOENT) {
+		return nil, err
+	}
+
+	var drifts []types.NetworkDrift
+	keys := map[string]bool{}
+	for _, element := range elements {
+		keys[elementKey(element)] = true
+		if expected[elementKey(element)] == nil {
+			drifts = append(drifts, types.NetworkDrift{Kind: types.NetworkDriftKindOrphanPortRules})
+		}
+	}
+	for key, port := range expected {
+		if !keys[key] {
+			drifts = append(drifts, types.NetworkDrift{
+				Kind:      types.NetworkDriftKindMissingPortRules,
+				Interface: interfaceNames[port.NetworkInterfaceID],
+			})
+		}
+	}
+	return drifts, nil
+}
+
+// rulesDrifted compares the chains of both tables and the expressions of their rules with the
+// ones added by Setup, the data of the expressions is not compared, see nftables.ListRules.
+func (f *nftablesFirewall) rulesDrifted(allocated map[string]*types.NetworkInterface) (bool, error) {
+	batch := &nftables.Batch{}
+	f.addBridgeTable(batch)
+	f.addInetTable(batch)
+	for _, networkInterface := range allocated {
+		if networkInterface.IngressPolicy == nil {
+			continue
+		} else if err := f.addIngressChain(batch, networkInterface); err != nil {
+			return false, err
+		}
+	}
+
+	// the expression names of the rules by table and chain name
+	expected := map[*nftables.Table]map[string][][]string{f.bridgeTable: {}, f.inetTable: {}}
+	for _, chain := range batch.Chains() {
+		expected[chain.Table][chain.Name] = nil
+	}
+	for _, rule := range batch.Rules() {
+		chainRules := expected[rule.Chain.Table]
+		chainRules[rule.Chain.Name] = append(chainRules[rule.Chain.Name], nftables.ExprNames(rule))
+	}
+
+	for table, chainRules := range expected {
+		chains, err := nftables.ListChains(table)
+		if err != nil {
+			return false, fmt.Errorf("failed to list chains of %s table: %w", table.Name, err)
+		} else if len(chains) != len(chainRules) {
+			return true, nil
+		}
+
+		for _, name := range chains {
+			expectedRules, ok := chainRules[name]
+			if !ok {
+				return true, nil
+			}
+
+			rules, err := nftables.ListRules(&nftables.Chain{Table: table, Name: name})
+			if err != nil {
+				return false, fmt.Errorf("failed to list rules of %s chain: %w", name, err)
+			} else if !slices.EqualFunc(rules, expectedRules, slices.Equal[[]string]) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// elementKey identifies an element by its key and its data or chain.
+func elementKey(element nftables.Element) string {
+	return hex.EncodeToString(element.Key) + "/" + hex.EncodeToString(element.Data) + "/" + element.Chain
+}
+
+// elementInterfaceName returns the interface name the key of an element starts with.
+func elementInterfaceName(key []byte) string {
+	name := key[:min(len(key), unix.IFNAMSIZ)]
+	if index := bytes.IndexByte(name, 0); index != -1 {
+		name = name[:index]
+	}
+	return string(name)
+}
@@ -0,0 +1,23 @@
+package networkprovider
+
+import "github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+
+// GetPoolStats counts the addresses of the machine networks, the network, gateway and broadcast
+// addresses are reserved and counted as neither allocated nor free.
+func (p *Provider) GetPoolStats() types.NetworkPoolStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var stats types.NetworkPoolStats
+	for _, n := range p.networks() {
+		stats.Free += n.size - len(n.allocatedIPs)
+		for _, name := range n.allocatedIPs {
+			switch name {
+			case "network", "broadcast", n.bridgeInterface:
+			default:
+				stats.Allocated++
+			}
+		}
+	}
+	return stats
+}
@@ -3,49 +3,88 @@ package networkprovider
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/vishvananda/netlink"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
+	"log/slog"
 	"net"
 	"os/exec"
+	"slices"
 	"sync"
 )
 
 type Provider struct {
+	log             *slog.Logger
 	db              *gorm.DB
-	bridgeInterface string
-	networkCIDR     *net.IPNet
-	networkAddr     net.IP
-	gatewayAddr     net.IP
-	allocatedIPs    []string // ip address = array offset + base address, value = tap interface Name
-	lock            sync.Mutex
+	uplinkInterface string
+	// defaultNetwork is the network of the machines assigned to no private network.
+	defaultNetwork  *network
+	privateNetworks []*network
+	// ipv6Prefix is nil when the machines only get an ipv4 address.
+	ipv6Prefix      *net.IPNet
+	ipv6GatewayAddr net.IP
+	blockedNetworks []*net.IPNet
+	// hostAddr is the ipv4 address of the node the ports are published on, no port can be
+	// published when nil.
+	hostAddr           net.IP
+	firstHostPort      uint16
+	lastHostPort       uint16
+	allocatedHostPorts map[hostPort]string // value = machine ID
+	firewall           firewall
+	lock               sync.Mutex
 }
 
 var _ types.NetworkProvider = (*Provider)(nil)
 
-func New(db *gorm.DB) (*Provider, error) {
-	_, networkCIDR, err := net.ParseCIDR("192.168.100.0/24")
+func New(db *gorm.DB, config *types.Config) (*Provider, error) {
+	defaultNetwork, err := newNetwork("", 0, config.Network.CIDR, config.Network.BridgeInterface)
 	if err != nil {
-		return nil, fmt.Errorf("invalid network CIDR: %w", err)
+		return nil, err
 	}
 
 	p := &Provider{
+		log:             slog.With(slog.String("component", "networkprovider")),
 		db:              db,
-		bridgeInterface: "br0",
-		networkCIDR:     networkCIDR,
+		uplinkInterface: config.Network.UplinkInterface,
+		defaultNetwork:  defaultNetwork,
+		hostAddr:        net.ParseIP(config.IPAddr).To4(),
 		lock:            sync.Mutex{},
 	}
-
-	_, err = p.SetupBridge("eth0")
-	if err != nil {
-		return nil, fmt.Errorf("failed to setup bridge interface: %w", err)
+	for _, networkConfig := range config.Network.PrivateNetworks {
+		privateNetwork, err := newNetwork(networkConfig.Name, networkConfig.ID, networkConfig.CIDR, networkConfig.BridgeInterface)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private network %s: %w", networkConfig.Name, err)
+		}
+		p.privateNetworks = append(p.privateNetworks, privateNetwork)
+	}
+	if p.firstHostPort, p.lastHostPort, err = config.Network.ParseHostPortRange(); err != nil {
+		return nil, fmt.Errorf("invalid host port range: %w", err)
+	}
+	p.firewall = newFirewall(p, config.Network.FirewallBackend)
+	if config.Network.IPv6Prefix != "" {
+		if _, p.ipv6Prefix, err = net.ParseCIDR(config.Network.IPv6Prefix); err != nil {
+			return nil, fmt.Errorf("invalid network ipv6 prefix: %w", err)
+		}
+		p.ipv6GatewayAddr = p.calculateIPv6FromOffset(1)
+	}
+	for _, network := range config.Network.BlockedNetworks {
+		_, blockedNetwork, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocked network: %w", err)
+		}
+		p.blockedNetworks = append(p.blockedNetworks, blockedNetwork)
 	}
 
-	ones, bits := p.networkCIDR.Mask.Size()
-	maxAddresses := 1 << (bits - ones)
-	p.allocatedIPs = make([]string, maxAddresses)
-	p.allocatedIPs[p.calculateOffsetFromIP(p.networkAddr)] = "network" // claim network address
-	p.allocatedIPs[p.calculateOffsetFromIP(p.gatewayAddr)] = "br0"     // claim gateway address
+	if p.uplinkInterface == "" {
+		if p.uplinkInterface, err = findDefaultRouteInterface(); err != nil {
+			return nil, fmt.Errorf("failed to detect uplink interface: %w", err)
+		}
+		p.log.Info("detected uplink interface", slog.String("interface", p.uplinkInterface))
+	}
 
 	var allocatedNetworkInterfaces []*types.NetworkInterface
 	err = db.WithContext(context.Background()).Find(&allocatedNetworkInterfaces, "released_at IS NULL").Error
@@ -54,72 +93,138 @@ func New(db *gorm.DB) (*Provider, error) {
 	}
 
 	for _, networkInterface := range allocatedNetworkInterfaces {
-		p.allocatedIPs[p.calculateOffsetFromIP(networkInterface.IPAddress)] = networkInterface.Name
+		p.claimAllocatedInterface(networkInterface)
 	}
 
-	return p, nil
-}
+	publishedPorts, err := p.loadPublishedPorts(context.Background())
+	if err != nil {
+		return nil, err
+	}
 
-func (p *Provider) runCmd(ctx context.Context, name string, args ...string) error {
-	cmd := exec.CommandContext(ctx, name, args...)
-	stderr := new(bytes.Buffer)
-	cmd.Stderr = stderr
+	if err = p.setupBridges(); err != nil {
+		return nil, fmt.Errorf("failed to setup bridge interfaces: %w", err)
+	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("%w: %s", err, stderr.String())
+	if err = p.firewall.Setup(context.Background(), allocatedNetworkInterfaces, publishedPorts); err != nil {
+		return nil, fmt.Errorf("failed to setup firewall rules: %w", err)
 	}
 
-	return nil
+	return p, nil
 }
 
-func (p *Provider) calculateOffsetFromIP(ip net.IP) int {
-	ipCopy := make(net.IP, len(ip))
-	copy(ipCopy, ip)
-
-	baseIPCopy := make(net.IP, len(p.networkAddr))
-	copy(baseIPCopy, p.networkAddr)
+// claimAllocatedInterface reserves the address of an interface loaded from the database. An
+// interface allocated in a previous network keeps working: its gateway stays on the bridge, and
+// both its address and the tap index derived from its mac address are reserved in the current
+// network, since they are not the same offset anymore.
+func (p *Provider) claimAllocatedInterface(networkInterface *types.NetworkInterface) {
+	log := p.log.With(slog.String("interface", networkInterface.Name))
+	n, err := p.findNetwork(networkInterface.Network)
+	if err != nil {
+		log.Warn("network interface belongs to a network which is no longer configured",
+			slog.String("network", networkInterface.Network))
+		return
+	}
 
-	if ipCopy.To4() != nil {
-		ipCopy = ipCopy.To4()
+	inNetwork := n.cidr.Contains(networkInterface.IPAddress)
+	if networkInterface.NetworkCIDR == nil || (*net.IPNet)(networkInterface.NetworkCIDR).String() == n.cidr.String() {
+		if inNetwork {
+			n.allocatedIPs[n.calculateOffsetFromIP(networkInterface.IPAddress)] = networkInterface.Name
+			return
+		}
 	}
 
-	if baseIPCopy.To4() != nil {
-		baseIPCopy = baseIPCopy.To4()
+	if networkInterface.NetworkCIDR != nil && networkInterface.GatewayAddress != nil {
+		previousNetwork := networkInterface.NetworkCIDR.ToNetIPNet()
+		gateway := &net.IPNet{IP: networkInterface.GatewayAddress, Mask: previousNetwork.Mask}
+		log.Warn("network interface was allocated in a previous network, it is kept until released",
+			slog.String("network-cidr", previousNetwork.String()))
+		if !slices.ContainsFunc(n.previousNetworks, func(network *net.IPNet) bool {
+			return network.String() == gateway.String()
+		}) {
+			n.previousNetworks = append(n.previousNetworks, gateway)
+		}
+		if n.cidr.Contains(gateway.IP) {
+			n.allocatedIPs[n.calculateOffsetFromIP(gateway.IP)] = n.bridgeInterface
+		}
+	} else {
+		log.Warn("network interface is outside of the network and has no recorded network")
 	}
 
-	offset := 0
-	for i := 0; i < len(ipCopy); i++ {
-		offset = (offset << 8) | int(ipCopy[i]-baseIPCopy[i])
+	if inNetwork {
+		n.allocatedIPs[n.calculateOffsetFromIP(networkInterface.IPAddress)] = networkInterface.Name
+	}
+	if index := n.calculateIndexFromHwAddr(networkInterface.MacAddress); index != -1 {
+		n.allocatedIPs[index] = networkInterface.Name
 	}
-	return offset
 }
 
-func (p *Provider) calculateIPFromOffset(offset int) net.IP {
-	ip := make(net.IP, len(p.networkAddr))
-	copy(ip, p.networkAddr)
-
-	if ip.To4() != nil {
-		ip = ip.To4()
+// releaseAllocatedInterface frees every offset claimed by the interface, the names of the tap
+// interfaces are unique across the networks.
+func (p *Provider) releaseAllocatedInterface(name string) {
+	for _, n := range p.networks() {
+		n.releaseAllocatedInterface(name)
 	}
+}
 
-	for i := len(ip) - 1; i >= 0; i-- {
-		ip[i] += byte(offset & 0xFF)
-		offset >>= 8
+func findDefaultRouteInterface() (string, error) {
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
+		return "", fmt.Errorf("failed to list routes: %w", err)
+	}
 
-		if offset == 0 {
-			break
+	var defaultRoute *netlink.Route
+	for _, route := range routes {
+		if route.Dst != nil {
+			if ones, _ := route.Dst.Mask.Size(); ones != 0 {
+				continue
+			}
+		}
+		if defaultRoute == nil || route.Priority < defaultRoute.Priority {
+			defaultRoute = &route
 		}
 	}
-	return ip
+	if defaultRoute == nil {
+		return "", errors.New("no default route")
+	}
+
+	link, err := netlink.LinkByIndex(defaultRoute.LinkIndex)
+	if err != nil {
+		return "", fmt.Errorf("failed to find default route interface: %w", err)
+	}
+
+	return link.Attrs().Name, nil
 }
 
-func (p *Provider) calculateIndexFromHwAddr(hwAddr net.HardwareAddr) int {
-	if len(hwAddr) == 6 && hwAddr[0] == 0x52 && hwAddr[1] == 0x54 && hwAddr[2] == 0x00 {
-		index := (int(hwAddr[3]) << 16) | (int(hwAddr[4]) << 8) | int(hwAddr[5])
-		if index >= 0 && index < len(p.allocatedIPs) {
-			return index
-		}
+func (p *Provider) runCmd(ctx context.Context, name string, args ...string) error {
+	_, err := p.runCmdOutput(ctx, name, args...)
+	return err
+}
+
+func (p *Provider) runCmdOutput(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	_, span := tracing.Start(ctx, "networkprovider."+name, attribute.StringSlice("command.args", args))
+	err := cmd.Run()
+	if err != nil {
+		err = fmt.Errorf("%w: %s", err, stderr.String())
 	}
+	tracing.End(span, err)
+	return stdout.String(), err
+}
 
-	return -1
+// calculateIPv6FromOffset returns the ipv6 address of the ipv4 address at the same offset.
+func (p *Provider) calculateIPv6FromOffset(offset int) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, p.ipv6Prefix.IP.To16())
+
+	carry := offset
+	for i := len(ip) - 1; i >= 0 && carry > 0; i-- {
+		sum := int(ip[i]) + carry&0xFF
+		ip[i] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+	return ip
 }
@@ -0,0 +1,183 @@
+package networkprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/nrednav/cuid2"
+	"gorm.io/gorm"
+)
+
+type hostPort struct {
+	protocol coretypes.ContainerPortProtocol
+	port     uint16
+}
+
+// loadPublishedPorts claims the host ports of the allocated interfaces, the ports left over by
+// an interface released without unpublishing them are deleted.
+func (p *Provider) loadPublishedPorts(ctx context.Context) ([]*types.PublishedPort, error) {
+	allocatedInterfaces := p.db.Model(&types.NetworkInterface{}).Select("id").Where("released_at IS NULL")
+	err := p.db.WithContext(ctx).
+		Where("network_interface_id NOT IN (?)", allocatedInterfaces).
+		Delete(&types.PublishedPort{}).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete released published ports: %w", err)
+	}
+
+	var ports []*types.PublishedPort
+	if err = p.db.WithContext(ctx).Find(&ports).Error; err != nil {
+		return nil, fmt.Errorf("could not find published ports: %w", err)
+	}
+
+	p.allocatedHostPorts = map[hostPort]string{}
+	for _, port := range ports {
+		p.allocatedHostPorts[hostPort{protocol: port.Protocol, port: port.HostPort}] = port.MachineID
+	}
+	return ports, nil
+}
+
+// PublishPorts reuses the host port of a port the machine already publishes to the same
+// container port, a port without host port is given the first free one of the range.
+func (p *Provider) PublishPorts(ctx context.Context, opts types.NetworkPublishPortsOptions) ([]*types.PublishedPort, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var current []*types.PublishedPort
+	if err := p.db.WithContext(ctx).Find(&current, "machine_id = ?", opts.MachineID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find published ports: %w", err)
+	}
+
+	if len(opts.Ports) > 0 && p.hostAddr == nil {
+		return nil, errors.New("ports can only be published on the ipv4 address of the node")
+	}
+
+	var (
+		published, added []*types.PublishedPort
+		reserved         = map[hostPort]bool{}
+		// the requested host ports come first so that no allocated port can take them
+		requested, unallocated []types.NetworkPublishPortOptions
+	)
+	for _, port := range opts.Ports {
+		index := slices.IndexFunc(current, func(existing *types.PublishedPort) bool {
+			return existing.ContainerID == port.ContainerID && existing.Protocol == port.Spec.Protocol &&
+				existing.ContainerPort == port.Spec.ContainerPort &&
+				(port.Spec.HostPort == 0 || existing.HostPort == port.Spec.HostPort)
+		})
+		if index == -1 && port.Spec.HostPort != 0 {
+			requested = append(requested, port)
+			continue
+		} else if index == -1 {
+			unallocated = append(unallocated, port)
+			continue
+		}
+
+		publishedPort := current[index]
+		current = slices.Delete(current, index, index+1)
+		reserved[hostPort{protocol: publishedPort.Protocol, port: publishedPort.HostPort}] = true
+		published = append(published, publishedPort)
+	}
+
+	for _, port := range append(requested, unallocated...) {
+		key := hostPort{protocol: port.Spec.Protocol, port: port.Spec.HostPort}
+		if key.port == 0 {
+			if key.port = p.findAvailableHostPort(key.protocol, reserved); key.port == 0 {
+				return nil, fmt.Errorf("%w: no available %s port in range %d-%d", types.ErrHostPortUnavailable,
+					key.protocol, p.firstHostPort, p.lastHostPort)
+			}
+		} else if owner := p.allocatedHostPorts[key]; reserved[key] || (owner != "" && owner != opts.MachineID) {
+			return nil, fmt.Errorf("%w: %s port %d is already published", types.ErrHostPortUnavailable, key.protocol, key.port)
+		}
+
+		reserved[key] = true
+		publishedPort := &types.PublishedPort{
+			ID:                 cuid2.Generate(),
+			MachineID:          opts.MachineID,
+			ContainerID:        port.ContainerID,
+			NetworkInterfaceID: opts.NetworkInterface.ID,
+			Protocol:           key.protocol,
+			HostPort:           key.port,
+			ContainerPort:      port.Spec.ContainerPort,
+			GuestAddress:       opts.NetworkInterface.IPAddress,
+		}
+		published = append(published, publishedPort)
+		added = append(added, publishedPort)
+	}
+
+	// the ports left in current are no longer published, they are deleted first since a new
+	// port can take their host port. The rules are applied before the commit so that the rows
+	// only outlive a failure along with the rules they had.
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(current) > 0 {
+			if err := tx.Delete(&current).Error; err != nil {
+				return fmt.Errorf("failed to delete unpublished ports from database: %w", err)
+			}
+		}
+		if len(published) > 0 {
+			if err := tx.Save(&published).Error; err != nil {
+				return fmt.Errorf("failed to persist published ports in database: %w", err)
+			}
+		}
+
+		if err := p.firewall.UnpublishPorts(ctx, current); err != nil {
+			return fmt.Errorf("failed to remove firewall rules of published ports: %w", err)
+		}
+		if err := p.firewall.PublishPorts(ctx, published); err != nil {
+			_ = p.firewall.UnpublishPorts(context.Background(), added)
+			_ = p.firewall.PublishPorts(context.Background(), current)
+			return fmt.Errorf("failed to apply firewall rules of published ports: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, port := range current {
+		delete(p.allocatedHostPorts, hostPort{protocol: port.Protocol, port: port.HostPort})
+	}
+	for _, port := range published {
+		p.allocatedHostPorts[hostPort{protocol: port.Protocol, port: port.HostPort}] = opts.MachineID
+	}
+
+	return published, nil
+}
+
+func (p *Provider) UnpublishPorts(ctx context.Context, machineID string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var ports []*types.PublishedPort
+	if err := p.db.WithContext(ctx).Find(&ports, "machine_id = ?", machineID).Error; err != nil {
+		return fmt.Errorf("failed to find published ports: %w", err)
+	} else if len(ports) == 0 {
+		return nil
+	}
+
+	if err := p.firewall.UnpublishPorts(ctx, ports); err != nil {
+		return fmt.Errorf("failed to remove firewall rules of published ports: %w", err)
+	}
+
+	if err := p.db.WithContext(ctx).Delete(&ports).Error; err != nil {
+		return fmt.Errorf("failed to delete published ports from database: %w", err)
+	}
+
+	for _, port := range ports {
+		delete(p.allocatedHostPorts, hostPort{protocol: port.Protocol, port: port.HostPort})
+	}
+	return nil
+}
+
+func (p *Provider) findAvailableHostPort(protocol coretypes.ContainerPortProtocol, reserved map[hostPort]bool) uint16 {
+	for port := uint32(p.firstHostPort); port <= uint32(p.lastHostPort); port++ {
+		key := hostPort{protocol: protocol, port: uint16(port)}
+		if p.allocatedHostPorts[key] == "" && !reserved[key] {
+			return key.port
+		}
+	}
+	return 0
+}
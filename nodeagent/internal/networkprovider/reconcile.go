@@ -0,0 +1,136 @@
+package networkprovider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/vishvananda/netlink"
+	"net"
+	"strings"
+)
+
+// Reconcile makes the tap interfaces match the allocated interfaces, then the firewall rules. A
+// tap interface is expected for every allocated interface, from its allocation until its release.
+func (p *Provider) Reconcile(ctx context.Context) ([]types.NetworkDrift, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var interfaces []*types.NetworkInterface
+	if err := p.db.WithContext(ctx).Find(&interfaces, "released_at IS NULL").Error; err != nil {
+		return nil, fmt.Errorf("could not find allocated network interfaces: %w", err)
+	}
+
+	ports, err := p.loadPublishedPorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts, err := p.reconcileLinks(ctx, interfaces)
+	if err != nil {
+		return nil, err
+	}
+
+	firewallDrifts, err := p.firewall.Reconcile(ctx, interfaces, ports)
+	if err != nil {
+		return drifts, fmt.Errorf("failed to reconcile firewall rules: %w", err)
+	}
+
+	return append(drifts, firewallDrifts...), nil
+}
+
+// reconcileLinks creates the missing tap interfaces, repairs the ones which are down, have another
+// mac address or are not on the bridge of their network, and deletes the tap interfaces on a
+// bridge without allocated interface.
+func (p *Provider) reconcileLinks(ctx context.Context, interfaces []*types.NetworkInterface) ([]types.NetworkDrift, error) {
+	// the networks by index of their bridge
+	networks := map[int]*network{}
+	bridges := map[*network]netlink.Link{}
+	for _, n := range p.networks() {
+		bridge, err := netlink.LinkByName(n.bridgeInterface)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find bridge %s: %w", n.bridgeInterface, err)
+		}
+		networks[bridge.Attrs().Index] = n
+		bridges[n] = bridge
+	}
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	linksByName := map[string]netlink.Link{}
+	for _, link := range links {
+		linksByName[link.Attrs().Name] = link
+	}
+
+	var drifts []types.NetworkDrift
+	allocated := map[string]bool{}
+	for _, networkInterface := range interfaces {
+		allocated[networkInterface.Name] = true
+		n, err := p.findNetwork(networkInterface.Network)
+		if err != nil {
+			// the interface is kept until released, as when the provider is created
+			continue
+		}
+
+		link, exists := linksByName[networkInterface.Name]
+		if !exists {
+			drifts = append(drifts, types.NetworkDrift{
+				Kind:      types.NetworkDriftKindMissingLink,
+				Interface: networkInterface.Name,
+				Error:     createTap(n, networkInterface),
+			})
+		} else if !isLinkConfigured(link, bridges[n], networkInterface.MacAddress) {
+			drifts = append(drifts, types.NetworkDrift{
+				Kind:      types.NetworkDriftKindLinkConfig,
+				Interface: networkInterface.Name,
+				Error:     configureLink(link, bridges[n], networkInterface.MacAddress),
+			})
+		}
+	}
+
+	for _, link := range links {
+		attrs := link.Attrs()
+		n, onBridge := networks[attrs.MasterIndex]
+		if link.Type() != "tuntap" || !strings.HasPrefix(attrs.Name, "tap") || !onBridge || allocated[attrs.Name] {
+			continue
+		}
+
+		drifts = append(drifts, types.NetworkDrift{
+			Kind:      types.NetworkDriftKindOrphanLink,
+			Interface: attrs.Name,
+			Error:     p.deleteOrphanLink(ctx, n, link),
+		})
+	}
+
+	return drifts, nil
+}
+
+func isLinkConfigured(link netlink.Link, bridge netlink.Link, macAddress net.HardwareAddr) bool {
+	attrs := link.Attrs()
+	return attrs.MasterIndex == bridge.Attrs().Index &&
+		bytes.Equal(attrs.HardwareAddr, macAddress) &&
+		attrs.Flags&net.FlagUp != 0
+}
+
+func configureLink(link netlink.Link, bridge netlink.Link, macAddress net.HardwareAddr) error {
+	if !bytes.Equal(link.Attrs().HardwareAddr, macAddress) {
+		if err := netlink.LinkSetHardwareAddr(link, macAddress); err != nil {
+			return fmt.Errorf("failed to set mac address: %w", err)
+		}
+	}
+
+	if err := netlink.LinkSetUp(link); err != nil {
+		return fmt.Errorf("failed to set tap interface up: %w", err)
+	}
+
+	if link.Attrs().MasterIndex != bridge.Attrs().Index {
+		if err := netlink.LinkSetMaster(link, bridge); err != nil {
+			return fmt.Errorf("failed to set tap interface master: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -20,7 +20,7 @@ func (p *Provider) ReleaseInterface(ctx context.Context, networkInterface *types
 	}
 
 	if link != nil {
-		if err = p.applyTapFirewallRules(ctx, networkInterface, true); err != nil {
+		if err = p.firewall.RemoveInterface(ctx, networkInterface); err != nil {
 			return fmt.Errorf("failed to apply firewall rules to tap interface: %w", err)
 		}
 
@@ -30,7 +30,7 @@ func (p *Provider) ReleaseInterface(ctx context.Context, networkInterface *types
 	}
 
 	if networkInterface.ReleasedAt == nil {
-		p.allocatedIPs[p.calculateOffsetFromIP(networkInterface.IPAddress)] = ""
+		p.releaseAllocatedInterface(networkInterface.Name)
 		networkInterface.ReleasedAt = typeutil.Ptr(time.Now())
 		if err = p.db.WithContext(ctx).Select("ReleasedAt").Save(&networkInterface).Error; err != nil {
 			return fmt.Errorf("failed to persist network interface changes in database: %w", err)
@@ -1,113 +1,133 @@
 package networkprovider
 
 import (
-	"context"
 	"fmt"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"log/slog"
 	"net"
 	"os"
+	"slices"
 	"strings"
 )
 
-var LocalNetworks = []string{
-	"10.0.0.0/8",
-	"172.16.0.0/12",
-	"192.168.0.0/16",
+// setupBridges sets up the bridge of every network, only the bridge of the default network gets
+// an ipv6 address.
+func (p *Provider) setupBridges() error {
+	for _, n := range p.networks() {
+		bridge, err := p.setupBridge(n)
+		if err != nil {
+			return fmt.Errorf("failed to setup bridge %s: %w", n.bridgeInterface, err)
+		}
+
+		if n == p.defaultNetwork {
+			if err = p.setupBridgeIPv6(bridge); err != nil {
+				return err
+			}
+		}
+	}
+
+	// enable IP forwarding in the kernel
+	if err := os.WriteFile("/proc/sys/net/ipv4/ip_forward", []byte("1"), 0644); err != nil {
+		return fmt.Errorf("failed to setup IP forwarding: %w", err)
+	}
+	return nil
 }
 
-func (p *Provider) SetupBridge(externalInterface string) (netlink.Link, error) {
-	bridge, err := netlink.LinkByName(p.bridgeInterface)
+func (p *Provider) setupBridge(n *network) (netlink.Link, error) {
+	bridge, err := netlink.LinkByName(n.bridgeInterface)
 	if err != nil {
 		attrs := netlink.NewLinkAttrs()
-		attrs.Name = p.bridgeInterface
+		attrs.Name = n.bridgeInterface
 		bridge = &netlink.Bridge{LinkAttrs: attrs}
 		if err = netlink.LinkAdd(bridge); err != nil {
 			return nil, fmt.Errorf("failed to create bridge: %w", err)
 		}
 	}
 
-	p.networkAddr = p.networkCIDR.IP
-	p.gatewayAddr = p.calculateIPFromOffset(1)
-	addr := &netlink.Addr{
-		IPNet: &net.IPNet{
-			IP:   p.gatewayAddr,
-			Mask: p.networkCIDR.Mask,
-		},
-	}
-
-	if err = netlink.AddrAdd(bridge, addr); err != nil && !strings.Contains(err.Error(), "file exists") {
-		return nil, fmt.Errorf("failed to add address to bridge: %w", err)
+	if err = p.syncBridgeAddresses(bridge, n.gateways()); err != nil {
+		return nil, err
 	}
 
 	if err = netlink.LinkSetUp(bridge); err != nil {
 		return nil, fmt.Errorf("failed to bring up bridge: %w", err)
 	}
 
-	// enable IP forwarding in the kernel
-	if err = os.WriteFile("/proc/sys/net/ipv4/ip_forward", []byte("1"), 0644); err != nil {
-		return nil, fmt.Errorf("failed to setup IP forwarding: %w", err)
-	}
-
-	if err = p.applyBridgeFirewallRules(externalInterface); err != nil {
-		return nil, fmt.Errorf("failed to setup firewall rules: %w", err)
-	}
-
 	return bridge, nil
 }
 
-func (p *Provider) applyBridgeFirewallRules(externalInterface string) error {
-	// NAT for external access
-	err := p.upsertIptablesRule("nat", "POSTROUTING",
-		"-s", p.networkCIDR.String(),
-		"-o", externalInterface,
-		"-j", "MASQUERADE")
-	if err != nil {
-		return fmt.Errorf("failed to setup NAT: %w", err)
+// setupBridgeIPv6 gives the bridge the ipv6 gateway address, or removes it once the node has no
+// ipv6 prefix anymore.
+func (p *Provider) setupBridgeIPv6(bridge netlink.Link) error {
+	var gateway *net.IPNet
+	if p.ipv6Prefix != nil {
+		gateway = &net.IPNet{IP: p.ipv6GatewayAddr, Mask: p.ipv6Prefix.Mask}
 	}
 
-	// Allow forwarding for VM traffic going to external networks
-	err = p.upsertIptablesRule("filter", "FORWARD",
-		"-i", p.bridgeInterface,
-		"-o", externalInterface,
-		"-j", "ACCEPT")
+	addrs, err := netlink.AddrList(bridge, netlink.FAMILY_V6)
 	if err != nil {
-		return fmt.Errorf("failed to allow VM outbound traffic: %w", err)
+		return fmt.Errorf("failed to list bridge ipv6 addresses: %w", err)
 	}
 
-	// Allow established connections
-	err = p.upsertIptablesRule("filter", "FORWARD",
-		"-i", externalInterface,
-		"-o", p.bridgeInterface,
-		"-m", "state",
-		"--state", "RELATED,ESTABLISHED",
-		"-j", "ACCEPT")
-	if err != nil {
-		return fmt.Errorf("failed to allow established connections: %w", err)
-	}
+	for _, addr := range addrs {
+		if addr.IP.IsLinkLocalUnicast() || (gateway != nil && addr.IPNet.String() == gateway.String()) {
+			continue
+		}
 
-	// Block VM access to local networks
-	for _, network := range LocalNetworks {
-		err = p.upsertIptablesRule("filter", "FORWARD",
-			"-i", p.bridgeInterface,
-			"-o", externalInterface,
-			"-d", network,
-			"-j", "DROP")
-		if err != nil {
-			return fmt.Errorf("failed to block VM access to local network %s: %w", network, err)
+		p.log.Info("removing stale bridge address", slog.String("address", addr.IPNet.String()))
+		if err = netlink.AddrDel(bridge, &addr); err != nil {
+			return fmt.Errorf("failed to remove stale bridge address %v: %w", addr.IPNet, err)
 		}
 	}
 
+	if gateway == nil {
+		return nil
+	}
+
+	// the address is ours, duplicate address detection would only delay its use
+	err = netlink.AddrAdd(bridge, &netlink.Addr{IPNet: gateway, Flags: unix.IFA_F_NODAD})
+	if err != nil && !strings.Contains(err.Error(), "file exists") {
+		return fmt.Errorf("failed to add ipv6 address to bridge: %w", err)
+	}
+
+	// forwarding disables router advertisements on every interface unless accept_ra is 2, the
+	// uplink may rely on them for its own address and default route
+	uplinkAcceptRA := fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/accept_ra", p.uplinkInterface)
+	if err = os.WriteFile(uplinkAcceptRA, []byte("2"), 0644); err != nil {
+		return fmt.Errorf("failed to keep accepting router advertisements on the uplink: %w", err)
+	}
+	if err = os.WriteFile("/proc/sys/net/ipv6/conf/all/forwarding", []byte("1"), 0644); err != nil {
+		return fmt.Errorf("failed to setup IPv6 forwarding: %w", err)
+	}
 	return nil
 }
 
-func (p *Provider) upsertIptablesRule(table, chain string, ruleSpec ...string) error {
-	checkArgs := []string{"-t", table, "-C", chain}
-	checkArgs = append(checkArgs, ruleSpec...)
-	if err := p.runCmd(context.Background(), "iptables", checkArgs...); err == nil {
-		return nil
+// syncBridgeAddresses sets the gateways as the only ipv4 addresses of the bridge, the gateway
+// of a network which is neither current nor used by an interface anymore is removed.
+func (p *Provider) syncBridgeAddresses(bridge netlink.Link, gateways []*net.IPNet) error {
+	addrs, err := netlink.AddrList(bridge, netlink.FAMILY_V4)
+	if err != nil {
+		return fmt.Errorf("failed to list bridge addresses: %w", err)
 	}
 
-	addArgs := []string{"-t", table, "-A", chain}
-	addArgs = append(addArgs, ruleSpec...)
-	return p.runCmd(context.Background(), "iptables", addArgs...)
+	for _, addr := range addrs {
+		if slices.ContainsFunc(gateways, func(gateway *net.IPNet) bool {
+			return gateway.String() == addr.IPNet.String()
+		}) {
+			continue
+		}
+
+		p.log.Info("removing stale bridge address", slog.String("address", addr.IPNet.String()))
+		if err = netlink.AddrDel(bridge, &addr); err != nil {
+			return fmt.Errorf("failed to remove stale bridge address %v: %w", addr.IPNet, err)
+		}
+	}
+
+	for _, gateway := range gateways {
+		err = netlink.AddrAdd(bridge, &netlink.Addr{IPNet: gateway})
+		if err != nil && !strings.Contains(err.Error(), "file exists") {
+			return fmt.Errorf("failed to add address to bridge: %w", err)
+		}
+	}
+	return nil
 }
@@ -3,18 +3,45 @@ package networkprovider
 import (
 	"context"
 	"fmt"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	"github.com/vishvananda/netlink"
-	"strings"
 )
 
-func (p *Provider) SetupInterface(ctx context.Context, networkInterface *types.NetworkInterface) error {
+func (p *Provider) SetupInterface(ctx context.Context, networkInterface *types.NetworkInterface, ingress *coretypes.MachineIngressPolicy) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	n, err := p.findNetwork(networkInterface.Network)
+	if err != nil {
+		return err
+	}
+
+	networkInterface.IngressPolicy = (*types.NetworkIngressPolicy)(ingress)
+	if err = p.db.WithContext(ctx).Select("IngressPolicy").Save(networkInterface).Error; err != nil {
+		return fmt.Errorf("failed to persist network interface changes in database: %w", err)
+	}
+
 	if link, err := netlink.LinkByName(networkInterface.Name); err == nil {
 		if err = netlink.LinkDel(link); err != nil {
 			return fmt.Errorf("failed to delete existing tap interface %s: %w", networkInterface.Name, err)
 		}
 	}
 
+	if err = createTap(n, networkInterface); err != nil {
+		return err
+	}
+
+	if err = p.firewall.AddInterface(ctx, networkInterface); err != nil {
+		return fmt.Errorf("failed to apply firewall rules to tap interface: %w", err)
+	}
+
+	return nil
+}
+
+// createTap adds the tap interface of the machine to the bridge of its network, the tap interface
+// is deleted when it can not be set up.
+func createTap(n *network, networkInterface *types.NetworkInterface) error {
 	tap := &netlink.Tuntap{
 		LinkAttrs: netlink.LinkAttrs{
 			Name: networkInterface.Name,
@@ -36,10 +63,10 @@ func (p *Provider) SetupInterface(ctx context.Context, networkInterface *types.N
 		return fmt.Errorf("failed to set tap interface up: %w", err)
 	}
 
-	bridge, err := netlink.LinkByName(p.bridgeInterface)
+	bridge, err := netlink.LinkByName(n.bridgeInterface)
 	if err != nil {
 		_ = netlink.LinkDel(tap)
-		return fmt.Errorf("failed to find bridge %s: %w", p.bridgeInterface, err)
+		return fmt.Errorf("failed to find bridge %s: %w", n.bridgeInterface, err)
 	}
 
 	if err = netlink.LinkSetMaster(tap, bridge); err != nil {
@@ -47,43 +74,5 @@ func (p *Provider) SetupInterface(ctx context.Context, networkInterface *types.N
 		return fmt.Errorf("failed to set tap interface master: %w", err)
 	}
 
-	if err = p.applyTapFirewallRules(ctx, networkInterface, false); err != nil {
-		return fmt.Errorf("failed to apply firewall rules to tap interface: %w", err)
-	}
-
-	return nil
-}
-
-func (p *Provider) applyTapFirewallRules(ctx context.Context, networkInterface *types.NetworkInterface, shouldRemove bool) error {
-	operation := "-A" // append
-	if shouldRemove {
-		operation = "-D" // delete
-	}
-
-	err := p.runCmd(ctx, "ebtables", operation, "FORWARD",
-		"-i", networkInterface.Name,
-		"-s", "!", strings.ToLower(networkInterface.MacAddress.String()),
-		"-j", "DROP")
-	if err != nil {
-		return fmt.Errorf("failed to apply mac filtering rule: %w", err)
-	}
-
-	err = p.runCmd(ctx, "iptables", operation, "FORWARD",
-		"-i", networkInterface.Name,
-		"!", "-s", networkInterface.IPAddress.String(),
-		"-j", "DROP")
-	if err != nil {
-		return fmt.Errorf("failed to apply ip filtering rule: %w", err)
-	}
-
-	_ = p.runCmd(ctx, "arptables", "-N", "FORWARD")
-	err = p.runCmd(ctx, "arptables", operation, "FORWARD",
-		"-i", networkInterface.Name,
-		"!", "--source-mac", strings.ToLower(networkInterface.MacAddress.String()),
-		"-j", "DROP")
-	if err != nil {
-		return fmt.Errorf("failed to apply arp filtering rule: %w", err)
-	}
-
 	return nil
 }
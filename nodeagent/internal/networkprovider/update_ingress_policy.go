@@ -0,0 +1,34 @@
+package networkprovider
+
+import (
+	"context"
+	"fmt"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/vishvananda/netlink"
+)
+
+// UpdateIngressPolicy applies the policy right away when the tap interface exists, it is applied
+// by SetupInterface otherwise.
+func (p *Provider) UpdateIngressPolicy(ctx context.Context, networkInterface *types.NetworkInterface, ingress *coretypes.MachineIngressPolicy) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	previous := networkInterface.IngressPolicy
+	networkInterface.IngressPolicy = (*types.NetworkIngressPolicy)(ingress)
+	if _, err := netlink.LinkByName(networkInterface.Name); err == nil {
+		if err = p.firewall.UpdateIngressPolicy(ctx, networkInterface); err != nil {
+			networkInterface.IngressPolicy = previous
+			return fmt.Errorf("failed to apply ingress policy: %w", err)
+		}
+	} else if !isLinkNotFoundError(err) {
+		networkInterface.IngressPolicy = previous
+		return fmt.Errorf("failed to find interface %s: %w", networkInterface.Name, err)
+	}
+
+	if err := p.db.WithContext(ctx).Select("IngressPolicy").Save(networkInterface).Error; err != nil {
+		return fmt.Errorf("failed to persist network interface changes in database: %w", err)
+	}
+
+	return nil
+}
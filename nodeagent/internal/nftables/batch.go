@@ -0,0 +1,352 @@
+// Package nftables speaks the nftables netlink protocol, only the parts used by the node agent
+// are implemented. Changes are queued in a Batch and applied as a single kernel transaction,
+// which either succeeds entirely or leaves the ruleset untouched.
+package nftables
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// Families of the tables.
+const (
+	FamilyINet   = unix.NFPROTO_INET
+	FamilyBridge = unix.NFPROTO_BRIDGE
+)
+
+// Types of the base chains.
+const (
+	ChainTypeFilter = "filter"
+	ChainTypeNAT    = "nat"
+)
+
+type (
+	Table struct {
+		Family byte
+		Name   string
+	}
+
+	Chain struct {
+		Table *Table
+		Name  string
+		// Hook attaches the chain to a netfilter hook, a chain without hook is only reached
+		// through a jump.
+		Hook *Hook
+	}
+
+	Hook struct {
+		Type     string
+		Num      uint32
+		Priority int32
+	}
+
+	Set struct {
+		Table *Table
+		Name  string
+		// KeyType is only used by the nft command to display the elements.
+		KeyType uint32
+		KeyLen  uint32
+		// VerdictMap maps each key to the chain its element jumps to.
+		VerdictMap bool
+		// DataType and DataLen make the set a map of each key to a value, DataType is only used
+		// by the nft command as for KeyType.
+		DataType uint32
+		DataLen  uint32
+		// id references the set from the batch creating it, outside of it the set is found by
+		// name.
+		id uint32
+	}
+
+	Element struct {
+		Key []byte
+		// Chain is the chain jumped to by the element of a verdict map.
+		Chain string
+		// Data is the value of the element of a map.
+		Data []byte
+	}
+
+	Rule struct {
+		Chain *Chain
+		Exprs []Expr
+	}
+
+	Batch struct {
+		messages     []*nl.NetlinkRequest
+		descriptions []string
+		nextSetID    uint32
+		chains       []*Chain
+		rules        []*Rule
+	}
+)
+
+func (b *Batch) AddTable(table *Table) {
+	msg := b.add(unix.NFT_MSG_NEWTABLE, table.Family, unix.NLM_F_CREATE, "add table "+table.Name)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_TABLE_NAME, nl.ZeroTerminated(table.Name)))
+}
+
+// DelTable removes the table with its chains, sets and rules, the table must exist.
+func (b *Batch) DelTable(table *Table) {
+	msg := b.add(unix.NFT_MSG_DELTABLE, table.Family, 0, "delete table "+table.Name)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_TABLE_NAME, nl.ZeroTerminated(table.Name)))
+}
+
+func (b *Batch) AddChain(chain *Chain) {
+	b.chains = append(b.chains, chain)
+	msg := b.add(unix.NFT_MSG_NEWCHAIN, chain.Table.Family, unix.NLM_F_CREATE, "add chain "+chain.Name)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_CHAIN_TABLE, nl.ZeroTerminated(chain.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_CHAIN_NAME, nl.ZeroTerminated(chain.Name)))
+	if chain.Hook != nil {
+		hook := nested(unix.NFTA_CHAIN_HOOK)
+		hook.AddRtAttr(unix.NFTA_HOOK_HOOKNUM, nl.BEUint32Attr(chain.Hook.Num))
+		hook.AddRtAttr(unix.NFTA_HOOK_PRIORITY, nl.BEUint32Attr(uint32(chain.Hook.Priority)))
+		msg.AddData(hook)
+		msg.AddData(nl.NewRtAttr(unix.NFTA_CHAIN_POLICY, nl.BEUint32Attr(VerdictAccept)))
+		msg.AddData(nl.NewRtAttr(unix.NFTA_CHAIN_TYPE, nl.ZeroTerminated(chain.Hook.Type)))
+	}
+}
+
+// FlushChain removes every rule of the chain.
+func (b *Batch) FlushChain(chain *Chain) {
+	msg := b.add(unix.NFT_MSG_DELRULE, chain.Table.Family, 0, "flush chain "+chain.Name)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_RULE_TABLE, nl.ZeroTerminated(chain.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_RULE_CHAIN, nl.ZeroTerminated(chain.Name)))
+}
+
+// DelChain removes the chain, it must be empty and no longer referenced.
+func (b *Batch) DelChain(chain *Chain) {
+	msg := b.add(unix.NFT_MSG_DELCHAIN, chain.Table.Family, 0, "delete chain "+chain.Name)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_CHAIN_TABLE, nl.ZeroTerminated(chain.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_CHAIN_NAME, nl.ZeroTerminated(chain.Name)))
+}
+
+func (b *Batch) AddSet(set *Set) {
+	b.nextSetID++
+	set.id = b.nextSetID
+
+	msg := b.add(unix.NFT_MSG_NEWSET, set.Table.Family, unix.NLM_F_CREATE, "add set "+set.Name)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_TABLE, nl.ZeroTerminated(set.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_NAME, nl.ZeroTerminated(set.Name)))
+	if set.VerdictMap || set.DataLen != 0 {
+		msg.AddData(nl.NewRtAttr(unix.NFTA_SET_FLAGS, nl.BEUint32Attr(unix.NFT_SET_MAP)))
+	} else {
+		msg.AddData(nl.NewRtAttr(unix.NFTA_SET_FLAGS, nl.BEUint32Attr(0)))
+	}
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_KEY_TYPE, nl.BEUint32Attr(set.KeyType)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_KEY_LEN, nl.BEUint32Attr(set.KeyLen)))
+	if set.VerdictMap {
+		msg.AddData(nl.NewRtAttr(unix.NFTA_SET_DATA_TYPE, nl.BEUint32Attr(unix.NFT_DATA_VERDICT)))
+	} else if set.DataLen != 0 {
+		msg.AddData(nl.NewRtAttr(unix.NFTA_SET_DATA_TYPE, nl.BEUint32Attr(set.DataType)))
+		msg.AddData(nl.NewRtAttr(unix.NFTA_SET_DATA_LEN, nl.BEUint32Attr(set.DataLen)))
+	}
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_ID, nl.BEUint32Attr(set.id)))
+}
+
+// FlushSet removes every element of the set.
+func (b *Batch) FlushSet(set *Set) {
+	msg := b.add(unix.NFT_MSG_DELSETELEM, set.Table.Family, 0, "flush set "+set.Name)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_ELEM_LIST_TABLE, nl.ZeroTerminated(set.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_ELEM_LIST_SET, nl.ZeroTerminated(set.Name)))
+}
+
+// AddElements adds the elements to the set, keys already in the set are left as is.
+func (b *Batch) AddElements(set *Set, elements []Element) {
+	if len(elements) == 0 {
+		return
+	}
+
+	msg := b.add(unix.NFT_MSG_NEWSETELEM, set.Table.Family, unix.NLM_F_CREATE, "add elements to set "+set.Name)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_ELEM_LIST_TABLE, nl.ZeroTerminated(set.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_ELEM_LIST_SET, nl.ZeroTerminated(set.Name)))
+	if set.id != 0 {
+		msg.AddData(nl.NewRtAttr(unix.NFTA_SET_ELEM_LIST_SET_ID, nl.BEUint32Attr(set.id)))
+	}
+	list := nested(unix.NFTA_SET_ELEM_LIST_ELEMENTS)
+	for _, element := range elements {
+		attr := nested(unix.NFTA_LIST_ELEM)
+		attr.AddChild(dataValue(unix.NFTA_SET_ELEM_KEY, element.Key))
+		if element.Chain != "" {
+			data := nested(unix.NFTA_SET_ELEM_DATA)
+			data.AddChild(verdict(VerdictJump, element.Chain))
+			attr.AddChild(data)
+		} else if element.Data != nil {
+			attr.AddChild(dataValue(unix.NFTA_SET_ELEM_DATA, element.Data))
+		}
+		list.AddChild(attr)
+	}
+	msg.AddData(list)
+}
+
+func (b *Batch) AddRule(rule *Rule) {
+	b.rules = append(b.rules, rule)
+	msg := b.add(unix.NFT_MSG_NEWRULE, rule.Chain.Table.Family, unix.NLM_F_CREATE|unix.NLM_F_APPEND,
+		"add rule to chain "+rule.Chain.Name)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_RULE_TABLE, nl.ZeroTerminated(rule.Chain.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_RULE_CHAIN, nl.ZeroTerminated(rule.Chain.Name)))
+	exprs := nested(unix.NFTA_RULE_EXPRESSIONS)
+	for _, expr := range rule.Exprs {
+		exprs.AddChild(expr)
+	}
+	msg.AddData(exprs)
+}
+
+// Chains returns the chains added to the batch, in order.
+func (b *Batch) Chains() []*Chain {
+	return b.chains
+}
+
+// Rules returns the rules added to the batch, in order.
+func (b *Batch) Rules() []*Rule {
+	return b.rules
+}
+
+func (b *Batch) add(msgType int, family byte, flags int, description string) *nl.NetlinkRequest {
+	msg := newMessage(unix.NFNL_SUBSYS_NFTABLES<<8|msgType, family, flags|unix.NLM_F_ACK)
+	b.messages = append(b.messages, msg)
+	b.descriptions = append(b.descriptions, description)
+	return msg
+}
+
+// Apply commits the batch in a single transaction, the first error reported by the kernel is
+// returned.
+func Apply(b *Batch) error {
+	if len(b.messages) == 0 {
+		return nil
+	}
+
+	fd, err := openSocket()
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	resID := nl.Swap16(unix.NFNL_SUBSYS_NFTABLES)
+	begin := newMessage(unix.NFNL_MSG_BATCH_BEGIN, unix.AF_UNSPEC, 0)
+	begin.Data[0].(*nl.Nfgenmsg).ResId = resID
+	end := newMessage(unix.NFNL_MSG_BATCH_END, unix.AF_UNSPEC, 0)
+	end.Data[0].(*nl.Nfgenmsg).ResId = resID
+
+	var payload []byte
+	descriptions := map[uint32]string{}
+	for _, msg := range append(append([]*nl.NetlinkRequest{begin}, b.messages...), end) {
+		payload = append(payload, msg.Serialize()...)
+	}
+	for index, msg := range b.messages {
+		descriptions[msg.Seq] = b.descriptions[index]
+	}
+	if err = unix.Sendto(fd, payload, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to send batch: %w", err)
+	}
+
+	buf := make([]byte, 64*1024)
+	for pending := len(b.messages); pending > 0; {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return fmt.Errorf("failed to receive batch acknowledgement: %w", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return fmt.Errorf("failed to parse batch acknowledgement: %w", err)
+		}
+
+		for _, msg := range msgs {
+			if msg.Header.Type != unix.NLMSG_ERROR {
+				continue
+			} else if len(msg.Data) < 4 {
+				return errors.New("truncated batch acknowledgement")
+			}
+
+			if errno := int32(binary.NativeEndian.Uint32(msg.Data[:4])); errno != 0 {
+				description, ok := descriptions[msg.Header.Seq]
+				if !ok {
+					description = "batch"
+				}
+				return fmt.Errorf("failed to %s: %w", description, unix.Errno(-errno))
+			}
+			pending--
+		}
+	}
+
+	return nil
+}
+
+// dump sends a dump request and passes the data of every message of the given type to fn, the
+// description names what is dumped in the errors. The data is overwritten by the next messages,
+// fn copies what it keeps.
+func dump(msg *nl.NetlinkRequest, msgType int, description string, fn func(data []byte) error) error {
+	fd, err := openSocket()
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	if err = unix.Sendto(fd, msg.Serialize(), 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to send dump request of %s: %w", description, err)
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return fmt.Errorf("failed to receive %s: %w", description, err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", description, err)
+		}
+
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case unix.NLMSG_DONE:
+				return nil
+			case unix.NLMSG_ERROR:
+				if len(msg.Data) < 4 {
+					return fmt.Errorf("truncated dump error of %s", description)
+				} else if errno := int32(binary.NativeEndian.Uint32(msg.Data[:4])); errno != 0 {
+					return fmt.Errorf("failed to list %s: %w", description, unix.Errno(-errno))
+				}
+			case uint16(unix.NFNL_SUBSYS_NFTABLES<<8 | msgType):
+				if err = fn(msg.Data); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func openSocket() (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return -1, fmt.Errorf("failed to open netfilter socket: %w", err)
+	}
+
+	if err = unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to bind netfilter socket: %w", err)
+	}
+	// errors would otherwise echo the whole message they refer to
+	_ = unix.SetsockoptInt(fd, unix.SOL_NETLINK, unix.NETLINK_CAP_ACK, 1)
+	err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &unix.Timeval{Sec: 10})
+	if err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to set netfilter socket timeout: %w", err)
+	}
+	return fd, nil
+}
+
+func newMessage(msgType int, family byte, flags int) *nl.NetlinkRequest {
+	msg := nl.NewNetlinkRequest(msgType, flags)
+	msg.AddData(&nl.Nfgenmsg{NfgenFamily: family, Version: unix.NFNETLINK_V0})
+	return msg
+}
+
+func nested(attrType int) *nl.RtAttr {
+	return nl.NewRtAttr(attrType|int(nl.NLA_F_NESTED), nil)
+}
@@ -0,0 +1,104 @@
+package nftables
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// assertMessage compares a message of the batch with the fixture, the sequence number and the
+// port id of the netlink header are skipped.
+func assertMessage(t *testing.T, b *Batch, index int, want []byte) {
+	t.Helper()
+
+	if len(b.messages) <= index {
+		t.Fatalf("batch has %d messages, want message %d", len(b.messages), index)
+	}
+
+	got := b.messages[index].Serialize()
+	if len(got) != len(want) || !bytes.Equal(got[:8], want[:8]) || !bytes.Equal(got[16:], want[16:]) {
+		t.Errorf("message %d =\n% x\nwant\n% x", index, got, want)
+	}
+	if length := binary.NativeEndian.Uint32(got[:4]); int(length) != len(got) {
+		t.Errorf("message %d length = %d, want %d", index, length, len(got))
+	}
+}
+
+func TestBatchEncoding(t *testing.T) {
+	table := &Table{Family: FamilyINet, Name: "baepo"}
+
+	t.Run("add table", func(t *testing.T) {
+		var b Batch
+		b.AddTable(table)
+		assertMessage(t, &b, 0, []byte{
+			0x20, 0x00, 0x00, 0x00, // length
+			0x00, 0x0a, 0x05, 0x04, // new table, request, ack and create
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // sequence and port id
+			0x01, 0x00, 0x00, 0x00, // inet family
+			0x0a, 0x00, 0x01, 0x00, 'b', 'a', 'e', 'p', 'o', 0x00, 0x00, 0x00, // name
+		})
+	})
+
+	t.Run("add verdict map elements", func(t *testing.T) {
+		var b Batch
+		set := &Set{Table: table, Name: "m", KeyLen: 4, VerdictMap: true}
+		b.AddElements(set, []Element{{Key: []byte{0x0a, 0x00, 0x00, 0x02}, Chain: "c"}})
+		assertMessage(t, &b, 0, []byte{
+			0x54, 0x00, 0x00, 0x00,
+			0x0c, 0x0a, 0x05, 0x04, // new set element
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x01, 0x00, 0x00, 0x00,
+			0x0a, 0x00, 0x01, 0x00, 'b', 'a', 'e', 'p', 'o', 0x00, 0x00, 0x00, // table
+			0x06, 0x00, 0x02, 0x00, 'm', 0x00, 0x00, 0x00, // set
+			0x2c, 0x00, 0x03, 0x80, // elements
+			0x28, 0x00, 0x01, 0x80, // element
+			0x0c, 0x00, 0x01, 0x80, // key
+			0x08, 0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x02,
+			0x18, 0x00, 0x02, 0x80, // data
+			0x14, 0x00, 0x02, 0x80, // verdict
+			0x08, 0x00, 0x01, 0x00, 0xff, 0xff, 0xff, 0xfd,
+			0x06, 0x00, 0x02, 0x00, 'c', 0x00, 0x00, 0x00,
+		})
+	})
+
+	t.Run("set created in the batch", func(t *testing.T) {
+		var b Batch
+		set := &Set{Table: table, Name: "s", KeyType: TypeIPAddr, KeyLen: 4}
+		b.AddSet(set)
+		b.AddElements(set, []Element{{Key: []byte{0x0a, 0x00, 0x00, 0x02}}})
+		assertMessage(t, &b, 0, []byte{
+			0x48, 0x00, 0x00, 0x00,
+			0x09, 0x0a, 0x05, 0x04, // new set
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x01, 0x00, 0x00, 0x00,
+			0x0a, 0x00, 0x01, 0x00, 'b', 'a', 'e', 'p', 'o', 0x00, 0x00, 0x00,
+			0x06, 0x00, 0x02, 0x00, 's', 0x00, 0x00, 0x00,
+			0x08, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, // flags
+			0x08, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x07, // key type
+			0x08, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x04, // key length
+			0x08, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x01, // set id
+		})
+		// the elements reference the set by its id as it does not exist before the batch
+		assertMessage(t, &b, 1, []byte{
+			0x44, 0x00, 0x00, 0x00,
+			0x0c, 0x0a, 0x05, 0x04,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x01, 0x00, 0x00, 0x00,
+			0x0a, 0x00, 0x01, 0x00, 'b', 'a', 'e', 'p', 'o', 0x00, 0x00, 0x00,
+			0x06, 0x00, 0x02, 0x00, 's', 0x00, 0x00, 0x00,
+			0x08, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x01, // set id
+			0x14, 0x00, 0x03, 0x80,
+			0x10, 0x00, 0x01, 0x80,
+			0x0c, 0x00, 0x01, 0x80,
+			0x08, 0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x02,
+		})
+	})
+
+	t.Run("no elements", func(t *testing.T) {
+		var b Batch
+		b.AddElements(&Set{Table: table, Name: "s"}, nil)
+		if len(b.messages) != 0 {
+			t.Errorf("batch has %d messages, want none", len(b.messages))
+		}
+	})
+}
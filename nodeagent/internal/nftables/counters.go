@@ -0,0 +1,105 @@
+package nftables
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+type CounterValue struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// ListCounters returns the values of the counter expressions of the chain, in the order of its
+// rules. A rule without counter has no entry.
+func ListCounters(chain *Chain) ([]CounterValue, error) {
+	msg := newMessage(unix.NFNL_SUBSYS_NFTABLES<<8|unix.NFT_MSG_GETRULE, chain.Table.Family, unix.NLM_F_DUMP)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_RULE_TABLE, nl.ZeroTerminated(chain.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_RULE_CHAIN, nl.ZeroTerminated(chain.Name)))
+
+	var counters []CounterValue
+	err := dump(msg, unix.NFT_MSG_NEWRULE, "rules of chain "+chain.Name, func(data []byte) error {
+		ruleCounters, err := parseRuleCounters(data)
+		counters = append(counters, ruleCounters...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+func parseRuleCounters(data []byte) ([]CounterValue, error) {
+	if len(data) < nl.SizeofNfgenmsg {
+		return nil, errors.New("truncated rule")
+	}
+
+	attrs, err := nl.ParseRouteAttr(data[nl.SizeofNfgenmsg:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rule: %w", err)
+	}
+
+	var counters []CounterValue
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != unix.NFTA_RULE_EXPRESSIONS {
+			continue
+		}
+
+		elements, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rule expressions: %w", err)
+		}
+		for _, element := range elements {
+			counter, ok, err := parseCounter(element.Value)
+			if err != nil {
+				return nil, err
+			} else if ok {
+				counters = append(counters, counter)
+			}
+		}
+	}
+	return counters, nil
+}
+
+func parseCounter(data []byte) (CounterValue, bool, error) {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return CounterValue{}, false, fmt.Errorf("failed to parse expression: %w", err)
+	}
+
+	var name string
+	var values []syscall.NetlinkRouteAttr
+	for _, attr := range attrs {
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case unix.NFTA_EXPR_NAME:
+			name = string(bytes.TrimRight(attr.Value, "\x00"))
+		case unix.NFTA_EXPR_DATA:
+			if values, err = nl.ParseRouteAttr(attr.Value); err != nil {
+				return CounterValue{}, false, fmt.Errorf("failed to parse expression data: %w", err)
+			}
+		}
+	}
+	if name != "counter" {
+		return CounterValue{}, false, nil
+	}
+
+	var counter CounterValue
+	for _, value := range values {
+		if len(value.Value) < 8 {
+			continue
+		}
+		switch value.Attr.Type & nl.NLA_TYPE_MASK {
+		case unix.NFTA_COUNTER_PACKETS:
+			counter.Packets = binary.BigEndian.Uint64(value.Value)
+		case unix.NFTA_COUNTER_BYTES:
+			counter.Bytes = binary.BigEndian.Uint64(value.Value)
+		}
+	}
+	return counter, true, nil
+}
@@ -0,0 +1,47 @@
+package nftables
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRuleCounters(t *testing.T) {
+	data := []byte{
+		0x01, 0x00, 0x00, 0x00, // inet family
+		0x48, 0x00, 0x04, 0x00, // expressions
+		0x10, 0x00, 0x01, 0x00, // masquerade, skipped
+		0x09, 0x00, 0x01, 0x00, 'm', 'a', 's', 'q', 0x00, 0x00, 0x00, 0x00,
+		0x34, 0x00, 0x01, 0x00, // counter
+		0x0c, 0x00, 0x01, 0x00, 'c', 'o', 'u', 'n', 't', 'e', 'r', 0x00,
+		0x24, 0x00, 0x02, 0x00, // data
+		0x0c, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, // bytes
+		0x0c, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, // packets
+		0x08, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, // padding attribute, skipped
+	}
+
+	counters, err := parseRuleCounters(data)
+	if err != nil {
+		t.Fatalf("parseRuleCounters() error = %v", err)
+	}
+
+	want := []CounterValue{{Packets: 3, Bytes: 4096}}
+	if !reflect.DeepEqual(counters, want) {
+		t.Errorf("parseRuleCounters() = %+v, want %+v", counters, want)
+	}
+}
+
+func TestParseRuleCountersWithoutCounter(t *testing.T) {
+	data := []byte{
+		0x01, 0x00, 0x00, 0x00,
+		0x14, 0x00, 0x04, 0x00,
+		0x10, 0x00, 0x01, 0x00,
+		0x09, 0x00, 0x01, 0x00, 'm', 'a', 's', 'q', 0x00, 0x00, 0x00, 0x00,
+	}
+
+	counters, err := parseRuleCounters(data)
+	if err != nil {
+		t.Fatalf("parseRuleCounters() error = %v", err)
+	} else if len(counters) != 0 {
+		t.Errorf("parseRuleCounters() = %+v, want none", counters)
+	}
+}
@@ -0,0 +1,102 @@
+package nftables
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// ListElements returns the elements of the set, with their data or chain for a map. The error
+// wraps unix.ENOENT when the set or its table does not exist.
+func ListElements(set *Set) ([]Element, error) {
+	msg := newMessage(unix.NFNL_SUBSYS_NFTABLES<<8|unix.NFT_MSG_GETSETELEM, set.Table.Family, unix.NLM_F_DUMP)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_ELEM_LIST_TABLE, nl.ZeroTerminated(set.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_SET_ELEM_LIST_SET, nl.ZeroTerminated(set.Name)))
+
+	var elements []Element
+	err := dump(msg, unix.NFT_MSG_NEWSETELEM, "elements of set "+set.Name, func(data []byte) error {
+		setElements, err := parseElements(data)
+		elements = append(elements, setElements...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+func parseElements(data []byte) ([]Element, error) {
+	if len(data) < nl.SizeofNfgenmsg {
+		return nil, errors.New("truncated set elements")
+	}
+
+	attrs, err := nl.ParseRouteAttr(data[nl.SizeofNfgenmsg:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse set elements: %w", err)
+	}
+
+	var elements []Element
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != unix.NFTA_SET_ELEM_LIST_ELEMENTS {
+			continue
+		}
+
+		list, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse set element list: %w", err)
+		}
+		for _, item := range list {
+			elementAttrs, err := nl.ParseRouteAttr(item.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse set element: %w", err)
+			}
+
+			var element Element
+			for _, elementAttr := range elementAttrs {
+				switch elementAttr.Attr.Type & nl.NLA_TYPE_MASK {
+				case unix.NFTA_SET_ELEM_KEY:
+					if element.Key, _, err = parseData(elementAttr.Value); err != nil {
+						return nil, fmt.Errorf("failed to parse set element key: %w", err)
+					}
+				case unix.NFTA_SET_ELEM_DATA:
+					if element.Data, element.Chain, err = parseData(elementAttr.Value); err != nil {
+						return nil, fmt.Errorf("failed to parse set element data: %w", err)
+					}
+				}
+			}
+			if element.Key != nil {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return elements, nil
+}
+
+// parseData returns the value of the data, or the chain jumped to by a verdict.
+func parseData(data []byte) ([]byte, string, error) {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, attr := range attrs {
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case unix.NFTA_DATA_VALUE:
+			return bytes.Clone(attr.Value), "", nil
+		case unix.NFTA_DATA_VERDICT:
+			verdictAttrs, err := nl.ParseRouteAttr(attr.Value)
+			if err != nil {
+				return nil, "", err
+			}
+			for _, verdictAttr := range verdictAttrs {
+				if verdictAttr.Attr.Type&nl.NLA_TYPE_MASK == unix.NFTA_VERDICT_CHAIN {
+					return nil, string(bytes.TrimRight(verdictAttr.Value, "\x00")), nil
+				}
+			}
+		}
+	}
+	return nil, "", nil
+}
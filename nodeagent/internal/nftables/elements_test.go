@@ -0,0 +1,74 @@
+package nftables
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseElements(t *testing.T) {
+	// the kernel nests the elements without the nested flag
+	data := []byte{
+		0x01, 0x00, 0x00, 0x00, // inet family
+		0x0a, 0x00, 0x01, 0x00, 'b', 'a', 'e', 'p', 'o', 0x00, 0x00, 0x00, // table
+		0x24, 0x00, 0x03, 0x00, // elements
+		0x10, 0x00, 0x01, 0x00, // element
+		0x0c, 0x00, 0x01, 0x00, // key
+		0x08, 0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x02,
+		0x10, 0x00, 0x01, 0x00,
+		0x0c, 0x00, 0x01, 0x00,
+		0x08, 0x00, 0x01, 0x00, 0xc0, 0xa8, 0x64, 0x05,
+	}
+
+	elements, err := parseElements(data)
+	if err != nil {
+		t.Fatalf("parseElements() error = %v", err)
+	}
+
+	want := []Element{{Key: []byte{0x0a, 0x00, 0x00, 0x02}}, {Key: []byte{0xc0, 0xa8, 0x64, 0x05}}}
+	if !reflect.DeepEqual(elements, want) {
+		t.Errorf("parseElements() = %v, want %v", elements, want)
+	}
+}
+
+func TestParseMapElements(t *testing.T) {
+	data := []byte{
+		0x01, 0x00, 0x00, 0x00,
+		0x48, 0x00, 0x03, 0x00,
+		0x28, 0x00, 0x01, 0x00, // verdict map element
+		0x0c, 0x00, 0x01, 0x00,
+		0x08, 0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x02,
+		0x18, 0x00, 0x02, 0x00, // data
+		0x14, 0x00, 0x02, 0x00, // verdict
+		0x08, 0x00, 0x01, 0x00, 0xff, 0xff, 0xff, 0xfd,
+		0x06, 0x00, 0x02, 0x00, 'c', 0x00, 0x00, 0x00,
+		0x1c, 0x00, 0x01, 0x00, // map element
+		0x0c, 0x00, 0x01, 0x00,
+		0x08, 0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x03,
+		0x0c, 0x00, 0x02, 0x00,
+		0x08, 0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x04,
+	}
+
+	elements, err := parseElements(data)
+	if err != nil {
+		t.Fatalf("parseElements() error = %v", err)
+	}
+
+	want := []Element{
+		{Key: []byte{0x0a, 0x00, 0x00, 0x02}, Chain: "c"},
+		{Key: []byte{0x0a, 0x00, 0x00, 0x03}, Data: []byte{0x0a, 0x00, 0x00, 0x04}},
+	}
+	if !reflect.DeepEqual(elements, want) {
+		t.Errorf("parseElements() = %v, want %v", elements, want)
+	}
+}
+
+func TestParseElementsErrors(t *testing.T) {
+	if _, err := parseElements([]byte{0x01, 0x00}); err == nil {
+		t.Error("parseElements() error = nil for a truncated message")
+	}
+
+	// the element list claims more bytes than there are
+	if _, err := parseElements([]byte{0x01, 0x00, 0x00, 0x00, 0x20, 0x00, 0x03, 0x00, 0x00, 0x00}); err == nil {
+		t.Error("parseElements() error = nil for a truncated attribute")
+	}
+}
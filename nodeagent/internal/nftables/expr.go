@@ -0,0 +1,209 @@
+package nftables
+
+import (
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// Expr is an expression of a rule.
+type Expr = *nl.RtAttr
+
+// Hooks of the base chains, the inet and bridge families number them the same way.
+const (
+	HookPrerouting  = unix.NF_INET_PRE_ROUTING
+	HookInput       = unix.NF_INET_LOCAL_IN
+	HookForward     = unix.NF_INET_FORWARD
+	HookOutput      = unix.NF_INET_LOCAL_OUT
+	HookPostrouting = unix.NF_INET_POST_ROUTING
+)
+
+// Verdicts of the Verdict expression, the netfilter ones are not exported by x/sys/unix.
+const (
+	VerdictDrop   = 0
+	VerdictAccept = 1
+	VerdictReturn = unix.NFT_RETURN
+	VerdictJump   = unix.NFT_JUMP
+)
+
+// Registers, a 16 bytes register spans four 32 bits registers. Concatenated keys are loaded
+// in consecutive 32 bits registers, each part rounded up to 4 bytes.
+const (
+	Reg1    = unix.NFT_REG_1
+	Reg32_0 = unix.NFT_REG32_00
+)
+
+// Data types of the set keys as known by the nft command.
+const (
+	TypeIPAddr    = 7
+	TypeIP6Addr   = 8
+	TypeEtherAddr = 9
+	TypeInetProto = 12
+	TypeInetServ  = 13
+	TypeIfName    = 41
+)
+
+// ConcatType is the type of a key made of the given types.
+func ConcatType(types ...uint32) uint32 {
+	var concat uint32
+	for _, t := range types {
+		concat = concat<<6 | t
+	}
+	return concat
+}
+
+// Concat builds a concatenated key, each part is padded to a register boundary.
+func Concat(parts ...[]byte) []byte {
+	var key []byte
+	for _, part := range parts {
+		key = append(key, part...)
+		for len(key)%4 != 0 {
+			key = append(key, 0)
+		}
+	}
+	return key
+}
+
+// IfName is the interface name as loaded by the meta expression, padded to IFNAMSIZ.
+func IfName(name string) []byte {
+	data := make([]byte, unix.IFNAMSIZ)
+	copy(data, name)
+	return data
+}
+
+func Meta(key uint32, dreg uint32) Expr {
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_META_DREG, nl.BEUint32Attr(dreg))
+	data.AddRtAttr(unix.NFTA_META_KEY, nl.BEUint32Attr(key))
+	return expr("meta", data)
+}
+
+func Payload(base, offset, length, dreg uint32) Expr {
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_PAYLOAD_DREG, nl.BEUint32Attr(dreg))
+	data.AddRtAttr(unix.NFTA_PAYLOAD_BASE, nl.BEUint32Attr(base))
+	data.AddRtAttr(unix.NFTA_PAYLOAD_OFFSET, nl.BEUint32Attr(offset))
+	data.AddRtAttr(unix.NFTA_PAYLOAD_LEN, nl.BEUint32Attr(length))
+	return expr("payload", data)
+}
+
+// Ct loads a conntrack key, the state is a bitmask in host byte order.
+func Ct(key uint32, dreg uint32) Expr {
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_CT_DREG, nl.BEUint32Attr(dreg))
+	data.AddRtAttr(unix.NFTA_CT_KEY, nl.BEUint32Attr(key))
+	return expr("ct", data)
+}
+
+func Cmp(op uint32, sreg uint32, value []byte) Expr {
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_CMP_SREG, nl.BEUint32Attr(sreg))
+	data.AddRtAttr(unix.NFTA_CMP_OP, nl.BEUint32Attr(op))
+	data.AddChild(dataValue(unix.NFTA_CMP_DATA, value))
+	return expr("cmp", data)
+}
+
+// Bitwise computes (reg & mask) ^ xor in place.
+func Bitwise(reg uint32, mask, xor []byte) Expr {
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_BITWISE_SREG, nl.BEUint32Attr(reg))
+	data.AddRtAttr(unix.NFTA_BITWISE_DREG, nl.BEUint32Attr(reg))
+	data.AddRtAttr(unix.NFTA_BITWISE_LEN, nl.BEUint32Attr(uint32(len(mask))))
+	data.AddChild(dataValue(unix.NFTA_BITWISE_MASK, mask))
+	data.AddChild(dataValue(unix.NFTA_BITWISE_XOR, xor))
+	return expr("bitwise", data)
+}
+
+// Lookup matches when the key in sreg is in the set, or when it is not once inverted.
+func Lookup(set *Set, sreg uint32, invert bool) Expr {
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_LOOKUP_SET, nl.ZeroTerminated(set.Name))
+	if set.id != 0 {
+		data.AddRtAttr(unix.NFTA_LOOKUP_SET_ID, nl.BEUint32Attr(set.id))
+	}
+	data.AddRtAttr(unix.NFTA_LOOKUP_SREG, nl.BEUint32Attr(sreg))
+	if invert {
+		data.AddRtAttr(unix.NFTA_LOOKUP_FLAGS, nl.BEUint32Attr(unix.NFT_LOOKUP_F_INV))
+	}
+	return expr("lookup", data)
+}
+
+// VerdictMap jumps to the chain the key in sreg maps to, the rule goes on when the key is not in
+// the map.
+func VerdictMap(set *Set, sreg uint32) Expr {
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_LOOKUP_SET, nl.ZeroTerminated(set.Name))
+	if set.id != 0 {
+		data.AddRtAttr(unix.NFTA_LOOKUP_SET_ID, nl.BEUint32Attr(set.id))
+	}
+	data.AddRtAttr(unix.NFTA_LOOKUP_SREG, nl.BEUint32Attr(sreg))
+	data.AddRtAttr(unix.NFTA_LOOKUP_DREG, nl.BEUint32Attr(unix.NFT_REG_VERDICT))
+	return expr("lookup", data)
+}
+
+// MapLookup loads the value the key in sreg maps to in dreg, the rule stops matching when the key
+// is not in the map.
+func MapLookup(set *Set, sreg, dreg uint32) Expr {
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_LOOKUP_SET, nl.ZeroTerminated(set.Name))
+	if set.id != 0 {
+		data.AddRtAttr(unix.NFTA_LOOKUP_SET_ID, nl.BEUint32Attr(set.id))
+	}
+	data.AddRtAttr(unix.NFTA_LOOKUP_SREG, nl.BEUint32Attr(sreg))
+	data.AddRtAttr(unix.NFTA_LOOKUP_DREG, nl.BEUint32Attr(dreg))
+	return expr("lookup", data)
+}
+
+// Verdict ends the evaluation of the rule, chain is only used by VerdictJump.
+func Verdict(code int32, chain string) Expr {
+	immediate := nested(unix.NFTA_IMMEDIATE_DATA)
+	immediate.AddChild(verdict(code, chain))
+
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_IMMEDIATE_DREG, nl.BEUint32Attr(unix.NFT_REG_VERDICT))
+	data.AddChild(immediate)
+	return expr("immediate", data)
+}
+
+func Masquerade() Expr {
+	return expr("masq", nil)
+}
+
+// DNAT translates the destination of an ipv4 packet to the address in addrReg and the port in
+// protoReg.
+func DNAT(addrReg, protoReg uint32) Expr {
+	data := nested(unix.NFTA_EXPR_DATA)
+	data.AddRtAttr(unix.NFTA_NAT_TYPE, nl.BEUint32Attr(unix.NFT_NAT_DNAT))
+	data.AddRtAttr(unix.NFTA_NAT_FAMILY, nl.BEUint32Attr(unix.NFPROTO_IPV4))
+	data.AddRtAttr(unix.NFTA_NAT_REG_ADDR_MIN, nl.BEUint32Attr(addrReg))
+	data.AddRtAttr(unix.NFTA_NAT_REG_PROTO_MIN, nl.BEUint32Attr(protoReg))
+	return expr("nat", data)
+}
+
+// Counter counts the packets and bytes reaching it, see ListCounters.
+func Counter() Expr {
+	return expr("counter", nested(unix.NFTA_EXPR_DATA))
+}
+
+func expr(name string, data *nl.RtAttr) Expr {
+	attr := nested(unix.NFTA_LIST_ELEM)
+	attr.AddRtAttr(unix.NFTA_EXPR_NAME, nl.ZeroTerminated(name))
+	if data != nil {
+		attr.AddChild(data)
+	}
+	return attr
+}
+
+func verdict(code int32, chain string) *nl.RtAttr {
+	attr := nested(unix.NFTA_DATA_VERDICT)
+	attr.AddRtAttr(unix.NFTA_VERDICT_CODE, nl.BEUint32Attr(uint32(code)))
+	if chain != "" {
+		attr.AddRtAttr(unix.NFTA_VERDICT_CHAIN, nl.ZeroTerminated(chain))
+	}
+	return attr
+}
+
+func dataValue(attrType int, value []byte) *nl.RtAttr {
+	attr := nested(attrType)
+	attr.AddRtAttr(unix.NFTA_DATA_VALUE, value)
+	return attr
+}
@@ -0,0 +1,120 @@
+package nftables
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// The fixtures are the attributes as sent to the kernel, the netlink headers are in the byte
+// order of the host and are written for little endian hosts.
+
+func TestExprEncoding(t *testing.T) {
+	set := &Set{Table: &Table{Family: FamilyINet, Name: "baepo"}, Name: "m"}
+	tests := []struct {
+		name string
+		expr Expr
+		want []byte
+	}{
+		{
+			name: "jump verdict",
+			expr: Verdict(VerdictJump, "fwd"),
+			want: []byte{
+				0x38, 0x00, 0x01, 0x80, // list element
+				0x0e, 0x00, 0x01, 0x00, 'i', 'm', 'm', 'e', 'd', 'i', 'a', 't', 'e', 0x00, 0x00, 0x00, // name
+				0x24, 0x00, 0x02, 0x80, // data
+				0x08, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, // verdict register
+				0x18, 0x00, 0x02, 0x80, // immediate data
+				0x14, 0x00, 0x02, 0x80, // verdict
+				0x08, 0x00, 0x01, 0x00, 0xff, 0xff, 0xff, 0xfd, // code
+				0x08, 0x00, 0x02, 0x00, 'f', 'w', 'd', 0x00, // chain
+			},
+		},
+		{
+			name: "accept verdict",
+			expr: Verdict(VerdictAccept, ""),
+			want: []byte{
+				0x30, 0x00, 0x01, 0x80,
+				0x0e, 0x00, 0x01, 0x00, 'i', 'm', 'm', 'e', 'd', 'i', 'a', 't', 'e', 0x00, 0x00, 0x00,
+				0x1c, 0x00, 0x02, 0x80,
+				0x08, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x10, 0x00, 0x02, 0x80,
+				0x0c, 0x00, 0x02, 0x80,
+				0x08, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x01,
+			},
+		},
+		{
+			name: "cmp with padded value",
+			expr: Cmp(unix.NFT_CMP_EQ, Reg1, []byte{0x0a, 0x00}),
+			want: []byte{
+				0x2c, 0x00, 0x01, 0x80,
+				0x08, 0x00, 0x01, 0x00, 'c', 'm', 'p', 0x00,
+				0x20, 0x00, 0x02, 0x80,
+				0x08, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x01, // source register
+				0x08, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, // operation
+				0x0c, 0x00, 0x03, 0x80, // compared data
+				0x06, 0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x00, // value, padded to 4 bytes
+			},
+		},
+		{
+			name: "meta",
+			expr: Meta(unix.NFT_META_IIFNAME, Reg1),
+			want: []byte{
+				0x24, 0x00, 0x01, 0x80,
+				0x09, 0x00, 0x01, 0x00, 'm', 'e', 't', 'a', 0x00, 0x00, 0x00, 0x00,
+				0x14, 0x00, 0x02, 0x80,
+				0x08, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x01, // destination register
+				0x08, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x06, // key
+			},
+		},
+		{
+			name: "inverted lookup",
+			expr: Lookup(set, Reg1, true),
+			want: []byte{
+				0x2c, 0x00, 0x01, 0x80,
+				0x0b, 0x00, 0x01, 0x00, 'l', 'o', 'o', 'k', 'u', 'p', 0x00, 0x00,
+				0x1c, 0x00, 0x02, 0x80,
+				0x06, 0x00, 0x01, 0x00, 'm', 0x00, 0x00, 0x00, // set name
+				0x08, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x01, // source register
+				0x08, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x01, // inverted
+			},
+		},
+		{
+			name: "masquerade",
+			expr: Masquerade(),
+			want: []byte{
+				0x10, 0x00, 0x01, 0x80,
+				0x09, 0x00, 0x01, 0x00, 'm', 'a', 's', 'q', 0x00, 0x00, 0x00, 0x00,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.expr.Serialize(); !bytes.Equal(got, test.want) {
+				t.Errorf("Serialize() =\n% x\nwant\n% x", got, test.want)
+			}
+		})
+	}
+}
+
+func TestConcat(t *testing.T) {
+	got := Concat([]byte{0x01, 0x02, 0x03}, []byte{0x04, 0x05}, []byte{0x06, 0x07, 0x08, 0x09})
+	want := []byte{0x01, 0x02, 0x03, 0x00, 0x04, 0x05, 0x00, 0x00, 0x06, 0x07, 0x08, 0x09}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Concat() = % x, want % x", got, want)
+	}
+
+	if got := ConcatType(TypeIPAddr, TypeInetServ); got != TypeIPAddr<<6|TypeInetServ {
+		t.Errorf("ConcatType() = %d, want %d", got, TypeIPAddr<<6|TypeInetServ)
+	}
+}
+
+func TestIfName(t *testing.T) {
+	got := IfName("tap1")
+	want := append([]byte("tap1"), make([]byte, unix.IFNAMSIZ-4)...)
+	if !bytes.Equal(got, want) {
+		t.Errorf("IfName() = % x, want % x", got, want)
+	}
+}
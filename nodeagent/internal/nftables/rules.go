@@ -0,0 +1,122 @@
+package nftables
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// ListChains returns the names of the chains of the table, none when the table does not exist.
+func ListChains(table *Table) ([]string, error) {
+	msg := newMessage(unix.NFNL_SUBSYS_NFTABLES<<8|unix.NFT_MSG_GETCHAIN, table.Family, unix.NLM_F_DUMP)
+
+	var chains []string
+	err := dump(msg, unix.NFT_MSG_NEWCHAIN, "chains of table "+table.Name, func(data []byte) error {
+		tableName, name, err := parseChain(data)
+		if err == nil && tableName == table.Name {
+			chains = append(chains, name)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chains, nil
+}
+
+// ListRules returns the names of the expressions of each rule of the chain, in the order of its
+// rules. The kernel adds defaults to the data of some expressions, so only their names are
+// compared with the rules of a batch, see ExprNames.
+func ListRules(chain *Chain) ([][]string, error) {
+	msg := newMessage(unix.NFNL_SUBSYS_NFTABLES<<8|unix.NFT_MSG_GETRULE, chain.Table.Family, unix.NLM_F_DUMP)
+	msg.AddData(nl.NewRtAttr(unix.NFTA_RULE_TABLE, nl.ZeroTerminated(chain.Table.Name)))
+	msg.AddData(nl.NewRtAttr(unix.NFTA_RULE_CHAIN, nl.ZeroTerminated(chain.Name)))
+
+	var rules [][]string
+	err := dump(msg, unix.NFT_MSG_NEWRULE, "rules of chain "+chain.Name, func(data []byte) error {
+		names, err := parseRuleExprNames(data)
+		rules = append(rules, names)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ExprNames returns the names of the expressions of the rule, as listed by ListRules.
+func ExprNames(rule *Rule) []string {
+	names := make([]string, 0, len(rule.Exprs))
+	for _, expr := range rule.Exprs {
+		attrs, err := nl.ParseRouteAttr(expr.Serialize()[unix.SizeofRtAttr:])
+		if err != nil {
+			continue
+		}
+		for _, attr := range attrs {
+			if attr.Attr.Type&nl.NLA_TYPE_MASK == unix.NFTA_EXPR_NAME {
+				names = append(names, string(bytes.TrimRight(attr.Value, "\x00")))
+			}
+		}
+	}
+	return names
+}
+
+func parseChain(data []byte) (string, string, error) {
+	if len(data) < nl.SizeofNfgenmsg {
+		return "", "", errors.New("truncated chain")
+	}
+
+	attrs, err := nl.ParseRouteAttr(data[nl.SizeofNfgenmsg:])
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse chain: %w", err)
+	}
+
+	var table, name string
+	for _, attr := range attrs {
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case unix.NFTA_CHAIN_TABLE:
+			table = string(bytes.TrimRight(attr.Value, "\x00"))
+		case unix.NFTA_CHAIN_NAME:
+			name = string(bytes.TrimRight(attr.Value, "\x00"))
+		}
+	}
+	return table, name, nil
+}
+
+func parseRuleExprNames(data []byte) ([]string, error) {
+	if len(data) < nl.SizeofNfgenmsg {
+		return nil, errors.New("truncated rule")
+	}
+
+	attrs, err := nl.ParseRouteAttr(data[nl.SizeofNfgenmsg:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rule: %w", err)
+	}
+
+	names := []string{}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != unix.NFTA_RULE_EXPRESSIONS {
+			continue
+		}
+
+		elements, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rule expressions: %w", err)
+		}
+		for _, element := range elements {
+			exprAttrs, err := nl.ParseRouteAttr(element.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse expression: %w", err)
+			}
+			for _, exprAttr := range exprAttrs {
+				if exprAttr.Attr.Type&nl.NLA_TYPE_MASK == unix.NFTA_EXPR_NAME {
+					names = append(names, string(bytes.TrimRight(exprAttr.Value, "\x00")))
+				}
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,55 @@
+package nftables
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParseChain(t *testing.T) {
+	data := []byte{
+		0x01, 0x00, 0x00, 0x00, // inet family
+		0x0a, 0x00, 0x01, 0x00, 'b', 'a', 'e', 'p', 'o', 0x00, 0x00, 0x00, // table
+		0x0c, 0x00, 0x03, 0x00, 'f', 'o', 'r', 'w', 'a', 'r', 'd', 0x00, // name
+	}
+
+	table, name, err := parseChain(data)
+	if err != nil {
+		t.Fatalf("parseChain() error = %v", err)
+	} else if table != "baepo" || name != "forward" {
+		t.Errorf("parseChain() = %q, %q, want %q, %q", table, name, "baepo", "forward")
+	}
+}
+
+func TestParseRuleExprNames(t *testing.T) {
+	data := []byte{
+		0x01, 0x00, 0x00, 0x00, // inet family
+		0x0a, 0x00, 0x01, 0x00, 'b', 'a', 'e', 'p', 'o', 0x00, 0x00, 0x00, // table
+		0x28, 0x00, 0x04, 0x00, // expressions
+		0x10, 0x00, 0x01, 0x00,
+		0x09, 0x00, 0x01, 0x00, 'm', 'e', 't', 'a', 0x00, 0x00, 0x00, 0x00,
+		0x14, 0x00, 0x01, 0x00,
+		0x0e, 0x00, 0x01, 0x00, 'i', 'm', 'm', 'e', 'd', 'i', 'a', 't', 'e', 0x00, 0x00, 0x00,
+	}
+
+	names, err := parseRuleExprNames(data)
+	if err != nil {
+		t.Fatalf("parseRuleExprNames() error = %v", err)
+	} else if want := []string{"meta", "immediate"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("parseRuleExprNames() = %v, want %v", names, want)
+	}
+}
+
+func TestExprNames(t *testing.T) {
+	rule := &Rule{Exprs: []Expr{
+		Meta(unix.NFT_META_IIFNAME, Reg1),
+		Cmp(unix.NFT_CMP_EQ, Reg1, IfName("tap1")),
+		Counter(),
+		Verdict(VerdictAccept, ""),
+	}}
+
+	if got, want := ExprNames(rule), []string{"meta", "cmp", "counter", "immediate"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExprNames() = %v, want %v", got, want)
+	}
+}
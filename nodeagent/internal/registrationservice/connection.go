@@ -9,9 +9,11 @@ import (
 	"time"
 
 	"connectrpc.com/connect"
-	"github.com/baepo-cloud/baepo-node/core/v1pbadapter"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	apiv1pb "github.com/baepo-cloud/baepo-proto/go/baepo/api/v1"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type (
@@ -41,6 +43,8 @@ func (s *Service) openConnection(ctx context.Context) error {
 	conn.nodeID = registration.NodeId
 	conn.log = s.log.With(slog.String("node-id", registration.NodeId))
 	conn.log.Info("node registration completed")
+	metrics.RegistrationConnected.Set(1)
+	defer metrics.RegistrationConnected.Set(0)
 
 	if err = conn.startMachineEventListener(ctx); err != nil {
 		return fmt.Errorf("failed to start machine event listener: %w", err)
@@ -84,15 +88,51 @@ func (c *Connection) dispatchLoop(ctx context.Context) error {
 	}
 }
 
-func (c *Connection) handleIncomingEvent(ctx context.Context, anyEvent *apiv1pb.NodeControllerServerEvent) error {
+func (c *Connection) handleIncomingEvent(ctx context.Context, anyEvent *apiv1pb.NodeControllerServerEvent) (err error) {
+	// events share a single stream, each of them is traced on its own
+	ctx, span := tracing.Start(ctx, "registrationservice.handleIncomingEvent",
+		attribute.String("event.type", fmt.Sprintf("%T", anyEvent.Event)))
+	defer func() { tracing.End(span, err) }()
+
 	switch event := anyEvent.Event.(type) {
 	case *apiv1pb.NodeControllerServerEvent_CreateMachine:
+		// the control plane has no dedicated spec update event, it sends the machine again along
+		// with its desired state
+		if machine, err := c.service.machineService.FindByID(ctx, event.CreateMachine.MachineId); err == nil {
+			desiredState, known := toMachineDesiredState(event.CreateMachine.DesiredState, machine)
+			if !known {
+				c.log.Warn("unknown desired state, leaving machine as is",
+					slog.String("machine-id", machine.ID),
+					slog.Any("desired-state", event.CreateMachine.DesiredState))
+				return c.updateMachineSpec(ctx, event.CreateMachine)
+			}
+
+			_, err = c.updateMachine(ctx, machine, desiredState, event.CreateMachine)
+			return err
+		}
+
 		_, err := c.createMachine(ctx, event.CreateMachine)
+		if errors.Is(err, types.ErrInsufficientCapacity) {
+			return c.sendMachineRejectedEvent(event.CreateMachine.MachineId, err)
+		}
 		return err
 	case *apiv1pb.NodeControllerServerEvent_UpdateMachineDesiredState:
-		_, err := c.service.machineService.UpdateDesiredState(ctx, types.MachineUpdateDesiredStateOptions{
-			MachineID:    event.UpdateMachineDesiredState.MachineId,
-			DesiredState: v1pbadapter.ToMachineDesiredState(event.UpdateMachineDesiredState.DesiredState),
+		machine, err := c.service.machineService.FindByID(ctx, event.UpdateMachineDesiredState.MachineId)
+		if err != nil {
+			return err
+		}
+
+		desiredState, known := toMachineDesiredState(event.UpdateMachineDesiredState.DesiredState, machine)
+		if !known {
+			c.log.Warn("unknown desired state, leaving machine as is",
+				slog.String("machine-id", machine.ID),
+				slog.Any("desired-state", event.UpdateMachineDesiredState.DesiredState))
+			return nil
+		}
+
+		_, err = c.service.machineService.UpdateDesiredState(ctx, types.MachineUpdateDesiredStateOptions{
+			MachineID:    machine.ID,
+			DesiredState: desiredState,
 		})
 		return err
 	case *apiv1pb.NodeControllerServerEvent_Ping:
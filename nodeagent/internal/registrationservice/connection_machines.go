@@ -7,11 +7,13 @@ import (
 	"log/slog"
 
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
 	"github.com/baepo-cloud/baepo-node/core/v1pbadapter"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
 	apiv1pb "github.com/baepo-cloud/baepo-proto/go/baepo/api/v1"
 	corev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/core/v1"
 	"github.com/nrednav/cuid2"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -65,34 +67,40 @@ func (c *Connection) syncMachines(ctx context.Context, machines []*apiv1pb.NodeC
 }
 
 func (c *Connection) syncMachineFromSpec(ctx context.Context, spec *apiv1pb.NodeControllerServerEvent_Machine) (*types.Machine, error) {
-	desiredState := v1pbadapter.ToMachineDesiredState(spec.DesiredState)
-	log := c.log.With(slog.String("machine-id", spec.MachineId), slog.Any("desired-state", desiredState))
+	log := c.log.With(slog.String("machine-id", spec.MachineId), slog.Any("desired-state", spec.DesiredState))
 	machine, err := c.service.machineService.FindByID(ctx, spec.MachineId)
+	if err != nil && !errors.Is(err, types.ErrMachineNotFound) {
+		return nil, fmt.Errorf("failed to find machine: %w", err)
+	}
+
+	desiredState, known := toMachineDesiredState(spec.DesiredState, machine)
+	if !known {
+		log.Warn("unknown desired state, leaving machine as is")
+		return machine, nil
+	}
+
 	shouldSendFakeTerminationEvents := false
-	if errors.Is(err, types.ErrMachineNotFound) {
+	if machine == nil {
 		if spec.DesiredState == corev1pb.MachineDesiredState_MachineDesiredState_Terminated {
 			shouldSendFakeTerminationEvents = true
 		} else {
 			log.Info("missing machine, creating")
 			machine, err = c.createMachine(ctx, spec)
-			if err != nil {
+			if errors.Is(err, types.ErrInsufficientCapacity) {
+				log.Warn("machine rejected", slog.Any("error", err))
+				return nil, c.sendMachineRejectedEvent(spec.MachineId, err)
+			} else if err != nil {
 				return nil, fmt.Errorf("failed to create machine: %w", err)
 			}
 		}
-	} else if err != nil {
-		return nil, fmt.Errorf("failed to find machine: %w", err)
-	} else if current := machine.DesiredState; current != desiredState {
-		log.Info("desired state mismatch, updating", slog.Any("current-desired-state", current))
-		machine, err = c.service.machineService.UpdateDesiredState(ctx, types.MachineUpdateDesiredStateOptions{
-			MachineID:    spec.MachineId,
-			DesiredState: desiredState,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to update machine desired state: %w", err)
-		}
+	} else if machine, err = c.updateMachine(ctx, machine, desiredState, spec); err != nil {
+		return nil, err
 	}
 
-	previousEvents, err := c.service.machineService.ListEvents(ctx, spec.MachineId)
+	previousEvents, err := c.service.machineService.ListEvents(ctx, types.MachineListEventsOptions{
+		MachineID: spec.MachineId,
+		Limit:     c.service.config.Events.ReplayLimit,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list previous machine events: %w", err)
 	}
@@ -144,9 +152,14 @@ func (c *Connection) syncMachineFromSpec(ctx context.Context, spec *apiv1pb.Node
 }
 
 func (c *Connection) createMachine(ctx context.Context, machine *apiv1pb.NodeControllerServerEvent_Machine) (*types.Machine, error) {
+	desiredState, known := toMachineDesiredState(machine.DesiredState, nil)
+	if !known {
+		return nil, fmt.Errorf("unknown desired state: %v", machine.DesiredState)
+	}
+
 	opts := types.MachineCreateOptions{
 		MachineID:    machine.MachineId,
-		DesiredState: v1pbadapter.ToMachineDesiredState(machine.DesiredState),
+		DesiredState: desiredState,
 		Spec:         (*types.MachineSpec)(v1pbadapter.ToMachineSpec(machine.Spec)),
 		Containers:   make([]types.MachineCreateContainerOptions, len(machine.Containers)),
 	}
@@ -160,6 +173,97 @@ func (c *Connection) createMachine(ctx context.Context, machine *apiv1pb.NodeCon
 	return c.service.machineService.Create(ctx, opts)
 }
 
+// toMachineDesiredState converts a desired state sent by the control plane for a machine, nil when
+// the node does not have it. A paused machine only lives in the memory of its runtime, so a machine
+// without runtime is stopped instead. It returns false for a state this node does not know about.
+func toMachineDesiredState(state corev1pb.MachineDesiredState, machine *types.Machine) (coretypes.MachineDesiredState, bool) {
+	desiredState := v1pbadapter.ToMachineDesiredState(state)
+	if desiredState == coretypes.MachineDesiredStateUnknown {
+		return desiredState, false
+	}
+
+	if desiredState == coretypes.MachineDesiredStatePaused && (machine == nil || !typeutil.Includes([]coretypes.MachineState{
+		coretypes.MachineStateRunning,
+		coretypes.MachineStateDegraded,
+		coretypes.MachineStatePaused,
+	}, machine.State)) {
+		return coretypes.MachineDesiredStateStopped, true
+	}
+
+	return desiredState, true
+}
+
+// updateMachine applies the desired state and the spec sent by the control plane to a machine the
+// node already has.
+func (c *Connection) updateMachine(ctx context.Context, machine *types.Machine, desiredState coretypes.MachineDesiredState, spec *apiv1pb.NodeControllerServerEvent_Machine) (*types.Machine, error) {
+	if current := machine.DesiredState; current != desiredState {
+		c.log.Info("desired state mismatch, updating", slog.String("machine-id", machine.ID),
+			slog.Any("desired-state", desiredState), slog.Any("current-desired-state", current))
+		updatedMachine, err := c.service.machineService.UpdateDesiredState(ctx, types.MachineUpdateDesiredStateOptions{
+			MachineID:    machine.ID,
+			DesiredState: desiredState,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update machine desired state: %w", err)
+		}
+		machine = updatedMachine
+	}
+
+	if err := c.updateMachineSpec(ctx, spec); err != nil {
+		return nil, err
+	}
+	return machine, nil
+}
+
+// updateMachineSpec resizes the machine when the spec sent by the control plane differs. Specs
+// that can not be applied are logged rather than failing the connection.
+func (c *Connection) updateMachineSpec(ctx context.Context, machine *apiv1pb.NodeControllerServerEvent_Machine) error {
+	if machine.Spec == nil {
+		return nil
+	}
+
+	_, err := c.service.machineService.UpdateSpec(ctx, types.MachineUpdateSpecOptions{
+		MachineID: machine.MachineId,
+		Cpus:      machine.Spec.Cpus,
+		MemoryMB:  machine.Spec.MemoryMb,
+	})
+	if errors.Is(err, types.ErrInvalidMachineSpec) || errors.Is(err, types.ErrInsufficientCapacity) ||
+		errors.Is(err, types.ErrMachineBusy) {
+		c.log.Warn("failed to update machine spec", slog.String("machine-id", machine.MachineId), slog.Any("error", err))
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to update machine spec: %w", err)
+	}
+
+	return nil
+}
+
+// sendMachineRejectedEvent reports a machine the node has no capacity for as terminated, so that
+// the control plane can schedule it somewhere else.
+func (c *Connection) sendMachineRejectedEvent(machineID string, reason error) error {
+	c.log.Warn("rejecting machine", slog.String("machine-id", machineID), slog.Any("error", reason))
+	err := c.stream.Send(&apiv1pb.NodeControllerClientEvent{
+		Event: &apiv1pb.NodeControllerClientEvent_Machine{
+			Machine: &corev1pb.MachineEvent{
+				EventId:   cuid2.Generate(),
+				MachineId: machineID,
+				Timestamp: timestamppb.Now(),
+				Event: &corev1pb.MachineEvent_Terminated{
+					Terminated: &corev1pb.MachineEvent_TerminatedEvent{
+						Cause:              corev1pb.MachineTerminationCause_MachineTerminationCause_NoNodeAvailable,
+						TerminationDetails: typeutil.Ptr(reason.Error()),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send machine rejected event: %w", err)
+	}
+
+	return nil
+}
+
 func (c *Connection) sendMachineEvent(ctx context.Context, event *types.MachineEvent) error {
 	anyProto, err := event.ProtoPayload()
 	if err != nil {
@@ -179,6 +283,16 @@ func (c *Connection) sendMachineEvent(ctx context.Context, event *types.MachineE
 				Container: proto,
 			},
 		})
+	case *structpb.Struct:
+		if event.Type != types.MachineEventTypePortsPublished {
+			return nil
+		}
+
+		portsEvent, err := newPortsPublishedEvent(event, proto)
+		if err != nil {
+			return err
+		}
+		return c.stream.Send(portsEvent)
 	default:
 		return nil
 	}
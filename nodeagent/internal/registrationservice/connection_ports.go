@@ -0,0 +1,42 @@
+package registrationservice
+
+import (
+	"fmt"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	apiv1pb "github.com/baepo-cloud/baepo-proto/go/baepo/api/v1"
+	corev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/core/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// publishedPortsFieldNumber is the field of the machine event carrying the published ports. The
+// core protocol does not declare it yet, a control plane unaware of it keeps the ports among the
+// unknown fields of the event. It is kept far from the declared fields so that the protocol can
+// grow without colliding with it.
+const publishedPortsFieldNumber protowire.Number = 1000
+
+// newPortsPublishedEvent wraps the published ports stored with the event in a machine event whose
+// event is left unset, the core protocol has no variant for them.
+func newPortsPublishedEvent(event *types.MachineEvent, ports *structpb.Struct) (*apiv1pb.NodeControllerClientEvent, error) {
+	payload, err := proto.Marshal(ports)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal published ports: %w", err)
+	}
+
+	machineEvent := &corev1pb.MachineEvent{
+		EventId:   event.ID,
+		MachineId: event.MachineID,
+		Timestamp: timestamppb.New(event.Timestamp),
+	}
+	field := protowire.AppendTag(nil, publishedPortsFieldNumber, protowire.BytesType)
+	machineEvent.ProtoReflect().SetUnknown(protowire.AppendBytes(field, payload))
+
+	return &apiv1pb.NodeControllerClientEvent{
+		Event: &apiv1pb.NodeControllerClientEvent_Machine{
+			Machine: machineEvent,
+		},
+	}, nil
+}
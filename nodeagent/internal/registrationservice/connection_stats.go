@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	apiv1pb "github.com/baepo-cloud/baepo-proto/go/baepo/api/v1"
-	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/mem"
 )
 
@@ -27,25 +26,18 @@ func (c *Connection) newStatsProto(ctx context.Context) (*apiv1pb.NodeController
 		return nil, err
 	}
 
-	cpuInfo, err := cpu.Info()
+	capacity, err := c.service.machineService.GetCapacity(ctx)
 	if err != nil {
-		return nil, err
-	}
-
-	machines, err := c.service.machineService.List(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list machines: %w", err)
-	}
-
-	reservedMemoryMB := uint64(0)
-	for _, machine := range machines {
-		reservedMemoryMB += machine.Spec.MemoryMB
+		return nil, fmt.Errorf("failed to get node capacity: %w", err)
 	}
 
+	// the protocol has no field for the allocatable memory, machines the node can not fit are
+	// rejected on creation and rescheduled by the control plane. It has none for the warm pools
+	// either, their size and hits are only reported by the node api.
 	return &apiv1pb.NodeControllerClientEvent_Stats{
-		TotalMemoryMb:    memInfo.Total / 1024 / 1024,
+		TotalMemoryMb:    capacity.TotalMemoryMB,
 		UsedMemoryMb:     memInfo.Used / 1024 / 1024,
-		ReservedMemoryMb: reservedMemoryMB,
-		CpuCount:         uint32(len(cpuInfo)),
+		ReservedMemoryMb: capacity.ReservedMemoryMB,
+		CpuCount:         capacity.TotalCpus,
 	}, nil
 }
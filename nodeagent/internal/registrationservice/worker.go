@@ -2,16 +2,21 @@ package registrationservice
 
 import (
 	"context"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
 	"log/slog"
 	"time"
 )
 
 func (s *Service) startRegistrationWorker(ctx context.Context) {
-	for {
+	for attempt := 0; ; attempt++ {
 		select {
 		case <-ctx.Done():
 			return
 		default:
+			if attempt > 0 {
+				metrics.RegistrationReconnects.Inc()
+			}
+
 			connCtx, cancelConn := context.WithCancel(ctx)
 			err := s.openConnection(connCtx)
 			cancelConn()
@@ -0,0 +1,56 @@
+package runtimeservice
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"go.opentelemetry.io/otel/attribute"
+	"os"
+)
+
+// Adopt hands a runtime started with deferred containers over to a machine. Its directory becomes
+// the one of the machine, with a link left in place for the paths the hypervisor was started with,
+// and the vm is configured for the machine. The runtime is terminated when it can not be adopted.
+func (s *Service) Adopt(ctx context.Context, opts types.RuntimeAdoptOptions) (err error) {
+	ctx, span := tracing.Start(ctx, "runtimeservice.Adopt",
+		attribute.String("machine.id", opts.Machine.ID),
+		attribute.String("runtime.id", opts.RuntimeID))
+	defer func() { tracing.End(span, err) }()
+
+	runtimeDir := s.GetMachineDirectory(opts.RuntimeID)
+	machineDir := s.GetMachineDirectory(opts.Machine.ID)
+	if err = os.Rename(runtimeDir, machineDir); err != nil {
+		_ = s.Terminate(context.Background(), opts.RuntimeID)
+		return fmt.Errorf("failed to move runtime directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = s.Terminate(context.Background(), opts.Machine.ID)
+		}
+	}()
+
+	if err = os.Symlink(machineDir, runtimeDir); err != nil {
+		return fmt.Errorf("failed to link runtime directory: %w", err)
+	}
+
+	if err = s.createRuntimeConfigFile(ctx, types.RuntimeStartOptions{Machine: opts.Machine}); err != nil {
+		return fmt.Errorf("failed to create runtime config file: %w", err)
+	}
+
+	config, err := s.newRuntimeConfig(ctx, types.RuntimeStartOptions{Machine: opts.Machine})
+	if err != nil {
+		return fmt.Errorf("failed to create runtime config: %w", err)
+	}
+
+	client, closeClient := s.GetAPIClient(opts.Machine.ID)
+	defer closeClient()
+
+	if _, err = client.Adopt(ctx, connect.NewRequest(&nodeapi.RuntimeAdoptRequest{Config: config})); err != nil {
+		return fmt.Errorf("failed to adopt vm: %w", err)
+	}
+
+	return nil
+}
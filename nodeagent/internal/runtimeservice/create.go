@@ -5,17 +5,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"go.opentelemetry.io/otel/attribute"
 	"io"
 	"log/slog"
+	"net"
 	"os"
 	"os/exec"
 	"syscall"
 )
 
-func (s *Service) Start(ctx context.Context, opts types.RuntimeStartOptions) error {
-	if err := s.createRuntimeConfigFile(opts); err != nil {
+func (s *Service) Start(ctx context.Context, opts types.RuntimeStartOptions) (err error) {
+	ctx, span := tracing.Start(ctx, "runtimeservice.Start", attribute.String("machine.id", opts.Machine.ID))
+	defer func() { tracing.End(span, err) }()
+
+	if err = s.createRuntimeConfigFile(ctx, opts); err != nil {
 		return fmt.Errorf("failed to create runtime config file: %w", err)
 	}
 
@@ -46,15 +52,37 @@ func (s *Service) Start(ctx context.Context, opts types.RuntimeStartOptions) err
 	return nil
 }
 
-func (s *Service) createRuntimeConfigFile(opts types.RuntimeStartOptions) error {
-	runtimeDir := s.GetMachineDirectory(opts.Machine.ID)
-	_ = os.MkdirAll(runtimeDir, 0644)
+func (s *Service) createRuntimeConfigFile(ctx context.Context, opts types.RuntimeStartOptions) error {
+	_ = os.MkdirAll(s.GetMachineDirectory(opts.Machine.ID), 0644)
+
+	initConfig, err := s.newRuntimeConfig(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	configPath := s.getRuntimeConfigPath(opts.Machine.ID)
+	configFile, err := os.Create(configPath)
+	if err != nil {
+		return err
+	}
+
+	defer configFile.Close()
+	if err = json.NewEncoder(configFile).Encode(initConfig); err != nil {
+		return err
+	}
+
+	return nil
+}
 
+func (s *Service) newRuntimeConfig(ctx context.Context, opts types.RuntimeStartOptions) (coretypes.RuntimeConfig, error) {
+	runtimeDir := s.GetMachineDirectory(opts.Machine.ID)
 	initConfig := coretypes.RuntimeConfig{
-		WorkingDir: runtimeDir,
-		MachineID:  opts.Machine.ID,
-		Cpus:       opts.Machine.Spec.Cpus,
-		MemoryMB:   opts.Machine.Spec.MemoryMB,
+		WorkingDir:      runtimeDir,
+		MachineID:       opts.Machine.ID,
+		Cpus:            opts.Machine.Spec.Cpus,
+		MemoryMB:        opts.Machine.Spec.MemoryMB,
+		MaxCpus:         s.config.MachineMaxCpus,
+		HotplugMemoryMB: s.config.MachineHotplugMemoryMB,
 		Network: coretypes.RuntimeNetworkConfig{
 			InterfaceName:  opts.Machine.NetworkInterface.Name,
 			IPAddress:      opts.Machine.NetworkInterface.IPAddress,
@@ -63,7 +91,26 @@ func (s *Service) createRuntimeConfigFile(opts types.RuntimeStartOptions) error
 			GatewayAddress: opts.Machine.NetworkInterface.GatewayAddress,
 			Hostname:       opts.Machine.ID,
 		},
-		Containers: make([]coretypes.RuntimeContainerConfig, len(opts.Machine.Containers)),
+		Containers:      make([]coretypes.RuntimeContainerConfig, len(opts.Machine.Containers)),
+		RestoreFrom:     opts.RestoreFrom,
+		DeferContainers: opts.DeferContainers,
+		Tracing:         s.config.Tracing,
+		TraceContext:    tracing.Inject(ctx),
+	}
+	if networkInterface := opts.Machine.NetworkInterface; networkInterface.IPv6Prefix != nil {
+		ipv6Prefix := networkInterface.IPv6Prefix.ToNetIPNet()
+		initConfig.Network.IPv6Address = networkInterface.IPv6Address
+		initConfig.Network.IPv6GatewayAddress = networkInterface.IPv6GatewayAddress
+		initConfig.Network.IPv6Prefix = &ipv6Prefix
+	}
+	// the machines ask the resolver of the node on the gateway, unless it is disabled
+	if dns := s.config.Network.DNS; dns.Disabled {
+		for _, upstream := range dns.Upstreams {
+			initConfig.Network.Nameservers = append(initConfig.Network.Nameservers, net.ParseIP(upstream))
+		}
+	} else {
+		initConfig.Network.Nameservers = []net.IP{opts.Machine.NetworkInterface.GatewayAddress}
+		initConfig.Network.SearchDomain = dns.Domain
 	}
 	containerVolumes := map[string]*types.MachineVolume{}
 	for _, machineVolume := range opts.Machine.Volumes {
@@ -73,7 +120,7 @@ func (s *Service) createRuntimeConfigFile(opts types.RuntimeStartOptions) error
 	for index, container := range opts.Machine.Containers {
 		volume, ok := containerVolumes[container.ID]
 		if !ok {
-			return fmt.Errorf("failed to find machine volume")
+			return initConfig, fmt.Errorf("failed to find machine volume")
 		}
 
 		imageSpec := volume.Image.Spec
@@ -111,18 +158,7 @@ func (s *Service) createRuntimeConfigFile(opts types.RuntimeStartOptions) error
 		}
 	}
 
-	configPath := s.getRuntimeConfigPath(opts.Machine.ID)
-	configFile, err := os.Create(configPath)
-	if err != nil {
-		return err
-	}
-
-	defer configFile.Close()
-	if err = json.NewEncoder(configFile).Encode(initConfig); err != nil {
-		return err
-	}
-
-	return nil
+	return initConfig, nil
 }
 
 func pipeToLogger(r io.Reader, logger *slog.Logger, level slog.Level, stream string) {
@@ -0,0 +1,110 @@
+package runtimeservice
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"net"
+	"os"
+	"path"
+	"syscall"
+	"time"
+)
+
+var runtimeSocketNames = []string{"runtime.sock", "vmm.socket"}
+
+func (s *Service) GC(ctx context.Context, opts types.RuntimeGCOptions) ([]types.GCResource, error) {
+	entries, err := os.ReadDir(s.getRuntimesDirectory())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list runtime directories: %w", err)
+	}
+
+	var resources []types.GCResource
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink != 0 {
+			// the link left by an adopted runtime goes away along with the directory of the machine
+			linkPath := path.Join(s.getRuntimesDirectory(), entry.Name())
+			if _, err = os.Stat(linkPath); errors.Is(err, os.ErrNotExist) {
+				resource := types.GCResource{Kind: types.GCResourceKindRuntime, Name: entry.Name()}
+				if !opts.DryRun {
+					resource.Error = os.Remove(linkPath)
+				}
+				resources = append(resources, resource)
+			}
+			continue
+		} else if !entry.IsDir() {
+			continue
+		}
+
+		machineID := entry.Name()
+		if typeutil.Includes(opts.IdleMachineIDs, machineID) {
+			resources = append(resources, s.collectStaleSockets(machineID, opts.DryRun)...)
+		}
+		if typeutil.Includes(opts.KeepMachineIDs, machineID) {
+			continue
+		}
+
+		resource := types.GCResource{Kind: types.GCResourceKindRuntime, Name: machineID}
+		if !opts.DryRun {
+			resource.Error = s.removeRuntime(ctx, machineID)
+		}
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+func (s *Service) removeRuntime(ctx context.Context, machineID string) error {
+	client, closeClient := s.GetClient(machineID)
+	_, err := client.GetState(ctx, connect.NewRequest(&emptypb.Empty{}))
+	closeClient()
+	if err == nil {
+		// the runtime outlived its controller, it must be stopped before its directory goes away
+		if err = s.Terminate(ctx, machineID); err != nil {
+			return fmt.Errorf("failed to terminate orphan runtime: %w", err)
+		}
+	}
+
+	if err = os.RemoveAll(s.GetMachineDirectory(machineID)); err != nil {
+		return fmt.Errorf("failed to remove runtime directory: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) collectStaleSockets(machineID string, dryRun bool) []types.GCResource {
+	var resources []types.GCResource
+	for _, socketName := range runtimeSocketNames {
+		socketPath := path.Join(s.GetMachineDirectory(machineID), socketName)
+		if !isStaleSocket(socketPath) {
+			continue
+		}
+
+		resource := types.GCResource{Kind: types.GCResourceKindSocket, Name: socketPath}
+		if !dryRun {
+			resource.Error = os.Remove(socketPath)
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+func isStaleSocket(socketPath string) bool {
+	if _, err := os.Stat(socketPath); err != nil {
+		return false
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED)
+	}
+
+	_ = conn.Close()
+	return false
+}
@@ -2,6 +2,8 @@ package runtimeservice
 
 import (
 	"context"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	"github.com/baepo-cloud/baepo-proto/go/baepo/node/v1/nodev1pbconnect"
 	"net"
 	"net/http"
@@ -10,23 +12,45 @@ import (
 )
 
 func (s *Service) GetClient(machineID string) (nodev1pbconnect.RuntimeClient, func()) {
+	httpClient, closeConns := s.newHTTPClient(machineID, 5*time.Second)
+	return nodev1pbconnect.NewRuntimeClient(httpClient, "http://runtime", tracing.Interceptor()), closeConns
+}
+
+// GetAPIClient returns a client without response header timeout, operations such as snapshots
+// can take a while and are bounded by the request context instead.
+func (s *Service) GetAPIClient(machineID string) (nodeapi.RuntimeClient, func()) {
+	httpClient, closeConns := s.newHTTPClient(machineID, 0)
+	return nodeapi.NewRuntimeClient(httpClient, "http://runtime", tracing.Interceptor()), closeConns
+}
+
+// GetStreamAPIClient returns a client speaking http/2, which bidirectional streams such as exec
+// require. Streams are bounded by the request context.
+func (s *Service) GetStreamAPIClient(machineID string) (nodeapi.RuntimeClient, func()) {
+	httpClient, closeConns := s.newHTTPClientWithTransport(machineID, &http.Transport{
+		Protocols: nodeapi.StreamClientProtocols(),
+	})
+	return nodeapi.NewRuntimeClient(httpClient, "http://runtime", tracing.Interceptor()), closeConns
+}
+
+func (s *Service) newHTTPClient(machineID string, responseHeaderTimeout time.Duration) (*http.Client, func()) {
+	return s.newHTTPClientWithTransport(machineID, &http.Transport{
+		IdleConnTimeout:       10 * time.Second,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+	})
+}
+
+func (s *Service) newHTTPClientWithTransport(machineID string, transport *http.Transport) (*http.Client, func()) {
 	var conns []net.Conn
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
-				conn, err := net.Dial("unix", path.Join(s.GetMachineDirectory(machineID), "runtime.sock"))
-				if err != nil {
-					return nil, err
-				}
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		conn, err := net.Dial("unix", path.Join(s.GetMachineDirectory(machineID), "runtime.sock"))
+		if err != nil {
+			return nil, err
+		}
 
-				conns = append(conns, conn)
-				return conn, nil
-			},
-			IdleConnTimeout:       10 * time.Second,
-			ResponseHeaderTimeout: 5 * time.Second,
-		},
+		conns = append(conns, conn)
+		return conn, nil
 	}
-	return nodev1pbconnect.NewRuntimeClient(httpClient, "http://runtime"), func() {
+	return &http.Client{Transport: transport}, func() {
 		for _, conn := range conns {
 			_ = conn.Close()
 		}
@@ -0,0 +1,30 @@
+package runtimeservice
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+)
+
+func (s *Service) Pause(ctx context.Context, machineID string) error {
+	client, closeClient := s.GetAPIClient(machineID)
+	defer closeClient()
+
+	if _, err := client.Pause(ctx, connect.NewRequest(&nodeapi.RuntimePauseRequest{})); err != nil {
+		return fmt.Errorf("failed to pause vm: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) Resume(ctx context.Context, machineID string) error {
+	client, closeClient := s.GetAPIClient(machineID)
+	defer closeClient()
+
+	if _, err := client.Resume(ctx, connect.NewRequest(&nodeapi.RuntimeResumeRequest{})); err != nil {
+		return fmt.Errorf("failed to resume vm: %w", err)
+	}
+
+	return nil
+}
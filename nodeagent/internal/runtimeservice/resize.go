@@ -0,0 +1,26 @@
+package runtimeservice
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (s *Service) Resize(ctx context.Context, machineID string, cpus uint32, memoryMB uint64) error {
+	client, closeClient := s.GetAPIClient(machineID)
+	defer closeClient()
+
+	_, err := client.Resize(ctx, connect.NewRequest(&nodeapi.RuntimeResizeRequest{
+		Cpus:     cpus,
+		MemoryMB: memoryMB,
+	}))
+	if connect.CodeOf(err) == connect.CodeInvalidArgument {
+		return fmt.Errorf("%w: %v", types.ErrInvalidMachineSpec, err)
+	} else if err != nil {
+		return fmt.Errorf("failed to resize vm: %w", err)
+	}
+
+	return nil
+}
@@ -21,7 +21,11 @@ func New(config *types.Config) *Service {
 }
 
 func (s *Service) GetMachineDirectory(machineID string) string {
-	return path.Join(s.config.StorageDirectory, "runtimes", machineID)
+	return path.Join(s.getRuntimesDirectory(), machineID)
+}
+
+func (s *Service) getRuntimesDirectory() string {
+	return path.Join(s.config.StorageDirectory, "runtimes")
 }
 
 func (s *Service) getRuntimeConfigPath(machineID string) string {
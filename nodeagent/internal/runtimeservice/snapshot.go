@@ -0,0 +1,20 @@
+package runtimeservice
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+)
+
+func (s *Service) Snapshot(ctx context.Context, machineID string, directory string) error {
+	client, closeClient := s.GetAPIClient(machineID)
+	defer closeClient()
+
+	_, err := client.Snapshot(ctx, connect.NewRequest(&nodeapi.RuntimeSnapshotRequest{Directory: directory}))
+	if err != nil {
+		return fmt.Errorf("failed to snapshot vm: %w", err)
+	}
+
+	return nil
+}
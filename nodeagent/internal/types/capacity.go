@@ -0,0 +1,48 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// NodeCapacity describes the resources machines can be scheduled on. Allocatable is the host
+	// capacity minus the system reservation, multiplied by the overcommit ratio.
+	NodeCapacity struct {
+		TotalCpus           uint32
+		AllocatableCpus     uint32
+		ReservedCpus        uint32
+		TotalMemoryMB       uint64
+		AllocatableMemoryMB uint64
+		ReservedMemoryMB    uint64
+		TotalDiskMB         uint64
+		AllocatableDiskMB   uint64
+		ReservedDiskMB      uint64
+	}
+
+	CapacityResource string
+
+	// InsufficientCapacityError is returned when admitting a machine would exceed the node capacity.
+	InsufficientCapacityError struct {
+		Resource  CapacityResource
+		Requested uint64
+		Available uint64
+	}
+)
+
+const (
+	CapacityResourceCpu    CapacityResource = "cpu"
+	CapacityResourceMemory CapacityResource = "memory"
+	CapacityResourceDisk   CapacityResource = "disk"
+)
+
+// ErrInsufficientCapacity is returned when the node can not fit the requested resources.
+var ErrInsufficientCapacity = errors.New("insufficient node capacity")
+
+func (e *InsufficientCapacityError) Error() string {
+	return fmt.Sprintf("%v: %v requested %d, available %d", ErrInsufficientCapacity, e.Resource, e.Requested, e.Available)
+}
+
+func (e *InsufficientCapacityError) Is(target error) bool {
+	return target == ErrInsufficientCapacity
+}
@@ -1,14 +1,151 @@
 package types
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+)
+
 type Config struct {
-	Debug            bool
-	ClusterID        string
-	BootstrapToken   string
-	IPAddr           string
-	APIAddr          string
-	GatewayAddr      string
-	StorageDirectory string
-	RuntimeBinary    string
-	VolumeGroup      string
-	ControlPlaneURL  string
+	Debug          bool   `yaml:"debug"`
+	ClusterID      string `yaml:"cluster_id"`
+	BootstrapToken string `yaml:"bootstrap_token"`
+	IPAddr         string `yaml:"ip_addr"`
+	APIAddr        string `yaml:"api_addr"`
+	GatewayAddr    string `yaml:"gateway_addr"`
+	// MetricsAddr is where the prometheus metrics are served, they are not served when empty.
+	MetricsAddr      string `yaml:"metrics_addr"`
+	StorageDirectory string `yaml:"storage_directory"`
+	RuntimeBinary    string `yaml:"runtime_binary"`
+	VolumeGroup      string `yaml:"volume_group"`
+	ControlPlaneURL  string `yaml:"control_plane_url"`
+	GCDryRun         bool   `yaml:"gc_dry_run"`
+	// MachineMaxCpus is the number of vcpus a running machine can be resized up to.
+	MachineMaxCpus uint32 `yaml:"machine_max_cpus"`
+	// MachineHotplugMemoryMB is the memory a running machine can be given on top of its spec, the
+	// memory of a running machine cannot change when zero.
+	MachineHotplugMemoryMB uint64 `yaml:"machine_hotplug_memory_mb"`
+	// SnapshotDirectory is where snapshots are stored, they go into the machine runtime directory when empty.
+	SnapshotDirectory string           `yaml:"snapshot_directory"`
+	Events            EventsConfig     `yaml:"events"`
+	Admission         AdmissionConfig  `yaml:"admission"`
+	WarmPools         []WarmPoolConfig `yaml:"warm_pools"`
+	Network           NetworkConfig    `yaml:"network"`
+	// Tracing is also passed to the runtimes and the init of the machines.
+	Tracing coretypes.TracingConfig `yaml:"tracing"`
+}
+
+type EventsConfig struct {
+	// Retention is the maximum age of an event, the latest state events of a machine are always kept.
+	Retention time.Duration `yaml:"retention"`
+	// MaxPerMachine is the maximum number of events kept for a single machine.
+	MaxPerMachine int `yaml:"max_per_machine"`
+	// TerminatedRetention is how long events of a terminated machine are kept.
+	TerminatedRetention time.Duration `yaml:"terminated_retention"`
+	// ReplayLimit is the maximum number of events per machine sent to the control plane on reconnect.
+	ReplayLimit int `yaml:"replay_limit"`
+}
+
+type AdmissionConfig struct {
+	// SystemReservedCpus, SystemReservedMemoryMB and SystemReservedDiskMB are kept for the host
+	// and never given to machines.
+	SystemReservedCpus     uint32 `yaml:"system_reserved_cpus"`
+	SystemReservedMemoryMB uint64 `yaml:"system_reserved_memory_mb"`
+	SystemReservedDiskMB   uint64 `yaml:"system_reserved_disk_mb"`
+	// CpuOvercommitRatio and MemoryOvercommitRatio multiply the allocatable resources, a ratio
+	// of 1 disables overcommit.
+	CpuOvercommitRatio    float64 `yaml:"cpu_overcommit_ratio"`
+	MemoryOvercommitRatio float64 `yaml:"memory_overcommit_ratio"`
+}
+
+type NetworkConfig struct {
+	// CIDR is the network of the machines, its first address is given to the bridge.
+	CIDR string `yaml:"cidr"`
+	// IPv6Prefix is the optional ipv6 network of the machines, each machine gets the address at
+	// the offset of its ipv4 address and the first address is given to the bridge.
+	IPv6Prefix      string `yaml:"ipv6_prefix"`
+	BridgeInterface string `yaml:"bridge_interface"`
+	// UplinkInterface carries the traffic of the machines, it is detected from the default route when empty.
+	UplinkInterface string `yaml:"uplink_interface"`
+	// BlockedNetworks can not be reached by the machines through the uplink.
+	BlockedNetworks []string `yaml:"blocked_networks"`
+	// FirewallBackend applies the firewall rules, iptables when empty.
+	FirewallBackend FirewallBackend `yaml:"firewall_backend"`
+	// HostPortRange is the inclusive range the published ports without a host port are
+	// allocated from, for instance 30000-32767.
+	HostPortRange string `yaml:"host_port_range"`
+	// PrivateNetworks are the networks a machine can be assigned to instead of the default one,
+	// the machines of different networks can not reach each other.
+	PrivateNetworks []PrivateNetworkConfig `yaml:"private_networks"`
+	DNS             DNSConfig              `yaml:"dns"`
+	// ReconcileInterval is how often the tap interfaces and the firewall rules are compared with
+	// the allocated interfaces, they are also compared when the node agent starts.
+	ReconcileInterval time.Duration `yaml:"reconcile_interval"`
+}
+
+// DNSConfig is the resolver of the machines, served on the gateway address of every network.
+type DNSConfig struct {
+	// Disabled gives the upstream resolvers to the machines instead of the gateway address, the
+	// names of the machines are then not resolved.
+	Disabled bool `yaml:"disabled"`
+	// Domain is the search domain of the machines, the names of the machines resolve with and
+	// without it.
+	Domain string `yaml:"domain"`
+	// Upstreams are the ip addresses of the resolvers the other names are forwarded to, in order.
+	Upstreams []string `yaml:"upstreams"`
+	// CacheSize is the number of forwarded answers kept, an answer is kept at most for its ttl.
+	CacheSize int `yaml:"cache_size"`
+}
+
+// PrivateNetworkConfig is an ipv4 only network with a bridge of its own.
+type PrivateNetworkConfig struct {
+	Name string `json:"name" yaml:"name"`
+	// ID numbers the tap interfaces and the mac addresses of the machines of the network, it must
+	// not change while machines are assigned to the network.
+	ID              uint8  `json:"id" yaml:"id"`
+	CIDR            string `json:"cidr" yaml:"cidr"`
+	BridgeInterface string `json:"bridge_interface" yaml:"bridge_interface"`
+}
+
+// ParseHostPortRange returns the first and the last port of the host port range.
+func (c NetworkConfig) ParseHostPortRange() (uint16, uint16, error) {
+	first, last, ok := strings.Cut(c.HostPortRange, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid port range %q", c.HostPortRange)
+	}
+
+	firstPort, err := strconv.ParseUint(first, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid first port: %w", err)
+	}
+	lastPort, err := strconv.ParseUint(last, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid last port: %w", err)
+	} else if firstPort == 0 || firstPort > lastPort {
+		return 0, 0, fmt.Errorf("invalid port range %q", c.HostPortRange)
+	}
+	return uint16(firstPort), uint16(lastPort), nil
+}
+
+type FirewallBackend string
+
+const (
+	// FirewallBackendIptables shells out to iptables, ip6tables, ebtables and arptables.
+	FirewallBackendIptables FirewallBackend = "iptables"
+	// FirewallBackendNftables keeps the rules in dedicated nftables tables updated over netlink.
+	FirewallBackendNftables FirewallBackend = "nftables"
+)
+
+type WarmPoolConfig struct {
+	Image string `json:"image" yaml:"image"`
+	// Images are the images of the containers of multi-container machines, in order. Image is
+	// the shorthand for single container machines.
+	Images   []string `json:"images" yaml:"images"`
+	Cpus     uint32   `json:"cpus" yaml:"cpus"`
+	MemoryMB uint64   `json:"memory_mb" yaml:"memory_mb"`
+	// Size is the number of idle machines kept ready for this image and size.
+	Size int `json:"size" yaml:"size"`
 }
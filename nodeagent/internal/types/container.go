@@ -3,6 +3,7 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	"time"
 )
@@ -19,6 +20,8 @@ type (
 	ContainerSpec coretypes.ContainerSpec
 )
 
+var ErrContainerNotFound = errors.New("container not found")
+
 func (*ContainerSpec) GormDataType() string {
 	return "jsonb"
 }
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+)
+
+type (
+	GCResourceKind string
+
+	GCOptions struct {
+		DryRun bool
+	}
+
+	GCResource struct {
+		Kind  GCResourceKind
+		Name  string
+		Error error
+	}
+
+	GCReport struct {
+		DryRun    bool
+		Resources []GCResource
+		Errors    []error
+		StartedAt time.Time
+		Duration  time.Duration
+	}
+)
+
+const (
+	GCResourceKindRuntime          GCResourceKind = "runtime"
+	GCResourceKindSocket           GCResourceKind = "socket"
+	GCResourceKindVolume           GCResourceKind = "volume"
+	GCResourceKindNetworkInterface GCResourceKind = "network_interface"
+	GCResourceKindSnapshot         GCResourceKind = "snapshot"
+)
+
+func (r *GCReport) Count(kind GCResourceKind) (collected int, failed int) {
+	for _, resource := range r.Resources {
+		if resource.Kind != kind {
+			continue
+		} else if resource.Error != nil {
+			failed++
+		} else {
+			collected++
+		}
+	}
+	return collected, failed
+}
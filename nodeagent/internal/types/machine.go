@@ -1,14 +1,18 @@
 package types
 
 import (
+	"connectrpc.com/connect"
 	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	corev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/core/v1"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"io"
 	"time"
 )
 
@@ -22,8 +26,15 @@ type (
 		Volumes            []*MachineVolume
 		Containers         []*Container
 		NetworkInterface   *NetworkInterface
+		PublishedPorts     []*PublishedPort
 		CreatedAt          time.Time
+		StartedAt          *time.Time
 		TerminatedAt       *time.Time
+		// RestoreFrom is the vm snapshot directory the next runtime start restores from.
+		RestoreFrom *string
+		// AdoptFrom is the runtime of the warm pool vm the next runtime start takes over instead of
+		// booting a vm, the machine is created with the volumes and network interface of the vm.
+		AdoptFrom *string
 	}
 
 	MachineEventType string
@@ -37,6 +48,9 @@ type (
 		Container   *Container
 		Payload     []byte
 		Timestamp   time.Time
+		// CollapsedCount is the number of consecutive container health flaps merged into
+		// this event, the payload always reflects the latest one.
+		CollapsedCount int `gorm:"not null;default:0"`
 	}
 
 	MachineSpec coretypes.MachineSpec
@@ -72,6 +86,9 @@ type (
 		DesiredState coretypes.MachineDesiredState
 		Spec         *MachineSpec
 		Containers   []MachineCreateContainerOptions
+		// Network is the private network the machine is assigned to, the default network of the
+		// node when empty.
+		Network string
 	}
 
 	MachineCreateContainerOptions struct {
@@ -84,6 +101,23 @@ type (
 		DesiredState coretypes.MachineDesiredState
 	}
 
+	MachineUpdateSpecOptions struct {
+		MachineID string
+		Cpus      uint32
+		MemoryMB  uint64
+		// Ingress replaces the ingress policy of the machine, the current one is kept when nil
+		// unless ClearIngress is set.
+		Ingress      *coretypes.MachineIngressPolicy
+		ClearIngress bool
+	}
+
+	MachineListEventsOptions struct {
+		MachineID string
+		// Limit bounds the result to the most recent events and the latest event of each type,
+		// zero means no limit.
+		Limit int
+	}
+
 	MachineGetMachineLogsOptions struct {
 		MachineID string
 		Follow    bool
@@ -95,6 +129,52 @@ type (
 		Follow      bool
 	}
 
+	MachineExecOptions struct {
+		MachineID string
+		// Container is the id or the name of the container, the first container of the machine
+		// is used when empty.
+		Container string
+	}
+
+	// MachineExecSession is an exec stream opened on the runtime of a machine.
+	MachineExecSession struct {
+		ContainerID string
+		Stream      *connect.BidiStreamForClient[nodeapi.ExecRequest, nodeapi.ExecResponse]
+		// Close releases the runtime connection once the stream is done.
+		Close func()
+	}
+
+	MachineArchiveOptions struct {
+		MachineID string
+		// Container is the id or the name of the container, the first container of the machine
+		// is used when empty.
+		Container string
+		Path      string
+	}
+
+	// MachineConsoleSession is a serial console stream opened on the runtime of a machine.
+	MachineConsoleSession struct {
+		Stream *connect.BidiStreamForClient[nodeapi.ConsoleRequest, nodeapi.ConsoleResponse]
+		// Close releases the runtime connection once the stream is done.
+		Close func()
+	}
+
+	MachinePortForwardOptions struct {
+		MachineID string
+		// Container is the id or the name of the container, the first container of the machine
+		// is used when empty.
+		Container string
+	}
+
+	// MachinePortForwardSession is a port forward stream opened on the runtime of a machine, it
+	// tunnels a single connection.
+	MachinePortForwardSession struct {
+		ContainerID string
+		Stream      *connect.BidiStreamForClient[nodeapi.PortForwardRequest, nodeapi.PortForwardResponse]
+		// Close releases the runtime connection once the stream is done.
+		Close func()
+	}
+
 	MachineService interface {
 		List(ctx context.Context) ([]*Machine, error)
 
@@ -104,13 +184,49 @@ type (
 
 		UpdateDesiredState(ctx context.Context, opts MachineUpdateDesiredStateOptions) (*Machine, error)
 
-		ListEvents(ctx context.Context, machineID string) ([]*MachineEvent, error)
+		UpdateSpec(ctx context.Context, opts MachineUpdateSpecOptions) (*Machine, error)
+
+		GetIngressStats(ctx context.Context, machineID string) (*NetworkIngressStats, error)
+
+		Pause(ctx context.Context, machineID string) (*Machine, error)
+
+		Resume(ctx context.Context, machineID string) (*Machine, error)
+
+		CreateSnapshot(ctx context.Context, opts MachineCreateSnapshotOptions) (*Snapshot, error)
+
+		ListSnapshots(ctx context.Context, opts MachineListSnapshotsOptions) ([]*Snapshot, error)
+
+		RestoreSnapshot(ctx context.Context, snapshotID string) (*Machine, error)
+
+		DeleteSnapshot(ctx context.Context, snapshotID string) error
+
+		ListEvents(ctx context.Context, opts MachineListEventsOptions) ([]*MachineEvent, error)
 
 		SubscribeToEvents(ctx context.Context) <-chan *MachineEvent
 
 		GetMachineLogs(ctx context.Context, opts MachineGetMachineLogsOptions) (<-chan MachineLog, error)
 
 		GetContainerLogs(ctx context.Context, opts MachineGetContainerLogsOptions) (<-chan MachineContainerLog, error)
+
+		Exec(ctx context.Context, opts MachineExecOptions) (*MachineExecSession, error)
+
+		AttachConsole(ctx context.Context, machineID string) (*MachineConsoleSession, error)
+
+		PortForward(ctx context.Context, opts MachinePortForwardOptions) (*MachinePortForwardSession, error)
+
+		GetStats(ctx context.Context, machineID string, interval time.Duration, fn func(*nodeapi.MachineStats) error) error
+
+		UploadArchive(ctx context.Context, opts MachineArchiveOptions, archive io.Reader) error
+
+		DownloadArchive(ctx context.Context, opts MachineArchiveOptions, archive io.Writer) error
+
+		PerformGC(ctx context.Context, opts GCOptions) (*GCReport, error)
+
+		CompactEvents(ctx context.Context) error
+
+		GetWarmPoolStats(ctx context.Context) ([]WarmPoolStats, error)
+
+		GetCapacity(ctx context.Context) (*NodeCapacity, error)
 	}
 )
 
@@ -118,9 +234,33 @@ const (
 	MachineEventTypeStateChanged          MachineEventType = "state_changed"
 	MachineEventTypeDesiredStateChanged   MachineEventType = "desired_state_changed"
 	MachineEventTypeContainerStateChanged MachineEventType = "container_state_changed"
+	MachineEventTypeStarted               MachineEventType = "started"
+	MachineEventTypeExpired               MachineEventType = "expired"
+	MachineEventTypeTerminated            MachineEventType = "terminated"
+	MachineEventTypeSpecChanged           MachineEventType = "spec_changed"
+	MachineEventTypePortsPublished        MachineEventType = "ports_published"
 )
 
-var ErrMachineNotFound = errors.New("machine not found")
+var (
+	ErrMachineNotFound   = errors.New("machine not found")
+	ErrMachineNotRunning = errors.New("machine not running")
+	ErrMachineNotPaused  = errors.New("machine not paused")
+	ErrMachineNotStopped = errors.New("machine not stopped")
+	ErrMachineBusy       = errors.New("machine is being reconciled")
+	// ErrInvalidMachineSpec is returned when a spec is out of the limits of the machine.
+	ErrInvalidMachineSpec = errors.New("invalid machine spec")
+)
+
+// ExpiresAt returns the moment the machine exceeds its timeout, or nil when the
+// machine has no timeout or has not started yet.
+func (m *Machine) ExpiresAt() *time.Time {
+	if m.Spec == nil || m.Spec.Timeout == nil || m.StartedAt == nil {
+		return nil
+	}
+
+	expiresAt := m.StartedAt.Add(time.Duration(*m.Spec.Timeout) * time.Second)
+	return &expiresAt
+}
 
 func (*MachineSpec) GormDataType() string {
 	return "jsonb"
@@ -146,12 +286,25 @@ func (e MachineEvent) ProtoPayload() (proto.Message, error) {
 			return nil, err
 		}
 		return &event, nil
-	case MachineEventTypeDesiredStateChanged, MachineEventTypeStateChanged:
+	case MachineEventTypeDesiredStateChanged, MachineEventTypeStateChanged, MachineEventTypeStarted,
+		MachineEventTypeTerminated:
 		var event corev1pb.MachineEvent
 		if err := proto.Unmarshal(e.Payload, &event); err != nil {
 			return nil, err
 		}
 		return &event, nil
+	case MachineEventTypeSpecChanged:
+		var spec corev1pb.MachineSpec
+		if err := proto.Unmarshal(e.Payload, &spec); err != nil {
+			return nil, err
+		}
+		return &spec, nil
+	case MachineEventTypePortsPublished, MachineEventTypeExpired:
+		var ports structpb.Struct
+		if err := proto.Unmarshal(e.Payload, &ports); err != nil {
+			return nil, err
+		}
+		return &ports, nil
 	default:
 		return nil, fmt.Errorf("unknown proto type: %v", e.Type)
 	}
@@ -3,7 +3,9 @@ package types
 import (
 	"context"
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	"net"
 	"time"
 )
@@ -18,23 +20,170 @@ type (
 		MacAddress     net.HardwareAddr `gorm:"type:text"`
 		GatewayAddress net.IP           `gorm:"type:text"`
 		NetworkCIDR    *GormNetIPNet    `gorm:"column:network_cidr"`
-		AllocatedAt    *time.Time
-		ReleasedAt     *time.Time
-		CreatedAt      time.Time
+		// Network is the name of the private network of the interface, empty for the default
+		// network.
+		Network string
+		// IPv6Address, IPv6GatewayAddress and IPv6Prefix are only set when the node has an ipv6 prefix.
+		IPv6Address        net.IP        `gorm:"column:ipv6_address;type:text"`
+		IPv6GatewayAddress net.IP        `gorm:"column:ipv6_gateway_address;type:text"`
+		IPv6Prefix         *GormNetIPNet `gorm:"column:ipv6_prefix"`
+		// IngressPolicy is the policy of the machine enforced by the firewall, all the traffic
+		// reaches the machine when nil.
+		IngressPolicy *NetworkIngressPolicy
+		AllocatedAt   *time.Time
+		ReleasedAt    *time.Time
+		CreatedAt     time.Time
+	}
+
+	NetworkIngressPolicy coretypes.MachineIngressPolicy
+
+	// NetworkIngressStats counts the traffic reaching an interface, the replies to the
+	// connections opened by the machine are not counted.
+	NetworkIngressStats struct {
+		DefaultDeny bool
+		// Rules are the counters of the rules of the policy, in the same order.
+		Rules []NetworkIngressCounter
+		// Unmatched counts the traffic allowed by no rule, which is dropped with a default deny.
+		Unmatched NetworkIngressCounter
+	}
+
+	NetworkIngressCounter struct {
+		Packets uint64
+		Bytes   uint64
+	}
+
+	// PublishedPort forwards a host port of the node address to a container port of a machine.
+	PublishedPort struct {
+		ID                 string `gorm:"primaryKey"`
+		MachineID          string
+		ContainerID        string
+		NetworkInterfaceID string
+		Protocol           coretypes.ContainerPortProtocol `gorm:"uniqueIndex:idx_published_ports_host_port"`
+		HostPort           uint16                          `gorm:"uniqueIndex:idx_published_ports_host_port"`
+		ContainerPort      uint16
+		// GuestAddress is the ipv4 address of the machine the traffic is forwarded to.
+		GuestAddress net.IP `gorm:"type:text"`
+		CreatedAt    time.Time
+	}
+
+	NetworkPublishPortsOptions struct {
+		MachineID        string
+		NetworkInterface *NetworkInterface
+		Ports            []NetworkPublishPortOptions
+	}
+
+	NetworkPublishPortOptions struct {
+		ContainerID string
+		Spec        coretypes.ContainerPortSpec
+	}
+
+	NetworkDriftKind string
+
+	// NetworkDrift is a difference between the state of the kernel and the allocated interfaces,
+	// found and repaired by a reconciliation.
+	NetworkDrift struct {
+		Kind NetworkDriftKind
+		// Interface is empty for the rules of the bridges and for the rules of the ports which
+		// are not published.
+		Interface string
+		// Error is set when the drift could not be repaired.
+		Error error
+	}
+
+	NetworkPoolStats struct {
+		Allocated int
+		Free      int
 	}
 
 	NetworkProvider interface {
 		GetInterface(name string) (*NetworkInterface, error)
 
-		AllocateInterface(ctx context.Context) (*NetworkInterface, error)
+		// AllocateInterface takes an address of the named private network, or of the default
+		// network when the name is empty.
+		AllocateInterface(ctx context.Context, network string) (*NetworkInterface, error)
+
+		// SetupInterface creates the tap interface and enforces the ingress policy on it.
+		SetupInterface(ctx context.Context, networkInterface *NetworkInterface, ingress *coretypes.MachineIngressPolicy) error
+
+		UpdateIngressPolicy(ctx context.Context, networkInterface *NetworkInterface, ingress *coretypes.MachineIngressPolicy) error
 
-		SetupInterface(ctx context.Context, networkInterface *NetworkInterface) error
+		GetIngressStats(ctx context.Context, networkInterface *NetworkInterface) (*NetworkIngressStats, error)
 
 		ReleaseInterface(ctx context.Context, networkInterface *NetworkInterface) error
+
+		// PublishPorts makes the published ports of a machine the given ones, the host ports
+		// already allocated to the machine are kept.
+		PublishPorts(ctx context.Context, opts NetworkPublishPortsOptions) ([]*PublishedPort, error)
+
+		// UnpublishPorts releases every host port allocated to the machine.
+		UnpublishPorts(ctx context.Context, machineID string) error
+
+		GC(ctx context.Context, opts GCOptions) ([]GCResource, error)
+
+		GetPoolStats() NetworkPoolStats
+
+		// Reconcile makes the tap interfaces and the firewall rules match the allocated
+		// interfaces, it returns the drifts it found.
+		Reconcile(ctx context.Context) ([]NetworkDrift, error)
+
+		// GetGatewayAddresses returns the ipv4 gateway address of every network, where the
+		// resolver of the machines listens.
+		GetGatewayAddresses() []net.IP
 	}
 )
 
-var ErrNetworkInterfaceNotFound = errors.New("network interface not found")
+const (
+	// NetworkDriftKindMissingLink is an allocated interface without tap interface, it is created.
+	NetworkDriftKindMissingLink NetworkDriftKind = "missing_link"
+	// NetworkDriftKindLinkConfig is a tap interface which is down, has another mac address or is
+	// not on the bridge of its network, it is fixed.
+	NetworkDriftKindLinkConfig NetworkDriftKind = "link_config"
+	// NetworkDriftKindOrphanLink is a tap interface on a bridge without allocated interface, it is
+	// deleted.
+	NetworkDriftKindOrphanLink NetworkDriftKind = "orphan_link"
+	// NetworkDriftKindMissingRules is an allocated interface without all its firewall rules,
+	// they are applied again.
+	NetworkDriftKindMissingRules NetworkDriftKind = "missing_rules"
+	// NetworkDriftKindOrphanRules are firewall rules of an interface which is not allocated, they
+	// are removed.
+	NetworkDriftKindOrphanRules NetworkDriftKind = "orphan_rules"
+	// NetworkDriftKindBridgeRules is a rule of the bridges, the address translation or the
+	// isolation of the networks which is missing or was changed, the rules are applied again.
+	NetworkDriftKindBridgeRules NetworkDriftKind = "bridge_rules"
+	// NetworkDriftKindMissingPortRules is a published port which is not forwarded, its rules are
+	// applied again.
+	NetworkDriftKindMissingPortRules NetworkDriftKind = "missing_port_rules"
+	// NetworkDriftKindOrphanPortRules is a forwarded port which is not published, its rules are
+	// removed.
+	NetworkDriftKindOrphanPortRules NetworkDriftKind = "orphan_port_rules"
+)
+
+var (
+	ErrNetworkInterfaceNotFound = errors.New("network interface not found")
+	ErrNetworkNotFound          = errors.New("network not found")
+	ErrHostPortUnavailable      = errors.New("host port unavailable")
+)
+
+func (*NetworkIngressPolicy) GormDataType() string {
+	return "jsonb"
+}
+
+func (p *NetworkIngressPolicy) Scan(value interface{}) error {
+	return json.Unmarshal(value.([]byte), &p)
+}
+
+func (p *NetworkIngressPolicy) Value() (driver.Value, error) {
+	// a json null would be loaded back as an empty policy instead of no policy
+	if p == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(p)
+}
+
+func (p *NetworkIngressPolicy) ToCore() *coretypes.MachineIngressPolicy {
+	return (*coretypes.MachineIngressPolicy)(p)
+}
 
 func (*GormNetIPNet) GormDataType() string {
 	return "text"
@@ -51,6 +200,11 @@ func (v *GormNetIPNet) Scan(value interface{}) error {
 }
 
 func (v *GormNetIPNet) Value() (driver.Value, error) {
+	// the ipv6 prefix of an interface is nil when the node has none
+	if v == nil {
+		return nil, nil
+	}
+
 	ipNet := net.IPNet(*v)
 	return ipNet.String(), nil
 }
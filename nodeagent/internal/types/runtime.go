@@ -2,21 +2,59 @@ package types
 
 import (
 	"context"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
 	"github.com/baepo-cloud/baepo-proto/go/baepo/node/v1/nodev1pbconnect"
 )
 
 type (
 	RuntimeStartOptions struct {
 		Machine *Machine
+		// RestoreFrom is the snapshot directory the vm is restored from instead of being booted.
+		RestoreFrom *string
+		// DeferContainers boots the vm without starting its containers, they are started once
+		// the init is configured.
+		DeferContainers bool
+	}
+
+	RuntimeAdoptOptions struct {
+		// RuntimeID is the id the runtime was started with, its directory is moved to the one of
+		// the machine.
+		RuntimeID string
+		Machine   *Machine
+	}
+
+	RuntimeGCOptions struct {
+		GCOptions
+		// KeepMachineIDs are the machines whose runtime directory must not be removed.
+		KeepMachineIDs []string
+		// IdleMachineIDs are kept machines that are not being reconciled, their sockets
+		// are removed when nothing listens on them anymore.
+		IdleMachineIDs []string
 	}
 
 	RuntimeService interface {
 		Start(ctx context.Context, opts RuntimeStartOptions) error
 
+		Adopt(ctx context.Context, opts RuntimeAdoptOptions) error
+
 		Terminate(ctx context.Context, machineID string) error
 
+		Pause(ctx context.Context, machineID string) error
+
+		Resume(ctx context.Context, machineID string) error
+
+		Snapshot(ctx context.Context, machineID string, directory string) error
+
+		Resize(ctx context.Context, machineID string, cpus uint32, memoryMB uint64) error
+
 		GetClient(machineID string) (nodev1pbconnect.RuntimeClient, func())
 
+		GetAPIClient(machineID string) (nodeapi.RuntimeClient, func())
+
+		GetStreamAPIClient(machineID string) (nodeapi.RuntimeClient, func())
+
 		GetMachineDirectory(machineID string) string
+
+		GC(ctx context.Context, opts RuntimeGCOptions) ([]GCResource, error)
 	}
 )
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+type (
+	Snapshot struct {
+		ID        string `gorm:"primaryKey"`
+		MachineID string
+		Machine   *Machine
+		// Path is the directory holding the memory and device state written by the hypervisor.
+		Path      string
+		Volumes   []*SnapshotVolume
+		CreatedAt time.Time
+	}
+
+	SnapshotVolume struct {
+		ID          string `gorm:"primaryKey"`
+		SnapshotID  string
+		ContainerID string
+		VolumeID    string
+		Volume      *Volume
+	}
+
+	MachineCreateSnapshotOptions struct {
+		MachineID string
+	}
+
+	MachineListSnapshotsOptions struct {
+		MachineID *string
+	}
+)
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
@@ -22,6 +22,18 @@ type (
 		Allocate(ctx context.Context, volume *Volume) error
 
 		Release(ctx context.Context, volume *Volume) error
+
+		// Snapshot allocates a new volume holding a point in time copy of the source volume.
+		Snapshot(ctx context.Context, source *Volume) (*Volume, error)
+
+		// Restore replaces the content of the volume by the content of the snapshot, the volume
+		// keeps its path.
+		Restore(ctx context.Context, volume *Volume, snapshot *Volume) error
+
+		// GetCapacity returns the storage available to volumes, in MB.
+		GetCapacity(ctx context.Context) (uint64, error)
+
+		GC(ctx context.Context, opts GCOptions) ([]GCResource, error)
 	}
 )
 
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+type (
+	// WarmPool is a golden snapshot of a booted vm for the images of the containers of a machine
+	// and a size. Idle vms are restored from it ahead of time, machines matching it adopt one
+	// instead of going through a cold boot.
+	WarmPool struct {
+		ID       string `gorm:"primaryKey"`
+		Cpus     uint32
+		MemoryMB uint64
+		// SnapshotPath is the directory holding the memory and device state of the golden vm.
+		SnapshotPath string
+		// Volumes are the golden volumes, one per container in the order of the containers.
+		Volumes   []*WarmPoolVolume
+		Entries   []*WarmPoolEntry
+		CreatedAt time.Time
+	}
+
+	// WarmPoolVolume is the golden volume of a container of the pool, built from its image.
+	WarmPoolVolume struct {
+		ID         string `gorm:"primaryKey"`
+		WarmPoolID string
+		Position   int
+		Image      string
+		ImageID    string
+		VolumeID   string
+		Volume     *Volume
+	}
+
+	// WarmPoolEntry is an idle machine of a pool: a vm restored from the golden snapshot on copies
+	// of the golden volumes, kept paused until a machine adopts it.
+	WarmPoolEntry struct {
+		ID                 string `gorm:"primaryKey"`
+		WarmPoolID         string
+		WarmPool           *WarmPool
+		Volumes            []*WarmPoolEntryVolume
+		NetworkInterfaceID string
+		NetworkInterface   *NetworkInterface
+		// Ready is set once the vm is booted and paused, only ready entries are handed out.
+		Ready     bool `gorm:"not null;default:false"`
+		CreatedAt time.Time
+	}
+
+	// WarmPoolEntryVolume is the copy of a golden volume attached to the vm of an entry.
+	WarmPoolEntryVolume struct {
+		ID              string `gorm:"primaryKey"`
+		WarmPoolEntryID string
+		Position        int
+		VolumeID        string
+		Volume          *Volume
+	}
+
+	WarmPoolStats struct {
+		Images    []string
+		Cpus      uint32
+		MemoryMB  uint64
+		Size      int
+		Available int
+		Hits      uint64
+		Misses    uint64
+	}
+)
+
+// GetImages returns the images of the containers of the machines of the pool, in order. The
+// volumes must be loaded ordered by position.
+func (p *WarmPool) GetImages() []string {
+	images := make([]string, len(p.Volumes))
+	for index, volume := range p.Volumes {
+		images[index] = volume.Image
+	}
+	return images
+}
+
+// GetImageIDs returns the ids the images of the pool resolved to when it was built.
+func (p *WarmPool) GetImageIDs() []string {
+	imageIDs := make([]string, len(p.Volumes))
+	for index, volume := range p.Volumes {
+		imageIDs[index] = volume.ImageID
+	}
+	return imageIDs
+}
+
+// GetVolumes returns the volumes of the entry, in the order of the containers.
+func (e *WarmPoolEntry) GetVolumes() []*Volume {
+	volumes := make([]*Volume, len(e.Volumes))
+	for index, entryVolume := range e.Volumes {
+		volumes[index] = entryVolume.Volume
+	}
+	return volumes
+}
+
+// RuntimeID is the id the vm of the entry is started with, until a machine adopts it.
+func (e *WarmPoolEntry) RuntimeID() string {
+	return "warmpool-" + e.ID
+}
+
+// GetImages returns the images of the containers of the machines of the pool, Image is the
+// shorthand of Images for single container machines.
+func (c WarmPoolConfig) GetImages() []string {
+	if len(c.Images) > 0 {
+		return c.Images
+	}
+	return []string{c.Image}
+}
+
+func (c WarmPoolConfig) Matches(pool *WarmPool) bool {
+	return slices.Equal(c.GetImages(), pool.GetImages()) && c.Cpus == pool.Cpus && c.MemoryMB == pool.MemoryMB
+}
@@ -0,0 +1,24 @@
+package volumeprovider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GetCapacity returns the size of the thin pool machine volumes are provisioned from, in MB.
+func (p *Provider) GetCapacity(ctx context.Context) (uint64, error) {
+	output, err := p.runCmdOutput(ctx, "lvs", "--noheadings", "--units", "m", "--nosuffix", "-o", "lv_size",
+		fmt.Sprintf("%v/thinpool", p.volumeGroup))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get thin pool size: %w", err)
+	}
+
+	size, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse thin pool size: %w", err)
+	}
+
+	return uint64(size), nil
+}
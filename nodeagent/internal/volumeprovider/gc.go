@@ -0,0 +1,53 @@
+package volumeprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+)
+
+func (p *Provider) GC(ctx context.Context, opts types.GCOptions) ([]types.GCResource, error) {
+	p.gcLock.Lock()
+	defer p.gcLock.Unlock()
+
+	output, err := p.runCmdOutput(ctx, "lvs", "--noheadings", "--separator", ",", "-o", "lv_name,lv_attr", p.volumeGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logical volumes: %w", err)
+	}
+
+	var volumeIDs []string
+	err = p.db.WithContext(ctx).Model(&types.Volume{}).Where("released_at IS NULL").Pluck("id", &volumeIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	knownVolumes := map[string]bool{}
+	for _, volumeID := range volumeIDs {
+		knownVolumes[volumeID] = true
+	}
+
+	var resources []types.GCResource
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ",")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+
+		// only thin volumes ("V") and snapshots ("s") are created by the provider, this
+		// leaves the thin pool and any volume managed outside the agent untouched
+		name, volumeType := parts[0], parts[1][0]
+		if (volumeType != 'V' && volumeType != 's') || knownVolumes[name] {
+			continue
+		}
+
+		resource := types.GCResource{Kind: types.GCResourceKindVolume, Name: name}
+		if !opts.DryRun {
+			resource.Error = p.runCmd(ctx, "lvremove", "-y", fmt.Sprintf("%v/%v", p.volumeGroup, name))
+		}
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
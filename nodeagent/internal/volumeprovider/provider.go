@@ -4,14 +4,22 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 	"os/exec"
+	"sync"
+	"time"
 )
 
 type Provider struct {
 	db          *gorm.DB
 	volumeGroup string
+	// gcLock is held by the garbage collector, and shared by the operations which create a
+	// logical volume before inserting its row so that it is not collected in between.
+	gcLock sync.RWMutex
 }
 
 var _ types.VolumeProvider = (*Provider)(nil)
@@ -28,9 +36,34 @@ func (p *Provider) runCmd(ctx context.Context, name string, args ...string) erro
 	stderr := new(bytes.Buffer)
 	cmd.Stderr = stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := observeCommand(ctx, name, args, cmd.Run); err != nil {
 		return fmt.Errorf("%w: %s", err, stderr.String())
 	}
 
 	return nil
 }
+
+func (p *Provider) runCmdOutput(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := observeCommand(ctx, name, args, cmd.Run); err != nil {
+		return "", fmt.Errorf("%w: %s", err, stderr.String())
+	}
+
+	return stdout.String(), nil
+}
+
+func observeCommand(ctx context.Context, name string, args []string, run func() error) error {
+	_, span := tracing.Start(ctx, "volumeprovider."+name, attribute.StringSlice("command.args", args))
+	startedAt := time.Now()
+	err := run()
+	tracing.End(span, err)
+	metrics.VolumeCommandDuration.WithLabelValues(name).Observe(time.Since(startedAt).Seconds())
+	if err != nil {
+		metrics.VolumeCommandFailures.WithLabelValues(name).Inc()
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package volumeprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/baepo-cloud/baepo-node/core/typeutil"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/types"
+	"github.com/nrednav/cuid2"
+)
+
+func (p *Provider) Snapshot(ctx context.Context, source *types.Volume) (*types.Volume, error) {
+	if source.Path == nil {
+		return nil, fmt.Errorf("cannot snapshot volume %v which is not allocated", source.ID)
+	}
+
+	p.gcLock.RLock()
+	defer p.gcLock.RUnlock()
+
+	volume := &types.Volume{
+		ID:       cuid2.Generate(),
+		Size:     source.Size,
+		SourceID: &source.ID,
+	}
+	volume.Path = typeutil.Ptr(fmt.Sprintf("/dev/%v/%v", p.volumeGroup, volume.ID))
+
+	isThin, err := p.isThinVolume(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	if isThin {
+		err = p.runCmd(ctx, "lvcreate", "-y", "--snapshot", "--setactivationskip", "n", "--name", volume.ID, *source.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create thin snapshot: %w", err)
+		}
+	} else if err = p.copyToThinVolume(ctx, source, volume); err != nil {
+		return nil, err
+	}
+
+	volume.AllocatedAt = typeutil.Ptr(time.Now())
+	if err = p.db.WithContext(ctx).Create(&volume).Error; err != nil {
+		_ = p.runCmd(context.Background(), "lvremove", "-y", fmt.Sprintf("%v/%v", p.volumeGroup, volume.ID))
+		return nil, fmt.Errorf("failed to persist volume: %w", err)
+	}
+
+	return volume, nil
+}
+
+func (p *Provider) Restore(ctx context.Context, volume *types.Volume, snapshot *types.Volume) error {
+	if snapshot.Path == nil {
+		return fmt.Errorf("cannot restore from volume %v which is not allocated", snapshot.ID)
+	}
+
+	err := p.runCmd(ctx, "lvremove", "-y", fmt.Sprintf("%v/%v", p.volumeGroup, volume.ID))
+	if err != nil && !strings.Contains(strings.ToLower(err.Error()), "failed to find logical volume") {
+		return fmt.Errorf("failed to remove logical volume: %w", err)
+	}
+
+	// snapshot volumes are always thin, the restored volume is a thin snapshot of it so that the
+	// snapshot can be restored again later on
+	err = p.runCmd(ctx, "lvcreate", "-y", "--snapshot", "--setactivationskip", "n", "--name", volume.ID, *snapshot.Path)
+	if err != nil {
+		return fmt.Errorf("failed to restore logical volume: %w", err)
+	}
+
+	return nil
+}
+
+// copyToThinVolume is used for volumes allocated as thick snapshots of an image, lvm cannot
+// snapshot a snapshot so the content is copied into a new thin volume instead.
+func (p *Provider) copyToThinVolume(ctx context.Context, source *types.Volume, volume *types.Volume) error {
+	err := p.runCmd(ctx, "lvcreate",
+		"-y",
+		"--virtualsize", fmt.Sprintf("%vM", volume.Size),
+		"--thin",
+		"--name", volume.ID,
+		fmt.Sprintf("%v/thinpool", p.volumeGroup),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create logical volume: %w", err)
+	}
+
+	err = p.runCmd(ctx, "dd", "if="+*source.Path, "of="+*volume.Path, "bs=4M", "conv=sparse")
+	if err != nil {
+		_ = p.runCmd(context.Background(), "lvremove", "-y", fmt.Sprintf("%v/%v", p.volumeGroup, volume.ID))
+		return fmt.Errorf("failed to copy volume content: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Provider) isThinVolume(ctx context.Context, volume *types.Volume) (bool, error) {
+	output, err := p.runCmdOutput(ctx, "lvs", "--noheadings", "-o", "segtype", *volume.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect logical volume: %w", err)
+	}
+
+	return strings.TrimSpace(output) == "thin", nil
+}
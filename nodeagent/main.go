@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"errors"
+	"flag"
 	"fmt"
 	"github.com/baepo-cloud/baepo-node/core/fxlog"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/apiserver"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/config"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/dnsserver"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/gatewayserver"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/imageprovider"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/machineservice"
+	"github.com/baepo-cloud/baepo-node/nodeagent/internal/metrics"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/networkprovider"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/registrationservice"
 	"github.com/baepo-cloud/baepo-node/nodeagent/internal/runtimeservice"
@@ -27,20 +31,40 @@ import (
 	"net/http"
 	"os"
 	"path"
-	"path/filepath"
+	"strings"
 	"time"
 )
 
-var Debug = os.Getenv("DEBUG") == "true"
-
 func main() {
-	if Debug {
+	flags := flag.NewFlagSet("nodeagent", flag.ExitOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s [-config file] [config validate|config print-effective]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	configFile := flags.String("config", os.Getenv(config.FileEnv), "path of the yaml config file, env variables override it")
+	_ = flags.Parse(os.Args[1:])
+
+	if args := flags.Args(); len(args) > 0 {
+		if err := runCommand(*configFile, args); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.Debug {
 		slog.SetLogLoggerLevel(slog.LevelDebug)
 	}
 
 	fx.New(
 		fxlog.Logger(),
-		fx.Provide(provideConfig),
+		fx.Supply(cfg),
+		fx.Invoke(setupTracing),
 		fx.Provide(provideGORM),
 		fx.Provide(fx.Annotate(networkprovider.New, fx.As(new(types.NetworkProvider)))),
 		fx.Provide(fx.Annotate(imageprovider.New, fx.As(new(types.ImageProvider)))),
@@ -51,6 +75,8 @@ func main() {
 		fx.Provide(registrationservice.New),
 		fx.Provide(apiserver.New),
 		fx.Provide(gatewayserver.New),
+		fx.Provide(metrics.New),
+		fx.Provide(dnsserver.New),
 		fx.Provide(func(lc fx.Lifecycle, service *machineservice.Service) types.MachineService {
 			lc.Append(fx.Hook{
 				OnStart: func(ctx context.Context) error {
@@ -93,55 +119,62 @@ func main() {
 				},
 			})
 		}),
+		fx.Invoke(func(lc fx.Lifecycle, server *dnsserver.Server) {
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					return server.Start(ctx)
+				},
+				OnStop: func(ctx context.Context) error {
+					return server.Stop(ctx)
+				},
+			})
+		}),
+		fx.Invoke(func(lc fx.Lifecycle, server *metrics.Server) {
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					return server.Start(ctx)
+				},
+				OnStop: func(ctx context.Context) error {
+					return server.Stop(ctx)
+				},
+			})
+		}),
 	).Run()
 }
 
-func provideConfig() (*types.Config, error) {
-	config := types.Config{
-		Debug:            Debug,
-		ClusterID:        os.Getenv("NODE_CLUSTER_ID"),
-		BootstrapToken:   os.Getenv("NODE_BOOTSTRAP_TOKEN"),
-		IPAddr:           os.Getenv("NODE_IP_ADDR"),
-		APIAddr:          os.Getenv("NODE_API_ADDR"),
-		GatewayAddr:      os.Getenv("NODE_GATEWAY_ADDR"),
-		StorageDirectory: os.Getenv("NODE_STORAGE_DIRECTORY"),
-		RuntimeBinary:    os.Getenv("NODE_RUNTIME_BINARY"),
-		VolumeGroup:      os.Getenv("NODE_VOLUME_GROUP"),
-		ControlPlaneURL:  os.Getenv("NODE_CONTROL_PLANE_URL"),
-	}
-	if config.APIAddr == "" {
-		config.APIAddr = ":3443"
-	}
-	if config.GatewayAddr == "" {
-		config.GatewayAddr = ":8443"
-	}
-	if config.StorageDirectory == "" {
-		config.StorageDirectory = "/var/lib/baepo"
-	}
-	if config.VolumeGroup == "" {
-		config.VolumeGroup = "vg_baepo"
-	}
-	if config.ControlPlaneURL == "" {
-		config.ControlPlaneURL = "https://api.baepo.cloud"
-	}
-	if config.ClusterID == "" {
-		return nil, errors.New("NODE_CLUSTER_ID env variable required")
+func runCommand(configFile string, args []string) error {
+	if len(args) != 2 || args[0] != "config" {
+		return fmt.Errorf("unknown command %v", strings.Join(args, " "))
 	}
-	if config.BootstrapToken == "" {
-		return nil, errors.New("NODE_BOOTSTRAP_TOKEN env variable required")
-	}
-	if config.RuntimeBinary == "" {
-		return nil, errors.New("NODE_RUNTIME_BINARY env variable required")
-	}
-	if !filepath.IsAbs(config.StorageDirectory) {
-		absPath, err := filepath.Abs(config.StorageDirectory)
+
+	switch args[1] {
+	case "validate":
+		if _, err := config.Load(configFile); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+
+		fmt.Println("config is valid")
+		return nil
+	case "print-effective":
+		cfg, err := config.Load(configFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path of the storage directory: %w", err)
+			return fmt.Errorf("invalid config: %w", err)
 		}
-		config.StorageDirectory = absPath
+
+		return config.Print(os.Stdout, cfg)
+	default:
+		return fmt.Errorf("unknown config command %v", args[1])
+	}
+}
+
+func setupTracing(lc fx.Lifecycle, config *types.Config) error {
+	shutdown, err := tracing.Setup(context.Background(), "baepo-nodeagent", config.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracing: %w", err)
 	}
 
-	return &config, nil
+	lc.Append(fx.Hook{OnStop: shutdown})
+	return nil
 }
 
 func provideGORM(config *types.Config) (*gorm.DB, error) {
@@ -157,10 +190,17 @@ func provideGORM(config *types.Config) (*gorm.DB, error) {
 		&types.Volume{},
 		&types.Image{},
 		&types.NetworkInterface{},
+		&types.PublishedPort{},
 		&types.Machine{},
 		&types.MachineEvent{},
 		&types.MachineVolume{},
 		&types.Container{},
+		&types.Snapshot{},
+		&types.SnapshotVolume{},
+		&types.WarmPool{},
+		&types.WarmPoolVolume{},
+		&types.WarmPoolEntry{},
+		&types.WarmPoolEntryVolume{},
 	)
 	if err != nil {
 		return nil, err
@@ -179,5 +219,5 @@ func provideControlPlaneApiClient(config *types.Config) apiv1pbconnect.NodeContr
 			PingTimeout:     60 * time.Second,
 		},
 	}
-	return apiv1pbconnect.NewNodeControllerServiceClient(client, config.ControlPlaneURL)
+	return apiv1pbconnect.NewNodeControllerServiceClient(client, config.ControlPlaneURL, tracing.Interceptor())
 }
@@ -2,12 +2,16 @@ module github.com/baepo-cloud/baepo-node/nodeagentctl
 
 go 1.24.2
 
+replace github.com/baepo-cloud/baepo-node/core => ../core
+
 require (
 	connectrpc.com/connect v1.18.1
 	github.com/baepo-cloud/baepo-cli v0.0.0-20250428125150-7dd566c83a0d
+	github.com/baepo-cloud/baepo-node/core v0.0.0-00010101000000-000000000000
 	github.com/baepo-cloud/baepo-proto/go v0.0.0-20250808102228-88fd923179a3
 	github.com/joho/godotenv v1.5.1
 	github.com/spf13/cobra v1.9.1
+	golang.org/x/term v0.29.0
 )
 
 require (
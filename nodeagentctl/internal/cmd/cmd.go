@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"github.com/baepo-cloud/baepo-cli/pkg/iostream"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
 	"github.com/baepo-cloud/baepo-proto/go/baepo/node/v1/nodev1pbconnect"
 	"github.com/spf13/cobra"
 	"net"
@@ -33,18 +34,34 @@ func Execute() error {
 	return nil
 }
 
-func newClient() (nodev1pbconnect.NodeServiceClient, error) {
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: newTransport()}
+}
+
+func newTransport() *http.Transport {
 	storageDir := os.Getenv("NODE_STORAGE_DIRECTORY")
 	if storageDir == "" {
 		storageDir = "/var/lib/baepo"
 	}
 
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
-				return net.Dial("unix", filepath.Join(storageDir, "agent.sock"))
-			},
+	return &http.Transport{
+		DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", filepath.Join(storageDir, "agent.sock"))
 		},
 	}
-	return nodev1pbconnect.NewNodeServiceClient(httpClient, "http://agent"), nil
+}
+
+func newClient() (nodev1pbconnect.NodeServiceClient, error) {
+	return nodev1pbconnect.NewNodeServiceClient(newHTTPClient(), "http://agent"), nil
+}
+
+func newAPIClient() (nodeapi.NodeServiceClient, error) {
+	return nodeapi.NewNodeServiceClient(newHTTPClient(), "http://agent"), nil
+}
+
+// newStreamAPIClient returns a client speaking http/2, which bidirectional streams require.
+func newStreamAPIClient() (nodeapi.NodeServiceClient, error) {
+	transport := newTransport()
+	transport.Protocols = nodeapi.StreamClientProtocols()
+	return nodeapi.NewNodeServiceClient(&http.Client{Transport: transport}, "http://agent"), nil
 }
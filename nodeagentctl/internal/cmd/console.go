@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// consoleDetachKey is Ctrl-], the same escape as telnet and virsh console.
+const consoleDetachKey = 0x1d
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "console <machine-id>",
+		Short: "Attach to the serial console of a machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newStreamAPIClient()
+			if err != nil {
+				return err
+			}
+
+			stdinFd := int(os.Stdin.Fd())
+			if !term.IsTerminal(stdinFd) {
+				return errors.New("the input is not a terminal")
+			}
+
+			stream := client.AttachConsole(cmd.Context())
+			defer stream.CloseResponse()
+
+			err = stream.Send(&nodeapi.ConsoleRequest{MachineID: args[0]})
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+
+			fmt.Printf("Connected to the console of %s, press Ctrl-] to detach.\r\n", args[0])
+			state, err := term.MakeRaw(stdinFd)
+			if err != nil {
+				return fmt.Errorf("failed to set terminal in raw mode: %w", err)
+			}
+			defer term.Restore(stdinFd, state)
+
+			detached := make(chan struct{})
+			go func() {
+				defer close(detached)
+
+				buf := make([]byte, 1024)
+				for {
+					n, err := os.Stdin.Read(buf)
+					if err != nil {
+						return
+					}
+
+					input := buf[:n]
+					index := bytes.IndexByte(input, consoleDetachKey)
+					if index >= 0 {
+						input = input[:index]
+					}
+					if len(input) > 0 {
+						if err = stream.Send(&nodeapi.ConsoleRequest{Input: input}); err != nil {
+							return
+						}
+					}
+					if index >= 0 {
+						return
+					}
+				}
+			}()
+
+			outputs := make(chan error, 1)
+			go func() {
+				for {
+					res, err := stream.Receive()
+					if err != nil {
+						outputs <- err
+						return
+					}
+
+					_, _ = os.Stdout.Write(res.Output)
+				}
+			}()
+
+			select {
+			case <-detached:
+				_ = stream.CloseRequest()
+				fmt.Print("\r\nDetached from the console.\r\n")
+				return nil
+			case err = <-outputs:
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+		},
+	}
+	rootCmd.AddCommand(cmd)
+}
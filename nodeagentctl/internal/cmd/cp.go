@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"connectrpc.com/connect"
+	"errors"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type containerPath struct {
+	machineID   string
+	containerID string
+	path        string
+}
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "cp <machine-id>:[container:]<path> <local-path> | <local-path> <machine-id>:[container:]<path>",
+		Short: "Copy files between a container and the local filesystem",
+		Long: "Copy files between a container and the local filesystem. The container defaults to the " +
+			"first container of the machine. When the destination is an existing directory the source " +
+			"is copied into it, otherwise the source is copied as the destination.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newStreamAPIClient()
+			if err != nil {
+				return err
+			}
+
+			source, sourceIsRemote := parseContainerPath(args[0])
+			destination, destinationIsRemote := parseContainerPath(args[1])
+			switch {
+			case sourceIsRemote && !destinationIsRemote:
+				stream, err := client.DownloadArchive(cmd.Context(), connect.NewRequest(&nodeapi.DownloadArchiveRequest{
+					MachineID:   source.machineID,
+					ContainerID: source.containerID,
+					Path:        source.path,
+				}))
+				if err != nil {
+					return err
+				}
+				defer stream.Close()
+
+				archive := nodeapi.NewChunkReader(func() ([]byte, error) {
+					if !stream.Receive() {
+						if err := stream.Err(); err != nil {
+							return nil, err
+						}
+						return nil, io.EOF
+					}
+					return stream.Msg().Data, nil
+				})
+				return extractLocalArchive(archive, args[1])
+			case !sourceIsRemote && destinationIsRemote:
+				stream := client.UploadArchive(cmd.Context())
+				err = stream.Send(&nodeapi.UploadArchiveRequest{
+					MachineID:   destination.machineID,
+					ContainerID: destination.containerID,
+					Path:        destination.path,
+				})
+				if err == nil {
+					archive := bufio.NewWriterSize(nodeapi.NewChunkWriter(func(data []byte) error {
+						return stream.Send(&nodeapi.UploadArchiveRequest{Data: data})
+					}), nodeapi.ArchiveChunkSize)
+					if err = writeLocalArchive(archive, args[0]); err == nil {
+						err = archive.Flush()
+					}
+				}
+				// a send error means the agent closed the stream, its error is returned on close
+				if _, closeErr := stream.CloseAndReceive(); closeErr != nil {
+					return closeErr
+				} else if err != nil && !errors.Is(err, io.EOF) {
+					return err
+				}
+				return nil
+			default:
+				return errors.New("exactly one of the paths must be a container path")
+			}
+		},
+	}
+	rootCmd.AddCommand(cmd)
+}
+
+// parseContainerPath parses machine:container:/path and machine:/path, local paths may contain a
+// colon when they start with a dot or a slash.
+func parseContainerPath(arg string) (containerPath, bool) {
+	if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") || !strings.Contains(arg, ":") {
+		return containerPath{}, false
+	}
+
+	parts := strings.SplitN(arg, ":", 3)
+	if len(parts) == 3 {
+		return containerPath{machineID: parts[0], containerID: parts[1], path: parts[2]}, true
+	}
+
+	return containerPath{machineID: parts[0], path: parts[1]}, true
+}
+
+func writeLocalArchive(archive io.Writer, source string) error {
+	source = filepath.Clean(source)
+	base := filepath.Base(source)
+	writer := tar.NewWriter(archive)
+	err := filepath.WalkDir(source, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(name); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(source, name)
+		if err != nil {
+			return err
+		}
+
+		header.Name = path.Join(base, filepath.ToSlash(relative))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err = writer.WriteHeader(header); err != nil {
+			return err
+		} else if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(writer, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// extractLocalArchive extracts the archive in the destination when it is an existing directory,
+// otherwise the root entry of the archive is renamed to the destination.
+func extractLocalArchive(archive io.Reader, destination string) error {
+	rootPath, renameTo := destination, ""
+	if info, err := os.Stat(destination); err != nil || !info.IsDir() {
+		rootPath, renameTo = filepath.Dir(destination), filepath.Base(destination)
+	}
+
+	root, err := os.OpenRoot(rootPath)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		target := path.Clean(header.Name)
+		if !fs.ValidPath(target) {
+			return fmt.Errorf("invalid archive entry %v", header.Name)
+		} else if renameTo != "" {
+			_, rest, _ := strings.Cut(target, "/")
+			target = path.Join(renameTo, rest)
+		}
+
+		if err = extractLocalArchiveEntry(root, rootPath, target, header, reader); err != nil {
+			return fmt.Errorf("failed to extract %v: %w", header.Name, err)
+		}
+	}
+}
+
+func extractLocalArchiveEntry(root *os.Root, rootPath string, target string, header *tar.Header, content io.Reader) error {
+	mode := header.FileInfo().Mode()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := root.Mkdir(target, mode.Perm()); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	case tar.TypeReg:
+		file, err := root.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if _, err = io.Copy(file, content); err != nil {
+			return err
+		}
+		_ = file.Chmod(mode)
+		// ownership is only kept when running as root
+		_ = file.Chown(header.Uid, header.Gid)
+	case tar.TypeSymlink:
+		// the parent is resolved through the root first so that the link can not be created
+		// outside of the destination
+		if _, err := root.Stat(path.Dir(target)); err != nil {
+			return err
+		}
+
+		name := filepath.Join(rootPath, filepath.FromSlash(target))
+		_ = os.Remove(name)
+		if err := os.Symlink(header.Linkname, name); err != nil {
+			return err
+		}
+		_ = os.Lchown(name, header.Uid, header.Gid)
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "skipping unsupported archive entry %v\n", header.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func init() {
+	var (
+		container string
+		stdin     bool
+		tty       bool
+	)
+	cmd := &cobra.Command{
+		Use:   "exec <machine-id> [-c container] -- <command> [args...]",
+		Short: "Run a command in a container of a machine",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newStreamAPIClient()
+			if err != nil {
+				return err
+			}
+
+			start := &nodeapi.ExecStart{
+				MachineID:   args[0],
+				ContainerID: container,
+				Command:     args[1:],
+				TTY:         tty,
+			}
+			stdinFd := int(os.Stdin.Fd())
+			restoreTerminal := func() {}
+			if tty {
+				if !term.IsTerminal(stdinFd) {
+					return errors.New("the input is not a terminal, run without -t")
+				}
+
+				if cols, rows, err := term.GetSize(stdinFd); err == nil {
+					start.Size = &nodeapi.ExecTerminalSize{Rows: uint16(rows), Cols: uint16(cols)}
+				}
+
+				state, err := term.MakeRaw(stdinFd)
+				if err != nil {
+					return fmt.Errorf("failed to set terminal in raw mode: %w", err)
+				}
+				restoreTerminal = func() {
+					_ = term.Restore(stdinFd, state)
+				}
+				defer restoreTerminal()
+			}
+
+			stream := client.Exec(cmd.Context())
+			defer stream.CloseResponse()
+
+			// the input and the terminal resizes are sent from different goroutines
+			var sendLock sync.Mutex
+			send := func(req *nodeapi.ExecRequest) error {
+				sendLock.Lock()
+				defer sendLock.Unlock()
+				return stream.Send(req)
+			}
+
+			if err = send(&nodeapi.ExecRequest{Start: start}); err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+
+			if stdin {
+				go func() {
+					buf := make([]byte, 32*1024)
+					for {
+						n, err := os.Stdin.Read(buf)
+						if n > 0 {
+							if sendErr := send(&nodeapi.ExecRequest{Stdin: buf[:n]}); sendErr != nil {
+								return
+							}
+						}
+						if err != nil {
+							_ = send(&nodeapi.ExecRequest{CloseStdin: true})
+							return
+						}
+					}
+				}()
+			} else if err = send(&nodeapi.ExecRequest{CloseStdin: true}); err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+
+			if tty {
+				resizes := make(chan os.Signal, 1)
+				signal.Notify(resizes, syscall.SIGWINCH)
+				defer signal.Stop(resizes)
+				go func() {
+					for range resizes {
+						if cols, rows, err := term.GetSize(stdinFd); err == nil {
+							_ = send(&nodeapi.ExecRequest{
+								Resize: &nodeapi.ExecTerminalSize{Rows: uint16(rows), Cols: uint16(cols)},
+							})
+						}
+					}
+				}()
+			}
+
+			for {
+				res, err := stream.Receive()
+				if errors.Is(err, io.EOF) {
+					return nil
+				} else if err != nil {
+					return err
+				}
+
+				if len(res.Stdout) > 0 {
+					_, _ = os.Stdout.Write(res.Stdout)
+				}
+				if len(res.Stderr) > 0 {
+					_, _ = os.Stderr.Write(res.Stderr)
+				}
+				if res.ExitCode != nil && *res.ExitCode != 0 {
+					// os.Exit skips the deferred calls
+					restoreTerminal()
+					os.Exit(int(*res.ExitCode))
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&container, "container", "c", "", "Container id or name, defaults to the first container")
+	cmd.Flags().BoolVarP(&stdin, "interactive", "i", false, "Forward the standard input")
+	cmd.Flags().BoolVarP(&tty, "tty", "t", false, "Allocate a terminal")
+	rootCmd.AddCommand(cmd)
+}
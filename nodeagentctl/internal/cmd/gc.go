@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"connectrpc.com/connect"
+	"fmt"
+	"github.com/baepo-cloud/baepo-cli/pkg/iostream"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Collect orphaned runtimes, volumes and network interfaces",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.RunGC(cmd.Context(), connect.NewRequest(&nodeapi.NodeRunGCRequest{DryRun: dryRun}))
+			if err != nil {
+				return err
+			}
+
+			ioStream.Array(res.Msg.Resources, []any{
+				iostream.FieldConfig{
+					DisplayName: "Kind",
+					FormatFunc: func(obj *nodeapi.GCResource) string {
+						return obj.Kind
+					},
+				},
+				iostream.FieldConfig{
+					DisplayName: "Name",
+					FormatFunc: func(obj *nodeapi.GCResource) string {
+						return obj.Name
+					},
+				},
+				iostream.FieldConfig{
+					DisplayName: "Error",
+					FormatFunc: func(obj *nodeapi.GCResource) string {
+						if obj.Error == nil {
+							return ""
+						}
+						return *obj.Error
+					},
+				},
+			}, iostream.ObjectOptions{Full: true})
+
+			for _, message := range res.Msg.Errors {
+				fmt.Printf("error: %s\n", message)
+			}
+
+			verb := "collected"
+			if res.Msg.DryRun {
+				verb = "would collect"
+			}
+			fmt.Printf("%s %d resource(s) in %v\n", verb, len(res.Msg.Resources), res.Msg.Duration)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only report what would be collected")
+	rootCmd.AddCommand(cmd)
+}
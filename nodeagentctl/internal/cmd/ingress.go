@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"connectrpc.com/connect"
+	"fmt"
+	"github.com/baepo-cloud/baepo-cli/pkg/iostream"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+	"strconv"
+	"strings"
+)
+
+type ingressRuleStats struct {
+	Rule    string
+	Counter *nodeapi.IngressCounter
+}
+
+func init() {
+	ingressCmd := &cobra.Command{
+		Use:   "ingress",
+		Short: "Manage the ingress policy of machines",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				panic(err)
+			}
+		},
+	}
+
+	var (
+		rules       []string
+		defaultDeny bool
+	)
+	setCmd := &cobra.Command{
+		Use:   "set <machine-id>",
+		Short: "Replace the ingress policy of a machine",
+		Long: "Replace the ingress policy of a machine. A rule is <protocol>[:<port>[-<port>]][@<cidr>,...] " +
+			"where the protocol is tcp, udp, icmp or any, for instance tcp:8000-8100@10.0.0.0/8.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policy := &nodeapi.IngressPolicy{DefaultDeny: defaultDeny}
+			for _, value := range rules {
+				rule, err := parseIngressRule(value)
+				if err != nil {
+					return err
+				}
+				policy.Rules = append(policy.Rules, rule)
+			}
+
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.UpdateMachineSpec(cmd.Context(), connect.NewRequest(&nodeapi.NodeUpdateMachineSpecRequest{
+				MachineID: args[0],
+				Ingress:   policy,
+			}))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("ingress policy of machine %s updated with %d rule(s)\n", res.Msg.Machine.MachineID, len(policy.Rules))
+			return nil
+		},
+	}
+	setCmd.Flags().StringArrayVarP(&rules, "rule", "r", nil, "rule allowing traffic, can be repeated")
+	setCmd.Flags().BoolVar(&defaultDeny, "default-deny", false, "drop the traffic no rule allows")
+	ingressCmd.AddCommand(setCmd)
+
+	ingressCmd.AddCommand(&cobra.Command{
+		Use:   "clear <machine-id>",
+		Short: "Remove the ingress policy of a machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.UpdateMachineSpec(cmd.Context(), connect.NewRequest(&nodeapi.NodeUpdateMachineSpecRequest{
+				MachineID:    args[0],
+				ClearIngress: true,
+			}))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("ingress policy of machine %s removed\n", res.Msg.Machine.MachineID)
+			return nil
+		},
+	})
+
+	ingressCmd.AddCommand(&cobra.Command{
+		Use:   "stats <machine-id>",
+		Short: "Show the traffic counted by the ingress policy of a machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.GetMachineIngressStats(cmd.Context(), connect.NewRequest(&nodeapi.NodeGetMachineIngressStatsRequest{
+				MachineID: args[0],
+			}))
+			if err != nil {
+				return err
+			}
+
+			var rows []*ingressRuleStats
+			for index, counter := range res.Msg.Rules {
+				rows = append(rows, &ingressRuleStats{Rule: fmt.Sprintf("#%d", index), Counter: counter})
+			}
+			unmatched := "unmatched (accepted)"
+			if res.Msg.DefaultDeny {
+				unmatched = "unmatched (dropped)"
+			}
+			rows = append(rows, &ingressRuleStats{Rule: unmatched, Counter: res.Msg.Unmatched})
+
+			ioStream.Array(rows, []any{
+				iostream.FieldConfig{
+					DisplayName: "Rule",
+					FormatFunc: func(obj *ingressRuleStats) string {
+						return obj.Rule
+					},
+				},
+				iostream.FieldConfig{
+					DisplayName: "Packets",
+					FormatFunc: func(obj *ingressRuleStats) string {
+						return fmt.Sprint(obj.Counter.Packets)
+					},
+				},
+				iostream.FieldConfig{
+					DisplayName: "Bytes",
+					FormatFunc: func(obj *ingressRuleStats) string {
+						return fmt.Sprint(obj.Counter.Bytes)
+					},
+				},
+			}, iostream.ObjectOptions{Full: true})
+			return nil
+		},
+	})
+
+	rootCmd.AddCommand(ingressCmd)
+}
+
+func parseIngressRule(value string) (*nodeapi.IngressRule, error) {
+	rule := &nodeapi.IngressRule{}
+	value, sources, hasSources := strings.Cut(value, "@")
+	if hasSources {
+		rule.SourceCIDRs = strings.Split(sources, ",")
+	}
+
+	protocol, ports, hasPorts := strings.Cut(value, ":")
+	if protocol != "any" {
+		rule.Protocol = protocol
+	}
+	if !hasPorts {
+		return rule, nil
+	}
+
+	from, to, isRange := strings.Cut(ports, "-")
+	fromPort, err := strconv.ParseUint(from, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in rule %s: %w", value, err)
+	}
+	rule.FromPort, rule.ToPort = uint16(fromPort), uint16(fromPort)
+	if isRange {
+		toPort, err := strconv.ParseUint(to, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in rule %s: %w", value, err)
+		}
+		rule.ToPort = uint16(toPort)
+	}
+	return rule, nil
+}
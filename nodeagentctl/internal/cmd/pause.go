@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"connectrpc.com/connect"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "pause <machine-id>",
+		Short: "Pause a running machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.PauseMachine(cmd.Context(), connect.NewRequest(&nodeapi.NodePauseMachineRequest{
+				MachineID: args[0],
+			}))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("machine %s is pausing\n", res.Msg.Machine.MachineID)
+			return nil
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "resume <machine-id>",
+		Short: "Resume a paused machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.ResumeMachine(cmd.Context(), connect.NewRequest(&nodeapi.NodeResumeMachineRequest{
+				MachineID: args[0],
+			}))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("machine %s is resuming\n", res.Msg.Machine.MachineID)
+			return nil
+		},
+	})
+}
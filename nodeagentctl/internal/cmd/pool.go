@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"connectrpc.com/connect"
+	"fmt"
+	"github.com/baepo-cloud/baepo-cli/pkg/iostream"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+func init() {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "pool",
+		Short: "Show the warm pools size and hit rate",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.GetWarmPoolStats(cmd.Context(), connect.NewRequest(&nodeapi.NodeGetWarmPoolStatsRequest{}))
+			if err != nil {
+				return err
+			}
+
+			ioStream.Array(res.Msg.Pools, []any{
+				iostream.FieldConfig{
+					DisplayName: "Images",
+					FormatFunc: func(obj *nodeapi.WarmPoolStats) string {
+						return strings.Join(obj.Images, ", ")
+					},
+				},
+				iostream.FieldConfig{
+					DisplayName: "Size",
+					FormatFunc: func(obj *nodeapi.WarmPoolStats) string {
+						return fmt.Sprintf("%d cpu / %d MB", obj.Cpus, obj.MemoryMB)
+					},
+				},
+				iostream.FieldConfig{
+					DisplayName: "Available",
+					FormatFunc: func(obj *nodeapi.WarmPoolStats) string {
+						return fmt.Sprintf("%d/%d", obj.Available, obj.Size)
+					},
+				},
+				iostream.FieldConfig{
+					DisplayName: "Hits",
+					FormatFunc: func(obj *nodeapi.WarmPoolStats) string {
+						return fmt.Sprint(obj.Hits)
+					},
+				},
+				iostream.FieldConfig{
+					DisplayName: "Misses",
+					FormatFunc: func(obj *nodeapi.WarmPoolStats) string {
+						return fmt.Sprint(obj.Misses)
+					},
+				},
+			}, iostream.ObjectOptions{Full: true})
+			return nil
+		},
+	})
+}
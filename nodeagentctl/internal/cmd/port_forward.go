@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+)
+
+type portForwardSpec struct {
+	localPort  uint16
+	remotePort uint16
+}
+
+func init() {
+	var containerID string
+	var address string
+	cmd := &cobra.Command{
+		Use:   "port-forward <machine-id> [local-port:]<remote-port>...",
+		Short: "Forward local ports to a machine",
+		Long: "Forward local ports to the loopback of a machine. Connections are tunneled through the " +
+			"runtime of the machine, they do not depend on its network. A local port of 0 picks a free port.",
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newStreamAPIClient()
+			if err != nil {
+				return err
+			}
+
+			specs := make([]portForwardSpec, len(args)-1)
+			for index, arg := range args[1:] {
+				if specs[index], err = parsePortForwardSpec(arg); err != nil {
+					return err
+				}
+			}
+
+			errs := make(chan error, len(specs))
+			for _, spec := range specs {
+				listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(int(spec.localPort))))
+				if err != nil {
+					return fmt.Errorf("failed to listen on port %d: %w", spec.localPort, err)
+				}
+				defer listener.Close()
+
+				fmt.Printf("Forwarding from %s -> %d\n", listener.Addr(), spec.remotePort)
+				go func() {
+					for {
+						conn, err := listener.Accept()
+						if err != nil {
+							errs <- err
+							return
+						}
+
+						go func() {
+							defer conn.Close()
+
+							err := forwardConnection(cmd.Context(), client, args[0], containerID, spec.remotePort, conn)
+							if err != nil {
+								fmt.Fprintf(os.Stderr, "Connection to port %d failed: %v\n", spec.remotePort, err)
+							}
+						}()
+					}
+				}()
+			}
+
+			return <-errs
+		},
+	}
+	cmd.Flags().StringVarP(&containerID, "container", "c", "", "Container id or name, defaults to the first container")
+	cmd.Flags().StringVar(&address, "address", "127.0.0.1", "Local address to listen on")
+	rootCmd.AddCommand(cmd)
+}
+
+func parsePortForwardSpec(value string) (portForwardSpec, error) {
+	local, remote, found := strings.Cut(value, ":")
+	if !found {
+		remote = local
+	}
+
+	localPort, err := strconv.ParseUint(local, 10, 16)
+	if err != nil {
+		return portForwardSpec{}, fmt.Errorf("invalid local port %q", local)
+	}
+
+	remotePort, err := strconv.ParseUint(remote, 10, 16)
+	if err != nil || remotePort == 0 {
+		return portForwardSpec{}, fmt.Errorf("invalid remote port %q", remote)
+	}
+
+	return portForwardSpec{localPort: uint16(localPort), remotePort: uint16(remotePort)}, nil
+}
+
+func forwardConnection(ctx context.Context, client nodeapi.NodeServiceClient, machineID, containerID string, port uint16, conn net.Conn) error {
+	stream := client.PortForward(ctx)
+	defer stream.CloseResponse()
+
+	err := stream.Send(&nodeapi.PortForwardRequest{
+		MachineID:   machineID,
+		ContainerID: containerID,
+		Port:        uint32(port),
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	go func() {
+		defer stream.CloseRequest()
+
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				if sendErr := stream.Send(&nodeapi.PortForwardRequest{Data: buf[:n]}); sendErr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		res, err := stream.Receive()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if _, err = conn.Write(res.Data); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"connectrpc.com/connect"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var (
+		cpus     uint32
+		memoryMB uint64
+	)
+	cmd := &cobra.Command{
+		Use:   "resize <machine-id>",
+		Short: "Change the cpus and memory of a machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.UpdateMachineSpec(cmd.Context(), connect.NewRequest(&nodeapi.NodeUpdateMachineSpecRequest{
+				MachineID: args[0],
+				Cpus:      cpus,
+				MemoryMB:  memoryMB,
+			}))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("machine %s resized to %d cpu(s) and %d MB\n", res.Msg.Machine.MachineID,
+				res.Msg.Machine.Cpus, res.Msg.Machine.MemoryMB)
+			return nil
+		},
+	}
+	cmd.Flags().Uint32Var(&cpus, "cpus", 0, "number of vcpus")
+	cmd.Flags().Uint64Var(&memoryMB, "memory", 0, "memory in MB")
+	_ = cmd.MarkFlagRequired("cpus")
+	_ = cmd.MarkFlagRequired("memory")
+	rootCmd.AddCommand(cmd)
+}
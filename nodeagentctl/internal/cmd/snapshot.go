@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"connectrpc.com/connect"
+	"fmt"
+	"github.com/baepo-cloud/baepo-cli/pkg/iostream"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+	"strings"
+	"time"
+)
+
+func init() {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Manage machine snapshots",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				panic(err)
+			}
+		},
+	}
+
+	snapshotCmd.AddCommand(&cobra.Command{
+		Use:   "create <machine-id>",
+		Short: "Snapshot the memory and volumes of a machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.CreateSnapshot(cmd.Context(), connect.NewRequest(&nodeapi.NodeCreateSnapshotRequest{
+				MachineID: args[0],
+			}))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("snapshot %s created\n", res.Msg.Snapshot.SnapshotID)
+			return nil
+		},
+	})
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List snapshots",
+	}
+	machineID := listCmd.Flags().StringP("machine", "m", "", "Filter by machine ID")
+	listCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		client, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+
+		req := &nodeapi.NodeListSnapshotsRequest{}
+		if *machineID != "" {
+			req.MachineID = machineID
+		}
+
+		res, err := client.ListSnapshots(cmd.Context(), connect.NewRequest(req))
+		if err != nil {
+			return err
+		}
+
+		ioStream.Array(res.Msg.Snapshots, []any{
+			iostream.FieldConfig{
+				DisplayName: "ID",
+				FormatFunc: func(obj *nodeapi.Snapshot) string {
+					return obj.SnapshotID
+				},
+			},
+			iostream.FieldConfig{
+				DisplayName: "Machine ID",
+				FormatFunc: func(obj *nodeapi.Snapshot) string {
+					return obj.MachineID
+				},
+			},
+			iostream.FieldConfig{
+				DisplayName: "Volumes",
+				FormatFunc: func(obj *nodeapi.Snapshot) string {
+					return strings.Join(obj.VolumeIDs, ", ")
+				},
+			},
+			iostream.FieldConfig{
+				DisplayName: "Created At",
+				FormatFunc: func(obj *nodeapi.Snapshot) string {
+					return obj.CreatedAt.Format(time.RFC3339)
+				},
+			},
+		}, iostream.ObjectOptions{Full: true})
+		return nil
+	}
+	snapshotCmd.AddCommand(listCmd)
+
+	snapshotCmd.AddCommand(&cobra.Command{
+		Use:   "restore <snapshot-id>",
+		Short: "Restore a stopped machine from a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			res, err := client.RestoreSnapshot(cmd.Context(), connect.NewRequest(&nodeapi.NodeRestoreSnapshotRequest{
+				SnapshotID: args[0],
+			}))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("machine %s is restoring\n", res.Msg.Machine.MachineID)
+			return nil
+		},
+	})
+
+	snapshotCmd.AddCommand(&cobra.Command{
+		Use:   "delete <snapshot-id>",
+		Short: "Delete a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newAPIClient()
+			if err != nil {
+				return err
+			}
+
+			_, err = client.DeleteSnapshot(cmd.Context(), connect.NewRequest(&nodeapi.NodeDeleteSnapshotRequest{
+				SnapshotID: args[0],
+			}))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("snapshot %s deleted\n", args[0])
+			return nil
+		},
+	})
+
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"connectrpc.com/connect"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func init() {
+	var interval uint32
+	cmd := &cobra.Command{
+		Use:   "top <machine-id>",
+		Short: "Display the resource usage of a machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newStreamAPIClient()
+			if err != nil {
+				return err
+			}
+
+			stream, err := client.GetMachineStats(cmd.Context(), connect.NewRequest(&nodeapi.NodeGetMachineStatsRequest{
+				MachineID:       args[0],
+				IntervalSeconds: interval,
+			}))
+			if err != nil {
+				return err
+			}
+			defer stream.Close()
+
+			fmt.Println("Waiting for the first sample...")
+			for stream.Receive() {
+				printMachineStats(args[0], stream.Msg())
+			}
+			return stream.Err()
+		},
+	}
+	cmd.Flags().Uint32VarP(&interval, "interval", "n", 2, "Seconds between two samples")
+	rootCmd.AddCommand(cmd)
+}
+
+func printMachineStats(machineID string, stats *nodeapi.MachineStats) {
+	var out strings.Builder
+	// moves the cursor home and clears the screen, as top does
+	out.WriteString("\033[H\033[2J")
+	_, _ = fmt.Fprintf(&out, "Machine %s - %s\n\n", machineID, stats.Timestamp.Local().Format("15:04:05"))
+	_, _ = fmt.Fprintf(&out, "CPU:    %.1f%% of %d cpus\n", stats.CPUPercent, stats.Cpus)
+	if stats.MemoryTotalBytes > 0 {
+		used := stats.MemoryTotalBytes - stats.MemoryAvailableBytes
+		_, _ = fmt.Fprintf(&out, "Memory: %s used of %s (%.1f%%), %s allocated by the vmm\n", formatBytes(float64(used)),
+			formatBytes(float64(stats.MemoryTotalBytes)), float64(used)/float64(stats.MemoryTotalBytes)*100,
+			formatBytes(float64(stats.MemoryActualBytes)))
+	} else {
+		_, _ = fmt.Fprintf(&out, "Memory: %s allocated by the vmm, guest stats unavailable\n", formatBytes(float64(stats.MemoryActualBytes)))
+	}
+
+	writer := tabwriter.NewWriter(&out, 0, 0, 3, ' ', 0)
+	if len(stats.Disks) > 0 {
+		_, _ = fmt.Fprintln(writer, "\nDISK\tREAD/S\tWRITE/S\tREAD\tWRITTEN")
+		for _, disk := range stats.Disks {
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", disk.Name, formatBytes(disk.ReadBytesPerSecond),
+				formatBytes(disk.WriteBytesPerSecond), formatBytes(float64(disk.ReadBytes)), formatBytes(float64(disk.WriteBytes)))
+		}
+	}
+	if len(stats.Networks) > 0 {
+		_, _ = fmt.Fprintln(writer, "\nNETWORK\tRX/S\tTX/S\tRX\tTX")
+		for _, network := range stats.Networks {
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", network.Name, formatBytes(network.RxBytesPerSecond),
+				formatBytes(network.TxBytesPerSecond), formatBytes(float64(network.RxBytes)), formatBytes(float64(network.TxBytes)))
+		}
+	}
+	if len(stats.Containers) > 0 {
+		_, _ = fmt.Fprintln(writer, "\nCONTAINER\tPROCESSES\tCPU\tMEMORY")
+		for _, container := range stats.Containers {
+			_, _ = fmt.Fprintf(writer, "%s\t%d\t%.1f%%\t%s\n", container.ContainerID, container.Processes,
+				container.CPUPercent, formatBytes(float64(container.MemoryRSSBytes)))
+		}
+	}
+	_ = writer.Flush()
+
+	_, _ = os.Stdout.WriteString(out.String())
+}
+
+func formatBytes(value float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%.0f %s", value, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
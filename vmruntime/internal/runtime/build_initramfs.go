@@ -22,21 +22,8 @@ func (r *Runtime) buildInitRamFS(ctx context.Context) error {
 	}
 	defer os.RemoveAll(tmpDir)
 
-	maskSize, _ := r.config.Network.NetworkCIDR.Mask.Size()
-	initConfig := coretypes.InitConfig{
-		IPAddress:      fmt.Sprintf("%s/%d", r.config.Network.IPAddress.String(), maskSize),
-		MacAddress:     r.config.Network.MacAddress,
-		GatewayAddress: r.config.Network.GatewayAddress.String(),
-		Hostname:       r.config.MachineID,
-		Containers:     make([]coretypes.InitContainerConfig, len(r.config.Containers)),
-	}
-	for index, container := range r.config.Containers {
-		initConfig.Containers[index] = coretypes.InitContainerConfig{
-			ContainerID:   container.ContainerID,
-			ContainerSpec: container.ContainerSpec,
-			Volume:        fmt.Sprintf("/dev/vd%v", string(alphabet[index%len(alphabet)])),
-		}
-	}
+	initConfig := r.newInitConfig()
+	initConfig.DeferContainers = r.config.DeferContainers
 
 	configFile, err := os.Create(r.getInitConfigPath())
 	if err != nil {
@@ -75,6 +62,39 @@ func (r *Runtime) buildInitRamFS(ctx context.Context) error {
 	return nil
 }
 
+func (r *Runtime) newInitConfig() coretypes.InitConfig {
+	maskSize, _ := r.config.Network.NetworkCIDR.Mask.Size()
+	initConfig := coretypes.InitConfig{
+		IPAddress:      fmt.Sprintf("%s/%d", r.config.Network.IPAddress.String(), maskSize),
+		MacAddress:     r.config.Network.MacAddress,
+		GatewayAddress: r.config.Network.GatewayAddress.String(),
+		Hostname:       r.config.MachineID,
+		DNS:            coretypes.InitDNSConfig{SearchDomain: r.config.Network.SearchDomain},
+		Containers:     make([]coretypes.InitContainerConfig, len(r.config.Containers)),
+		Tracing:        r.config.Tracing,
+	}
+	if network := r.config.Network; network.IPv6Prefix != nil {
+		prefixSize, _ := network.IPv6Prefix.Mask.Size()
+		initConfig.IPv6Address = fmt.Sprintf("%s/%d", network.IPv6Address.String(), prefixSize)
+		initConfig.IPv6GatewayAddress = network.IPv6GatewayAddress.String()
+	}
+	for _, nameserver := range r.config.Network.Nameservers {
+		initConfig.DNS.Nameservers = append(initConfig.DNS.Nameservers, nameserver.String())
+	}
+	// a file of the host can not be written from the guest, init spans go to the machine logs instead
+	if initConfig.Tracing.Exporter == coretypes.TracingExporterFile {
+		initConfig.Tracing = coretypes.TracingConfig{Exporter: coretypes.TracingExporterStdout}
+	}
+	for index, container := range r.config.Containers {
+		initConfig.Containers[index] = coretypes.InitContainerConfig{
+			ContainerID:   container.ContainerID,
+			ContainerSpec: container.ContainerSpec,
+			Volume:        fmt.Sprintf("/dev/vd%v", string(alphabet[index%len(alphabet)])),
+		}
+	}
+	return initConfig
+}
+
 func copyFile(src, dst string) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+var (
+	ErrConsoleAttached    = errors.New("a console is already attached")
+	ErrConsoleUnavailable = errors.New("serial console is not connected")
+)
+
+// AttachConsole registers the handler of the serial console output, a single console can be
+// attached at a time since its input is written to the serial socket.
+func (m *logManager) AttachConsole(output func(data []byte)) (func(), error) {
+	m.serialConnLock.Lock()
+	defer m.serialConnLock.Unlock()
+
+	if m.consoleOutput != nil {
+		return nil, ErrConsoleAttached
+	}
+
+	m.consoleOutput = output
+	return func() {
+		m.serialConnLock.Lock()
+		defer m.serialConnLock.Unlock()
+
+		m.consoleOutput = nil
+	}, nil
+}
+
+func (m *logManager) WriteConsoleInput(data []byte) error {
+	m.serialConnLock.Lock()
+	conn := m.serialConn
+	m.serialConnLock.Unlock()
+
+	if conn == nil {
+		return ErrConsoleUnavailable
+	}
+
+	if _, err := conn.Write(data); err != nil {
+		return fmt.Errorf("failed to write to serial socket: %w", err)
+	}
+
+	return nil
+}
+
+func (m *logManager) setSerialConn(conn net.Conn) {
+	m.serialConnLock.Lock()
+	defer m.serialConnLock.Unlock()
+
+	m.serialConn = conn
+}
+
+func (m *logManager) writeConsoleOutput(data []byte) {
+	m.serialConnLock.Lock()
+	output := m.consoleOutput
+	m.serialConnLock.Unlock()
+
+	if output != nil {
+		output(data)
+	}
+}
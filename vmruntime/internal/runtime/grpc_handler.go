@@ -1,26 +1,35 @@
 package runtime
 
 import (
+	"bytes"
 	"connectrpc.com/connect"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/baepo-cloud/baepo-node/core/logmanager"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	"github.com/baepo-cloud/baepo-node/vmruntime/internal/chclient"
 	nodev1pb "github.com/baepo-cloud/baepo-proto/go/baepo/node/v1"
 	"github.com/baepo-cloud/baepo-proto/go/baepo/node/v1/nodev1pbconnect"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 type grpcHandler struct {
 	runtime *Runtime
 }
 
-var _ nodev1pbconnect.RuntimeHandler = (*grpcHandler)(nil)
+var (
+	_ nodev1pbconnect.RuntimeHandler = (*grpcHandler)(nil)
+	_ nodeapi.RuntimeHandler         = (*grpcHandler)(nil)
+)
 
 func (r *Runtime) startGrpcServer() error {
 	unixSocket := filepath.Join(r.config.WorkingDir, "runtime.sock")
@@ -32,8 +41,9 @@ func (r *Runtime) startGrpcServer() error {
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle(nodev1pbconnect.NewRuntimeHandler(&grpcHandler{runtime: r}))
-	r.httpServer = &http.Server{Handler: mux}
+	mux.Handle(nodev1pbconnect.NewRuntimeHandler(&grpcHandler{runtime: r}, tracing.Interceptor()))
+	mux.Handle(nodeapi.NewRuntimeHandler(&grpcHandler{runtime: r}, tracing.Interceptor()))
+	r.httpServer = &http.Server{Handler: mux, Protocols: nodeapi.ServerProtocols()}
 	go r.httpServer.Serve(ln)
 	return nil
 }
@@ -47,6 +57,209 @@ func (h *grpcHandler) GetState(ctx context.Context, req *connect.Request[emptypb
 	}), nil
 }
 
+func (h *grpcHandler) Pause(ctx context.Context, _ *connect.Request[nodeapi.RuntimePauseRequest]) (*connect.Response[nodeapi.RuntimePauseResponse], error) {
+	if err := h.runtime.pauseVM(ctx); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.RuntimePauseResponse{}), nil
+}
+
+func (h *grpcHandler) Resume(ctx context.Context, _ *connect.Request[nodeapi.RuntimeResumeRequest]) (*connect.Response[nodeapi.RuntimeResumeResponse], error) {
+	if err := h.runtime.resumeVM(ctx); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.RuntimeResumeResponse{}), nil
+}
+
+func (h *grpcHandler) Snapshot(ctx context.Context, req *connect.Request[nodeapi.RuntimeSnapshotRequest]) (*connect.Response[nodeapi.RuntimeSnapshotResponse], error) {
+	if err := h.runtime.snapshotVM(ctx, req.Msg.Directory); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.RuntimeSnapshotResponse{}), nil
+}
+
+func (h *grpcHandler) Resize(ctx context.Context, req *connect.Request[nodeapi.RuntimeResizeRequest]) (*connect.Response[nodeapi.RuntimeResizeResponse], error) {
+	err := h.runtime.resizeVM(ctx, req.Msg.Cpus, req.Msg.MemoryMB)
+	if errors.Is(err, ErrInvalidResize) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.RuntimeResizeResponse{}), nil
+}
+
+func (h *grpcHandler) Adopt(ctx context.Context, req *connect.Request[nodeapi.RuntimeAdoptRequest]) (*connect.Response[nodeapi.RuntimeAdoptResponse], error) {
+	if err := h.runtime.adopt(ctx, req.Msg.Config); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&nodeapi.RuntimeAdoptResponse{}), nil
+}
+
+func (h *grpcHandler) GetInitStatus(ctx context.Context, _ *connect.Request[nodeapi.RuntimeGetInitStatusRequest]) (*connect.Response[nodeapi.RuntimeGetInitStatusResponse], error) {
+	initClient, closeInitClient := h.runtime.newInitAPIClient()
+	defer closeInitClient()
+
+	res, err := initClient.GetStatus(ctx, connect.NewRequest(&nodeapi.InitGetStatusRequest{}))
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnavailable, fmt.Errorf("failed to get init status: %w", err))
+	}
+
+	return connect.NewResponse(&nodeapi.RuntimeGetInitStatusResponse{
+		Status: res.Msg,
+	}), nil
+}
+
+func (h *grpcHandler) Exec(ctx context.Context, stream *connect.BidiStream[nodeapi.ExecRequest, nodeapi.ExecResponse]) error {
+	start, err := stream.Receive()
+	if err != nil {
+		return err
+	}
+
+	initClient, closeInitClient := h.runtime.newInitStreamAPIClient()
+	defer closeInitClient()
+
+	return nodeapi.ProxyStream(start, stream, initClient.Exec(ctx))
+}
+
+func (h *grpcHandler) AttachConsole(ctx context.Context, stream *connect.BidiStream[nodeapi.ConsoleRequest, nodeapi.ConsoleResponse]) error {
+	// the serial socket must not block on a slow console, output is dropped when the buffer is full
+	outputs := make(chan []byte, 256)
+	detach, err := h.runtime.logManager.AttachConsole(func(data []byte) {
+		select {
+		case outputs <- bytes.Clone(data):
+		default:
+		}
+	})
+	if errors.Is(err, ErrConsoleAttached) {
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	} else if err != nil {
+		return err
+	}
+	defer detach()
+
+	inputErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Receive()
+			if err != nil {
+				inputErr <- err
+				return
+			}
+
+			if len(req.Input) == 0 {
+				continue
+			} else if err = h.runtime.logManager.WriteConsoleInput(req.Input); err != nil {
+				inputErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err = <-inputErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			} else if errors.Is(err, ErrConsoleUnavailable) {
+				return connect.NewError(connect.CodeUnavailable, err)
+			}
+			return err
+		case data := <-outputs:
+			if err = stream.Send(&nodeapi.ConsoleResponse{Output: data}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (h *grpcHandler) UploadArchive(ctx context.Context, stream *connect.ClientStream[nodeapi.UploadArchiveRequest]) (*connect.Response[nodeapi.UploadArchiveResponse], error) {
+	initClient, closeInitClient := h.runtime.newInitStreamAPIClient()
+	defer closeInitClient()
+
+	initStream := initClient.UploadArchive(ctx)
+	for stream.Receive() {
+		if err := initStream.Send(stream.Msg()); err != nil {
+			break
+		}
+	}
+	if err := stream.Err(); err != nil {
+		_, _ = initStream.CloseAndReceive()
+		return nil, err
+	}
+
+	return initStream.CloseAndReceive()
+}
+
+func (h *grpcHandler) DownloadArchive(ctx context.Context, req *connect.Request[nodeapi.DownloadArchiveRequest], stream *connect.ServerStream[nodeapi.DownloadArchiveResponse]) error {
+	initClient, closeInitClient := h.runtime.newInitStreamAPIClient()
+	defer closeInitClient()
+
+	initStream, err := initClient.DownloadArchive(ctx, connect.NewRequest(req.Msg))
+	if err != nil {
+		return err
+	}
+	defer initStream.Close()
+
+	for initStream.Receive() {
+		if err = stream.Send(initStream.Msg()); err != nil {
+			return err
+		}
+	}
+	return initStream.Err()
+}
+
+func (h *grpcHandler) PortForward(ctx context.Context, stream *connect.BidiStream[nodeapi.PortForwardRequest, nodeapi.PortForwardResponse]) error {
+	first, err := stream.Receive()
+	if err != nil {
+		return err
+	}
+
+	// the tunnel goes through the vsock connection of init, not the network of the guest
+	initClient, closeInitClient := h.runtime.newInitStreamAPIClient()
+	defer closeInitClient()
+
+	return nodeapi.ProxyStream(first, stream, initClient.PortForward(ctx))
+}
+
+func (h *grpcHandler) GetStats(ctx context.Context, req *connect.Request[nodeapi.RuntimeGetStatsRequest], stream *connect.ServerStream[nodeapi.MachineStats]) error {
+	interval := nodeapi.DefaultStatsInterval
+	if req.Msg.IntervalSeconds > 0 {
+		interval = time.Duration(req.Msg.IntervalSeconds) * time.Second
+	}
+
+	previous, err := h.runtime.sampleStats(ctx, interval)
+	if err != nil {
+		return connect.NewError(connect.CodeUnavailable, err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current, err := h.runtime.sampleStats(ctx, interval)
+			if err != nil {
+				return connect.NewError(connect.CodeUnavailable, err)
+			}
+
+			if err = stream.Send(newMachineStats(previous, current)); err != nil {
+				return err
+			}
+			previous = current
+		}
+	}
+}
+
 func (h *grpcHandler) GetLogs(ctx context.Context, req *connect.Request[nodev1pb.RuntimeGetLogsRequest], stream *connect.ServerStream[nodev1pb.RuntimeGetLogsResponse]) error {
 	initialLogs, err := h.runtime.logManager.ReadLogs(ctx)
 	if err != nil {
@@ -1,7 +1,7 @@
 package runtime
 
 import (
-	"bufio"
+	"bytes"
 	"connectrpc.com/connect"
 	"context"
 	"fmt"
@@ -16,6 +16,10 @@ import (
 	"time"
 )
 
+// maxSerialLineLength is the length past which a serial line is logged without waiting for its
+// end, a guest writing without newlines would otherwise grow it forever.
+const maxSerialLineLength = 16 * 1024
+
 type logManager struct {
 	manager         *logmanager.Manager
 	runtime         *Runtime
@@ -25,6 +29,11 @@ type logManager struct {
 	containerLogSeq atomic.Uint64
 	logHandlers     map[string]func(entry logmanager.Entry)
 	logHandlersLock sync.RWMutex
+	// the serial socket accepts a single client, its output is shared between the log recorder
+	// and the attached console
+	serialConn     net.Conn
+	consoleOutput  func(data []byte)
+	serialConnLock sync.Mutex
 }
 
 func newLogManager(runtime *Runtime) *logManager {
@@ -54,22 +63,51 @@ func (m *logManager) ListenSerialSocket() {
 			return err
 		}
 
-		scanner := bufio.NewScanner(c)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if line == "" {
-				continue
-			}
+		m.setSerialConn(c)
+		defer m.setSerialConn(nil)
 
-			_ = m.writeLog(logmanager.Entry{
-				Source:  logmanager.MachineLogEntrySource,
-				Message: line,
-			})
+		var line []byte
+		buf := make([]byte, 4096)
+		for {
+			n, err := c.Read(buf)
+			if n > 0 {
+				m.writeConsoleOutput(buf[:n])
+				line = append(line, buf[:n]...)
+				for {
+					index := bytes.IndexByte(line, '\n')
+					if index < 0 {
+						break
+					}
+
+					m.writeSerialLine(line[:index])
+					line = line[index+1:]
+				}
+				for len(line) >= maxSerialLineLength {
+					m.writeSerialLine(line[:maxSerialLineLength])
+					line = line[maxSerialLineLength:]
+				}
+			}
+			if err != nil {
+				break
+			}
 		}
+		m.writeSerialLine(line)
 		return c.Close()
 	})
 }
 
+func (m *logManager) writeSerialLine(line []byte) {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) == 0 {
+		return
+	}
+
+	_ = m.writeLog(logmanager.Entry{
+		Source:  logmanager.MachineLogEntrySource,
+		Message: string(line),
+	})
+}
+
 func (m *logManager) ListenInitLogs() {
 	m.startListener(func() error {
 		client, closeClient := m.runtime.newInitClient()
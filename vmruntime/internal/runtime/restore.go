@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	coretypes "github.com/baepo-cloud/baepo-node/core/types"
+	"github.com/baepo-cloud/baepo-node/vmruntime/internal/chclient"
+	"log/slog"
+	"os"
+	"path"
+	"time"
+)
+
+// prepareRestoreDirectory copies a vm snapshot into the working directory, rewriting the parts of
+// its config that belong to the machine being restored: disk paths, tap device, mac address and
+// sockets. The memory and device state files are linked, not copied.
+func (r *Runtime) prepareRestoreDirectory(source string) (string, error) {
+	restoreDir := r.getRestoreDirectoryPath()
+	_ = os.RemoveAll(restoreDir)
+	if err := os.MkdirAll(restoreDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create restore directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == "config.json" {
+			continue
+		} else if err = os.Symlink(path.Join(source, entry.Name()), path.Join(restoreDir, entry.Name())); err != nil {
+			return "", fmt.Errorf("failed to link snapshot file %s: %w", entry.Name(), err)
+		}
+	}
+
+	configBytes, err := os.ReadFile(path.Join(source, "config.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read snapshot config: %w", err)
+	}
+
+	var vmConfig map[string]any
+	if err = json.Unmarshal(configBytes, &vmConfig); err != nil {
+		return "", fmt.Errorf("failed to decode snapshot config: %w", err)
+	}
+
+	if disks, ok := vmConfig["disks"].([]any); ok {
+		if len(disks) != len(r.config.Containers) {
+			return "", fmt.Errorf("snapshot has %d disks but machine has %d containers", len(disks), len(r.config.Containers))
+		}
+
+		for index, disk := range disks {
+			if diskConfig, ok := disk.(map[string]any); ok {
+				diskConfig["path"] = r.config.Containers[index].VolumePath
+			}
+		}
+	}
+	if nets, ok := vmConfig["net"].([]any); ok && len(nets) > 0 {
+		if netConfig, ok := nets[0].(map[string]any); ok {
+			netConfig["tap"] = r.config.Network.InterfaceName
+			netConfig["mac"] = r.config.Network.MacAddress
+		}
+	}
+	if vsockConfig, ok := vmConfig["vsock"].(map[string]any); ok {
+		vsockConfig["socket"] = r.getInitDaemonSocketPath()
+	}
+	if serialConfig, ok := vmConfig["serial"].(map[string]any); ok {
+		serialConfig["socket"] = r.logManager.GetSerialSocketPath()
+	}
+
+	configBytes, err = json.Marshal(vmConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode snapshot config: %w", err)
+	} else if err = os.WriteFile(path.Join(restoreDir, "config.json"), configBytes, 0644); err != nil {
+		return "", fmt.Errorf("failed to write snapshot config: %w", err)
+	}
+
+	return restoreDir, nil
+}
+
+// configureInit hands the machine identity to the init of a restored vm, which still carries the
+// identity of the vm the snapshot was taken from.
+func (r *Runtime) configureInit(ctx context.Context) error {
+	initClient, closeInitClient := r.newInitAPIClient()
+	defer closeInitClient()
+
+	var err error
+	for retry := 0; retry < 50; retry++ {
+		_, err = initClient.Configure(ctx, connect.NewRequest(&nodeapi.InitConfigureRequest{
+			Config: r.newInitConfig(),
+		}))
+		if err == nil {
+			return nil
+		}
+
+		slog.Debug("failed to configure init, retrying", slog.Int("retry", retry), slog.Any("error", err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	return fmt.Errorf("failed to configure init: %w", err)
+}
+
+// adopt hands a vm restored with deferred containers over to a machine. The vm keeps the disks and
+// the tap device it was restored with, the machine is created with them, and the init is configured
+// with the identity and the containers of the machine.
+func (r *Runtime) adopt(ctx context.Context, config coretypes.RuntimeConfig) error {
+	if !r.config.DeferContainers {
+		return errors.New("vm is already configured")
+	}
+
+	r.config.RuntimeConfig = config
+	if res, err := r.vmmClient.GetVmInfoWithResponse(ctx); err != nil {
+		return fmt.Errorf("failed to get vm info: %w", err)
+	} else if res.JSON200 != nil && res.JSON200.State == chclient.Paused {
+		if err = r.resumeVM(ctx); err != nil {
+			return err
+		}
+	}
+
+	return r.configureInit(ctx)
+}
@@ -3,10 +3,13 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	"github.com/baepo-cloud/baepo-node/core/vsock"
 	"github.com/baepo-cloud/baepo-node/vmruntime/internal/chclient"
 	"github.com/baepo-cloud/baepo-proto/go/baepo/node/v1/nodev1pbconnect"
+	"go.opentelemetry.io/otel/attribute"
 	"net"
 	"net/http"
 	"os/exec"
@@ -59,17 +62,42 @@ func (r *Runtime) Start(ctx context.Context) error {
 	}
 
 	r.logManager = newLogManager(r)
+	bootCtx, span := tracing.Start(ctx, "vmruntime.boot", attribute.String("machine.id", r.config.MachineID))
+	err := r.boot(bootCtx)
+	tracing.End(span, err)
+	if err != nil {
+		return err
+	}
+
+	return r.vmmCmd.Wait()
+}
+
+func (r *Runtime) boot(ctx context.Context) error {
+	if r.config.RestoreFrom != nil {
+		restoreDir, err := r.prepareRestoreDirectory(*r.config.RestoreFrom)
+		if err != nil {
+			return err
+		} else if err = r.startHypervisor(ctx); err != nil {
+			return err
+		} else if err = r.restoreVM(ctx, restoreDir); err != nil {
+			return err
+		} else if r.config.DeferContainers {
+			// the vm waits to be adopted by a machine, which configures the init
+			return nil
+		}
+
+		return r.configureInit(ctx)
+	}
+
 	if err := r.buildInitRamFS(ctx); err != nil {
 		return err
 	} else if err = r.startHypervisor(ctx); err != nil {
 		return err
 	} else if err = r.createVM(ctx); err != nil {
 		return err
-	} else if err = r.bootVM(ctx); err != nil {
-		return err
 	}
 
-	return r.vmmCmd.Wait()
+	return r.bootVM(ctx)
 }
 
 func (r *Runtime) Stop(ctx context.Context) error {
@@ -97,23 +125,43 @@ func (r *Runtime) ForceStop(ctx context.Context) error {
 }
 
 func (r *Runtime) newInitClient() (nodev1pbconnect.InitClient, func()) {
+	httpClient, closeConns := r.newInitHTTPClient()
+	return nodev1pbconnect.NewInitClient(httpClient, "http://init", tracing.Interceptor()), closeConns
+}
+
+func (r *Runtime) newInitAPIClient() (nodeapi.InitClient, func()) {
+	httpClient, closeConns := r.newInitHTTPClient()
+	return nodeapi.NewInitClient(httpClient, "http://init", tracing.Interceptor()), closeConns
+}
+
+// newInitStreamAPIClient returns a client speaking http/2, which bidirectional streams require.
+// Streams have no response header timeout, they are bounded by the request context.
+func (r *Runtime) newInitStreamAPIClient() (nodeapi.InitClient, func()) {
+	httpClient, closeConns := r.newInitHTTPClientWithTransport(&http.Transport{
+		Protocols: nodeapi.StreamClientProtocols(),
+	})
+	return nodeapi.NewInitClient(httpClient, "http://init", tracing.Interceptor()), closeConns
+}
+
+func (r *Runtime) newInitHTTPClient() (*http.Client, func()) {
+	return r.newInitHTTPClientWithTransport(&http.Transport{
+		IdleConnTimeout:       10 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+	})
+}
+
+func (r *Runtime) newInitHTTPClientWithTransport(transport *http.Transport) (*http.Client, func()) {
 	var conns []net.Conn
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
-				conn, err := vsock.DialContext(ctx, r.getInitDaemonSocketPath(), coretypes.InitServerPort)
-				if err != nil {
-					return nil, err
-				}
-
-				conns = append(conns, conn)
-				return conn, nil
-			},
-			IdleConnTimeout:       10 * time.Second,
-			ResponseHeaderTimeout: 5 * time.Second,
-		},
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		conn, err := vsock.DialContext(ctx, r.getInitDaemonSocketPath(), coretypes.InitServerPort)
+		if err != nil {
+			return nil, err
+		}
+
+		conns = append(conns, conn)
+		return conn, nil
 	}
-	return nodev1pbconnect.NewInitClient(httpClient, "http://init"), func() {
+	return &http.Client{Transport: transport}, func() {
 		for _, conn := range conns {
 			_ = conn.Close()
 		}
@@ -132,6 +180,10 @@ func (r *Runtime) getInitRamFSPath() string {
 	return path.Join(r.config.WorkingDir, "initramfs.gz")
 }
 
+func (r *Runtime) getRestoreDirectoryPath() string {
+	return path.Join(r.config.WorkingDir, "restore")
+}
+
 func (r *Runtime) getInitConfigPath() string {
 	return path.Join(r.config.WorkingDir, "initconfig.json")
 }
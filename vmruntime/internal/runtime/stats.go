@@ -0,0 +1,155 @@
+package runtime
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/nodeapi"
+	"github.com/baepo-cloud/baepo-node/vmruntime/internal/chclient"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// statsSample holds the cumulative counters of the vm at a point in time, rates are computed
+// between two samples.
+type statsSample struct {
+	timestamp time.Time
+	info      *chclient.VmInfo
+	counters  chclient.VmCounters
+	guest     *nodeapi.InitGetStatsResponse
+}
+
+// sampleStats reads the counters of the vmm and of init. Init is given the interval to answer, it
+// does not while the vm is paused and the sample is then left without guest stats.
+func (r *Runtime) sampleStats(ctx context.Context, interval time.Duration) (*statsSample, error) {
+	sample := &statsSample{timestamp: time.Now()}
+
+	infoRes, err := r.vmmClient.GetVmInfoWithResponse(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vm info: %w", err)
+	} else if infoRes.JSON200 == nil {
+		return nil, fmt.Errorf("failed to get vm info (status code %v): %v", infoRes.StatusCode(), string(infoRes.Body))
+	}
+	sample.info = infoRes.JSON200
+
+	countersRes, err := r.vmmClient.GetVmCountersWithResponse(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vm counters: %w", err)
+	} else if statusCode := countersRes.StatusCode(); statusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get vm counters (status code %v): %v", statusCode, string(countersRes.Body))
+	} else if countersRes.JSON200 != nil {
+		sample.counters = *countersRes.JSON200
+	}
+
+	if sample.info.State != chclient.Running {
+		return sample, nil
+	}
+
+	initCtx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	initClient, closeInitClient := r.newInitAPIClient()
+	defer closeInitClient()
+
+	guestRes, err := initClient.GetStats(initCtx, connect.NewRequest(&nodeapi.InitGetStatsRequest{}))
+	if err != nil {
+		slog.Debug("failed to get guest stats", slog.Any("error", err))
+	} else {
+		sample.guest = guestRes.Msg
+	}
+
+	return sample, nil
+}
+
+// newMachineStats computes the stats of the interval between two samples.
+func newMachineStats(previous, current *statsSample) *nodeapi.MachineStats {
+	elapsed := current.timestamp.Sub(previous.timestamp)
+	stats := &nodeapi.MachineStats{
+		Timestamp: current.timestamp,
+		Cpus:      uint32(current.info.Config.Cpus.BootVcpus),
+	}
+	if current.info.MemoryActualSize != nil {
+		stats.MemoryActualBytes = uint64(*current.info.MemoryActualSize)
+	}
+
+	// devices are identified by their counters, cloud-hypervisor names them after their config id
+	for _, name := range slices.Sorted(maps.Keys(current.counters)) {
+		counters, previousCounters := current.counters[name], previous.counters[name]
+		name = strings.TrimPrefix(name, "_")
+		if _, ok := counters["read_bytes"]; ok {
+			stats.Disks = append(stats.Disks, &nodeapi.MachineDiskStats{
+				Name:                name,
+				ReadBytes:           uint64(counters["read_bytes"]),
+				WriteBytes:          uint64(counters["write_bytes"]),
+				ReadOps:             uint64(counters["read_ops"]),
+				WriteOps:            uint64(counters["write_ops"]),
+				ReadBytesPerSecond:  perSecond(counters["read_bytes"]-previousCounters["read_bytes"], elapsed),
+				WriteBytesPerSecond: perSecond(counters["write_bytes"]-previousCounters["write_bytes"], elapsed),
+			})
+		} else if _, ok = counters["rx_bytes"]; ok {
+			stats.Networks = append(stats.Networks, &nodeapi.MachineNetworkStats{
+				Name:             name,
+				RxBytes:          uint64(counters["rx_bytes"]),
+				TxBytes:          uint64(counters["tx_bytes"]),
+				RxFrames:         uint64(counters["rx_frames"]),
+				TxFrames:         uint64(counters["tx_frames"]),
+				RxBytesPerSecond: perSecond(counters["rx_bytes"]-previousCounters["rx_bytes"], elapsed),
+				TxBytesPerSecond: perSecond(counters["tx_bytes"]-previousCounters["tx_bytes"], elapsed),
+			})
+		}
+	}
+
+	if current.guest == nil {
+		return stats
+	}
+
+	stats.MemoryTotalBytes = current.guest.MemoryTotalBytes
+	stats.MemoryAvailableBytes = current.guest.MemoryAvailableBytes
+	if previous.guest != nil {
+		stats.CPUPercent = cpuPercent(previous.guest.CPUTimeUs, current.guest.CPUTimeUs, elapsed)
+	}
+
+	previousContainers := map[string]*nodeapi.InitContainerStats{}
+	if previous.guest != nil {
+		for _, container := range previous.guest.Containers {
+			previousContainers[container.ContainerID] = container
+		}
+	}
+	for _, container := range current.guest.Containers {
+		containerStats := &nodeapi.MachineContainerStats{
+			ContainerID:    container.ContainerID,
+			Processes:      container.Processes,
+			MemoryRSSBytes: container.MemoryRSSBytes,
+		}
+		if previousContainer, ok := previousContainers[container.ContainerID]; ok {
+			containerStats.CPUPercent = cpuPercent(previousContainer.CPUTimeUs, container.CPUTimeUs, elapsed)
+		}
+		stats.Containers = append(stats.Containers, containerStats)
+	}
+	slices.SortFunc(stats.Containers, func(a, b *nodeapi.MachineContainerStats) int {
+		return strings.Compare(a.ContainerID, b.ContainerID)
+	})
+
+	return stats
+}
+
+// cpuPercent returns 0 when the counter went backward, as when a container restarted.
+func cpuPercent(previousUs, currentUs uint64, elapsed time.Duration) float64 {
+	if currentUs < previousUs || elapsed <= 0 {
+		return 0
+	}
+
+	return float64(currentUs-previousUs) / float64(elapsed.Microseconds()) * 100
+}
+
+func perSecond(delta int64, elapsed time.Duration) float64 {
+	if delta < 0 || elapsed <= 0 {
+		return 0
+	}
+
+	return float64(delta) / elapsed.Seconds()
+}
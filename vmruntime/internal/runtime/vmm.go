@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/baepo-cloud/baepo-node/core/typeutil"
 	"github.com/baepo-cloud/baepo-node/vmruntime/internal/chclient"
@@ -13,6 +14,8 @@ import (
 	"time"
 )
 
+var ErrInvalidResize = errors.New("invalid resize")
+
 func (r *Runtime) startHypervisor(ctx context.Context) error {
 	if _, err := os.Stat(r.getHypervisorSocketPath()); err == nil {
 		_ = r.stopHypervisor(ctx)
@@ -53,15 +56,22 @@ func (r *Runtime) createVM(ctx context.Context) error {
 		}
 	}
 
+	memoryConfig := &chclient.MemoryConfig{
+		Size: int64(r.config.MemoryMB * 1024 * 1024), // convert Mib to bytes
+	}
+	if r.config.HotplugMemoryMB > 0 {
+		// virtio-mem lets the memory shrink back, acpi hotplug can only grow
+		memoryConfig.HotplugMethod = typeutil.Ptr("VirtioMem")
+		memoryConfig.HotplugSize = typeutil.Ptr(int64(r.config.HotplugMemoryMB * 1024 * 1024))
+	}
+
 	_, err := r.vmmClient.CreateVM(ctx, chclient.VmConfig{
 		Cpus: &chclient.CpusConfig{
 			BootVcpus: int(r.config.Cpus),
-			MaxVcpus:  int(r.config.Cpus),
+			MaxVcpus:  int(r.getMaxCpus()),
 		},
-		Memory: &chclient.MemoryConfig{
-			Size: int64(r.config.MemoryMB * 1024 * 1024), // convert Mib to bytes
-		},
-		Disks: &disksConfig,
+		Memory: memoryConfig,
+		Disks:  &disksConfig,
 		Net: &[]chclient.NetConfig{
 			{
 				Tap:       &r.config.Network.InterfaceName,
@@ -110,6 +120,88 @@ func (r *Runtime) bootVM(ctx context.Context) error {
 	return nil
 }
 
+func (r *Runtime) pauseVM(ctx context.Context) error {
+	res, err := r.vmmClient.PauseVMWithResponse(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to pause vm: %w", err)
+	} else if statusCode := res.StatusCode(); statusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to pause vm (status code %v): %v", statusCode, string(res.Body))
+	}
+
+	return nil
+}
+
+func (r *Runtime) resumeVM(ctx context.Context) error {
+	res, err := r.vmmClient.ResumeVMWithResponse(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to resume vm: %w", err)
+	} else if statusCode := res.StatusCode(); statusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to resume vm (status code %v): %v", statusCode, string(res.Body))
+	}
+
+	return nil
+}
+
+// resizeVM hot plugs vcpus and memory, the vm can not go beyond the limits it was created with.
+func (r *Runtime) resizeVM(ctx context.Context, cpus uint32, memoryMB uint64) error {
+	if cpus == 0 || cpus > r.getMaxCpus() {
+		return fmt.Errorf("%w: cpus must be between 1 and %d", ErrInvalidResize, r.getMaxCpus())
+	} else if memoryMB < r.config.MemoryMB || memoryMB > r.config.MemoryMB+r.config.HotplugMemoryMB {
+		return fmt.Errorf("%w: memory must be between %d and %d MB", ErrInvalidResize, r.config.MemoryMB,
+			r.config.MemoryMB+r.config.HotplugMemoryMB)
+	}
+
+	res, err := r.vmmClient.PutVmResizeWithResponse(ctx, chclient.VmResize{
+		DesiredVcpus: typeutil.Ptr(int(cpus)),
+		DesiredRam:   typeutil.Ptr(int64(memoryMB * 1024 * 1024)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resize vm: %w", err)
+	} else if statusCode := res.StatusCode(); statusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to resize vm (status code %v): %v", statusCode, string(res.Body))
+	}
+
+	return nil
+}
+
+func (r *Runtime) getMaxCpus() uint32 {
+	return max(r.config.MaxCpus, r.config.Cpus)
+}
+
+func (r *Runtime) snapshotVM(ctx context.Context, directory string) error {
+	res, err := r.vmmClient.PutVmSnapshotWithResponse(ctx, chclient.VmSnapshotConfig{
+		DestinationUrl: typeutil.Ptr("file://" + directory),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to snapshot vm: %w", err)
+	} else if statusCode := res.StatusCode(); statusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to snapshot vm (status code %v): %v", statusCode, string(res.Body))
+	}
+
+	return nil
+}
+
+// restoreVM recreates the vm from a snapshot, the vm is left paused by cloud hypervisor and must
+// be resumed once restored.
+func (r *Runtime) restoreVM(ctx context.Context, directory string) error {
+	res, err := r.vmmClient.PutVmRestoreWithResponse(ctx, chclient.RestoreConfig{
+		SourceUrl: "file://" + directory,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore vm: %w", err)
+	} else if statusCode := res.StatusCode(); statusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to restore vm (status code %v): %v", statusCode, string(res.Body))
+	}
+
+	if err = r.resumeVM(ctx); err != nil {
+		return err
+	}
+
+	r.logManager.ListenSerialSocket()
+	r.logManager.ListenInitLogs()
+	return nil
+}
+
 func (r *Runtime) terminateVM(ctx context.Context) error {
 	_, err := r.vmmClient.ShutdownVMWithResponse(ctx)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/baepo-cloud/baepo-node/core/tracing"
 	coretypes "github.com/baepo-cloud/baepo-node/core/types"
 	"github.com/baepo-cloud/baepo-node/vmruntime/internal/runtime"
 	"os"
@@ -31,8 +32,20 @@ func main() {
 		VMLinux:               os.Getenv("VMRUNTIME_VM_LINUX"),
 		Debug:                 os.Getenv("DEBUG") == "true",
 	})
-	ctx, cancel := context.WithCancel(context.Background())
+	shutdownTracing, err := tracing.Setup(context.Background(), "baepo-vmruntime", config.Tracing)
+	if err != nil {
+		panic(err)
+	}
+
+	// the boot spans are children of the node agent span which started the runtime
+	ctx, cancel := context.WithCancel(tracing.Extract(context.Background(), config.TraceContext))
 	defer cancel()
+	exit := func(code int) {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		_ = shutdownTracing(flushCtx)
+		os.Exit(code)
+	}
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGKILL)
@@ -52,7 +65,7 @@ func main() {
 	case err = <-errChan:
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stderr, "runtime error: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 	case sig := <-sigChan:
 		switch sig {
@@ -62,10 +75,10 @@ func main() {
 			defer stopCancelCtx()
 			if err = r.Stop(stopCtx); err != nil {
 				_, _ = fmt.Fprintf(os.Stderr, "error during graceful shutdown: %v\n", err)
-				os.Exit(1)
+				exit(1)
 			}
 			fmt.Println("graceful shutdown completed")
-			os.Exit(0)
+			exit(0)
 		case syscall.SIGKILL:
 			fmt.Println("force killing")
 			cancel()
@@ -74,9 +87,10 @@ func main() {
 			if err = r.ForceStop(stopCtx); err != nil {
 				_, _ = fmt.Fprintf(os.Stderr, "error during force stop: %v\n", err)
 			}
-			os.Exit(1)
+			exit(1)
 		}
 	}
+	exit(0)
 }
 
 func getRuntimeConfig() (*coretypes.RuntimeConfig, error) {